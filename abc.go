@@ -0,0 +1,975 @@
+package tonacity
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ABC is a plain text notation widely used for folk tunes. Each tune starts with header fields, one per line, such as "T:" for the
+// title, and ends the header with "K:" for the key. The music follows, with notes written as letters: "C" is middle C, "c" is the
+// octave above, and each ' raises a note another octave and each , lowers it. Note lengths are multiples of the unit note length set
+// by "L:", e.g. "C2" or "C/2". Only what affects pitch and timing is read; decorations, slurs, grace notes and lyrics are skipped.
+
+// AbcTempo The tempo of a tune, given as a number of beats per minute of a beat of some length, e.g. 120 quarter notes.
+type AbcTempo struct {
+	Beat           Duration
+	BeatsPerMinute int
+	Text           string // Words describing the tempo, e.g. "Allegro", if there are any
+}
+
+// AbcChordSymbol A chord name written above the music, e.g. "Am", along with when it is first played.
+type AbcChordSymbol struct {
+	Start Duration
+	Name  string
+}
+
+// AbcTune A single ABC tune, with repeats played out so its notes are in the order they are heard.
+type AbcTune struct {
+	Reference  int            // The number from the X: field
+	Title      string         // The first T: field
+	Time       *TimeSignature // The meter from the header, or nil if it is unmetered
+	UnitLength Duration       // The length of a note written without a length, e.g. 1/8
+	Tempo      *AbcTempo      // The tempo, or nil if none is given
+	Key        *KeySignature  // The key from the header, or nil for K:none
+	Pickup     Duration       // The length of the upbeat before the first bar line, or zero if the tune starts on a full bar
+	Notes      []TimedNote    // The notes and rests, in order
+	Chords     []AbcChordSymbol
+}
+
+// abcBar A bar line, along with any repeat marks and endings written on it.
+type abcBar struct {
+	startRepeat bool
+	endRepeat   bool
+	double      bool  // The bar line ends a section, e.g. "||" or "|]"
+	endings     []int // The passes through a repeat the music after this bar line is played on, or nil for every pass
+}
+
+// abcItem Something written in the body of a tune: a note, chord or rest, a chord symbol, or a bar line.
+type abcItem struct {
+	pitches  []SpelledPitch // The pitches of a note or chord, or nil for a rest
+	written  Duration       // The length as written, or zero if the item isn't a note
+	ratio    Duration       // What the written length is scaled by to give how long the note lasts, which differs inside a tuplet
+	wholeBar bool           // A rest lasting a whole bar, whatever its length
+	chord    string         // A chord symbol
+	bar      *abcBar
+}
+
+// note Returns the note that an item holding a note, chord or rest is played as.
+func (item *abcItem) note() Note {
+	if item.wholeBar {
+		rest := MakeRest(0, 0)
+		rest.SetDuration(item.written)
+		return *rest
+	}
+	note := makeNoteOfDuration(item.written, item.pitches...)
+	if item.ratio.Cmp(MakeDuration(1, 1)) != 0 {
+		note.SetDuration(item.written.Mul(item.ratio))
+	}
+	return *note
+}
+
+type abcParser struct {
+	tune    *AbcTune
+	inBody  bool
+	unit    Duration
+	time    *TimeSignature
+	key     *KeySignature
+	items   []abcItem
+	altered map[int]int // Alteration in force at each step, from accidentals earlier in the bar
+	last    int         // The index of the last note, or -1 if there hasn't been one
+	tied    bool        // Whether the last note is tied to the next
+	broken  Duration    // What the next note's length is scaled by after a broken rhythm, or zero if it isn't
+	tuplet  struct {
+		remaining int      // The number of notes still to come in the tuplet
+		ratio     Duration // What the length of each is scaled by
+	}
+}
+
+func newAbcParser() *abcParser {
+	return &abcParser{tune: &AbcTune{}, altered: make(map[int]int), last: -1}
+}
+
+// ReadAbc Reads every tune in an ABC file. Tunes start with an X: field and end at a blank line.
+func ReadAbc(r io.Reader) ([]*AbcTune, error) {
+	tunes := make([]*AbcTune, 0)
+	var p *abcParser
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		if p == nil {
+			if !strings.HasPrefix(text, "X:") {
+				// Anything between tunes is free text
+				continue
+			}
+			p = newAbcParser()
+		}
+		var err error
+		switch {
+		case strings.TrimSpace(text) == "":
+			tunes = append(tunes, p.finish())
+			p = nil
+		case strings.HasPrefix(text, "%"):
+			// A comment, or a directive for typesetting
+		case len(text) >= 2 && text[1] == ':' && isAbcFieldName(text[0]):
+			err = p.field(text[0], strings.TrimSpace(text[2:]))
+		case !p.inBody:
+			err = errors.New("music before the K: field")
+		default:
+			err = p.parseMusic(text)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("abc: line %d: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("abc: %v", err)
+	}
+	if p != nil {
+		tunes = append(tunes, p.finish())
+	}
+	return tunes, nil
+}
+
+func isAbcFieldName(c byte) bool {
+	return c >= 'A' && c <= 'Z' || c >= 'a' && c <= 'z'
+}
+
+// field Reads a header field, or a field changing the key, meter or unit note length part way through the tune.
+func (p *abcParser) field(name byte, value string) error {
+	// Fields can be followed by a comment
+	if i := strings.IndexByte(value, '%'); i >= 0 {
+		value = strings.TrimSpace(value[:i])
+	}
+	switch name {
+	case 'X':
+		n, err := strconv.Atoi(value)
+		if err != nil {
+			return fmt.Errorf("invalid reference number %q", value)
+		}
+		p.tune.Reference = n
+	case 'T':
+		if p.tune.Title == "" {
+			p.tune.Title = value
+		}
+	case 'M':
+		time, err := parseAbcMeter(value)
+		if err != nil {
+			return err
+		}
+		p.time = time
+		if !p.inBody {
+			p.tune.Time = time
+		}
+	case 'L':
+		unit, err := parseAbcFraction(value)
+		if err != nil {
+			return err
+		}
+		p.unit = unit
+	case 'Q':
+		tempo, err := parseAbcTempo(value)
+		if err != nil {
+			return err
+		}
+		p.tune.Tempo = tempo
+	case 'K':
+		key, err := parseAbcKey(value)
+		if err != nil {
+			return err
+		}
+		p.key = key
+		if !p.inBody {
+			p.tune.Key = key
+			p.inBody = true
+		}
+	}
+	return nil
+}
+
+// finish Plays the tune through, following its repeats, and returns it.
+func (p *abcParser) finish() *AbcTune {
+	tune := p.tune
+	tune.UnitLength = p.defaultUnit()
+	if tune.Tempo != nil && tune.Tempo.Beat.IsZero() {
+		tune.Tempo.Beat = tune.UnitLength
+	}
+
+	var now Duration
+	repeated := make(map[int]bool)
+	repeatStart, pass := 0, 1
+	skipping, finishedRepeat, barred := false, false, false
+	for i := 0; i < len(p.items); i++ {
+		item := &p.items[i]
+		switch {
+		case item.bar != nil:
+			bar := item.bar
+			if !barred && tune.Time != nil && !now.IsZero() && now.Cmp(tune.Time.Duration()) < 0 {
+				// The first bar line comes before a full bar, so the tune starts with an upbeat
+				tune.Pickup = now
+			}
+			barred = true
+			if skipping && (bar.double || bar.startRepeat || bar.endRepeat) {
+				skipping = false
+			}
+			if bar.endRepeat && !repeated[i] {
+				repeated[i] = true
+				pass++
+				i = repeatStart - 1
+				continue
+			}
+			if bar.endings != nil {
+				skipping = true
+				for _, ending := range bar.endings {
+					if ending == pass {
+						skipping = false
+					}
+				}
+			} else if finishedRepeat || bar.startRepeat {
+				pass = 1
+			}
+			finishedRepeat = bar.endRepeat
+			if bar.double || bar.startRepeat || bar.endRepeat {
+				repeatStart = i + 1
+			}
+		case skipping:
+			// In an ending for another pass
+		case item.chord != "":
+			tune.Chords = append(tune.Chords, AbcChordSymbol{now, item.chord})
+		default:
+			note := item.note()
+			tune.Notes = append(tune.Notes, TimedNote{now, note})
+			now = now.Add(note.duration)
+		}
+	}
+	return tune
+}
+
+// defaultUnit Returns the unit note length, which if it isn't given is a sixteenth in meters shorter than 3/4, and an eighth otherwise.
+func (p *abcParser) defaultUnit() Duration {
+	if !p.unit.IsZero() {
+		return p.unit
+	}
+	if p.time != nil && p.time.Duration().Cmp(MakeDuration(3, 4)) < 0 {
+		return MakeDuration(1, 16)
+	}
+	return MakeDuration(1, 8)
+}
+
+// parseAbcMeter Reads a meter, e.g. "6/8", "C" for common time, "C|" for cut time, or "none". Additive meters like "(2+2+3)/8" are
+// read as grouped time signatures.
+func parseAbcMeter(value string) (*TimeSignature, error) {
+	switch value {
+	case "none", "":
+		return nil, nil
+	case "C":
+		return MakeTimeSignature(4, 4), nil
+	case "C|":
+		return MakeTimeSignature(2, 2), nil
+	}
+	parts := strings.SplitN(value, "/", 2)
+	if len(parts) == 2 {
+		var groups []int
+		for _, term := range strings.Split(strings.Trim(strings.TrimSpace(parts[0]), "()"), "+") {
+			n, err := strconv.Atoi(strings.TrimSpace(term))
+			if err != nil {
+				groups = nil
+				break
+			}
+			groups = append(groups, n)
+		}
+		value, err := strconv.Atoi(strings.TrimSpace(parts[1]))
+		if err == nil && len(groups) > 0 {
+			time := MakeTimeSignature(groups[0], value)
+			if len(groups) > 1 {
+				time = MakeGroupedTimeSignature(value, groups...)
+			}
+			if time != nil {
+				return time, nil
+			}
+		}
+	}
+	return nil, fmt.Errorf("invalid meter %q", value)
+}
+
+// abcMeter Writes a meter, e.g. "6/8", or "(2+2+3)/8" if the groups of beats were given.
+func abcMeter(time *TimeSignature) string {
+	if groups := time.writtenGroups("+"); groups != "" {
+		return fmt.Sprintf("(%s)/%d", groups, time.noteValue)
+	}
+	return time.String()
+}
+
+// parseAbcFraction Reads a length written as a fraction of a whole note, e.g. "1/8".
+func parseAbcFraction(value string) (Duration, error) {
+	parts := strings.SplitN(value, "/", 2)
+	n, err := strconv.Atoi(strings.TrimSpace(parts[0]))
+	d := 1
+	if err == nil && len(parts) == 2 {
+		d, err = strconv.Atoi(strings.TrimSpace(parts[1]))
+	}
+	if err != nil || n <= 0 || d <= 0 {
+		return Duration{}, fmt.Errorf("invalid length %q", value)
+	}
+	return MakeDuration(int64(n), int64(d)), nil
+}
+
+// parseAbcTempo Reads a tempo, e.g. "1/4=120", or ""Allegro" 1/4=120". A tempo without a beat, e.g. "120", is in unit note lengths.
+// A beat made of several lengths, e.g. "1/4 3/8=40", is their total.
+func parseAbcTempo(value string) (*AbcTempo, error) {
+	tempo := &AbcTempo{}
+	for {
+		start := strings.IndexByte(value, '"')
+		if start < 0 {
+			break
+		}
+		end := strings.IndexByte(value[start+1:], '"')
+		if end < 0 {
+			return nil, fmt.Errorf("invalid tempo %q", value)
+		}
+		tempo.Text = value[start+1 : start+1+end]
+		value = value[:start] + value[start+end+2:]
+	}
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return tempo, nil
+	}
+	bpm := value
+	if i := strings.IndexByte(value, '='); i >= 0 {
+		bpm = value[i+1:]
+		for _, length := range strings.Fields(value[:i]) {
+			beat, err := parseAbcFraction(length)
+			if err != nil {
+				return nil, err
+			}
+			tempo.Beat = tempo.Beat.Add(beat)
+		}
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(bpm))
+	if err != nil || n <= 0 {
+		return nil, fmt.Errorf("invalid tempo %q", value)
+	}
+	tempo.BeatsPerMinute = n
+	return tempo, nil
+}
+
+// abcModes The names of the modes from BuildModeDictionary, by the abbreviations ABC uses for them.
+var abcModes = map[string]string{
+	"":    "Ionian",
+	"maj": "Ionian",
+	"ion": "Ionian",
+	"m":   "Aeolian",
+	"min": "Aeolian",
+	"aeo": "Aeolian",
+	"dor": "Dorian",
+	"phr": "Phrygian",
+	"lyd": "Lydian",
+	"mix": "Mixolydian",
+	"loc": "Locrian",
+}
+
+// parseAbcKey Reads a key, e.g. "G", "F#m", "Bb" or "D dor", where only the first three letters of the mode count and case doesn't
+// matter. "none" (or nothing) is no key signature. Anything after the mode, such as a clef, is ignored.
+func parseAbcKey(value string) (*KeySignature, error) {
+	switch value {
+	case "", "none", "HP":
+		// The Highland bagpipe key HP is written without a signature
+		return nil, nil
+	case "Hp":
+		return MakeKeySignature(2, false), nil
+	}
+	letter, ok := ParseLetter(value[0])
+	if !ok || value[0] > 'G' {
+		return nil, fmt.Errorf("invalid key %q", value)
+	}
+	rest := value[1:]
+	alter := 0
+	if strings.HasPrefix(rest, "#") {
+		alter, rest = 1, rest[1:]
+	} else if strings.HasPrefix(rest, "b") {
+		alter, rest = -1, rest[1:]
+	}
+	mode := strings.ToLower(strings.TrimSpace(rest))
+	if i := strings.IndexAny(mode, " \t"); i >= 0 {
+		mode = mode[:i]
+	}
+	if strings.Contains(mode, "=") {
+		// Not a mode but a setting like clef=bass
+		mode = ""
+	}
+	if len(mode) > 3 {
+		mode = mode[:3]
+	}
+	name, ok := abcModes[mode]
+	if !ok {
+		return nil, fmt.Errorf("unknown mode in key %q", value)
+	}
+	key := MakeModalKeySignature(letter, alter, name)
+	if key == nil {
+		return nil, fmt.Errorf("key %q needs more than seven sharps or flats", value)
+	}
+	return key, nil
+}
+
+// parseMusic Reads a line of the body of a tune.
+func (p *abcParser) parseMusic(line string) error {
+	for i := 0; i < len(line); {
+		c := line[i]
+		var err error
+		switch {
+		case c == '%':
+			return nil
+		case c == '"':
+			end := strings.IndexByte(line[i+1:], '"')
+			if end < 0 {
+				return errors.New("unterminated chord symbol")
+			}
+			// Quoted text starting with one of these is placed beside the note as an annotation, not a chord
+			if text := line[i+1 : i+1+end]; text != "" && !strings.ContainsRune("^_<>@", rune(text[0])) {
+				p.items = append(p.items, abcItem{chord: text})
+			}
+			i += end + 2
+		case c == '!' || c == '+' || c == '{':
+			// Decorations and grace notes
+			closing := map[byte]byte{'!': '!', '+': '+', '{': '}'}[c]
+			end := strings.IndexByte(line[i+1:], closing)
+			if end < 0 {
+				return fmt.Errorf("unterminated %c", c)
+			}
+			i += end + 2
+		case c == '(' && i+1 < len(line) && isDigit(line[i+1]):
+			i = p.parseTuplet(line, i+1)
+		case c == '[' && i+2 < len(line) && isAbcFieldName(line[i+1]) && line[i+2] == ':':
+			end := strings.IndexByte(line[i:], ']')
+			if end < 0 {
+				return errors.New("unterminated inline field")
+			}
+			err = p.field(line[i+1], strings.TrimSpace(line[i+3:i+end]))
+			i += end + 1
+		case c == '[' && i+1 < len(line) && isDigit(line[i+1]):
+			var endings []int
+			endings, i = parseAbcEndings(line, i+1)
+			if n := len(p.items); n > 0 && p.items[n-1].bar != nil {
+				p.items[n-1].bar.endings = endings
+			} else {
+				p.items = append(p.items, abcItem{bar: &abcBar{endings: endings}})
+			}
+		case c == '|' || c == ':' || c == '[' && i+1 < len(line) && line[i+1] == '|':
+			i = p.parseBar(line, i)
+		case c == '[':
+			i, err = p.parseChord(line, i+1)
+		case c == '>' || c == '<':
+			i = p.parseBrokenRhythm(line, i)
+		case c == '-':
+			p.tied = true
+			i++
+		case strings.IndexByte("^=_ABCDEFGabcdefg", c) >= 0:
+			var sp *SpelledPitch
+			sp, i, err = p.parsePitch(line, i)
+			if err == nil {
+				var length Duration
+				length, i = parseAbcLength(line, i)
+				p.addNote([]SpelledPitch{*sp}, length)
+			}
+		case c == 'z' || c == 'x':
+			var length Duration
+			length, i = parseAbcLength(line, i+1)
+			p.addNote(nil, length)
+		case c == 'Z' || c == 'X':
+			i = p.parseBarRests(line, i+1)
+		default:
+			// Spaces, slurs, decorations written as single letters, and anything else that doesn't change what is played
+			i++
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// readAbcNumber Reads the number starting at i, returning it and where it ends, or the given default if there isn't one.
+func readAbcNumber(line string, i int, otherwise int) (int, int) {
+	start := i
+	for i < len(line) && isDigit(line[i]) {
+		i++
+	}
+	if i == start {
+		return otherwise, i
+	}
+	n, _ := strconv.Atoi(line[start:i])
+	return n, i
+}
+
+// parseAbcLength Reads the multiple of the unit note length written after a note, e.g. "3", "/2", "3/2" or "//" (a quarter).
+func parseAbcLength(line string, i int) (Duration, int) {
+	n, i := readAbcNumber(line, i, 1)
+	d := 1
+	for i < len(line) && line[i] == '/' {
+		var divisor int
+		divisor, i = readAbcNumber(line, i+1, 2)
+		d *= divisor
+	}
+	if n == 0 || d == 0 {
+		return MakeDuration(1, 1), i
+	}
+	return MakeDuration(int64(n), int64(d)), i
+}
+
+// parsePitch Reads a pitch, e.g. "^f'", with its accidental and octave marks. Without an accidental, the pitch takes the accidental
+// last written on the same line or space in the bar, or else the one in the key signature.
+func (p *abcParser) parsePitch(line string, i int) (*SpelledPitch, int, error) {
+	alter, explicit := 0, false
+	for ; i < len(line) && strings.IndexByte("^=_", line[i]) >= 0; i++ {
+		explicit = true
+		switch line[i] {
+		case '^':
+			alter++
+		case '_':
+			alter--
+		}
+	}
+	if i >= len(line) {
+		return nil, i, errors.New("accidental without a note")
+	}
+	letter, ok := ParseLetter(line[i])
+	if !ok {
+		return nil, i, fmt.Errorf("accidental before %q", line[i])
+	}
+	octave := 4
+	if line[i] >= 'a' {
+		octave = 5
+	}
+	for i++; i < len(line) && (line[i] == '\'' || line[i] == ','); i++ {
+		if line[i] == '\'' {
+			octave++
+		} else {
+			octave--
+		}
+	}
+	step := octave*LettersInOctave + int(letter)
+	if explicit {
+		p.altered[step] = alter
+	} else if a, ok := p.altered[step]; ok {
+		alter = a
+	} else {
+		alter = p.key.Alter(letter)
+	}
+	return MakeSpelledPitch(letter, alter, octave), i, nil
+}
+
+// parseChord Reads the notes of a chord, e.g. "[CEG]2", up to and including its length. The chord lasts as long as its first note.
+func (p *abcParser) parseChord(line string, i int) (int, error) {
+	pitches := make([]SpelledPitch, 0, 4)
+	var length Duration
+	for i < len(line) && line[i] != ']' {
+		if strings.IndexByte("^=_ABCDEFGabcdefg", line[i]) < 0 {
+			i++
+			continue
+		}
+		sp, end, err := p.parsePitch(line, i)
+		if err != nil {
+			return end, err
+		}
+		var l Duration
+		l, i = parseAbcLength(line, end)
+		if len(pitches) == 0 {
+			length = l
+		}
+		pitches = append(pitches, *sp)
+	}
+	if i >= len(line) {
+		return i, errors.New("unterminated chord")
+	}
+	outer, i := parseAbcLength(line, i+1)
+	if len(pitches) > 0 {
+		p.addNote(pitches, length.Mul(outer))
+	}
+	return i, nil
+}
+
+// addNote Adds a note, chord or rest (if there are no pitches) lasting the given multiple of the unit note length. A note tied to the
+// one before with the same pitches lengthens it instead.
+func (p *abcParser) addNote(pitches []SpelledPitch, length Duration) {
+	written := p.defaultUnit().Mul(length)
+	if !p.broken.IsZero() {
+		written = written.Mul(p.broken)
+		p.broken = Duration{}
+	}
+	ratio := MakeDuration(1, 1)
+	if p.tuplet.remaining > 0 {
+		ratio = p.tuplet.ratio
+		p.tuplet.remaining--
+	}
+	tied := p.tied && p.last >= 0 && len(pitches) > 0 && p.items[p.last].ratio.Cmp(ratio) == 0 &&
+		samePitches(p.items[p.last].pitches, pitches)
+	p.tied = false
+	if tied {
+		p.items[p.last].written = p.items[p.last].written.Add(written)
+		return
+	}
+	p.items = append(p.items, abcItem{pitches: pitches, written: written, ratio: ratio})
+	p.last = len(p.items) - 1
+}
+
+func samePitches(a []SpelledPitch, b []SpelledPitch) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// parseBarRests Reads a rest lasting a number of whole bars, e.g. "Z4".
+func (p *abcParser) parseBarRests(line string, i int) int {
+	count, i := readAbcNumber(line, i, 1)
+	length := MakeDuration(1, 1)
+	if p.time != nil {
+		length = p.time.Duration()
+	}
+	for j := 0; j < count; j++ {
+		p.items = append(p.items, abcItem{written: length, ratio: MakeDuration(1, 1), wholeBar: true})
+	}
+	p.tied = false
+	return i
+}
+
+// parseTuplet Reads the start of a tuplet, "(p:q:r", meaning put p notes into the time of q for the next r notes. Only p is needed: q
+// depends on p (and for some p on the meter), and r is the same as p.
+func (p *abcParser) parseTuplet(line string, i int) int {
+	notes, i := readAbcNumber(line, i, 3)
+	time, count := 0, notes
+	if i < len(line) && line[i] == ':' {
+		time, i = readAbcNumber(line, i+1, 0)
+		if i < len(line) && line[i] == ':' {
+			count, i = readAbcNumber(line, i+1, notes)
+		}
+	}
+	if time == 0 {
+		switch notes {
+		case 2, 4, 8:
+			time = 3
+		case 3, 6:
+			time = 2
+		default:
+			time = 2
+			if p.time != nil && p.time.noteCount%3 == 0 && p.time.noteCount > 3 {
+				// Compound meters
+				time = 3
+			}
+		}
+	}
+	if notes > 0 {
+		p.tuplet.remaining = count
+		p.tuplet.ratio = MakeDuration(int64(time), int64(notes))
+	}
+	return i
+}
+
+// parseBrokenRhythm Reads a broken rhythm between two notes, e.g. "A>B", which dots the first note and halves the second. Each further
+// > dots the first note again.
+func (p *abcParser) parseBrokenRhythm(line string, i int) int {
+	c, n := line[i], 0
+	for ; i < len(line) && line[i] == c; i++ {
+		n++
+	}
+	short := MakeDuration(1, int64(1)<<uint(n))
+	long := MakeDuration(2, 1).Sub(short)
+	if c == '<' {
+		long, short = short, long
+	}
+	if p.last >= 0 {
+		p.items[p.last].written = p.items[p.last].written.Mul(long)
+		p.broken = short
+	}
+	return i
+}
+
+// parseBar Reads a bar line, e.g. "|", "||", "|]", "[|", "|:", ":|", "::" or ":|2", with any endings written straight after it.
+func (p *abcParser) parseBar(line string, i int) int {
+	bar := &abcBar{}
+	start := i
+	for ; i < len(line) && line[i] == ':'; i++ {
+		bar.endRepeat = true
+	}
+	lines := i
+	for i < len(line) && (line[i] == '|' || line[i] == ']' || line[i] == '[' && i+1 < len(line) && line[i+1] == '|') {
+		i++
+	}
+	symbol := line[lines:i]
+	bar.double = strings.Contains(symbol, "||") || strings.ContainsAny(symbol, "[]")
+	for ; i < len(line) && line[i] == ':'; i++ {
+		bar.startRepeat = true
+	}
+	if symbol == "" {
+		// "::" is an end and a start; a lone ":" means nothing
+		bar.startRepeat = i-start > 1
+		bar.endRepeat = bar.startRepeat
+		if !bar.startRepeat {
+			return i
+		}
+	}
+	if i < len(line) && isDigit(line[i]) {
+		bar.endings, i = parseAbcEndings(line, i)
+	}
+	p.items = append(p.items, abcItem{bar: bar})
+	p.altered = make(map[int]int)
+	return i
+}
+
+// parseAbcEndings Reads which passes through a repeat an ending is played on, e.g. "1", "1,3" or "1-3".
+func parseAbcEndings(line string, i int) ([]int, int) {
+	endings := make([]int, 0, 2)
+	for i < len(line) && isDigit(line[i]) {
+		var first int
+		first, i = readAbcNumber(line, i, 1)
+		last := first
+		if i+1 < len(line) && line[i] == '-' && isDigit(line[i+1]) {
+			last, i = readAbcNumber(line, i+1, first)
+		}
+		for n := first; n <= last; n++ {
+			endings = append(endings, n)
+		}
+		if i+1 < len(line) && line[i] == ',' && isDigit(line[i+1]) {
+			i++
+		}
+	}
+	return endings, i
+}
+
+// Write Writes the tune in ABC. Notes are written in order with a bar line at the end of each bar, so the notes mustn't overlap;
+// gaps between them are filled with rests. Notes crossing a bar line are split and tied, and no repeats are written.
+func (t *AbcTune) Write(w io.Writer) error {
+	var b strings.Builder
+	reference := t.Reference
+	if reference == 0 {
+		reference = 1
+	}
+	fmt.Fprintf(&b, "X:%d\n", reference)
+	if t.Title != "" {
+		fmt.Fprintf(&b, "T:%s\n", t.Title)
+	}
+	if t.Time != nil {
+		fmt.Fprintf(&b, "M:%s\n", abcMeter(t.Time))
+	} else {
+		b.WriteString("M:none\n")
+	}
+	unit := t.UnitLength
+	if unit.IsZero() {
+		unit = MakeDuration(1, 8)
+	}
+	fmt.Fprintf(&b, "L:%s\n", unit)
+	if t.Tempo != nil {
+		b.WriteString("Q:" + abcTempo(t.Tempo, unit) + "\n")
+	}
+	b.WriteString("K:" + AbcKey(t.Key) + "\n")
+
+	tokens, err := t.abcTokens(unit)
+	if err != nil {
+		return fmt.Errorf("abc: %v", err)
+	}
+	bars := 0
+	for i, token := range tokens {
+		b.WriteString(token)
+		if token == "|" || token == "|]" {
+			bars++
+		}
+		switch {
+		case i == len(tokens)-1:
+			b.WriteString("\n")
+		case token == "|" && bars%4 == 0:
+			// Four bars to a line
+			b.WriteString("\n")
+		default:
+			b.WriteString(" ")
+		}
+	}
+	_, err = io.WriteString(w, b.String())
+	return err
+}
+
+func abcTempo(tempo *AbcTempo, unit Duration) string {
+	beat := tempo.Beat
+	if beat.IsZero() {
+		beat = unit
+	}
+	written := fmt.Sprintf("%s=%d", beat, tempo.BeatsPerMinute)
+	if tempo.Text != "" {
+		written = fmt.Sprintf("%q %s", tempo.Text, written)
+	}
+	return written
+}
+
+// AbcKey Returns the given key as it is written in an ABC K: field, e.g. "F#m" or "D dor". A nil key is "none".
+func AbcKey(key *KeySignature) string {
+	if key == nil {
+		return "none"
+	}
+	pf := &PitchFactory{*MiddleC()}
+	tonic := SpellPitch(*pf.GetPitch(&key.tonic, 4), key)
+	name := tonic.letter.String() + map[int]string{-1: "b", 1: "#"}[tonic.Alter()]
+	switch mode := key.Mode(); mode {
+	case "Ionian":
+		return name
+	case "Aeolian":
+		return name + "m"
+	default:
+		return name + " " + strings.ToLower(mode[:3])
+	}
+}
+
+// abcTokens Returns the body of the tune as a list of notes and bar lines.
+func (t *AbcTune) abcTokens(unit Duration) ([]string, error) {
+	w := &abcWriter{unit: unit, tracker: newAccidentalTracker(t.Key), chords: t.Chords}
+	if t.Time != nil {
+		w.bar = t.Time.Duration()
+		w.barEnd = w.bar
+		if !t.Pickup.IsZero() {
+			w.barEnd = t.Pickup
+		}
+	}
+	for i := 0; i < len(t.Notes); {
+		note := &t.Notes[i].Note
+		switch start := t.Notes[i].Start; start.Cmp(w.now) {
+		case -1:
+			return nil, fmt.Errorf("note %d starts at %v, before the note before it ends", i+1, start)
+		case 1:
+			w.hold(nil, start.Sub(w.now))
+			continue
+		}
+		ratio := note.tupletRatio()
+		if ratio.Cmp(MakeDuration(1, 1)) == 0 {
+			if note.IsRest() && note.value == 0 && note.duration.Cmp(w.bar) == 0 && w.barEnd.Sub(w.now).Cmp(w.bar) == 0 {
+				w.add(w.chordSymbol() + "Z")
+				w.advance(note.duration)
+			} else {
+				w.hold(note.pitches, note.duration)
+			}
+			i++
+			continue
+		}
+		// A tuplet, which is written as a whole without splitting it at bar lines
+		j := i
+		for ; j < len(t.Notes) && t.Notes[j].Note.tupletRatio().Cmp(ratio) == 0; j++ {
+		}
+		p, q := ratio.numerator, ratio.Denominator()
+		tuplet := fmt.Sprintf("(%d:%d:%d", p, q, j-i)
+		if map[int64]int64{2: 3, 3: 2, 4: 3, 6: 2, 8: 3}[p] == q && int64(j-i) == p {
+			tuplet = fmt.Sprintf("(%d", p)
+		}
+		for ; i < j; i++ {
+			note := &t.Notes[i].Note
+			w.add(w.chordSymbol() + tuplet + w.note(note.pitches, MakeNote(note.value, note.dots).duration))
+			tuplet = ""
+			w.advance(note.duration)
+		}
+	}
+	if n := len(w.tokens); n > 0 && w.tokens[n-1] == "|" {
+		w.tokens[n-1] = "|]"
+	} else {
+		w.tokens = append(w.tokens, "|]")
+	}
+	return w.tokens, nil
+}
+
+type abcWriter struct {
+	unit    Duration
+	bar     Duration // The length of a bar, or zero if the tune is unmetered
+	barEnd  Duration
+	now     Duration
+	tracker *accidentalTracker
+	chords  []AbcChordSymbol
+	tokens  []string
+}
+
+func (w *abcWriter) add(token string) {
+	w.tokens = append(w.tokens, token)
+}
+
+// advance Moves on by the given duration, writing any bar lines passed.
+func (w *abcWriter) advance(duration Duration) {
+	w.now = w.now.Add(duration)
+	for !w.bar.IsZero() && w.now.Cmp(w.barEnd) >= 0 {
+		w.add("|")
+		w.tracker.barline()
+		w.barEnd = w.barEnd.Add(w.bar)
+	}
+}
+
+// chordSymbol Returns the quoted chord symbols due to be played by now.
+func (w *abcWriter) chordSymbol() string {
+	symbols := ""
+	for len(w.chords) > 0 && w.chords[0].Start.Cmp(w.now) <= 0 {
+		symbols += fmt.Sprintf("%q", w.chords[0].Name)
+		w.chords = w.chords[1:]
+	}
+	return symbols
+}
+
+// hold Writes the given pitches (or a rest if there are none) held for the given duration, split and tied at bar lines.
+func (w *abcWriter) hold(pitches []SpelledPitch, duration Duration) {
+	for !duration.IsZero() {
+		length := duration
+		if !w.bar.IsZero() && w.barEnd.Sub(w.now).Cmp(length) < 0 {
+			length = w.barEnd.Sub(w.now)
+		}
+		duration = duration.Sub(length)
+		token := w.chordSymbol() + w.note(pitches, length)
+		if len(pitches) > 0 && !duration.IsZero() {
+			token += "-"
+		}
+		w.add(token)
+		w.advance(length)
+	}
+}
+
+// note Returns the given pitches written as a note or chord, or a rest if there are none, of the given length.
+func (w *abcWriter) note(pitches []SpelledPitch, length Duration) string {
+	written := ""
+	for i := range pitches {
+		written += w.pitch(&pitches[i])
+	}
+	switch {
+	case len(pitches) == 0:
+		written = "z"
+	case len(pitches) > 1:
+		written = "[" + written + "]"
+	}
+	multiple := length.Div(w.unit)
+	switch n, d := multiple.numerator, multiple.Denominator(); {
+	case n == 1 && d == 1:
+	case d == 1:
+		written += fmt.Sprint(n)
+	case n == 1 && d == 2:
+		written += "/"
+	case n == 1:
+		written += fmt.Sprintf("/%d", d)
+	default:
+		written += fmt.Sprintf("%d/%d", n, d)
+	}
+	return written
+}
+
+// pitch Returns a pitch as it is written in ABC, e.g. "^f'", with an accidental only if the key and bar so far need one.
+func (w *abcWriter) pitch(sp *SpelledPitch) string {
+	written := ""
+	if alter, needed := w.tracker.accidental(sp); needed {
+		written = map[int]string{-2: "__", -1: "_", 0: "=", 1: "^", 2: "^^"}[alter]
+	}
+	octave := sp.Octave()
+	if octave >= 5 {
+		return written + strings.ToLower(sp.letter.String()) + strings.Repeat("'", octave-5)
+	}
+	return written + sp.letter.String() + strings.Repeat(",", 4-octave)
+}
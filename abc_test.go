@@ -0,0 +1,290 @@
+package tonacity
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const abcFixture = `%abc-2.1
+Free text before the first tune is ignored.
+
+X:7
+T:The Fixture
+T:An Alternative Title
+M:6/8
+L:1/8
+Q:"Lively" 3/8=100
+K:D dor % comment
+"Dm"DEF "C"G2A|^F=F F2- F/G/ A|
+(3ABc d>c B<A|]
+
+X:8
+T:Second
+K:G
+G4|
+`
+
+// abcSummary Describes timed notes as "start pitches length", e.g. "1/8 F♯4 1/4".
+func abcSummary(notes []TimedNote) []string {
+	summary := make([]string, len(notes))
+	for i := range notes {
+		pitches := "z"
+		if !notes[i].Note.IsRest() {
+			names := make([]string, 0)
+			for _, sp := range notes[i].Note.Pitches() {
+				names = append(names, sp.String())
+			}
+			pitches = strings.Join(names, "+")
+		}
+		summary[i] = fmt.Sprintf("%v %s %v", notes[i].Start, pitches, notes[i].Note.Duration())
+	}
+	return summary
+}
+
+func TestReadAbc(t *testing.T) {
+	tunes, err := ReadAbc(strings.NewReader(abcFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tunes) != 2 {
+		t.Fatalf("ReadAbc() read %d tunes, want 2", len(tunes))
+	}
+	tune := tunes[0]
+	if tune.Reference != 7 || tune.Title != "The Fixture" {
+		t.Errorf("ReadAbc() = X:%d T:%s, want X:7 T:The Fixture", tune.Reference, tune.Title)
+	}
+	if tune.Time == nil || tune.Time.String() != "6/8" {
+		t.Errorf("AbcTune.Time = %v, want 6/8", tune.Time)
+	}
+	if want := (AbcTempo{MakeDuration(3, 8), 100, "Lively"}); tune.Tempo == nil || *tune.Tempo != want {
+		t.Errorf("AbcTune.Tempo = %+v, want %+v", tune.Tempo, want)
+	}
+	if tune.Key == nil || tune.Key.Fifths() != 0 || tune.Key.Mode() != "Dorian" {
+		t.Errorf("AbcTune.Key = %+v, want D Dorian", tune.Key)
+	}
+	want := []string{
+		"0/1 D4 1/8", "1/8 E4 1/8", "1/4 F4 1/8", "3/8 G4 1/4", "5/8 A4 1/8",
+		"3/4 F♯4 1/8", "7/8 F4 1/8", "1/1 F4 5/16", "21/16 G4 1/16", "11/8 A4 1/8",
+		"3/2 A4 1/12", "19/12 B4 1/12", "5/3 C5 1/12", "7/4 D5 3/16", "31/16 C5 1/16", "2/1 B4 1/16", "33/16 A4 3/16",
+	}
+	if got := abcSummary(tune.Notes); !reflect.DeepEqual(got, want) {
+		t.Errorf("AbcTune.Notes = %v, want %v", got, want)
+	}
+	wantChords := []AbcChordSymbol{{Duration{}, "Dm"}, {MakeDuration(3, 8), "C"}}
+	if !reflect.DeepEqual(tune.Chords, wantChords) {
+		t.Errorf("AbcTune.Chords = %v, want %v", tune.Chords, wantChords)
+	}
+	// The tied F lasts a quarter and a sixteenth, which isn't a note value
+	if v := tune.Notes[7].Note.Value(); v != 0 {
+		t.Errorf("tied note value = %v, want 0", v)
+	}
+	if v, d := tune.Notes[13].Note.Value(), tune.Notes[13].Note.Dots(); v != 8 || d != 1 {
+		t.Errorf("broken rhythm note = %v with %v dots, want a dotted eighth", v, d)
+	}
+
+	second := tunes[1]
+	if second.Time != nil || second.UnitLength.Cmp(MakeDuration(1, 8)) != 0 || second.Key.Fifths() != 1 {
+		t.Errorf("ReadAbc() second tune = %+v, want unmetered in G with 1/8 units", second)
+	}
+}
+
+func TestReadAbc_Music(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		music  string
+		want   []string
+	}{
+		{"octaves", "L:1/4\nK:C", "C, C c c'", []string{"0/1 C3 1/4", "1/4 C4 1/4", "1/2 C5 1/4", "3/4 C6 1/4"}},
+		{"lengths", "L:1/8\nK:C", "C2 C/ C// C3/2 C/4", []string{"0/1 C4 1/4", "1/4 C4 1/16", "5/16 C4 1/32", "11/32 C4 3/16", "17/32 C4 1/32"}},
+		{"key signature", "L:1/4\nK:Bb", "B e =B B|B", []string{"0/1 B♭4 1/4", "1/4 E♭5 1/4", "1/2 B4 1/4", "3/4 B4 1/4", "1/1 B♭4 1/4"}},
+		{"accidentals by octave", "L:1/4\nK:C", "^c c C __D", []string{"0/1 C♯5 1/4", "1/4 C♯5 1/4", "1/2 C4 1/4", "3/4 D𝄫4 1/4"}},
+		{"mode", "L:1/4\nK:A mix", "c g", []string{"0/1 C♯5 1/4", "1/4 G5 1/4"}},
+		{"chord", "L:1/8\nK:G", "[DFA]2 [B,D]/2", []string{"0/1 D4+F♯4+A4 1/4", "1/4 B3+D4 1/16"}},
+		{"rests", "M:3/4\nL:1/4\nK:C", "z2 x|Z2|C", []string{"0/1 z 1/2", "1/2 z 1/4", "3/4 z 3/4", "3/2 z 3/4", "9/4 C4 1/4"}},
+		{"tuplets", "L:1/8\nK:C", "(3CDE (2FG (3:2:2AB c", []string{
+			"0/1 C4 1/12", "1/12 D4 1/12", "1/6 E4 1/12", "1/4 F4 3/16", "7/16 G4 3/16", "5/8 A4 1/12", "17/24 B4 1/12", "19/24 C5 1/8"}},
+		{"quintuplet in simple time", "M:4/4\nL:1/8\nK:C", "(5CDEFG A", []string{
+			"0/1 C4 1/20", "1/20 D4 1/20", "1/10 E4 1/20", "3/20 F4 1/20", "1/5 G4 1/20", "1/4 A4 1/8"}},
+		{"quintuplet in compound time", "M:6/8\nL:1/8\nK:C", "(5CDEFG A", []string{
+			"0/1 C4 3/40", "3/40 D4 3/40", "3/20 E4 3/40", "9/40 F4 3/40", "3/10 G4 3/40", "3/8 A4 1/8"}},
+		{"repeat", "L:1/4\nK:C", "C|:D:|E", []string{"0/1 C4 1/4", "1/4 D4 1/4", "1/2 D4 1/4", "3/4 E4 1/4"}},
+		{"repeat from start", "L:1/4\nK:C", "C D:|E", []string{"0/1 C4 1/4", "1/4 D4 1/4", "1/2 C4 1/4", "3/4 D4 1/4", "1/1 E4 1/4"}},
+		{"endings", "L:1/4\nK:C", "|:C|1D:|2E|F|]", []string{
+			"0/1 C4 1/4", "1/4 D4 1/4", "1/2 C4 1/4", "3/4 E4 1/4", "1/1 F4 1/4"}},
+		{"bracketed endings", "L:1/4\nK:C", "|:C|[1D|D:|[2E||:F:|", []string{
+			"0/1 C4 1/4", "1/4 D4 1/4", "1/2 D4 1/4", "3/4 C4 1/4", "1/1 E4 1/4", "5/4 F4 1/4", "3/2 F4 1/4"}},
+		{"double repeat", "L:1/4\nK:C", "C ::D:|", []string{"0/1 C4 1/4", "1/4 C4 1/4", "1/2 D4 1/4", "3/4 D4 1/4"}},
+		{"inline fields", "L:1/4\nK:C", "F [K:G] F [L:1/8] F\nK:F\nB", []string{"0/1 F4 1/4", "1/4 F♯4 1/4", "1/2 F♯4 1/8", "5/8 B♭4 1/8"}},
+		{"decorations", "L:1/4\nK:C", "~C !trill!D {g}E (F G) .A \"^text\"B", []string{
+			"0/1 C4 1/4", "1/4 D4 1/4", "1/2 E4 1/4", "3/4 F4 1/4", "1/1 G4 1/4", "5/4 A4 1/4", "3/2 B4 1/4"}},
+		{"default unit", "M:2/4\nK:C", "C", []string{"0/1 C4 1/16"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunes, err := ReadAbc(strings.NewReader("X:1\n" + tt.header + "\n" + tt.music + "\n"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := abcSummary(tunes[0].Notes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ReadAbc(%q) = %v, want %v", tt.music, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReadAbc_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		abc  string
+	}{
+		{"music before key", "X:1\nCDE\n"},
+		{"bad key", "X:1\nK:H\n"},
+		{"bad mode", "X:1\nK:C hypodorian\n"},
+		{"bad meter", "X:1\nM:3/5\nK:C\n"},
+		{"unterminated chord symbol", "X:1\nK:C\n\"Am C\n"},
+		{"unterminated chord", "X:1\nK:C\n[CEG\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadAbc(strings.NewReader(tt.abc)); err == nil {
+				t.Errorf("ReadAbc(%q) should fail", tt.abc)
+			}
+		})
+	}
+}
+
+func TestAbcKey(t *testing.T) {
+	tests := []struct {
+		key  *KeySignature
+		want string
+	}{
+		{nil, "none"},
+		{MakeKeySignature(2, false), "D"},
+		{MakeKeySignature(3, true), "F#m"},
+		{MakeKeySignature(-2, false), "Bb"},
+		{MakeModalKeySignature(LetterD, 0, "Dorian"), "D dor"},
+		{MakeModalKeySignature(LetterE, -1, "Mixolydian"), "Eb mix"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			if got := AbcKey(tt.key); got != tt.want {
+				t.Errorf("AbcKey() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAbcTune_Write(t *testing.T) {
+	tunes, err := ReadAbc(strings.NewReader(abcFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	if err := tunes[0].Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `X:7
+T:The Fixture
+M:6/8
+L:1/8
+Q:"Lively" 3/8=100
+K:D dor
+"Dm"D E F "C"G2 A | ^F =F F5/2 G/ A | (3A B c d3/2 c/ B/ A3/2 |]
+`
+	if buf.String() != want {
+		t.Errorf("AbcTune.Write() = \n%s\nwant\n%s", buf.String(), want)
+	}
+
+	again, err := ReadAbc(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := abcSummary(again[0].Notes), abcSummary(tunes[0].Notes); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadAbc(Write()) = %v, want %v", got, want)
+	}
+	if !reflect.DeepEqual(again[0].Chords, tunes[0].Chords) {
+		t.Errorf("ReadAbc(Write()) chords = %v, want %v", again[0].Chords, tunes[0].Chords)
+	}
+}
+
+func TestAbcTune_Write_BarLines(t *testing.T) {
+	f := *MakeSpelledPitch(LetterF, 1, 4)
+	tune := &AbcTune{Time: MakeTimeSignature(2, 4), UnitLength: MakeDuration(1, 4), Key: MakeKeySignature(0, false)}
+	rest := MakeRest(0, 0)
+	rest.SetDuration(MakeDuration(1, 2))
+	tune.Notes = []TimedNote{
+		{MakeDuration(1, 4), *MakeNote(2, 0, f)},
+		{MakeDuration(3, 4), *MakeNote(4, 0, f)},
+		{MakeDuration(1, 1), *rest},
+	}
+	var buf bytes.Buffer
+	if err := tune.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "z ^F- | ^F F | Z |]\n"
+	if got := buf.String()[strings.Index(buf.String(), "K:C\n")+4:]; got != want {
+		t.Errorf("AbcTune.Write() body = %q, want %q", got, want)
+	}
+
+	tune.Notes = append(tune.Notes, TimedNote{MakeDuration(1, 1), *MakeNote(4, 0, f)})
+	if err := tune.Write(&buf); err == nil {
+		t.Error("AbcTune.Write() of overlapping notes should fail")
+	}
+}
+
+func TestAbcTune_Pickup(t *testing.T) {
+	tunes, err := ReadAbc(strings.NewReader("X:1\nM:4/4\nL:1/4\nK:C\nG|c d e f|g4|]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tunes[0].Pickup; got.Cmp(MakeDuration(1, 4)) != 0 {
+		t.Errorf("ReadAbc() pickup = %v, want 1/4", got)
+	}
+	var buf bytes.Buffer
+	if err := tunes[0].Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := "G | c d e f | g4 |]\n"
+	if got := buf.String()[strings.Index(buf.String(), "K:C\n")+4:]; got != want {
+		t.Errorf("AbcTune.Write() body = %q, want %q", got, want)
+	}
+	tunes, err = ReadAbc(strings.NewReader("X:1\nM:4/4\nL:1/4\nK:C\nc d e f|g4|]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := tunes[0].Pickup; !got.IsZero() {
+		t.Errorf("ReadAbc() pickup of a tune starting on a full bar = %v, want 0", got)
+	}
+}
+
+func TestAbcTune_GroupedMeter(t *testing.T) {
+	tunes, err := ReadAbc(strings.NewReader("X:1\nM:(2+2+3)/8\nL:1/8\nK:C\nCDEFGAB|]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tunes[0].Time, MakeGroupedTimeSignature(8, 2, 2, 3); *got != *want {
+		t.Errorf("ReadAbc() meter = %v %v, want 7/8 grouped 2+2+3", got, got.Groups())
+	}
+	var buf bytes.Buffer
+	if err := tunes[0].Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\nM:(2+2+3)/8\n") {
+		t.Errorf("AbcTune.Write() = %q, want M:(2+2+3)/8", buf.String())
+	}
+	tunes, err = ReadAbc(strings.NewReader("X:1\nM:7/8\nL:1/8\nK:C\nCDEFGAB|]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	buf.Reset()
+	if err := tunes[0].Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "\nM:7/8\n") {
+		t.Errorf("AbcTune.Write() = %q, want M:7/8", buf.String())
+	}
+}
@@ -0,0 +1,149 @@
+package tonacity
+
+import (
+	"math/rand/v2"
+	"sort"
+)
+
+// ArpeggioPattern The order in which an arpeggiator plays the notes of a chord, one at a time.
+type ArpeggioPattern uint8
+
+const (
+	// ArpeggioUp From the lowest note to the top, e.g. C E G C for C Major over an octave.
+	ArpeggioUp ArpeggioPattern = iota
+	// ArpeggioDown From the top note to the lowest, e.g. C G E C.
+	ArpeggioDown
+	// ArpeggioUpDown Up to the top note then back down to the lowest, e.g. C E G C G E C.
+	ArpeggioUpDown
+	// ArpeggioAlberti The lowest, highest, middle, highest figure of an Alberti bass, e.g. C G E G, played in each octave in turn. Chords
+	// of more than three notes go back to the highest between each of their middle notes, e.g. C B E B G B for C Major Seventh.
+	ArpeggioAlberti
+	// ArpeggioRandom The notes in a random order, each played once.
+	ArpeggioRandom
+	// ArpeggioBrokenThirds Each note followed by the one above it, climbing a note at a time, e.g. C E E G G C.
+	ArpeggioBrokenThirds
+)
+
+// Arpeggiator A singer that breaks a chord up into its notes, played one at a time in a pattern over a number of octaves. The chord is
+// laid out from its lowest note upwards, repeated an octave higher for each extra octave, and topped with its lowest note an octave above
+// the last. The singer sings the pattern through once; use Loop to repeat it.
+type Arpeggiator struct {
+	tones   []SpelledPitch // The chord laid out over every octave, lowest first, including the top note
+	size    int            // The number of notes in the chord
+	pattern ArpeggioPattern
+	seed    uint64 // The seed for the random pattern
+	order   []int  // The index into the tones of each note in the pattern
+	next    int    // The index into the order of the note that will be sung next
+}
+
+// MakeArpeggiator Creates an arpeggiator of the given chord over the given number of octaves, with its notes spelled as in the given key
+// (nil for C Major). If the chord has no pitches, or octaves is less than 1, then nil is returned.
+func MakeArpeggiator(chord *Chord, key *KeySignature, pattern ArpeggioPattern, octaves int) *Arpeggiator {
+	pitches := chord.Pitches()
+	sort.SliceStable(pitches, func(i, j int) bool {
+		return pitches[i].value < pitches[j].value
+	})
+	spelled := make([]SpelledPitch, len(pitches))
+	for i, pitch := range pitches {
+		spelled[i] = SpellPitch(pitch, key)
+	}
+	return makeArpeggiator(spelled, pattern, octaves)
+}
+
+// MakeChordSymbolArpeggiator Creates an arpeggiator of the chord with the given symbol, with its lowest note in the given octave, over
+// the given number of octaves. The notes are spelled as the symbol spells them. If octaves is less than 1 then nil is returned.
+func MakeChordSymbolArpeggiator(symbol *ChordSymbol, octave int, pattern ArpeggioPattern, octaves int) *Arpeggiator {
+	return makeArpeggiator(symbol.Spell(octave), pattern, octaves)
+}
+
+// makeArpeggiator Creates an arpeggiator of the given notes, which must be in ascending order.
+func makeArpeggiator(chord []SpelledPitch, pattern ArpeggioPattern, octaves int) *Arpeggiator {
+	if len(chord) == 0 || octaves < 1 {
+		return nil
+	}
+	tones := make([]SpelledPitch, 0, len(chord)*octaves+1)
+	for octave := 0; octave < octaves; octave++ {
+		for _, tone := range chord {
+			tone.Transpose(HalfSteps(octave * OctaveValue))
+			tones = append(tones, tone)
+		}
+	}
+	top := chord[0]
+	top.Transpose(HalfSteps(octaves * OctaveValue))
+	a := &Arpeggiator{tones: append(tones, top), size: len(chord), pattern: pattern}
+	a.arrange()
+	return a
+}
+
+// Seed Sets the seed the random pattern is shuffled with, so that the same seed always gives the same order, and rearranges the notes.
+// Returns the arpeggiator.
+func (a *Arpeggiator) Seed(seed uint64) *Arpeggiator {
+	a.seed = seed
+	a.arrange()
+	return a
+}
+
+// arrange Works out the order the notes are played in, and goes back to the first of them.
+func (a *Arpeggiator) arrange() {
+	last := len(a.tones) - 1
+	a.order = a.order[:0]
+	switch a.pattern {
+	case ArpeggioUp:
+		for i := 0; i <= last; i++ {
+			a.order = append(a.order, i)
+		}
+	case ArpeggioDown:
+		for i := last; i >= 0; i-- {
+			a.order = append(a.order, i)
+		}
+	case ArpeggioUpDown:
+		for i := 0; i < last; i++ {
+			a.order = append(a.order, i)
+		}
+		for i := last; i >= 0; i-- {
+			a.order = append(a.order, i)
+		}
+	case ArpeggioAlberti:
+		for low := 0; low < last; low += a.size {
+			high := low + a.size - 1
+			a.order = append(a.order, low, high)
+			for middle := low + 1; middle < high; middle++ {
+				a.order = append(a.order, middle, high)
+			}
+		}
+	case ArpeggioRandom:
+		a.order = rand.New(rand.NewPCG(a.seed, a.seed)).Perm(last + 1)
+	case ArpeggioBrokenThirds:
+		for i := 0; i < last; i++ {
+			a.order = append(a.order, i, i+1)
+		}
+	}
+	a.next = 0
+}
+
+// Sing Produces the next note of the pattern, until it has all been played.
+func (a *Arpeggiator) Sing() (pitch Pitch, more bool) {
+	if a.next >= len(a.order) {
+		return
+	}
+	a.next++
+	return a.tones[a.order[a.next-1]].Pitch(), true
+}
+
+// Reset Returns to the first note of the pattern. The random pattern plays the same order again.
+func (a *Arpeggiator) Reset() {
+	a.next = 0
+}
+
+// Notes Returns the whole pattern as notes with the given rhythm, e.g. a single eighth note for even eighths, or a dotted eighth and
+// a sixteenth for a dotted rhythm. The rhythm is repeated for as long as the pattern lasts. If no rhythm is given then nil is returned.
+func (a *Arpeggiator) Notes(rhythm ...Duration) []Note {
+	if len(rhythm) == 0 {
+		return nil
+	}
+	notes := make([]Note, len(a.order))
+	for i, tone := range a.order {
+		notes[i] = *makeNoteOfDuration(rhythm[i%len(rhythm)], a.tones[tone])
+	}
+	return notes
+}
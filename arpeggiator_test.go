@@ -0,0 +1,116 @@
+package tonacity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestArpeggiator(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	cMajor := MakeChord(*pf.GetPitch(G(), 4), *pf.GetPitch(C(), 4), *pf.GetPitch(E(), 4))
+	tests := []struct {
+		name    string
+		pattern ArpeggioPattern
+		octaves int
+		want    string
+	}{
+		{"Up", ArpeggioUp, 1, "C4 E4 G4 C5"},
+		{"Up two octaves", ArpeggioUp, 2, "C4 E4 G4 C5 E5 G5 C6"},
+		{"Down", ArpeggioDown, 1, "C5 G4 E4 C4"},
+		{"Up and down", ArpeggioUpDown, 2, "C4 E4 G4 C5 E5 G5 C6 G5 E5 C5 G4 E4 C4"},
+		{"Alberti", ArpeggioAlberti, 2, "C4 G4 E4 G4 C5 G5 E5 G5"},
+		{"Broken thirds", ArpeggioBrokenThirds, 1, "C4 E4 E4 G4 G4 C5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			a := MakeArpeggiator(cMajor, nil, tt.pattern, tt.octaves)
+			if got := sung(a); got != tt.want {
+				t.Errorf("sung %v, want %v", got, tt.want)
+			}
+			a.Reset()
+			if got := sung(a); got != tt.want {
+				t.Errorf("sung %v after Reset(), want %v", got, tt.want)
+			}
+		})
+	}
+	if a := MakeArpeggiator(MakeChord(), nil, ArpeggioUp, 1); a != nil {
+		t.Error("MakeArpeggiator() of no pitches should be nil")
+	}
+	if a := MakeArpeggiator(cMajor, nil, ArpeggioUp, 0); a != nil {
+		t.Error("MakeArpeggiator() over no octaves should be nil")
+	}
+}
+
+func TestArpeggiator_Random(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	chord := MakeChord(*pf.GetPitch(C(), 4), *pf.GetPitch(E(), 4), *pf.GetPitch(G(), 4), *pf.GetPitch(B(), 4))
+	a := MakeArpeggiator(chord, nil, ArpeggioRandom, 2).Seed(7)
+	first := sung(a)
+	a.Reset()
+	if again := sung(a); again != first {
+		t.Errorf("sung %v after Reset(), want the same order %v", again, first)
+	}
+	if again := sung(MakeArpeggiator(chord, nil, ArpeggioRandom, 2).Seed(7)); again != first {
+		t.Errorf("sung %v with the same seed, want %v", again, first)
+	}
+	got := strings.Fields(first)
+	slices.Sort(got)
+	want := strings.Fields("C4 E4 G4 B4 C5 E5 G5 B5 C6")
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("sung %v, want each of %v once", first, want)
+	}
+}
+
+func TestMakeChordSymbolArpeggiator(t *testing.T) {
+	tests := []struct {
+		symbol  string
+		pattern ArpeggioPattern
+		want    string
+	}{
+		{"Bb7", ArpeggioUp, "B♭3 D4 F4 A♭4 B♭4"},
+		{"Cdim7", ArpeggioAlberti, "C3 B𝄫3 E♭3 B𝄫3 G♭3 B𝄫3"},
+		{"C/G", ArpeggioDown, "G4 E4 C4 G3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			symbol, err := ParseChordSymbol(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			a := MakeChordSymbolArpeggiator(symbol, 3, tt.pattern, 1)
+			var names []string
+			for _, note := range a.Notes(MakeDuration(1, 8)) {
+				for _, sp := range note.Pitches() {
+					names = append(names, sp.String())
+				}
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("Notes() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestArpeggiator_Notes(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	a := MakeArpeggiator(MakeChord(*pf.GetPitch(D(), 4), *pf.GetPitch(F().Sharp(), 4), *pf.GetPitch(A(), 4)), MakeKeySignature(2, false), ArpeggioUp, 1)
+	notes := a.Notes(MakeDuration(3, 16), MakeDuration(1, 16))
+	var got []string
+	for _, note := range notes {
+		sp := note.Pitches()[0]
+		got = append(got, fmt.Sprintf("%v:%d.%d", sp.String(), note.Value(), note.Dots()))
+	}
+	want := []string{"D4:8.1", "F♯4:16.0", "A4:8.1", "D5:16.0"}
+	if !slices.Equal(got, want) {
+		t.Errorf("Notes() = %v, want %v", got, want)
+	}
+	if notes := a.Notes(); notes != nil {
+		t.Errorf("Notes() without a rhythm = %v, want nil", notes)
+	}
+	if _, more := a.Sing(); !more {
+		t.Error("Notes() shouldn't move the singer on")
+	}
+}
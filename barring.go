@@ -0,0 +1,226 @@
+package tonacity
+
+import "fmt"
+
+// Remaining Returns how much more music the bar needs to be full according to its time signature, which is negative if it is overfull.
+// An unmetered bar always has nothing remaining.
+func (b *Bar) Remaining() Duration {
+	if b.time.noteCount == 0 {
+		return Duration{}
+	}
+	return b.time.Duration().Sub(b.Duration())
+}
+
+// Check Returns an error if the notes in the bar don't add up to the length of a bar in its time signature. An unmetered bar is always
+// correct.
+func (b *Bar) Check() error {
+	switch remaining := b.Remaining(); remaining.Cmp(Duration{}) {
+	case 1:
+		return fmt.Errorf("bar lasts %v, %v short of %v", b.Duration(), remaining, b.time.String())
+	case -1:
+		return fmt.Errorf("bar lasts %v, %v more than %v", b.Duration(), Duration{}.Sub(remaining), b.time.String())
+	}
+	return nil
+}
+
+// FillWithRests Adds rests to the end of the bar to make it full, using as few note values as possible.
+func (b *Bar) FillWithRests() {
+	if remaining := b.Remaining(); remaining.Cmp(Duration{}) > 0 {
+		b.notes = append(b.notes, MakeTiedNotes(remaining)...)
+	}
+}
+
+// Check Returns an error describing the first bar whose notes don't add up to the length of a bar in its time signature. The first bar
+// may be short, as a pickup (anacrusis), and so may the last, which often makes up the rest of the pickup bar. No bar may be overfull.
+func (s *Stave) Check() error {
+	for i := range s.bars {
+		bar := &s.bars[i]
+		if err := bar.Check(); err != nil && (bar.Remaining().Cmp(Duration{}) < 0 || (i > 0 && i < len(s.bars)-1)) {
+			return fmt.Errorf("bar %d: %w", i+1, err)
+		}
+	}
+	return nil
+}
+
+// Duration Returns how long the notes on the stave last.
+func (s *Stave) Duration() Duration {
+	var duration Duration
+	for i := range s.bars {
+		duration = duration.Add(s.bars[i].Duration())
+	}
+	return duration
+}
+
+// TimedNotes Returns every note on the stave along with when it starts, measured from the beginning of the first bar. Tied notes are
+// returned separately, as they are written.
+func (s *Stave) TimedNotes() []TimedNote {
+	var notes []TimedNote
+	var now Duration
+	for i := range s.bars {
+		for _, note := range s.bars[i].notes {
+			notes = append(notes, TimedNote{now, note})
+			now = now.Add(note.duration)
+		}
+	}
+	return notes
+}
+
+// BarNotes Divides the given notes, which must be in the order they start and not overlap, into bars of the given time signature, key
+// signature and clef (either of which may be nil). A note that crosses a bar line is split, with the part in each bar written in as few
+// note values as possible and tied to the next. Gaps between notes are filled with rests. The music can start with a pickup (anacrusis)
+// bar of the given length, which must be shorter than a full bar, or zero for none. The last bar ends with the last note, so it may be
+// short; use FillWithRests to complete it.
+func BarNotes(notes []TimedNote, time *TimeSignature, pickup Duration, key *KeySignature, clef *Clef) (*Stave, error) {
+	if time == nil {
+		return nil, fmt.Errorf("notes can't be barred without a time signature")
+	}
+	length := time.Duration()
+	if pickup.Cmp(Duration{}) < 0 || pickup.Cmp(length) >= 0 {
+		return nil, fmt.Errorf("pickup of %v must be shorter than a bar of %v", pickup, time.String())
+	}
+	stave := MakeStave()
+	bar := MakeBar(time, key, clef)
+	barEnd := length
+	if !pickup.IsZero() {
+		barEnd = pickup
+	}
+	var now Duration
+	// place Adds the given pitches (or a rest if there are none) lasting the given duration, starting a new bar whenever one fills up.
+	// The original note is kept if it fits in the bar, otherwise it is split and tied.
+	place := func(note *Note, pitches []SpelledPitch, duration Duration, tied bool) {
+		for !duration.IsZero() {
+			if now.Cmp(barEnd) == 0 {
+				stave.bars = append(stave.bars, *bar)
+				bar = MakeBar(time, key, clef)
+				barEnd = barEnd.Add(length)
+			}
+			part := duration
+			if space := barEnd.Sub(now); space.Cmp(part) < 0 {
+				part = space
+			}
+			duration = duration.Sub(part)
+			if note != nil && duration.IsZero() && part.Cmp(note.duration) == 0 {
+				bar.notes = append(bar.notes, *note)
+			} else {
+				written := MakeTiedNotes(part, pitches...)
+				if len(pitches) > 0 {
+					written[len(written)-1].tied = !duration.IsZero() || tied
+				}
+				bar.notes = append(bar.notes, written...)
+			}
+			now = now.Add(part)
+		}
+	}
+	for i := range notes {
+		note := &notes[i].Note
+		switch start := notes[i].Start; start.Cmp(now) {
+		case -1:
+			return nil, fmt.Errorf("note %d starts at %v, before the note before it ends at %v", i+1, start, now)
+		case 1:
+			place(nil, nil, start.Sub(now), false)
+		}
+		place(note, note.pitches, note.duration, note.tied)
+	}
+	if len(bar.notes) > 0 || len(stave.bars) == 0 {
+		stave.bars = append(stave.bars, *bar)
+	}
+	return stave, nil
+}
+
+// barLength Returns how long the bar with the given index lasts. A short first bar is a pickup and lasts as long as its notes, and so does
+// an unmetered bar; any other bar lasts as long as its time signature says.
+func (s *Stave) barLength(i int) Duration {
+	bar := &s.bars[i]
+	if bar.time.noteCount == 0 || (i == 0 && len(bar.notes) > 0 && bar.Remaining().Cmp(Duration{}) > 0) {
+		return bar.Duration()
+	}
+	return bar.time.Duration()
+}
+
+// barBeats Returns the beats of the bar, which for an unmetered bar is just one as long as the bar.
+func barBeats(bar *Bar, length Duration) []Duration {
+	if bar.time.noteCount == 0 {
+		return []Duration{length}
+	}
+	return bar.time.Beats()
+}
+
+// BarBeat Returns which bar and beat, both counted from 1, the given point from the start of the stave falls in, and how far into the beat
+// it is. The beats of a bar are those of its time signature, e.g. dotted quarters in 6/8, and a pickup bar's beats are counted back from
+// its end, so a quarter note pickup in 4/4 is beat 4. Points after the last bar carry on in bars like it, and points before the start are
+// taken to be the start. A stave with no bars returns bar 0.
+func (s *Stave) BarBeat(at Duration) (bar int, beat int, offset Duration) {
+	if len(s.bars) == 0 {
+		return 0, 0, Duration{}
+	}
+	if at.Cmp(Duration{}) < 0 {
+		at = Duration{}
+	}
+	var start Duration
+	i := 0
+	for ; i < len(s.bars)-1; i++ {
+		length := s.barLength(i)
+		if at.Cmp(start.Add(length)) < 0 {
+			break
+		}
+		start = start.Add(length)
+	}
+	bar = i + 1
+	length := s.barLength(i)
+	into := at.Sub(start)
+	if i == len(s.bars)-1 && s.bars[i].time.noteCount > 0 && into.Cmp(length) >= 0 {
+		// Carry on past the end in bars of the last one's time signature
+		if i == 0 && length.Cmp(s.bars[i].time.Duration()) < 0 {
+			bar, into, length = bar+1, into.Sub(length), s.bars[i].time.Duration()
+		}
+		ratio := into.Div(length)
+		extra := ratio.numerator / ratio.Denominator()
+		bar += int(extra)
+		into = into.Sub(length.Mul(MakeDuration(extra, 1)))
+	}
+	beats := barBeats(&s.bars[i], length)
+	if full := s.bars[i].time.Duration(); s.bars[i].time.noteCount > 0 && bar == 1 && length.Cmp(full) < 0 {
+		into = into.Add(full.Sub(length))
+	}
+	for beat = 1; beat < len(beats) && into.Cmp(beats[beat-1]) >= 0; beat++ {
+		into = into.Sub(beats[beat-1])
+	}
+	return bar, beat, into
+}
+
+// Position Returns the point, from the start of the stave, where the given beat of the given bar, both counted from 1, starts. Bars after
+// the last carry on like it. The earlier beats of a pickup bar come before the start, so are negative. An error is returned if there is no
+// such bar or beat.
+func (s *Stave) Position(bar int, beat int) (Duration, error) {
+	if bar < 1 || len(s.bars) == 0 {
+		return Duration{}, fmt.Errorf("there is no bar %d", bar)
+	}
+	var start Duration
+	i := 0
+	for ; i < bar-1 && i < len(s.bars)-1; i++ {
+		start = start.Add(s.barLength(i))
+	}
+	last := &s.bars[i]
+	length := s.barLength(i)
+	if i < bar-1 {
+		// Past the last bar
+		if last.time.noteCount == 0 {
+			return Duration{}, fmt.Errorf("there is no bar %d after an unmetered bar", bar)
+		}
+		start = start.Add(length)
+		i++
+		length = last.time.Duration()
+		start = start.Add(length.Mul(MakeDuration(int64(bar-1-i), 1)))
+	}
+	beats := barBeats(last, length)
+	if beat < 1 || beat > len(beats) {
+		return Duration{}, fmt.Errorf("bar %d has no beat %d", bar, beat)
+	}
+	if full := last.time.Duration(); last.time.noteCount > 0 && bar == 1 && length.Cmp(full) < 0 {
+		start = start.Sub(full.Sub(length))
+	}
+	for _, b := range beats[:beat-1] {
+		start = start.Add(b)
+	}
+	return start, nil
+}
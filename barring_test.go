@@ -0,0 +1,247 @@
+package tonacity
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// barSummary Writes out the notes of each bar, e.g. "C4:4 r:8. | E4:2~", with their values and ties.
+func barSummary(stave *Stave) string {
+	var bars []string
+	for _, bar := range stave.Bars() {
+		var notes []string
+		for _, note := range bar.Notes() {
+			name := "r"
+			if !note.IsRest() {
+				pitches := note.Pitches()
+				name = pitches[0].String()
+			}
+			written := fmt.Sprintf("%v:%d%v", name, note.Value(), strings.Repeat(".", note.Dots()))
+			if note.Tied() {
+				written += "~"
+			}
+			notes = append(notes, written)
+		}
+		bars = append(bars, strings.Join(notes, " "))
+	}
+	return strings.Join(bars, " | ")
+}
+
+func TestTimeSignature_Groups(t *testing.T) {
+	tests := []struct {
+		name     string
+		time     *TimeSignature
+		want     []int
+		compound bool
+	}{
+		{"4/4", MakeTimeSignature(4, 4), []int{1, 1, 1, 1}, false},
+		{"3/4", MakeTimeSignature(3, 4), []int{1, 1, 1}, false},
+		{"6/8", MakeTimeSignature(6, 8), []int{3, 3}, true},
+		{"12/8", MakeTimeSignature(12, 8), []int{3, 3, 3, 3}, true},
+		{"5/4", MakeTimeSignature(5, 4), []int{2, 3}, false},
+		{"7/8", MakeTimeSignature(7, 8), []int{2, 2, 3}, false},
+		{"7/8 as 3+2+2", MakeGroupedTimeSignature(8, 3, 2, 2), []int{3, 2, 2}, false},
+		{"6/8 as 2+2+2", MakeGroupedTimeSignature(8, 2, 2, 2), []int{2, 2, 2}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.time.Groups(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Groups() = %v, want %v", got, tt.want)
+			}
+			if got := tt.time.IsCompound(); got != tt.compound {
+				t.Errorf("IsCompound() = %v, want %v", got, tt.compound)
+			}
+		})
+	}
+	var beats []string
+	for _, beat := range MakeGroupedTimeSignature(8, 2, 2, 3).Beats() {
+		beats = append(beats, beat.String())
+	}
+	if got := strings.Join(beats, " "); got != "1/4 1/4 3/8" {
+		t.Errorf("Beats() = %v, want 1/4 1/4 3/8", got)
+	}
+	if got := MakeGroupedTimeSignature(8, 2, 2, 3).String(); got != "7/8" {
+		t.Errorf("String() = %v, want 7/8", got)
+	}
+	for _, ts := range []*TimeSignature{MakeGroupedTimeSignature(8), MakeGroupedTimeSignature(8, 2, 0), MakeGroupedTimeSignature(3, 2, 2)} {
+		if ts != nil {
+			t.Errorf("MakeGroupedTimeSignature() = %v, want nil", ts)
+		}
+	}
+}
+
+func TestBar_Check(t *testing.T) {
+	c := *MakeSpelledPitch(LetterC, 0, 4)
+	bar := MakeBar(MakeTimeSignature(3, 4), nil, nil)
+	bar.AddNotes(*MakeNote(4, 0, c), *MakeNote(16, 0, c))
+	if err := bar.Check(); err == nil || err.Error() != "bar lasts 5/16, 7/16 short of 3/4" {
+		t.Errorf("Check() = %v", err)
+	}
+	bar.FillWithRests()
+	if err := bar.Check(); err != nil {
+		t.Errorf("Check() after FillWithRests() = %v", err)
+	}
+	if got := barSummary(MakeStave(*bar)); got != "C4:4 C4:16 r:4.." {
+		t.Errorf("FillWithRests() gave %v", got)
+	}
+	bar.AddNotes(*MakeNote(8, 0, c))
+	if err := bar.Check(); err == nil || err.Error() != "bar lasts 7/8, 1/8 more than 3/4" {
+		t.Errorf("Check() = %v", err)
+	}
+	if err := MakeBar(nil, nil, nil).Check(); err != nil {
+		t.Errorf("Check() of an unmetered bar = %v", err)
+	}
+}
+
+func TestStave_Check(t *testing.T) {
+	c := *MakeSpelledPitch(LetterC, 0, 4)
+	bar := func(values ...int) Bar {
+		b := MakeBar(MakeTimeSignature(2, 4), nil, nil)
+		for _, value := range values {
+			b.AddNotes(*MakeNote(value, 0, c))
+		}
+		return *b
+	}
+	tests := []struct {
+		name  string
+		stave *Stave
+		want  string
+	}{
+		{"Full bars", MakeStave(bar(4, 4), bar(2)), ""},
+		{"Pickup", MakeStave(bar(8), bar(2), bar(4, 8)), ""},
+		{"Short in the middle", MakeStave(bar(2), bar(4), bar(2)), "bar 2: bar lasts 1/4, 1/4 short of 2/4"},
+		{"Overfull at the end", MakeStave(bar(2), bar(2, 8)), "bar 2: bar lasts 5/8, 1/8 more than 2/4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.stave.Check()
+			if got := fmt.Sprint(err); (err == nil && tt.want != "") || (err != nil && got != tt.want) {
+				t.Errorf("Check() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestBarNotes(t *testing.T) {
+	note := func(letter Letter, value int, dots int) Note {
+		return *MakeNote(value, dots, *MakeSpelledPitch(letter, 0, 4))
+	}
+	notes := []TimedNote{
+		{MakeDuration(0, 1), note(LetterC, 4, 0)},
+		{MakeDuration(1, 4), note(LetterD, 2, 0)},
+		{MakeDuration(3, 4), note(LetterE, 2, 0)},
+		{MakeDuration(3, 2), note(LetterF, 4, 0)},
+		{MakeDuration(7, 4), note(LetterG, 1, 0)},
+	}
+	stave, err := BarNotes(notes, MakeTimeSignature(3, 4), MakeDuration(1, 4), nil, MakeClef(GClef, 2, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := barSummary(stave), "C4:4 | D4:2 E4:4~ | E4:4 r:4 F4:4 | G4:2.~ | G4:4"; got != want {
+		t.Errorf("BarNotes() = %v, want %v", got, want)
+	}
+	if err := stave.Check(); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+	if clef := stave.Bars()[3].Clef(); clef == nil || clef.Sign() != GClef {
+		t.Errorf("BarNotes() bar 4 has clef %v, want treble", clef)
+	}
+	timed := stave.TimedNotes()
+	if len(timed) != 8 || timed[7].Start.Cmp(MakeDuration(5, 2)) != 0 {
+		t.Errorf("TimedNotes() = %v", timed)
+	}
+
+	// Tuplets that fit in a bar are kept, and a note tied on is still tied once split
+	triplet := note(LetterA, 8, 0)
+	triplet.SetTuplet(3, 2)
+	tied := note(LetterB, 4, 1)
+	tied.SetTied(true)
+	stave, err = BarNotes([]TimedNote{
+		{MakeDuration(0, 1), triplet},
+		{MakeDuration(1, 12), triplet},
+		{MakeDuration(1, 6), triplet},
+		{MakeDuration(1, 4), tied},
+		{MakeDuration(5, 8), note(LetterB, 8, 0)},
+	}, MakeGroupedTimeSignature(8, 2, 3), Duration{}, nil, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := barSummary(stave), "A4:8 A4:8 A4:8 B4:4.~ | B4:8"; got != want {
+		t.Errorf("BarNotes() = %v, want %v", got, want)
+	}
+	if actual, normal := stave.Bars()[0].Notes()[0].Tuplet(); actual != 3 || normal != 2 {
+		t.Errorf("BarNotes() lost the triplet, got %d:%d", actual, normal)
+	}
+
+	for _, tt := range []struct {
+		name   string
+		notes  []TimedNote
+		time   *TimeSignature
+		pickup Duration
+	}{
+		{"Overlapping notes", []TimedNote{{Duration{}, note(LetterC, 2, 0)}, {MakeDuration(1, 4), note(LetterD, 4, 0)}}, MakeTimeSignature(4, 4), Duration{}},
+		{"No time signature", nil, nil, Duration{}},
+		{"Pickup of a whole bar", nil, MakeTimeSignature(4, 4), MakeDuration(1, 1)},
+	} {
+		if _, err := BarNotes(tt.notes, tt.time, tt.pickup, nil, nil); err == nil {
+			t.Errorf("BarNotes() with %v should fail", tt.name)
+		}
+	}
+}
+
+func TestStave_BarBeat(t *testing.T) {
+	c := *MakeSpelledPitch(LetterC, 0, 4)
+	bar := func(time *TimeSignature, values ...int) Bar {
+		b := MakeBar(time, nil, nil)
+		for _, value := range values {
+			b.AddNotes(*MakeNote(value, 0, c))
+		}
+		return *b
+	}
+	common := MakeTimeSignature(4, 4)
+	stave := MakeStave(bar(common, 4), bar(common, 1), bar(common, 2, 2))
+	tests := []struct {
+		at     Duration
+		bar    int
+		beat   int
+		offset Duration
+	}{
+		{Duration{}, 1, 4, Duration{}},
+		{MakeDuration(1, 4), 2, 1, Duration{}},
+		{MakeDuration(5, 8), 2, 2, MakeDuration(1, 8)},
+		{MakeDuration(5, 4), 3, 1, Duration{}},
+		{MakeDuration(11, 4), 4, 3, Duration{}},
+		{MakeDuration(-1, 4), 1, 4, Duration{}},
+	}
+	for _, tt := range tests {
+		bar, beat, offset := stave.BarBeat(tt.at)
+		if bar != tt.bar || beat != tt.beat || offset.Cmp(tt.offset) != 0 {
+			t.Errorf("BarBeat(%v) = %d, %d, %v, want %d, %d, %v", tt.at, bar, beat, offset, tt.bar, tt.beat, tt.offset)
+		}
+		if tt.at.Cmp(Duration{}) < 0 {
+			continue
+		}
+		if at, err := stave.Position(tt.bar, tt.beat); err != nil || at.Add(tt.offset).Cmp(tt.at) != 0 {
+			t.Errorf("Position(%d, %d) = %v, %v, want %v", tt.bar, tt.beat, at, err, tt.at.Sub(tt.offset))
+		}
+	}
+	if at, err := stave.Position(1, 1); err != nil || at.Cmp(MakeDuration(-3, 4)) != 0 {
+		t.Errorf("Position(1, 1) = %v, %v, want -3/4 before the pickup", at, err)
+	}
+	if _, err := stave.Position(2, 5); err == nil {
+		t.Errorf("Position(2, 5) should fail in 4/4")
+	}
+	if _, err := stave.Position(0, 1); err == nil {
+		t.Errorf("Position(0, 1) should fail")
+	}
+
+	compound := MakeStave(bar(MakeTimeSignature(6, 8), 4, 8, 4, 8))
+	if bar, beat, offset := compound.BarBeat(MakeDuration(1, 2)); bar != 1 || beat != 2 || offset.Cmp(MakeDuration(1, 8)) != 0 {
+		t.Errorf("BarBeat() in 6/8 = %d, %d, %v, want 1, 2, 1/8", bar, beat, offset)
+	}
+	if at, err := compound.Position(3, 2); err != nil || at.Cmp(MakeDuration(15, 8)) != 0 {
+		t.Errorf("Position(3, 2) in 6/8 = %v, %v, want 15/8", at, err)
+	}
+}
@@ -0,0 +1,243 @@
+package tonacity
+
+// StemDirection Which way the stem of a note is drawn from its notehead.
+type StemDirection uint8
+
+const (
+	// NoStem For rests and whole notes, which have no stem.
+	NoStem StemDirection = iota
+	// StemUp A stem drawn up from the right of the notehead.
+	StemUp
+	// StemDown A stem drawn down from the left of the notehead.
+	StemDown
+)
+
+// BeamGroup Notes of a bar joined together by a beam, given as indexes into the bar's notes in order, all of whose stems point the same way.
+type BeamGroup struct {
+	Notes []int
+	Stem  StemDirection
+}
+
+// stemGoesUp Whether the stem of notes reaching from the given low to high staff steps points up, which it does when they reach further
+// below the middle line than above it.
+func stemGoesUp(low int, high int) bool {
+	return low+high < 8
+}
+
+// noteSteps Returns the lowest and highest staff steps of the pitches of a note written with the given clef. The note mustn't be a rest.
+func noteSteps(note *Note, clef *Clef) (low int, high int) {
+	for i := range note.pitches {
+		step := clef.StaffPosition(&note.pitches[i])
+		if i == 0 || step < low {
+			low = step
+		}
+		if i == 0 || step > high {
+			high = step
+		}
+	}
+	return
+}
+
+// beamable Whether a note can be joined to others by a beam, i.e. it sounds and is shorter than a quarter note.
+func beamable(note *Note) bool {
+	return !note.IsRest() && note.value >= 8
+}
+
+// beamUnits Returns the points in the bar, after its start, that beams mustn't cross. These are the ends of the groups the bar is felt
+// in, e.g. every dotted quarter in 6/8, except that in 4/4 a half bar of nothing but plain eighth notes is beamed together.
+func (b *Bar) beamUnits() []Duration {
+	beats := b.time.Beats()
+	ends := make([]Duration, 0, len(beats))
+	var now Duration
+	for _, beat := range beats {
+		now = now.Add(beat)
+		ends = append(ends, now)
+	}
+	if b.time.noteCount != 4 || b.time.noteValue != 4 || b.time.groups[0] != 0 {
+		return ends
+	}
+	// Beats 1 and 3 are kept as boundaries, beats 2 and 4 are dropped from any half bar of plain eighths
+	half := MakeDuration(1, 2)
+	plain := [2]bool{true, true}
+	now = Duration{}
+	for i := range b.notes {
+		note := &b.notes[i]
+		if h := now.Cmp(half) >= 0; note.IsRest() || note.value != 8 || note.dots != 0 || note.tupletRatio().Cmp(MakeDuration(1, 1)) != 0 {
+			if h {
+				plain[1] = false
+			} else {
+				plain[0] = false
+			}
+		}
+		now = now.Add(note.duration)
+	}
+	units := make([]Duration, 0, len(ends))
+	for i, end := range ends {
+		if i%2 == 0 && plain[i/2%2] && i+1 < len(ends) {
+			continue
+		}
+		units = append(units, end)
+	}
+	return units
+}
+
+// BeamGroups Returns the notes of the bar that are joined by beams. Notes shorter than a quarter are beamed together within each group
+// of beats the bar is felt in, e.g. in dotted quarters in 6/8, or 2+2+3 eighths in 7/8, and in 4/4 four plain eighths in a half bar are
+// beamed together. A rest, a longer note, or the end of a group breaks a beam, and a note on its own isn't beamed. Each group's stems point
+// the way that suits the note furthest from the middle line. An unmetered bar is beamed by quarter notes.
+func (b *Bar) BeamGroups() []BeamGroup {
+	var units []Duration
+	if b.time.noteCount == 0 {
+		for end := MakeDuration(1, 4); end.Cmp(b.Duration()) < 0; end = end.Add(MakeDuration(1, 4)) {
+			units = append(units, end)
+		}
+	} else {
+		units = b.beamUnits()
+	}
+	var groups []BeamGroup
+	var current []int
+	finish := func() {
+		if len(current) > 1 {
+			groups = append(groups, BeamGroup{Notes: current, Stem: b.groupStem(current)})
+		}
+		current = nil
+	}
+	var now Duration
+	unit := 0
+	for i := range b.notes {
+		note := &b.notes[i]
+		for unit < len(units) && now.Cmp(units[unit]) >= 0 {
+			// A new group of beats starts
+			finish()
+			unit++
+		}
+		end := now.Add(note.duration)
+		if !beamable(note) || (unit < len(units) && end.Cmp(units[unit]) > 0) {
+			// The note breaks the beam, either by not having one or by crossing into the next group
+			finish()
+		} else {
+			current = append(current, i)
+		}
+		now = end
+	}
+	finish()
+	return groups
+}
+
+// groupStem Returns the stem direction shared by the given notes of the bar.
+func (b *Bar) groupStem(notes []int) StemDirection {
+	var low, high int
+	for j, i := range notes {
+		l, h := noteSteps(&b.notes[i], b.clef)
+		if j == 0 || l < low {
+			low = l
+		}
+		if j == 0 || h > high {
+			high = h
+		}
+	}
+	if stemGoesUp(low, high) {
+		return StemUp
+	}
+	return StemDown
+}
+
+// Stems Returns which way the stem of each note in the bar points. Notes joined by a beam share a direction; any other note's stem goes
+// up when it reaches further below the middle line than above it. Rests and notes of a whole note or longer have no stem.
+func (b *Bar) Stems() []StemDirection {
+	stems := make([]StemDirection, len(b.notes))
+	for i := range b.notes {
+		note := &b.notes[i]
+		if note.IsRest() || (note.value != 0 && note.value <= 1) || (note.value == 0 && note.duration.Cmp(MakeDuration(1, 1)) >= 0) {
+			continue
+		}
+		stems[i] = b.groupStem([]int{i})
+	}
+	for _, group := range b.BeamGroups() {
+		for _, i := range group.Notes {
+			stems[i] = group.Stem
+		}
+	}
+	return stems
+}
+
+// RegroupRhythm Rewrites the notes of the bar so that they show where the beats of its time signature fall, splitting notes into notes
+// tied together (or rests) where needed. A note that starts part way through a group of beats is split where the next group starts, e.g.
+// a quarter note on the second eighth of 6/8 crossing into the second dotted quarter. In 4/4 and other bars of four beats, a note that
+// starts after the beginning of the bar is split at the middle so as not to hide the third beat. Each part is written in as few note
+// values as possible. Tuplets, and unmetered bars, are left as they are.
+func (b *Bar) RegroupRhythm() {
+	if b.time.noteCount == 0 {
+		return
+	}
+	var boundaries []Duration
+	var now Duration
+	for _, beat := range b.time.Beats() {
+		boundaries = append(boundaries, now)
+		now = now.Add(beat)
+	}
+	var middle Duration
+	if groups := b.time.Groups(); len(groups) == 4 && groups[0]+groups[1] == groups[2]+groups[3] {
+		middle = boundaries[2]
+	}
+	// nextBoundary Returns the first group boundary after the given point, or the end of the bar
+	nextBoundary := func(point Duration) Duration {
+		for _, boundary := range boundaries {
+			if boundary.Cmp(point) > 0 {
+				return boundary
+			}
+		}
+		return b.time.Duration()
+	}
+	onBoundary := func(point Duration) bool {
+		for _, boundary := range boundaries {
+			if boundary.Cmp(point) == 0 {
+				return true
+			}
+		}
+		return false
+	}
+	notes := make([]Note, 0, len(b.notes))
+	now = Duration{}
+	for i := range b.notes {
+		note := b.notes[i]
+		end := now.Add(note.duration)
+		if note.tupletRatio().Cmp(MakeDuration(1, 1)) != 0 || note.duration.IsZero() {
+			notes = append(notes, note)
+			now = end
+			continue
+		}
+		// Find where the note has to be split
+		var splits []Duration
+		for start := now; ; {
+			split := end
+			if !onBoundary(start) {
+				split = nextBoundary(start)
+			} else if !middle.IsZero() && !start.IsZero() && start.Cmp(middle) < 0 {
+				split = middle
+			}
+			if split.Cmp(end) >= 0 || split.Cmp(start) <= 0 {
+				// The rest of the note fits, or it runs over the end of the bar
+				break
+			}
+			splits = append(splits, split)
+			start = split
+		}
+		if len(splits) == 0 {
+			notes = append(notes, note)
+			now = end
+			continue
+		}
+		start := now
+		for j, split := range append(splits, end) {
+			parts := MakeTiedNotes(split.Sub(start), note.pitches...)
+			if !note.IsRest() {
+				parts[len(parts)-1].tied = j < len(splits) || note.tied
+			}
+			notes = append(notes, parts...)
+			start = split
+		}
+		now = end
+	}
+	b.notes = notes
+}
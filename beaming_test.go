@@ -0,0 +1,152 @@
+package tonacity
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// rhythmBar Returns a bar of the given time signature with a note of each value, e.g. 8 for an eighth note, 0 for an eighth rest, and
+// -4 for a dotted quarter.
+func rhythmBar(time *TimeSignature, pitch *SpelledPitch, values ...int) *Bar {
+	bar := MakeBar(time, nil, MakeClef(GClef, 2, 0))
+	for _, value := range values {
+		switch {
+		case value == 0:
+			bar.AddNotes(*MakeNote(8, 0))
+		case value < 0:
+			bar.AddNotes(*MakeNote(-value, 1, *pitch))
+		default:
+			bar.AddNotes(*MakeNote(value, 0, *pitch))
+		}
+	}
+	return bar
+}
+
+func TestBar_BeamGroups(t *testing.T) {
+	c := MakeSpelledPitch(LetterC, 0, 5)
+	tests := []struct {
+		name   string
+		bar    *Bar
+		groups [][]int
+	}{
+		{"6/8 in dotted quarters", rhythmBar(MakeTimeSignature(6, 8), c, 8, 8, 8, 8, 8, 8), [][]int{{0, 1, 2}, {3, 4, 5}}},
+		{"4/4 eighths in half bars", rhythmBar(MakeTimeSignature(4, 4), c, 8, 8, 8, 8, 8, 8, 8, 8), [][]int{{0, 1, 2, 3}, {4, 5, 6, 7}}},
+		{"4/4 with a quarter", rhythmBar(MakeTimeSignature(4, 4), c, 8, 8, 4, 8, 8, 8, 8), [][]int{{0, 1}, {3, 4, 5, 6}}},
+		{"4/4 dotted rhythm", rhythmBar(MakeTimeSignature(4, 4), c, -8, 16, -8, 16, 2), [][]int{{0, 1}, {2, 3}}},
+		{"7/8 as 2+2+3", rhythmBar(MakeTimeSignature(7, 8), c, 8, 8, 8, 8, 8, 8, 8), [][]int{{0, 1}, {2, 3}, {4, 5, 6}}},
+		{"Rests break beams", rhythmBar(MakeTimeSignature(3, 4), c, 8, 0, 8, 8, 4), [][]int{{2, 3}}},
+		{"Sixteenths by the beat", rhythmBar(MakeTimeSignature(2, 4), c, 16, 16, 16, 16, 8, 8), [][]int{{0, 1, 2, 3}, {4, 5}}},
+		{"Crossing a beat", rhythmBar(MakeTimeSignature(2, 4), c, 8, -8, 8), nil},
+		{"No short notes", rhythmBar(MakeTimeSignature(3, 4), c, 4, 4, 4), nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got [][]int
+			for _, group := range tt.bar.BeamGroups() {
+				got = append(got, group.Notes)
+				if group.Stem != StemDown {
+					t.Errorf("BeamGroups() stem = %v, want down", group.Stem)
+				}
+			}
+			if !reflect.DeepEqual(got, tt.groups) {
+				t.Errorf("BeamGroups() = %v, want %v", got, tt.groups)
+			}
+		})
+	}
+}
+
+func TestBar_Stems(t *testing.T) {
+	bar := MakeBar(MakeTimeSignature(4, 4), nil, MakeClef(GClef, 2, 0))
+	note := func(value int, letter Letter, octave int) Note {
+		return *MakeNote(value, 0, *MakeSpelledPitch(letter, 0, octave))
+	}
+	// The beam of C4 and A5 goes down as A5 is further from the middle line, and B4 on the middle line goes down
+	bar.AddNotes(note(8, LetterC, 4), note(8, LetterA, 5), note(4, LetterB, 4), note(4, LetterA, 4), *MakeNote(4, 0))
+	want := []StemDirection{StemDown, StemDown, StemDown, StemUp, NoStem}
+	if got := bar.Stems(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Stems() = %v, want %v", got, want)
+	}
+	whole := MakeBar(MakeTimeSignature(4, 4), nil, MakeClef(FClef, 4, 0))
+	whole.AddNotes(note(1, LetterC, 3))
+	if got := whole.Stems(); !reflect.DeepEqual(got, []StemDirection{NoStem}) {
+		t.Errorf("Stems() of a whole note = %v", got)
+	}
+	bass := MakeBar(MakeTimeSignature(2, 4), nil, MakeClef(FClef, 4, 0))
+	bass.AddNotes(note(4, LetterC, 3), note(4, LetterE, 4))
+	if got := bass.Stems(); !reflect.DeepEqual(got, []StemDirection{StemUp, StemDown}) {
+		t.Errorf("Stems() in the bass clef = %v", got)
+	}
+}
+
+func TestBar_RegroupRhythm(t *testing.T) {
+	c := MakeSpelledPitch(LetterC, 0, 4)
+	tests := []struct {
+		name string
+		bar  *Bar
+		want string
+	}{
+		{"6/8 across the dotted quarter", rhythmBar(MakeTimeSignature(6, 8), c, 8, 8, 4, 4), "C4:8 C4:8 C4:8~ C4:8 C4:4"},
+		{"4/4 across the middle", rhythmBar(MakeTimeSignature(4, 4), c, 4, 2, 4), "C4:4 C4:4~ C4:4 C4:4"},
+		{"4/4 off the beat", rhythmBar(MakeTimeSignature(4, 4), c, 8, 4, 4, 4, 8), "C4:8 C4:8~ C4:8 C4:8~ C4:8 C4:8~ C4:8 C4:8"},
+		{"4/4 on the beat", rhythmBar(MakeTimeSignature(4, 4), c, -2, 4), "C4:2. C4:4"},
+		{"3/4 across a beat", rhythmBar(MakeTimeSignature(3, 4), c, 4, 2), "C4:4 C4:2"},
+		{"7/8 across a group", rhythmBar(MakeTimeSignature(7, 8), c, 8, 4, 8, -4), "C4:8 C4:8~ C4:8 C4:8 C4:4."},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.bar.RegroupRhythm()
+			if got := barSummary(MakeStave(*tt.bar)); got != tt.want {
+				t.Errorf("RegroupRhythm() = %v, want %v", got, tt.want)
+			}
+			if err := tt.bar.Check(); err != nil {
+				t.Errorf("RegroupRhythm() changed the length: %v", err)
+			}
+		})
+	}
+
+	// Rests are split without ties, a tie into the next bar is kept, and tuplets are left alone
+	bar := MakeBar(MakeTimeSignature(4, 4), nil, nil)
+	triplet := *MakeNote(8, 0, *c)
+	triplet.SetTuplet(3, 2)
+	tied := *MakeNote(4, 0, *c)
+	tied.SetTied(true)
+	bar.AddNotes(*MakeNote(4, 0), *MakeNote(2, 0), triplet, triplet, triplet)
+	bar.RegroupRhythm()
+	if got, want := barSummary(MakeStave(*bar)), "r:4 r:4 r:4 C4:8 C4:8 C4:8"; got != want {
+		t.Errorf("RegroupRhythm() = %v, want %v", got, want)
+	}
+	bar = MakeBar(MakeTimeSignature(2, 4), nil, nil)
+	bar.AddNotes(*MakeNote(8, 0, *c), tied, *MakeNote(8, 0, *c))
+	bar.RegroupRhythm()
+	if got, want := barSummary(MakeStave(*bar)), "C4:8 C4:8~ C4:8~ C4:8"; got != want {
+		t.Errorf("RegroupRhythm() = %v, want %v", got, want)
+	}
+}
+
+func TestMusicXML_Beams(t *testing.T) {
+	score := &MusicXMLScore{Parts: []*MusicXMLPart{{ID: "P1", Staves: []*Stave{MakeStave(*rhythmBar(MakeTimeSignature(2, 4),
+		MakeSpelledPitch(LetterE, 0, 4), -8, 16, 4))}}}}
+	var buf bytes.Buffer
+	if err := score.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<stem>up</stem>`,
+		`<beam number="1">begin</beam>`,
+		`<beam number="1">end</beam>`,
+		`<beam number="2">backward hook</beam>`,
+	} {
+		if !strings.Contains(buf.String(), want) {
+			t.Errorf("MusicXMLScore.Write() is missing %v:\n%s", want, buf.String())
+		}
+	}
+	again, err := ReadMusicXML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Unsupported) > 0 {
+		t.Errorf("ReadMusicXML() reported %v", again.Unsupported)
+	}
+}
@@ -0,0 +1,284 @@
+package tonacity
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// ChordPro is a text format for songs, with chords written in square brackets just before the lyric they are played on, e.g.
+// "[G]Amazing [C]grace", and directives in braces, e.g. "{title: Amazing Grace}". Lines starting with # are comments.
+
+// ChordProDirective A directive, e.g. {title: Amazing Grace}, or {start_of_chorus} which has no value.
+type ChordProDirective struct {
+	Name  string
+	Value string
+}
+
+// ChordProSegment A chord and the lyrics sung from it until the next chord.
+type ChordProSegment struct {
+	Chord     *ChordSymbol // The chord, or nil if there isn't one or it couldn't be understood
+	ChordText string       // The chord as it was written, which is kept for chords that couldn't be understood
+	Lyrics    string
+}
+
+// ChordProLine A line of a song: a directive, a comment, or lyrics with chords, which is empty for a blank line.
+type ChordProLine struct {
+	Directive *ChordProDirective
+	Comment   string // A comment line, including the #
+	Segments  []ChordProSegment
+}
+
+// ChordProSong A song read from ChordPro.
+type ChordProSong struct {
+	Title    string
+	Key      *KeySignature // The key from the {key} directive, or nil if there isn't one
+	Capo     int           // The fret a capo is placed on, from the {capo} directive, or 0 for no capo
+	Lines    []ChordProLine
+	Warnings []string // Problems that didn't stop the song being read or transposed, such as chords that couldn't be understood
+}
+
+// chordProAbbreviations The full names of directives with short forms.
+var chordProAbbreviations = map[string]string{
+	"t":   "title",
+	"st":  "subtitle",
+	"c":   "comment",
+	"soc": "start_of_chorus",
+	"eoc": "end_of_chorus",
+	"sov": "start_of_verse",
+	"eov": "end_of_verse",
+}
+
+// ReadChordPro Reads a song written in ChordPro. Chords that can't be understood are kept as they were written, with a warning.
+func ReadChordPro(r io.Reader) (*ChordProSong, error) {
+	song := &ChordProSong{}
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		text := strings.TrimRight(scanner.Text(), " \t\r")
+		trimmed := strings.TrimSpace(text)
+		var line ChordProLine
+		switch {
+		case strings.HasPrefix(trimmed, "#"):
+			line.Comment = trimmed
+		case strings.HasPrefix(trimmed, "{") && strings.HasSuffix(trimmed, "}"):
+			line.Directive = parseChordProDirective(trimmed[1 : len(trimmed)-1])
+			song.readDirective(line.Directive, n)
+		default:
+			line.Segments = song.readLyrics(text, n)
+		}
+		song.Lines = append(song.Lines, line)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("chordpro: %v", err)
+	}
+	return song, nil
+}
+
+// parseChordProDirective Reads the text inside the braces of a directive, where the name is separated from any value by a colon or a
+// space.
+func parseChordProDirective(text string) *ChordProDirective {
+	text = strings.TrimSpace(text)
+	split := strings.IndexAny(text, ": \t")
+	if split < 0 {
+		return &ChordProDirective{Name: text}
+	}
+	return &ChordProDirective{Name: text[:split], Value: strings.TrimSpace(text[split+1:])}
+}
+
+func (s *ChordProSong) warn(line int, format string, args ...interface{}) {
+	s.Warnings = append(s.Warnings, fmt.Sprintf("line %d: ", line)+fmt.Sprintf(format, args...))
+}
+
+// readDirective Takes the title, key and capo from the directives that set them.
+func (s *ChordProSong) readDirective(d *ChordProDirective, line int) {
+	name := strings.ToLower(d.Name)
+	if full, ok := chordProAbbreviations[name]; ok {
+		name = full
+	}
+	switch name {
+	case "title":
+		s.Title = d.Value
+	case "key":
+		key, err := parseChordProKey(d.Value)
+		if err != nil {
+			s.warn(line, "%v", err)
+			return
+		}
+		s.Key = key
+	case "capo":
+		capo, err := strconv.Atoi(d.Value)
+		if err != nil || capo < 0 {
+			s.warn(line, "invalid capo %q", d.Value)
+			return
+		}
+		s.Capo = capo
+	}
+}
+
+// parseChordProKey Reads a key written as a chord symbol for its tonic, e.g. "G" or "Em".
+func parseChordProKey(value string) (*KeySignature, error) {
+	chord, err := ParseChordSymbol(value)
+	if err != nil || chord.bass != nil || (chord.quality != "" && !chord.IsMinor()) {
+		return nil, fmt.Errorf("invalid key %q", value)
+	}
+	mode := "Ionian"
+	if chord.IsMinor() {
+		mode = "Aeolian"
+	}
+	key := MakeModalKeySignature(chord.root.letter, chord.root.Alter(), mode)
+	if key == nil {
+		return nil, fmt.Errorf("key %q needs more than seven sharps or flats", value)
+	}
+	return key, nil
+}
+
+// readLyrics Splits a line of lyrics at each chord.
+func (s *ChordProSong) readLyrics(text string, line int) []ChordProSegment {
+	segments := make([]ChordProSegment, 0)
+	if text == "" {
+		return segments
+	}
+	open := strings.IndexByte(text, '[')
+	if open != 0 {
+		if open < 0 {
+			open = len(text)
+		}
+		segments = append(segments, ChordProSegment{Lyrics: text[:open]})
+	}
+	for open < len(text) {
+		end := strings.IndexByte(text[open:], ']')
+		if end < 0 {
+			s.warn(line, "unterminated chord %q", text[open:])
+			if len(segments) == 0 {
+				segments = append(segments, ChordProSegment{})
+			}
+			segments[len(segments)-1].Lyrics += text[open:]
+			break
+		}
+		segment := ChordProSegment{ChordText: text[open+1 : open+end]}
+		// Chords starting with * are annotations, which aren't meant to be chords
+		if !strings.HasPrefix(segment.ChordText, "*") {
+			chord, err := ParseChordSymbol(segment.ChordText)
+			if err != nil {
+				s.warn(line, "%v, keeping it as written", err)
+			}
+			segment.Chord = chord
+		}
+		next := strings.IndexByte(text[open+end:], '[')
+		if next < 0 {
+			next = len(text)
+		} else {
+			next += open + end
+		}
+		segment.Lyrics = text[open+end+1 : next]
+		segments = append(segments, segment)
+		open = next
+	}
+	return segments
+}
+
+// Transpose Moves every {key} directive and every chord in the song by the given number of half steps. Each chord is spelled for the key
+// in force where it is, moved by the same amount, so that it keeps its place in that key (see ChordSymbol.TransposeInKey). Chords before
+// the first {key} directive are taken to be in its key, or if the song has none then in the key of its first chord. Chords that couldn't
+// be understood are left as they are, with a warning.
+func (s *ChordProSong) Transpose(halfSteps HalfSteps) {
+	key := s.firstKey()
+	for i := range s.Lines {
+		line := &s.Lines[i]
+		if line.Directive != nil && strings.EqualFold(line.Directive.Name, "key") {
+			if current, err := parseChordProKey(line.Directive.Value); err == nil {
+				key = current
+				moved := *current
+				moved.Transpose(halfSteps)
+				line.Directive.Value = chordProKey(&moved)
+			}
+		}
+		for j := range line.Segments {
+			segment := &line.Segments[j]
+			switch {
+			case segment.Chord != nil && key != nil:
+				segment.Chord.TransposeInKey(halfSteps, key)
+				segment.ChordText = segment.Chord.String()
+			case segment.Chord != nil:
+				segment.Chord.Transpose(halfSteps)
+				segment.ChordText = segment.Chord.String()
+			case segment.ChordText != "" && !strings.HasPrefix(segment.ChordText, "*"):
+				s.warn(i+1, "chord %q was not transposed", segment.ChordText)
+			}
+		}
+	}
+	if s.Key != nil {
+		moved := *s.Key
+		moved.Transpose(halfSteps)
+		s.Key = &moved
+	}
+}
+
+// firstKey Returns the key of the first {key} directive in the song that can be read, or if there isn't one then the key of its first
+// chord, or nil if it has neither.
+func (s *ChordProSong) firstKey() *KeySignature {
+	for _, line := range s.Lines {
+		if line.Directive != nil && strings.EqualFold(line.Directive.Name, "key") {
+			if key, err := parseChordProKey(line.Directive.Value); err == nil {
+				return key
+			}
+		}
+	}
+	return s.firstChordKey()
+}
+
+// firstChordKey Returns the major or minor key of the first chord in the song, or nil if it has no chords.
+func (s *ChordProSong) firstChordKey() *KeySignature {
+	for _, line := range s.Lines {
+		for _, segment := range line.Segments {
+			if chord := segment.Chord; chord != nil {
+				mode := "Ionian"
+				if chord.IsMinor() {
+					mode = "Aeolian"
+				}
+				if key := MakeModalKeySignature(chord.root.letter, chord.root.Alter(), mode); key != nil {
+					return key
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// chordProKey Returns a key written as the chord symbol of its tonic, e.g. "Em".
+func chordProKey(key *KeySignature) string {
+	pf := &PitchFactory{*MiddleC()}
+	tonic := SpellPitch(*pf.GetPitch(&key.tonic, 4), key)
+	if key.IsMinor() {
+		return chordSymbolNote(&tonic) + "m"
+	}
+	return chordSymbolNote(&tonic)
+}
+
+// Write Writes the song in ChordPro.
+func (s *ChordProSong) Write(w io.Writer) error {
+	var b strings.Builder
+	for _, line := range s.Lines {
+		switch {
+		case line.Directive != nil && line.Directive.Value == "":
+			fmt.Fprintf(&b, "{%s}", line.Directive.Name)
+		case line.Directive != nil:
+			fmt.Fprintf(&b, "{%s: %s}", line.Directive.Name, line.Directive.Value)
+		case line.Comment != "":
+			b.WriteString(line.Comment)
+		}
+		for _, segment := range line.Segments {
+			if segment.Chord != nil {
+				b.WriteString("[" + segment.Chord.String() + "]")
+			} else if segment.ChordText != "" {
+				b.WriteString("[" + segment.ChordText + "]")
+			}
+			b.WriteString(segment.Lyrics)
+		}
+		b.WriteString("\n")
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
@@ -0,0 +1,141 @@
+package tonacity
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const chordProFixture = `{title: Fixture Song}
+{key: G}
+{capo: 2}
+# arranged for the band
+{soc}
+[G]Amazing [C/G]grace, how [G]sweet the [D7]sound
+That [Em]saved a [H7]wretch like [*riff]me
+{eoc}
+
+  no chords here
+`
+
+func TestReadChordPro(t *testing.T) {
+	song, err := ReadChordPro(strings.NewReader(chordProFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if song.Title != "Fixture Song" || song.Capo != 2 {
+		t.Errorf("ReadChordPro() = title %q capo %d, want Fixture Song and 2", song.Title, song.Capo)
+	}
+	if song.Key == nil || song.Key.Fifths() != 1 || song.Key.IsMinor() {
+		t.Errorf("ChordProSong.Key = %v, want G Major", song.Key)
+	}
+	if len(song.Lines) != 10 {
+		t.Fatalf("ReadChordPro() read %d lines, want 10", len(song.Lines))
+	}
+	if got := song.Lines[3].Comment; got != "# arranged for the band" {
+		t.Errorf("comment = %q", got)
+	}
+	want := []ChordProSegment{
+		{ChordText: "G", Lyrics: "Amazing "},
+		{ChordText: "C/G", Lyrics: "grace, how "},
+		{ChordText: "G", Lyrics: "sweet the "},
+		{ChordText: "D7", Lyrics: "sound"},
+	}
+	segments := song.Lines[5].Segments
+	if len(segments) != len(want) {
+		t.Fatalf("segments = %+v, want %+v", segments, want)
+	}
+	for i, segment := range segments {
+		if segment.ChordText != want[i].ChordText || segment.Lyrics != want[i].Lyrics || segment.Chord == nil {
+			t.Errorf("segment %d = %+v, want %+v", i, segment, want[i])
+		}
+	}
+	if line := song.Lines[6].Segments; line[0].Lyrics != "That " || line[2].Chord != nil || line[2].ChordText != "H7" || line[3].Chord != nil {
+		t.Errorf("second line = %+v", line)
+	}
+	if len(song.Warnings) != 1 || !strings.Contains(song.Warnings[0], "line 7") || !strings.Contains(song.Warnings[0], "H7") {
+		t.Errorf("ChordProSong.Warnings = %v, want one for H7 on line 7", song.Warnings)
+	}
+}
+
+func TestChordProSong_Transpose(t *testing.T) {
+	song, err := ReadChordPro(strings.NewReader(chordProFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	song.Transpose(-4)
+	var buf bytes.Buffer
+	if err := song.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	want := `{title: Fixture Song}
+{key: Eb}
+{capo: 2}
+# arranged for the band
+{soc}
+[Eb]Amazing [Ab/Eb]grace, how [Eb]sweet the [Bb7]sound
+That [Cm]saved a [H7]wretch like [*riff]me
+{eoc}
+
+  no chords here
+`
+	if buf.String() != want {
+		t.Errorf("ChordProSong.Write() = \n%s\nwant\n%s", buf.String(), want)
+	}
+	if song.Key.Fifths() != -3 {
+		t.Errorf("ChordProSong.Key = %v, want E♭ Major", song.Key)
+	}
+	if n := len(song.Warnings); n != 2 || !strings.Contains(song.Warnings[1], "not transposed") {
+		t.Errorf("ChordProSong.Warnings = %v, want H7 not transposed", song.Warnings)
+	}
+
+	again, err := ReadChordPro(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	again.Transpose(4)
+	if got, want := again.Lines[5].Segments, func() []ChordProSegment {
+		original, _ := ReadChordPro(strings.NewReader(chordProFixture))
+		return original.Lines[5].Segments
+	}(); !reflect.DeepEqual(got, want) {
+		t.Errorf("transposing back = %#v, want %#v", got, want)
+	}
+}
+
+func TestChordProSong_TransposeKeyChange(t *testing.T) {
+	song, err := ReadChordPro(strings.NewReader("{key: C}\n[C]One [Ab]two [Bb]three\n{key: Eb}\n[Eb]Four [Cb]five [B7]six\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	song.Transpose(2)
+	var buf bytes.Buffer
+	if err := song.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "{key: D}\n[D]One [Bb]two [C]three\n{key: F}\n[F]Four [Db]five [Db7]six\n"; buf.String() != want {
+		t.Errorf("ChordProSong.Write() = %q, want %q", buf.String(), want)
+	}
+	if song.Key.Fifths() != -1 {
+		t.Errorf("ChordProSong.Key = %v, want F Major", song.Key)
+	}
+}
+
+func TestChordProSong_TransposeWithoutKey(t *testing.T) {
+	song, err := ReadChordPro(strings.NewReader("[Dm]One [A7]two [Bb]three\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// D Minor down a half step is C♯ Minor, as D♭ Minor would need more than seven flats
+	song.Transpose(-1)
+	var buf bytes.Buffer
+	if err := song.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if want := "[C#m]One [G#7]two [A]three\n"; buf.String() != want {
+		t.Errorf("ChordProSong.Write() = %q, want %q", buf.String(), want)
+	}
+	if song.Key != nil {
+		t.Errorf("ChordProSong.Key = %v, want nil", song.Key)
+	}
+}
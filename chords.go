@@ -47,8 +47,28 @@ const (
 	PerfectFourth = HalfStepValue * 5
 	// PerfectFifth The interval of a perfect fifth, in half steps.
 	PerfectFifth = HalfStepValue * 7
+	// MajorSixth The interval of a major sixth, in half steps.
+	MajorSixth = HalfStepValue * 9
 )
 
+// intervalNames The names of the intervals up to two octaves, by their size in half steps. The tritone is named as an augmented fourth.
+var intervalNames = [OctaveValue*2 + 1]string{
+	"Unison", "Minor Second", "Major Second", "Minor Third", "Major Third", "Perfect Fourth", "Augmented Fourth", "Perfect Fifth",
+	"Minor Sixth", "Major Sixth", "Minor Seventh", "Major Seventh", "Octave",
+	"Minor Ninth", "Major Ninth", "Minor Tenth", "Major Tenth", "Perfect Eleventh", "Augmented Eleventh", "Perfect Twelfth",
+	"Minor Thirteenth", "Major Thirteenth", "Minor Fourteenth", "Major Fourteenth", "Double Octave",
+}
+
+// IntervalName Returns the name of an interval of the given number of half steps, going up or down, e.g. "Perfect Fifth" for 7 or -7.
+// Intervals wider than two octaves are named as a smaller interval plus a number of octaves, e.g. "Major Third + 2 Octaves".
+func IntervalName(halfSteps HalfSteps) string {
+	size := abs(int(halfSteps))
+	if size < len(intervalNames) {
+		return intervalNames[size]
+	}
+	return fmt.Sprintf("%s + %d Octaves", intervalNames[size%OctaveValue], size/OctaveValue)
+}
+
 // Chord A collection of specific pitches, making a chord.
 type Chord struct {
 	pitches []Pitch // The pitches that make up this chord
@@ -58,8 +78,14 @@ func MakeChord(pitches ...Pitch) *Chord {
 	return &Chord{pitches}
 }
 
+// Pitches Returns a copy of the pitches that make up this chord, in the order they were given.
+func (c *Chord) Pitches() []Pitch {
+	return append([]Pitch(nil), c.pitches...)
+}
+
 func (c *Chord) String() string {
-	return fmt.Sprintf("%v", c.pitches)
+	text, _ := c.MarshalText()
+	return string(text)
 }
 
 // ChordFactory The purpose of this class is to allow creating chords using scale intervals, without needing to worry
@@ -222,6 +248,18 @@ func CreateChordDictionary() (dict *PatternDictionary) {
 	return
 }
 
+// WalkChords Calls visit with the pattern of every chord in the dictionary in root position, along with its name as it follows the root,
+// e.g. " Major" or "5". Inversions aren't visited.
+func (d *PatternDictionary) WalkChords(visit func(pattern *Pattern, name string)) {
+	d.Walk(func(pattern *Pattern, entries []interface{}) {
+		for _, entry := range entries {
+			if e, ok := entry.(*chordDictionaryEntry); ok && e.rootIndex == 0 {
+				visit(pattern, e.name)
+			}
+		}
+	})
+}
+
 // GetName will return the name of this chord, if its intervals are a valid pattern in the given dictionary. This function is
 // specifically preferable for guitars or similar, where extended chords (those with ninths - a stretch on a piano, elevenths, and thirteenths) are used more.
 func (c *Chord) GetName(dict *PatternDictionary, pitchNamer *PitchNamer) (name string, ok bool) {
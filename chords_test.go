@@ -1,6 +1,7 @@
 package tonacity
 
 import (
+	"reflect"
 	"testing"
 )
 
@@ -100,3 +101,40 @@ func TestChord_GetName(t *testing.T) {
 		})
 	}
 }
+
+func TestIntervalName(t *testing.T) {
+	tests := []struct {
+		halfSteps HalfSteps
+		want      string
+	}{
+		{0, "Unison"},
+		{PerfectFifth, "Perfect Fifth"},
+		{-MinorThird, "Minor Third"},
+		{6, "Augmented Fourth"},
+		{OctaveValue, "Octave"},
+		{OctaveValue + MajorSecond, "Major Ninth"},
+		{OctaveValue * 2, "Double Octave"},
+		{OctaveValue*3 + MajorThird, "Major Third + 3 Octaves"},
+	}
+	for _, tt := range tests {
+		if got := IntervalName(tt.halfSteps); got != tt.want {
+			t.Errorf("IntervalName(%d) = %v, want %v", tt.halfSteps, got, tt.want)
+		}
+	}
+}
+
+func TestPatternDictionary_WalkChords(t *testing.T) {
+	chords := make(map[string][]HalfSteps)
+	CreateChordDictionary().WalkChords(func(pattern *Pattern, name string) {
+		chords[name] = pattern.Intervals()
+	})
+	if len(chords) != 10 {
+		t.Errorf("WalkChords() visited %d chords, want 10", len(chords))
+	}
+	if got := chords[" Dominant Seventh"]; !reflect.DeepEqual(got, CreateDominantSeventhPattern().Intervals()) {
+		t.Errorf("WalkChords() visited Dominant Seventh with %v", got)
+	}
+	if got := chords["5"]; !reflect.DeepEqual(got, CreatePowerChordPattern().Intervals()) {
+		t.Errorf("WalkChords() visited 5 with %v", got)
+	}
+}
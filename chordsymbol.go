@@ -0,0 +1,363 @@
+package tonacity
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Lead sheets and chord charts name chords with symbols like "F♯m7" or "G/B": a root, a quality written after it, and for a slash
+// chord the note played in the bass. Unlike a Chord, a symbol says nothing about which octave each pitch is played in.
+
+// chordQualities The half steps above the root of each pitch of a chord, by the quality written after the root. Several ways of
+// writing the same quality are accepted, and the one written is kept so the symbol is written back the same way.
+var chordQualities = map[string][]HalfSteps{
+	"":      {MajorThird, PerfectFifth},
+	"maj":   {MajorThird, PerfectFifth},
+	"m":     {MinorThird, PerfectFifth},
+	"min":   {MinorThird, PerfectFifth},
+	"-":     {MinorThird, PerfectFifth},
+	"dim":   {MinorThird, 6},
+	"°":     {MinorThird, 6},
+	"aug":   {MajorThird, 8},
+	"+":     {MajorThird, 8},
+	"5":     {PerfectFifth},
+	"sus2":  {MajorSecond, PerfectFifth},
+	"sus":   {PerfectFourth, PerfectFifth},
+	"sus4":  {PerfectFourth, PerfectFifth},
+	"6":     {MajorThird, PerfectFifth, MajorSixth},
+	"m6":    {MinorThird, PerfectFifth, MajorSixth},
+	"69":    {MajorThird, PerfectFifth, MajorSixth, 14},
+	"6/9":   {MajorThird, PerfectFifth, MajorSixth, 14},
+	"7":     {MajorThird, PerfectFifth, 10},
+	"7sus4": {PerfectFourth, PerfectFifth, 10},
+	"maj7":  {MajorThird, PerfectFifth, 11},
+	"M7":    {MajorThird, PerfectFifth, 11},
+	"Δ":     {MajorThird, PerfectFifth, 11},
+	"m7":    {MinorThird, PerfectFifth, 10},
+	"min7":  {MinorThird, PerfectFifth, 10},
+	"-7":    {MinorThird, PerfectFifth, 10},
+	"mMaj7": {MinorThird, PerfectFifth, 11},
+	"m7b5":  {MinorThird, 6, 10},
+	"ø":     {MinorThird, 6, 10},
+	"dim7":  {MinorThird, 6, MajorSixth},
+	"°7":    {MinorThird, 6, MajorSixth},
+	"aug7":  {MajorThird, 8, 10},
+	"+7":    {MajorThird, 8, 10},
+	"add9":  {MajorThird, PerfectFifth, 14},
+	"madd9": {MinorThird, PerfectFifth, 14},
+	"9":     {MajorThird, PerfectFifth, 10, 14},
+	"maj9":  {MajorThird, PerfectFifth, 11, 14},
+	"m9":    {MinorThird, PerfectFifth, 10, 14},
+	"7b9":   {MajorThird, PerfectFifth, 10, 13},
+	"7#9":   {MajorThird, PerfectFifth, 10, 15},
+	"11":    {MajorThird, PerfectFifth, 10, 14, 17},
+	"m11":   {MinorThird, PerfectFifth, 10, 14, 17},
+	"13":    {MajorThird, PerfectFifth, 10, 14, 21},
+}
+
+// ChordSymbol A chord written as a symbol, e.g. "Am7" or "D/F♯".
+type ChordSymbol struct {
+	root    SpelledPitch
+	quality string        // What is written after the root, e.g. "m7"
+	bass    *SpelledPitch // The note played in the bass of a slash chord, or nil if it is the root
+}
+
+// ParseChordSymbol Reads a chord symbol, e.g. "Bbmaj7" or "C#m/G#". Accidentals may be written as # and b, or as ♯ and ♭. An error is
+// returned if the root or bass isn't a note name, or if the quality isn't one that is known. A slash starts the bass only if a note name
+// follows it, so "C6/9" is a quality and "C6/9/E" has E in the bass.
+func ParseChordSymbol(symbol string) (*ChordSymbol, error) {
+	root, rest, ok := parseChordSymbolNote(symbol)
+	if !ok {
+		return nil, fmt.Errorf("chord symbol %q doesn't start with a note name", symbol)
+	}
+	chord := &ChordSymbol{root: root}
+	if slash := strings.LastIndexByte(rest, '/'); slash >= 0 {
+		if bass, after, ok := parseChordSymbolNote(rest[slash+1:]); ok {
+			if after != "" {
+				return nil, fmt.Errorf("chord symbol %q has an invalid bass note", symbol)
+			}
+			chord.bass = &bass
+			rest = rest[:slash]
+		}
+	}
+	if _, ok := chordQualities[rest]; !ok {
+		return nil, fmt.Errorf("chord symbol %q has an unknown quality %q", symbol, rest)
+	}
+	chord.quality = rest
+	return chord, nil
+}
+
+// parseChordSymbolNote Reads the note name at the start of the given text, returning it and the rest of the text.
+func parseChordSymbolNote(text string) (SpelledPitch, string, bool) {
+	if text == "" || text[0] < 'A' || text[0] > 'G' {
+		return SpelledPitch{}, text, false
+	}
+	letter, _ := ParseLetter(text[0])
+	alter := 0
+	rest := text[1:]
+	for {
+		switch {
+		case strings.HasPrefix(rest, "#"):
+			alter, rest = alter+1, rest[1:]
+			continue
+		case strings.HasPrefix(rest, "♯"):
+			alter, rest = alter+1, rest[len("♯"):]
+			continue
+		case strings.HasPrefix(rest, "♭"):
+			alter, rest = alter-1, rest[len("♭"):]
+			continue
+		case strings.HasPrefix(rest, "b"):
+			alter, rest = alter-1, rest[1:]
+			continue
+		}
+		break
+	}
+	return *MakeSpelledPitch(letter, alter, 4), rest, true
+}
+
+// Root The root of the chord.
+func (c *ChordSymbol) Root() PitchClass {
+	return c.root.pitch.class
+}
+
+// Bass The note played in the bass, which is the root unless this is a slash chord.
+func (c *ChordSymbol) Bass() PitchClass {
+	if c.bass == nil {
+		return c.Root()
+	}
+	return c.bass.pitch.class
+}
+
+// Quality What is written after the root, e.g. "m7" for A minor seventh, or "" for a major triad.
+func (c *ChordSymbol) Quality() string {
+	return c.quality
+}
+
+// IsMinor Returns true if the chord has a minor third and a perfect fifth above its root.
+func (c *ChordSymbol) IsMinor() bool {
+	intervals := chordQualities[c.quality]
+	return len(intervals) > 1 && intervals[0] == MinorThird && intervals[1] == PerfectFifth
+}
+
+// PitchClasses Returns the pitch classes in the chord, starting with the bass and then the root and the notes above it. A bass note
+// that is also in the chord isn't repeated.
+func (c *ChordSymbol) PitchClasses() []PitchClass {
+	root := c.Root()
+	classes := make([]PitchClass, 0, 6)
+	if c.bass != nil {
+		classes = append(classes, c.Bass())
+	}
+	for _, interval := range append([]HalfSteps{0}, chordQualities[c.quality]...) {
+		pc := root.GetTransposedCopy(interval)
+		if c.bass == nil || *pc != c.Bass() {
+			classes = append(classes, *pc)
+		}
+	}
+	return classes
+}
+
+// chordToneLetters The number of letters above the root each pitch of a chord is written, by its size in half steps, e.g. a minor seventh
+// (10) is six letters up. Pitches in the octave above the root are the ninth, eleventh and thirteenth, so 15 half steps is a raised
+// ninth rather than a minor tenth.
+var (
+	chordToneLetters      = [OctaveValue]Letter{0, 1, 1, 2, 2, 3, 4, 4, 4, 5, 6, 6}
+	chordExtensionLetters = [OctaveValue]Letter{0, 1, 1, 1, 2, 3, 3, 4, 5, 5, 6, 6}
+)
+
+// Spell Writes out the pitches of the chord from the bass upwards, with the bass in the given octave. Each pitch is spelled by the
+// interval it makes with the root, so the seventh of C7 is B♭ rather than A♯, and the seventh of Cdim7 is B𝄫. As in PitchClasses, a
+// bass note that is also in the chord isn't repeated.
+func (c *ChordSymbol) Spell(octave int) []SpelledPitch {
+	intervals := chordQualities[c.quality]
+	pitches := make([]SpelledPitch, 0, len(intervals)+2)
+	root := *MakeSpelledPitch(c.root.letter, c.root.Alter(), octave)
+	if c.bass != nil {
+		bass := *MakeSpelledPitch(c.bass.letter, c.bass.Alter(), octave)
+		pitches = append(pitches, bass)
+		for above := octave + 1; root.pitch.value <= bass.pitch.value; above++ {
+			root = *MakeSpelledPitch(c.root.letter, c.root.Alter(), above)
+		}
+	}
+	diminished := false
+	for _, interval := range append([]HalfSteps{0}, intervals...) {
+		letters := chordToneLetters[interval%OctaveValue]
+		if interval >= OctaveValue {
+			letters = chordExtensionLetters[interval%OctaveValue]
+		}
+		if interval == 6 {
+			diminished = true
+		} else if interval == MajorSixth && diminished {
+			// Above a diminished fifth this is a diminished seventh
+			letters = 6
+		}
+		tone := SpelledPitch{*root.pitch.GetTransposedCopy(interval), (root.letter + letters) % LettersInOctave}
+		if c.bass == nil || tone.pitch.class != c.bass.pitch.class {
+			pitches = append(pitches, tone)
+		}
+	}
+	return pitches
+}
+
+// Transpose Moves the chord by the given number of half steps. The new root and bass are spelled with as small an accidental as
+// possible, preferring sharps; use Respell to spell them for a key.
+func (c *ChordSymbol) Transpose(halfSteps HalfSteps) {
+	c.root.Transpose(halfSteps)
+	if c.bass != nil {
+		c.bass.Transpose(halfSteps)
+	}
+	c.Respell(nil)
+}
+
+// Respell Spells the root and bass as they would be written in the given key (see SpellPitch).
+func (c *ChordSymbol) Respell(key *KeySignature) {
+	c.root = respellChordSymbolNote(&c.root, key)
+	if c.bass != nil {
+		bass := respellChordSymbolNote(c.bass, key)
+		c.bass = &bass
+	}
+}
+
+// TransposeInKey Moves the chord, which is in the given key, by the given number of half steps, spelling the root and bass for the key
+// moved by the same amount so that they keep their places relative to its tonic (see TransposeInKey). A Cb chord in E♭ Major moved down
+// a half step is B♭ in D Major, and A♭ in C Major moved up a whole step is B♭ in D Major.
+func (c *ChordSymbol) TransposeInKey(halfSteps HalfSteps, key *KeySignature) {
+	c.root = transposeChordSymbolNote(&c.root, halfSteps, key)
+	if c.bass != nil {
+		bass := transposeChordSymbolNote(c.bass, halfSteps, key)
+		c.bass = &bass
+	}
+}
+
+// transposeChordSymbolNote Moves a note of a chord symbol in the given key, keeping it in octave 4 as respellChordSymbolNote does.
+func transposeChordSymbolNote(sp *SpelledPitch, halfSteps HalfSteps, key *KeySignature) SpelledPitch {
+	moved := TransposeInKey(*sp, halfSteps, key)
+	return *MakeSpelledPitch(moved.letter, moved.Alter(), 4)
+}
+
+// respellChordSymbolNote Spells a note as it would be written in the given key. Symbols don't have octaves, so the note is moved back
+// into octave 4, which keeps symbols that name the same chord equal.
+func respellChordSymbolNote(sp *SpelledPitch, key *KeySignature) SpelledPitch {
+	spelled := SpellPitch(sp.pitch, key)
+	return *MakeSpelledPitch(spelled.letter, spelled.Alter(), 4)
+}
+
+func (c *ChordSymbol) String() string {
+	name := chordSymbolNote(&c.root) + c.quality
+	if c.bass != nil {
+		name += "/" + chordSymbolNote(c.bass)
+	}
+	return name
+}
+
+// chordSymbolNote Returns the name of a note in a chord symbol, with accidentals written as # and b as they usually are in text.
+func chordSymbolNote(sp *SpelledPitch) string {
+	alter := sp.Alter()
+	if alter > 0 {
+		return sp.letter.String() + strings.Repeat("#", alter)
+	}
+	return sp.letter.String() + strings.Repeat("b", -alter)
+}
+
+// Key Returns the major key whose tonic is the root of the chord, or the minor key if the chord is minor, e.g. F♯ Minor for F♯m7/E. If
+// that key would need more than seven sharps or flats then nil is returned.
+func (c *ChordSymbol) Key() *KeySignature {
+	mode := "Ionian"
+	if c.IsMinor() {
+		mode = "Aeolian"
+	}
+	return MakeModalKeySignature(c.root.letter, c.root.Alter(), mode)
+}
+
+// TransposedItem A chord symbol or pitch moved by TransposeItems. Only one of the two is set.
+type TransposedItem struct {
+	Chord *ChordSymbol
+	Pitch *SpelledPitch
+}
+
+// TransposeItems Transposes chord symbols, or pitches with octaves, by the given number of half steps, spelling the results for the key
+// the music moves to. That is the given key moved by the same amount, or if it is nil then the key of the first chord that has one (see
+// ChordSymbol.Key) moved, which is returned along with the items. Each item keeps its place relative to the tonic (see TransposeInKey).
+// The given key isn't changed. Anything that reads as a chord symbol is taken as one, so "G7" is a chord and not a pitch. An error is
+// returned if an item is neither.
+func TransposeItems(items []string, halfSteps HalfSteps, key *KeySignature) ([]TransposedItem, *KeySignature, error) {
+	transposed := make([]TransposedItem, len(items))
+	pitches := make([]SpelledPitch, len(items))
+	for i, item := range items {
+		chord, err := ParseChordSymbol(item)
+		if err == nil {
+			transposed[i].Chord = chord
+			if key == nil {
+				key = chord.Key()
+			}
+		} else if pitches[i].UnmarshalText([]byte(item)) != nil {
+			return nil, nil, fmt.Errorf("%q is neither a chord symbol nor a pitch", item)
+		}
+	}
+	for i := range transposed {
+		chord := transposed[i].Chord
+		switch {
+		case chord != nil && key != nil:
+			chord.TransposeInKey(halfSteps, key)
+		case chord != nil:
+			chord.Transpose(halfSteps)
+		case key != nil:
+			sp := TransposeInKey(pitches[i], halfSteps, key)
+			transposed[i].Pitch = &sp
+		default:
+			pitch := pitches[i].Pitch()
+			pitch.Transpose(halfSteps)
+			sp := SpellPitch(pitch, nil)
+			transposed[i].Pitch = &sp
+		}
+	}
+	if key == nil {
+		return transposed, nil, nil
+	}
+	moved := *key
+	moved.Transpose(halfSteps)
+	return transposed, &moved, nil
+}
+
+// NamedNotes Notes read from their names, and the name of the chord they make, as found by NameNotes.
+type NamedNotes struct {
+	Notes   []SpelledPitch // As written, in octave 4 if no octave was given
+	Octaves bool           // Whether every note was written with an octave
+	Name    string         // The name of the chord, if it is known
+	Known   bool
+}
+
+// NameNotes Reads note names, with or without octaves (see ParseNoteName), and names the chord they make from CreateChordDictionary. When
+// every note has an octave the lowest is the bass, so inversions are named as slash chords; otherwise only the pitch classes count. The
+// name is written with flats if they are asked for, or if any of the notes is written with one. An error is returned if a note can't be
+// read.
+func NameNotes(names []string, flats bool) (*NamedNotes, error) {
+	result := &NamedNotes{Notes: make([]SpelledPitch, len(names)), Octaves: true}
+	for i, name := range names {
+		sp, octave, err := ParseNoteName(name)
+		if err != nil {
+			return nil, err
+		}
+		result.Notes[i], result.Octaves = *sp, result.Octaves && octave
+		flats = flats || sp.Alter() < 0
+	}
+	namer := CreateSharpPitchNamer()
+	if flats {
+		namer = CreateFlatPitchNamer()
+	}
+	pitches := make([]Pitch, len(result.Notes))
+	classes := make([]PitchClass, 0, len(result.Notes))
+	for i := range result.Notes {
+		pitches[i] = result.Notes[i].pitch
+		if !slices.Contains(classes, pitches[i].class) {
+			classes = append(classes, pitches[i].class)
+		}
+	}
+	dict := CreateChordDictionary()
+	if result.Octaves {
+		result.Name, result.Known = MakeChord(pitches...).GetName(dict, namer)
+	} else {
+		result.Name, result.Known = GetChordName(dict, namer, classes)
+	}
+	return result, nil
+}
@@ -0,0 +1,270 @@
+package tonacity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseChordSymbol(t *testing.T) {
+	tests := []struct {
+		symbol      string
+		wantString  string
+		wantClasses []PitchClass
+		wantMinor   bool
+	}{
+		{"C", "C", []PitchClass{*C(), *E(), *G()}, false},
+		{"Am7", "Am7", []PitchClass{*A(), *C(), *E(), *G()}, true},
+		{"B♭maj7", "Bbmaj7", []PitchClass{*B().Flat(), *D(), *F(), *A()}, false},
+		{"F#m/C#", "F#m/C#", []PitchClass{*C().Sharp(), *F().Sharp(), *A()}, true},
+		{"D/F#", "D/F#", []PitchClass{*F().Sharp(), *D(), *A()}, false},
+		{"Bb5", "Bb5", []PitchClass{*B().Flat(), *F()}, false},
+		{"Ebsus4", "Ebsus4", []PitchClass{*E().Flat(), *A().Flat(), *B().Flat()}, false},
+		{"G7b9", "G7b9", []PitchClass{*G(), *B(), *D(), *F(), *A().Flat()}, false},
+		{"Cdim7", "Cdim7", []PitchClass{*C(), *E().Flat(), *G().Flat(), *A()}, false},
+		{"C6/9", "C6/9", []PitchClass{*C(), *E(), *G(), *A(), *D()}, false},
+		{"C6/9/E", "C6/9/E", []PitchClass{*E(), *C(), *G(), *A(), *D()}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			chord, err := ParseChordSymbol(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := chord.String(); got != tt.wantString {
+				t.Errorf("ChordSymbol.String() = %v, want %v", got, tt.wantString)
+			}
+			if got := chord.PitchClasses(); !reflect.DeepEqual(got, tt.wantClasses) {
+				t.Errorf("ChordSymbol.PitchClasses() = %v, want %v", got, tt.wantClasses)
+			}
+			if got := chord.IsMinor(); got != tt.wantMinor {
+				t.Errorf("ChordSymbol.IsMinor() = %v, want %v", got, tt.wantMinor)
+			}
+		})
+	}
+	for _, symbol := range []string{"", "H7", "Cfoo", "C/X", "C/Ebx", "C6/", "am"} {
+		if _, err := ParseChordSymbol(symbol); err == nil {
+			t.Errorf("ParseChordSymbol(%q) should fail", symbol)
+		}
+	}
+}
+
+func TestChordSymbol_Transpose(t *testing.T) {
+	tests := []struct {
+		symbol    string
+		halfSteps HalfSteps
+		key       *KeySignature
+		want      string
+	}{
+		{"G", 2, nil, "A"},
+		{"Bb", 1, nil, "B"},
+		{"C", 1, nil, "C#"},
+		{"C", 1, MakeKeySignature(-4, false), "Db"},
+		{"Am7/G", -2, MakeKeySignature(-2, false), "Gm7/F"},
+		{"D/F#", 3, MakeKeySignature(-1, false), "F/A"},
+		{"E", -1, MakeKeySignature(-4, true), "Eb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			chord, err := ParseChordSymbol(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chord.Transpose(tt.halfSteps)
+			if tt.key != nil {
+				chord.Respell(tt.key)
+			}
+			if got := chord.String(); got != tt.want {
+				t.Errorf("ChordSymbol.Transpose(%d) = %v, want %v", tt.halfSteps, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChordSymbol_TransposeInKey(t *testing.T) {
+	tests := []struct {
+		symbol    string
+		halfSteps HalfSteps
+		key       *KeySignature
+		want      string
+	}{
+		{"Ab", 2, MakeKeySignature(0, false), "Bb"},
+		{"Bb", 2, MakeKeySignature(0, false), "C"},
+		{"D/F#", 2, MakeKeySignature(0, false), "E/G#"},
+		{"Gb", -1, MakeKeySignature(-3, false), "F"},
+		{"Cb", -1, MakeKeySignature(-3, false), "Bb"},
+		{"B7", -1, MakeKeySignature(-3, false), "Bb7"},
+		{"E7", 3, MakeKeySignature(0, true), "G7"},
+		{"Bb", -1, MakeKeySignature(-1, true), "A"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			chord, err := ParseChordSymbol(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			chord.TransposeInKey(tt.halfSteps, tt.key)
+			if got := chord.String(); got != tt.want {
+				t.Errorf("ChordSymbol.TransposeInKey(%d) = %v, want %v", tt.halfSteps, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChordSymbol_Spell(t *testing.T) {
+	tests := []struct {
+		symbol string
+		octave int
+		want   string
+	}{
+		{"C", 4, "C4 E4 G4"},
+		{"C7", 4, "C4 E4 G4 B♭4"},
+		{"Cdim7", 4, "C4 E♭4 G♭4 B𝄫4"},
+		{"F#m7b5", 3, "F♯3 A3 C4 E4"},
+		{"G7#9", 3, "G3 B3 D4 F4 A♯4"},
+		{"Bbmaj9", 2, "B♭2 D3 F3 A3 C4"},
+		{"D/F#", 3, "F♯3 D4 A4"},
+		{"Am7/G", 3, "G3 A3 C4 E4"},
+		{"Ab/C", 4, "C4 A♭4 E♭5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			chord, err := ParseChordSymbol(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			pitches := chord.Spell(tt.octave)
+			names := make([]string, len(pitches))
+			for i := range pitches {
+				names[i] = pitches[i].String()
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("ChordSymbol.Spell() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestChordSymbol_Key(t *testing.T) {
+	tests := []struct {
+		symbol string
+		want   string
+	}{
+		{"C", "C"},
+		{"F#m7/E", "F#m"},
+		{"Bbmaj7", "Bb"},
+		{"G7", "G"},
+		{"Cdim", "C"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.symbol, func(t *testing.T) {
+			chord, err := ParseChordSymbol(tt.symbol)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got, _ := chord.Key().MarshalText(); string(got) != tt.want {
+				t.Errorf("ChordSymbol.Key() = %s, want %v", got, tt.want)
+			}
+		})
+	}
+	if chord, _ := ParseChordSymbol("Fbm"); chord.Key() != nil {
+		t.Errorf("ChordSymbol.Key() of Fbm = %v, want nil", chord.Key())
+	}
+}
+
+func TestTransposeItems(t *testing.T) {
+	tests := []struct {
+		name      string
+		items     string
+		halfSteps HalfSteps
+		key       string
+		want      string
+		wantKey   string
+	}{
+		{"Chords", "Cmaj7 Am7 Dm7 G7", 3, "", "Ebmaj7 Cm7 Fm7 Bb7", "Eb"},
+		{"In a key", "E B7 G#4", -1, "E", "Eb Bb7 G4", "Eb"},
+		{"Key of the first chord", "Am E7 C5", 2, "", "Bm F#7 D5", "Bm"},
+		{"Pitches without a key", "C4 F#4", 1, "", "C#4 G4", ""},
+		{"Chromatic chords", "C Ab Bb F", 2, "", "D Bb C G", "D"},
+		{"Chromatic chords in a flat key", "Gb Cb B7", -1, "Eb", "F Bb Bb7", "D"},
+		{"Chromatic pitches", "Ab4 B3", 2, "C", "Bb4 C#4", "D"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var key *KeySignature
+			if tt.key != "" {
+				key = new(KeySignature)
+				if err := key.UnmarshalText([]byte(tt.key)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			items, moved, err := TransposeItems(strings.Fields(tt.items), tt.halfSteps, key)
+			if err != nil {
+				t.Fatal(err)
+			}
+			names := make([]string, len(items))
+			for i, item := range items {
+				if item.Chord != nil {
+					names[i] = item.Chord.String()
+				} else {
+					text, _ := item.Pitch.MarshalText()
+					names[i] = string(text)
+				}
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("TransposeItems() = %v, want %v", got, tt.want)
+			}
+			gotKey := ""
+			if moved != nil {
+				text, _ := moved.MarshalText()
+				gotKey = string(text)
+			}
+			if gotKey != tt.wantKey {
+				t.Errorf("TransposeItems() key = %v, want %v", gotKey, tt.wantKey)
+			}
+			if key != nil {
+				if text, _ := key.MarshalText(); string(text) != tt.key {
+					t.Errorf("TransposeItems() changed the given key to %s", text)
+				}
+			}
+		})
+	}
+	if _, _, err := TransposeItems([]string{"C", "X"}, 1, nil); err == nil {
+		t.Error("TransposeItems() of X should fail")
+	}
+}
+
+func TestNameNotes(t *testing.T) {
+	tests := []struct {
+		name        string
+		notes       string
+		flats       bool
+		want        string
+		wantOctaves bool
+		wantKnown   bool
+	}{
+		{"Pitch classes", "C E G", false, "C Major", false, true},
+		{"Repeated pitch classes", "C E G C", false, "C Major", false, true},
+		{"Inversion", "E3 G3 C4", false, "C Major/E", true, true},
+		{"Flats from the notes", "Eb G Bb", false, "E♭ Major", false, true},
+		{"Flats asked for", "C# F G#", true, "D♭ Major", false, true},
+		{"Mixed octaves", "C4 E G", false, "C Major", false, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := NameNotes(strings.Fields(tt.notes), tt.flats)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.Name != tt.want || got.Octaves != tt.wantOctaves || got.Known != tt.wantKnown {
+				t.Errorf("NameNotes() = %v %v %v, want %v %v %v", got.Name, got.Octaves, got.Known, tt.want, tt.wantOctaves, tt.wantKnown)
+			}
+			if len(got.Notes) != len(strings.Fields(tt.notes)) {
+				t.Errorf("NameNotes() read %d notes", len(got.Notes))
+			}
+		})
+	}
+	if _, err := NameNotes([]string{"C", "X"}, false); err == nil {
+		t.Error("NameNotes() of X should fail")
+	}
+}
@@ -0,0 +1,326 @@
+package tonacity
+
+import (
+	"fmt"
+	"math"
+	"math/cmplx"
+)
+
+// Recognising chords from audio works on "chroma": the energy in a signal folded down onto the twelve pitch classes. Octave
+// information is thrown away on purpose, because a C Major chord is a C Major chord whether it is voiced C3 E3 G3 or E4 G4 C5.
+
+// ChromaBinCount The number of bins in a chroma vector, one per pitch class.
+const ChromaBinCount = OctaveValue
+
+// NoChord The name given to stretches of audio where no chord could be recognised, e.g. silence.
+const NoChord = "N"
+
+// ChromaVector The relative energy of each of the twelve pitch classes. Bins are indexed by pitch class value, so C is bin 0, C♯ is bin 1, etc.
+type ChromaVector [ChromaBinCount]float64
+
+// Energy Returns the energy in the bin of the given pitch class.
+func (v *ChromaVector) Energy(pitchClass PitchClass) float64 {
+	return v[normalisePitchClassValue(pitchClass.value)]
+}
+
+// Norm Returns the Euclidean length of the vector.
+func (v *ChromaVector) Norm() float64 {
+	var sum float64
+	for _, e := range v {
+		sum += e * e
+	}
+	return math.Sqrt(sum)
+}
+
+// Similarity Returns the cosine similarity of the two vectors, between 0 (nothing in common) and 1 (identical up to scale).
+// If either vector is silent then 0 is returned.
+func (v *ChromaVector) Similarity(other *ChromaVector) float64 {
+	n := v.Norm() * other.Norm()
+	if n == 0 {
+		return 0
+	}
+	var dot float64
+	for i := range v {
+		dot += v[i] * other[i]
+	}
+	return dot / n
+}
+
+// ChromaFrame The chroma of a single window of audio, which begins Time seconds into the signal.
+type ChromaFrame struct {
+	Time   float64
+	Chroma ChromaVector
+}
+
+// ChromagramExtractor Turns PCM audio into a sequence of chroma frames by taking the FFT of overlapping Hann-windowed frames and
+// summing the power of each frequency bin into the pitch class nearest to it.
+type ChromagramExtractor struct {
+	sampleRate   float64
+	frameSize    int     // Number of samples in each FFT, must be a power of two
+	hopSize      int     // Number of samples between the starts of consecutive frames
+	concertPitch float64 // The frequency of A4, used to decide which pitch class a frequency belongs to
+	minFrequency float64 // Frequencies below this are ignored, as the FFT cannot separate semitones down there
+	maxFrequency float64 // Frequencies above this are ignored, as they are mostly harmonics
+	window       []float64
+}
+
+// NewChromagramExtractor Creates an extractor for audio at the given sample rate. The frame size must be a power of two, and
+// should be large enough to separate neighbouring semitones at the lowest frequency of interest (4096 at 44.1kHz is a good start).
+func NewChromagramExtractor(sampleRate float64, frameSize int, hopSize int, concertPitch float64) (*ChromagramExtractor, error) {
+	if frameSize < 2 || frameSize&(frameSize-1) != 0 {
+		return nil, fmt.Errorf("frame size %d is not a power of two", frameSize)
+	}
+	if hopSize < 1 {
+		return nil, fmt.Errorf("hop size %d must be positive", hopSize)
+	}
+	if sampleRate <= 0 || concertPitch <= 0 {
+		return nil, fmt.Errorf("sample rate and concert pitch must be positive")
+	}
+	window := make([]float64, frameSize)
+	for i := range window {
+		window[i] = 0.5 - 0.5*math.Cos(2*math.Pi*float64(i)/float64(frameSize-1))
+	}
+	return &ChromagramExtractor{sampleRate, frameSize, hopSize, concertPitch, 80, 5000, window}, nil
+}
+
+// SetFrequencyRange Restricts the frequencies that contribute to the chroma to those between min and max Hertz inclusive.
+func (e *ChromagramExtractor) SetFrequencyRange(min float64, max float64) {
+	e.minFrequency = min
+	e.maxFrequency = max
+}
+
+// Extract Computes the chroma of every full frame of the given mono samples. Each frame is normalised so its largest bin is 1,
+// unless it is silent, in which case all of its bins are 0.
+func (e *ChromagramExtractor) Extract(samples []float64) []ChromaFrame {
+	frames := make([]ChromaFrame, 0)
+	buffer := make([]complex128, e.frameSize)
+	for start := 0; start+e.frameSize <= len(samples); start += e.hopSize {
+		for i := range buffer {
+			buffer[i] = complex(samples[start+i]*e.window[i], 0)
+		}
+		fft(buffer)
+		frames = append(frames, ChromaFrame{float64(start) / e.sampleRate, e.fold(buffer)})
+	}
+	return frames
+}
+
+// fold Sums the power of each FFT bin into the chroma bin of the nearest pitch class.
+func (e *ChromagramExtractor) fold(spectrum []complex128) (chroma ChromaVector) {
+	binWidth := e.sampleRate / float64(e.frameSize)
+	for k := 1; k < e.frameSize/2; k++ {
+		frequency := float64(k) * binWidth
+		if frequency < e.minFrequency || frequency > e.maxFrequency {
+			continue
+		}
+		// Half steps from A4, which is pitch class A (9)
+		halfSteps := int(math.Round(OctaveValue * math.Log2(frequency/e.concertPitch)))
+		magnitude := cmplx.Abs(spectrum[k])
+		chroma[normalisePitchClassValue(HalfSteps((halfSteps+9)%OctaveValue))] += magnitude * magnitude
+	}
+	var max float64
+	for _, v := range chroma {
+		max = math.Max(max, v)
+	}
+	if max < 1e-9 {
+		return ChromaVector{}
+	}
+	for i := range chroma {
+		chroma[i] /= max
+	}
+	return
+}
+
+// normalisePitchClassValue Maps any number of half steps onto the range [0, 12).
+func normalisePitchClassValue(value HalfSteps) HalfSteps {
+	value %= OctaveValue
+	if value < 0 {
+		value += OctaveValue
+	}
+	return value
+}
+
+// fft An in-place, iterative, radix-2 Cooley-Tukey fast Fourier transform. The length of x must be a power of two.
+func fft(x []complex128) {
+	n := len(x)
+	for i, j := 1, 0; i < n; i++ {
+		bit := n >> 1
+		for ; j&bit != 0; bit >>= 1 {
+			j ^= bit
+		}
+		j ^= bit
+		if i < j {
+			x[i], x[j] = x[j], x[i]
+		}
+	}
+	for size := 2; size <= n; size <<= 1 {
+		step := cmplx.Exp(complex(0, -2*math.Pi/float64(size)))
+		for start := 0; start < n; start += size {
+			w := complex(1, 0)
+			for k := 0; k < size/2; k++ {
+				even, odd := x[start+k], x[start+k+size/2]*w
+				x[start+k] = even + odd
+				x[start+k+size/2] = even - odd
+				w *= step
+			}
+		}
+	}
+}
+
+// chordTemplate The ideal chroma of a chord: equal energy in each of its pitch classes and none elsewhere.
+type chordTemplate struct {
+	name   string
+	root   PitchClass
+	chroma ChromaVector
+}
+
+// ChordRecogniser Matches chroma against every chord in a chord dictionary, in every key.
+type ChordRecogniser struct {
+	templates []chordTemplate
+	threshold float64 // The similarity below which a frame is considered to contain no chord
+}
+
+// NewChordRecogniser Creates a recogniser for the chords in the given dictionary (see CreateChordDictionary), naming them with
+// the given pitch namer in the same way as GetChordName.
+func NewChordRecogniser(dict *PatternDictionary, pitchNamer *PitchNamer) *ChordRecogniser {
+	templates := make([]chordTemplate, 0)
+	dict.Walk(func(pattern *Pattern, entries []interface{}) {
+		for _, entry := range entries {
+			e, ok := entry.(*chordDictionaryEntry)
+			// Inversions share the pitch classes of the root position chord, and chroma can't tell them apart
+			if !ok || e.rootIndex != 0 {
+				continue
+			}
+			for root := HalfSteps(0); root < OctaveValue; root++ {
+				t := chordTemplate{name: fmt.Sprintf("%s%s", pitchNamer.Name(PitchClass{root}), e.name), root: PitchClass{root}}
+				t.chroma[root] = 1
+				value := root
+				for _, interval := range pattern.intervals {
+					value = normalisePitchClassValue(value + interval)
+					t.chroma[value] = 1
+				}
+				templates = append(templates, t)
+			}
+		}
+	})
+	return &ChordRecogniser{templates, 0.5}
+}
+
+// SetThreshold Sets the similarity (between 0 and 1) that a frame must reach with a chord for it to be recognised.
+func (r *ChordRecogniser) SetThreshold(threshold float64) {
+	r.threshold = threshold
+}
+
+// Recognise Returns the name and root of the chord whose template best matches the given chroma. The bool will be false if the chroma is
+// silent or nothing matches well enough.
+func (r *ChordRecogniser) Recognise(chroma *ChromaVector) (name string, root PitchClass, ok bool) {
+	best := r.threshold
+	for i := range r.templates {
+		similarity := chroma.Similarity(&r.templates[i].chroma)
+		if similarity > best {
+			best = similarity
+			name, root, ok = r.templates[i].name, r.templates[i].root, true
+		}
+	}
+	return
+}
+
+// ChordSegment A stretch of audio, from Start to End seconds, over which the same chord was recognised. Name is NoChord if nothing was.
+type ChordSegment struct {
+	Start float64
+	End   float64
+	Name  string
+	Root  PitchClass
+}
+
+// RecogniseTrack Recognises the chord in each frame and merges consecutive frames with the same chord into segments. frameDuration
+// is the length in seconds of the audio each frame covers, used to close off the final segment.
+func (r *ChordRecogniser) RecogniseTrack(frames []ChromaFrame, frameDuration float64) []ChordSegment {
+	track := make([]ChordSegment, 0)
+	for i := range frames {
+		name, root, ok := r.Recognise(&frames[i].Chroma)
+		if !ok {
+			name = NoChord
+		}
+		if n := len(track); n > 0 && track[n-1].Name == name {
+			continue
+		} else if n > 0 {
+			track[n-1].End = frames[i].Time
+		}
+		track = append(track, ChordSegment{frames[i].Time, frames[i].Time, name, root})
+	}
+	if n := len(track); n > 0 {
+		track[n-1].End = frames[len(frames)-1].Time + frameDuration
+	}
+	return track
+}
+
+// Key profiles from Krumhansl and Kessler's probe-tone experiments, starting from the tonic, giving how well each pitch class was
+// judged to fit in a major or minor key.
+var (
+	majorKeyProfile = [ChromaBinCount]float64{6.35, 2.23, 3.48, 2.33, 4.38, 4.09, 2.52, 5.19, 2.39, 3.66, 2.29, 2.88}
+	minorKeyProfile = [ChromaBinCount]float64{6.33, 2.68, 3.52, 5.38, 2.60, 3.53, 2.54, 4.75, 3.98, 2.69, 3.34, 3.17}
+)
+
+// KeyEstimate The key that best explains a piece of audio, and the correlation (between -1 and 1) of its profile with the audio's chroma.
+type KeyEstimate struct {
+	Tonic       PitchClass
+	Minor       bool
+	Correlation float64
+}
+
+// Name Returns the name of the estimated key, e.g. "A Minor".
+func (k *KeyEstimate) Name(pitchNamer *PitchNamer) string {
+	if k.Minor {
+		return pitchNamer.Name(k.Tonic) + " Minor"
+	}
+	return pitchNamer.Name(k.Tonic) + " Major"
+}
+
+// EstimateKey Estimates the key of the given frames by correlating their summed chroma against the major and minor key profiles
+// rotated to every tonic (the Krumhansl-Schmuckler algorithm).
+func EstimateKey(frames []ChromaFrame) KeyEstimate {
+	var total ChromaVector
+	for i := range frames {
+		for j, v := range frames[i].Chroma {
+			total[j] += v
+		}
+	}
+	best := KeyEstimate{Correlation: math.Inf(-1)}
+	for tonic := HalfSteps(0); tonic < OctaveValue; tonic++ {
+		for _, minor := range []bool{false, true} {
+			profile := majorKeyProfile
+			if minor {
+				profile = minorKeyProfile
+			}
+			var rotated [ChromaBinCount]float64
+			for i := range profile {
+				rotated[normalisePitchClassValue(tonic+HalfSteps(i))] = profile[i]
+			}
+			if c := correlation(total[:], rotated[:]); c > best.Correlation {
+				best = KeyEstimate{PitchClass{tonic}, minor, c}
+			}
+		}
+	}
+	return best
+}
+
+// correlation Pearson's correlation coefficient of two equally sized samples.
+func correlation(a []float64, b []float64) float64 {
+	var meanA, meanB float64
+	for i := range a {
+		meanA += a[i]
+		meanB += b[i]
+	}
+	meanA /= float64(len(a))
+	meanB /= float64(len(b))
+	var cov, varA, varB float64
+	for i := range a {
+		cov += (a[i] - meanA) * (b[i] - meanB)
+		varA += (a[i] - meanA) * (a[i] - meanA)
+		varB += (b[i] - meanB) * (b[i] - meanB)
+	}
+	if varA == 0 || varB == 0 {
+		return 0
+	}
+	return cov / math.Sqrt(varA*varB)
+}
@@ -0,0 +1,114 @@
+package tonacity
+
+import (
+	"math"
+	"testing"
+)
+
+const testSampleRate = 22050
+
+// synthesise Returns the given number of seconds of equal-amplitude sine waves at the frequencies of the given pitches.
+func synthesise(seconds float64, pitches ...*Pitch) []float64 {
+	samples := make([]float64, int(seconds*testSampleRate))
+	for _, p := range pitches {
+		f := p.FrequencyInHertz(StandardConcertPitch)
+		for i := range samples {
+			samples[i] += math.Sin(2*math.Pi*f*float64(i)/testSampleRate) / float64(len(pitches))
+		}
+	}
+	return samples
+}
+
+func TestChromagramExtractor_Extract(t *testing.T) {
+	e, err := NewChromagramExtractor(testSampleRate, 4096, 2048, StandardConcertPitch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	frames := e.Extract(synthesise(1, A4()))
+	if len(frames) == 0 {
+		t.Fatal("Extract() returned no frames")
+	}
+	for _, frame := range frames {
+		if got := frame.Chroma.Energy(*A()); got != 1 {
+			t.Errorf("Extract() energy of A at %vs = %v, want 1", frame.Time, got)
+		}
+		for i, v := range frame.Chroma {
+			if i != 9 && v > 0.05 {
+				t.Errorf("Extract() energy of bin %d at %vs = %v, want ~0", i, frame.Time, v)
+			}
+		}
+	}
+	if silent := e.Extract(make([]float64, 8192)); silent[0].Chroma.Norm() != 0 {
+		t.Errorf("Extract() of silence = %v, want all zero", silent[0].Chroma)
+	}
+}
+
+func TestNewChromagramExtractor_InvalidFrameSize(t *testing.T) {
+	if _, err := NewChromagramExtractor(testSampleRate, 1000, 500, StandardConcertPitch); err == nil {
+		t.Error("NewChromagramExtractor() with a frame size that isn't a power of two should fail")
+	}
+}
+
+func TestChordRecogniser_RecogniseTrack(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	signal := synthesise(1, pf.GetPitch(C(), 4), pf.GetPitch(E(), 4), pf.GetPitch(G(), 4))
+	signal = append(signal, make([]float64, testSampleRate)...)
+	signal = append(signal, synthesise(1, pf.GetPitch(G(), 3), pf.GetPitch(B(), 3), pf.GetPitch(D(), 4), pf.GetPitch(F(), 4))...)
+	signal = append(signal, synthesise(1, pf.GetPitch(A(), 3), pf.GetPitch(C(), 4), pf.GetPitch(E(), 4))...)
+
+	e, _ := NewChromagramExtractor(testSampleRate, 4096, 4096, StandardConcertPitch)
+	r := NewChordRecogniser(CreateChordDictionary(), CreateSharpPitchNamer())
+	track := r.RecogniseTrack(e.Extract(signal), 4096.0/testSampleRate)
+
+	// Frames straddling a change hear both chords, so only check the chord heard in the middle of each second
+	tests := []struct {
+		time float64
+		want string
+	}{
+		{0.5, "C Major"},
+		{1.5, NoChord},
+		{2.5, "G Dominant Seventh"},
+		{3.5, "A Minor"},
+	}
+	for _, tt := range tests {
+		found := false
+		for _, segment := range track {
+			if segment.Start <= tt.time && tt.time < segment.End {
+				found = true
+				if segment.Name != tt.want {
+					t.Errorf("RecogniseTrack() at %vs = %v, want %v", tt.time, segment.Name, tt.want)
+				}
+			}
+		}
+		if !found {
+			t.Errorf("RecogniseTrack() has no segment covering %vs: %v", tt.time, track)
+		}
+	}
+}
+
+func TestEstimateKey(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	namer := CreateFlatPitchNamer()
+	tests := []struct {
+		name    string
+		classes []*PitchClass
+		want    string
+	}{
+		{"C Major", []*PitchClass{C(), D(), E(), F(), G(), A(), B(), C(), E(), G(), C()}, "C Major"},
+		{"A Minor", []*PitchClass{A(), B(), C(), D(), E(), F(), G().Sharp(), A(), C(), E(), A()}, "A Minor"},
+		{"B♭ Major", []*PitchClass{B().Flat(), C(), D(), E().Flat(), F(), G(), A(), B().Flat(), D(), F(), B().Flat()}, "B♭ Major"},
+	}
+	e, _ := NewChromagramExtractor(testSampleRate, 4096, 2048, StandardConcertPitch)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			signal := make([]float64, 0)
+			for _, c := range tt.classes {
+				signal = append(signal, synthesise(0.25, pf.GetPitch(c, 4))...)
+			}
+			got := EstimateKey(e.Extract(signal))
+			if name := got.Name(namer); name != tt.want {
+				t.Errorf("EstimateKey() = %v, want %v", name, tt.want)
+			}
+		})
+	}
+}
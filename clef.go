@@ -0,0 +1,136 @@
+package tonacity
+
+import "fmt"
+
+// MakeTrebleClef Creates the treble clef, a G clef on the second line, used for most music above middle C.
+func MakeTrebleClef() *Clef {
+	return MakeClef(GClef, 2, 0)
+}
+
+// MakeBassClef Creates the bass clef, an F clef on the fourth line, used for most music below middle C.
+func MakeBassClef() *Clef {
+	return MakeClef(FClef, 4, 0)
+}
+
+// MakeAltoClef Creates the alto clef, a C clef on the middle line, used for the viola.
+func MakeAltoClef() *Clef {
+	return MakeClef(CClef, 3, 0)
+}
+
+// MakeTenorClef Creates the tenor clef, a C clef on the fourth line, used for the high parts of the cello, bassoon and trombone.
+func MakeTenorClef() *Clef {
+	return MakeClef(CClef, 4, 0)
+}
+
+// MakePercussionClef Creates the clef for unpitched percussion, whose lines stand for instruments rather than pitches.
+func MakePercussionClef() *Clef {
+	return MakeClef(PercussionClef, 3, 0)
+}
+
+// MakeOctaveTrebleClef Creates the treble clef marked with an 8 below, which sounds an octave lower than written, used for the guitar and the
+// tenor voice.
+func MakeOctaveTrebleClef() *Clef {
+	return MakeClef(GClef, 2, -1)
+}
+
+// clefNames The names of the clefs in common use.
+var clefNames = map[Clef]string{
+	*MakeTrebleClef():       "treble",
+	*MakeBassClef():         "bass",
+	*MakeAltoClef():         "alto",
+	*MakeTenorClef():        "tenor",
+	*MakeOctaveTrebleClef(): "treble 8vb",
+	*MakeClef(GClef, 2, 1):  "treble 8va",
+	*MakeClef(FClef, 4, -1): "bass 8vb",
+	*MakeClef(CClef, 1, 0):  "soprano",
+	*MakeClef(CClef, 2, 0):  "mezzo-soprano",
+	*MakeClef(CClef, 5, 0):  "baritone",
+	*MakeClef(FClef, 3, 0):  "baritone",
+	*MakeClef(GClef, 1, 0):  "French violin",
+}
+
+func (c *Clef) String() string {
+	if c.sign == PercussionClef {
+		return "percussion"
+	}
+	if name, ok := clefNames[*c]; ok {
+		return name
+	}
+	name := map[ClefSign]string{GClef: "G", FClef: "F", CClef: "C"}[c.sign]
+	text := fmt.Sprintf("%s clef on line %d", name, c.line)
+	if c.octaveChange != 0 {
+		text += fmt.Sprintf(" %+d octaves", c.octaveChange)
+	}
+	return text
+}
+
+// reference Returns the pitch written on the clef's line, and the line, counting up from 1 at the bottom. Percussion is written on the
+// same lines as the treble clef.
+func (c *Clef) reference() (*SpelledPitch, int) {
+	switch c.sign {
+	case FClef:
+		return MakeSpelledPitch(LetterF, 0, 3), c.line
+	case CClef:
+		return MakeSpelledPitch(LetterC, 0, 4), c.line
+	case PercussionClef:
+		return MakeSpelledPitch(LetterG, 0, 4), 2
+	default:
+		return MakeSpelledPitch(LetterG, 0, 4), c.line
+	}
+}
+
+// StaffPosition Returns where the given pitch, as it sounds, is written on a stave with this clef, in steps above the bottom line, so 0 is
+// the bottom line, 1 the space above it, 8 the top line, and -2 the first ledger line below the stave. The pitch's accidental doesn't
+// change where it is written, but an octave clef does, e.g. the guitar's E2 is written as E3. A nil clef is taken to be the treble clef.
+func (c *Clef) StaffPosition(sp *SpelledPitch) int {
+	if c == nil {
+		c = MakeTrebleClef()
+	}
+	reference, line := c.reference()
+	return sp.Step() - c.octaveChange*LettersInOctave - reference.Step() + (line-1)*2
+}
+
+// PitchAt Returns the pitch that sounds when written at the given position on a stave with this clef, counted as by StaffPosition, with the given
+// accidental, e.g. -1 for a flat. A nil clef is taken to be the treble clef.
+func (c *Clef) PitchAt(position int, alter int) *SpelledPitch {
+	if c == nil {
+		c = MakeTrebleClef()
+	}
+	reference, line := c.reference()
+	step := position + c.octaveChange*LettersInOctave + reference.Step() - (line-1)*2
+	octave, letter := step/LettersInOctave, step%LettersInOctave
+	if letter < 0 {
+		octave, letter = octave-1, letter+LettersInOctave
+	}
+	return MakeSpelledPitch(Letter(letter), alter, octave)
+}
+
+// LedgerLines Returns how many ledger lines the given pitch needs on a stave with this clef, which is positive above the stave and
+// negative below it, e.g. -1 for middle C in the treble clef. A note in the space just outside the stave needs none.
+func (c *Clef) LedgerLines(sp *SpelledPitch) int {
+	switch position := c.StaffPosition(sp); {
+	case position > 8:
+		return (position - 8) / 2
+	case position < 0:
+		return position / 2
+	}
+	return 0
+}
+
+// BestClef Returns whichever of the given clefs writes the range from the low to the high pitch with the fewest ledger lines, counting
+// those needed by both ends. If no clefs are given, the treble and bass clefs are chosen between. When clefs do equally well, the first
+// is chosen.
+func BestClef(low *SpelledPitch, high *SpelledPitch, clefs ...*Clef) *Clef {
+	if len(clefs) == 0 {
+		clefs = []*Clef{MakeTrebleClef(), MakeBassClef()}
+	}
+	var best *Clef
+	fewest := 0
+	for _, clef := range clefs {
+		lines := abs(clef.LedgerLines(low)) + abs(clef.LedgerLines(high))
+		if best == nil || lines < fewest {
+			best, fewest = clef, lines
+		}
+	}
+	return best
+}
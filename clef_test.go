@@ -0,0 +1,108 @@
+package tonacity
+
+import "testing"
+
+func TestClef_StaffPosition(t *testing.T) {
+	tests := []struct {
+		name  string
+		pitch *SpelledPitch
+		clef  *Clef
+		want  int
+	}{
+		{"E4 treble", MakeSpelledPitch(LetterE, 0, 4), nil, 0},
+		{"middle C treble", MakeSpelledPitch(LetterC, 0, 4), MakeClef(GClef, 2, 0), -2},
+		{"F♯5 treble", MakeSpelledPitch(LetterF, 1, 5), MakeClef(GClef, 2, 0), 8},
+		{"A3 bass", MakeSpelledPitch(LetterA, 0, 3), MakeClef(FClef, 4, 0), 8},
+		{"middle C alto", MakeSpelledPitch(LetterC, 0, 4), MakeClef(CClef, 3, 0), 4},
+		{"middle C tenor", MakeSpelledPitch(LetterC, 0, 4), MakeClef(CClef, 4, 0), 6},
+		{"G3 treble sounding an octave lower", MakeSpelledPitch(LetterG, 0, 3), MakeClef(GClef, 2, -1), 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.clef.StaffPosition(tt.pitch); got != tt.want {
+				t.Errorf("StaffPosition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestClef_PitchAt(t *testing.T) {
+	for _, clef := range []*Clef{nil, MakeBassClef(), MakeAltoClef(), MakeTenorClef(), MakeOctaveTrebleClef(), MakeClef(GClef, 2, 1)} {
+		for position := -10; position <= 18; position++ {
+			sp := clef.PitchAt(position, 0)
+			if got := clef.StaffPosition(sp); got != position {
+				t.Errorf("%v: StaffPosition(PitchAt(%d)) = %d", clef, position, got)
+			}
+		}
+	}
+	tests := []struct {
+		clef     *Clef
+		position int
+		alter    int
+		want     string
+	}{
+		{MakeTrebleClef(), 0, 0, "E4"},
+		{MakeTrebleClef(), -2, 1, "C♯4"},
+		{MakeBassClef(), 4, -1, "D♭3"},
+		{MakeAltoClef(), 4, 0, "C4"},
+		{MakeOctaveTrebleClef(), 2, 0, "G3"},
+		{MakePercussionClef(), 2, 0, "G4"},
+	}
+	for _, tt := range tests {
+		if got := tt.clef.PitchAt(tt.position, tt.alter).String(); got != tt.want {
+			t.Errorf("%v: PitchAt(%d, %d) = %v, want %v", tt.clef, tt.position, tt.alter, got, tt.want)
+		}
+	}
+}
+
+func TestClef_LedgerLines(t *testing.T) {
+	tests := []struct {
+		name  string
+		pitch *SpelledPitch
+		clef  *Clef
+		want  int
+	}{
+		{"middle C treble", MakeSpelledPitch(LetterC, 0, 4), MakeTrebleClef(), -1},
+		{"D4 treble", MakeSpelledPitch(LetterD, 0, 4), MakeTrebleClef(), 0},
+		{"A5 treble", MakeSpelledPitch(LetterA, 0, 5), MakeTrebleClef(), 1},
+		{"C6 treble", MakeSpelledPitch(LetterC, 0, 6), MakeTrebleClef(), 2},
+		{"G5 treble", MakeSpelledPitch(LetterG, 0, 5), MakeTrebleClef(), 0},
+		{"middle C bass", MakeSpelledPitch(LetterC, 0, 4), MakeBassClef(), 1},
+		{"E2 bass", MakeSpelledPitch(LetterE, 0, 2), MakeBassClef(), -1},
+		{"middle C alto", MakeSpelledPitch(LetterC, 0, 4), MakeAltoClef(), 0},
+		{"E2 guitar", MakeSpelledPitch(LetterE, 0, 2), MakeOctaveTrebleClef(), -3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.clef.LedgerLines(tt.pitch); got != tt.want {
+				t.Errorf("LedgerLines() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBestClef(t *testing.T) {
+	tests := []struct {
+		name  string
+		low   *SpelledPitch
+		high  *SpelledPitch
+		clefs []*Clef
+		want  string
+	}{
+		{"Violin", MakeSpelledPitch(LetterG, 0, 3), MakeSpelledPitch(LetterE, 0, 5), nil, "treble"},
+		{"Cello", MakeSpelledPitch(LetterC, 0, 2), MakeSpelledPitch(LetterA, 0, 3), nil, "bass"},
+		{"Middle C either way", MakeSpelledPitch(LetterC, 0, 4), MakeSpelledPitch(LetterC, 0, 4), nil, "treble"},
+		{"Viola", MakeSpelledPitch(LetterC, 0, 3), MakeSpelledPitch(LetterD, 0, 5), []*Clef{MakeTrebleClef(), MakeAltoClef(), MakeBassClef()}, "alto"},
+		{"Guitar", MakeSpelledPitch(LetterE, 0, 2), MakeSpelledPitch(LetterE, 0, 5), []*Clef{MakeTrebleClef(), MakeOctaveTrebleClef()}, "treble 8vb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := BestClef(tt.low, tt.high, tt.clefs...).String(); got != tt.want {
+				t.Errorf("BestClef() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if got := MakeClef(CClef, 4, -1).String(); got != "C clef on line 4 -1 octaves" {
+		t.Errorf("String() = %v", got)
+	}
+}
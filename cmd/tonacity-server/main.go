@@ -0,0 +1,28 @@
+// Command tonacity-server serves the theory engine over HTTP, as described by /openapi.json, e.g.
+//
+//	tonacity-server -addr :8080
+//	curl 'localhost:8080/v1/chords/name?notes=C,E,G,B'
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/chris-franklin/tonacity/server"
+)
+
+func main() {
+	addr := flag.String("addr", ":8080", "the address to listen on")
+	flag.Parse()
+	srv := &http.Server{
+		Addr:              *addr,
+		Handler:           server.NewHandler(),
+		ReadHeaderTimeout: 5 * time.Second,
+		ReadTimeout:       10 * time.Second,
+		WriteTimeout:      10 * time.Second,
+	}
+	log.Printf("listening on %s", *addr)
+	log.Fatal(srv.ListenAndServe())
+}
@@ -0,0 +1,396 @@
+// Command tonacity answers everyday music theory questions, e.g.
+//
+//	tonacity name-chord C E G B
+//	tonacity spell-scale D dorian
+//	tonacity transpose --by 3 "Cmaj7 Am7 Dm7 G7"
+//	tonacity key-sig Eb
+//	tonacity freq A4 --concert 432
+//	tonacity intervals C4 G4
+//
+// Answers are written as plain text, or as JSON with --json. "tonacity repl" starts an interactive session that keeps a current key,
+// chord or scale between lines, with tab completion of commands, chord names and scale names.
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/chris-franklin/tonacity"
+)
+
+// errUsage Returned when the command line is wrong, after the usage has been written.
+var errUsage = errors.New("usage")
+
+// options The flags, which are accepted by every command and anywhere on the command line.
+type options struct {
+	json    bool
+	flats   bool
+	by      int
+	key     string
+	concert float64
+}
+
+// answer The result of a command, which is written as JSON with --json, or as the text it returns otherwise.
+type answer interface {
+	plain() string
+}
+
+// command A subcommand, with the arguments it takes and what it does.
+type command struct {
+	args  string
+	about string
+	run   func(args []string, opts *options) (answer, error)
+}
+
+var commands = map[string]command{
+	"name-chord":  {"NOTE...", "name the chord made by the notes, e.g. C E G B, or C4 E4 G4 for a named inversion", nameChord},
+	"spell-scale": {"ROOT SCALE", "write out a scale or mode, e.g. D dorian or Eb harmonic minor", spellScale},
+	"transpose":   {"--by N CHORD...", "transpose chord symbols or pitches by N half steps from --key (or the first chord's key)", transpose},
+	"key-sig":     {"KEY", "show the key signature of a key, e.g. Eb, F#m or D dorian", keySig},
+	"freq":        {"PITCH...", "show the frequency of pitches, with A4 at --concert Hz", freq},
+	"intervals":   {"PITCH PITCH...", "name the intervals between successive pitches", intervals},
+}
+
+func main() {
+	err := run(os.Args[1:], os.Stdin, os.Stdout, os.Stderr)
+	if errors.Is(err, errUsage) {
+		os.Exit(2)
+	} else if err != nil {
+		fmt.Fprintln(os.Stderr, "tonacity:", err)
+		os.Exit(1)
+	}
+}
+
+// run Runs the command named by the first argument, writing its answer to stdout and usage to stderr.
+func run(args []string, stdin io.Reader, stdout io.Writer, stderr io.Writer) error {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		usage(stderr)
+		return errUsage
+	}
+	if args[0] == "repl" {
+		return repl(stdin, stdout)
+	}
+	cmd, ok := commands[args[0]]
+	if !ok {
+		fmt.Fprintf(stderr, "tonacity: unknown command %q\n", args[0])
+		usage(stderr)
+		return errUsage
+	}
+	opts := &options{concert: tonacity.StandardConcertPitch}
+	fs := flag.NewFlagSet(args[0], flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.BoolVar(&opts.json, "json", false, "write the answer as JSON")
+	fs.BoolVar(&opts.flats, "flats", false, "name notes with flats rather than sharps")
+	fs.IntVar(&opts.by, "by", 0, "the number of half steps to transpose by")
+	fs.StringVar(&opts.key, "key", "", "the key being transposed from, which decides how notes are spelled, e.g. Eb")
+	fs.Float64Var(&opts.concert, "concert", opts.concert, "the frequency of A4 in Hz")
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "usage: tonacity %s %s [flags]\n", args[0], cmd.args)
+		fs.PrintDefaults()
+	}
+	positional, err := parseInterspersed(fs, args[1:])
+	if err != nil {
+		return errUsage
+	}
+	result, err := cmd.run(positional, opts)
+	if errors.Is(err, errUsage) {
+		fs.Usage()
+		return err
+	} else if err != nil {
+		return err
+	}
+	if opts.json {
+		enc := json.NewEncoder(stdout)
+		enc.SetIndent("", "  ")
+		return enc.Encode(result)
+	}
+	_, err = fmt.Fprintln(stdout, result.plain())
+	return err
+}
+
+// parseInterspersed Parses flags wherever they appear among the arguments, as in "freq A4 --concert 432", returning the arguments that
+// aren't flags. The flag package alone stops at the first argument that isn't a flag.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		if fs.NArg() == 0 {
+			return positional, nil
+		}
+		positional = append(positional, fs.Arg(0))
+		args = fs.Args()[1:]
+	}
+}
+
+func usage(w io.Writer) {
+	fmt.Fprintln(w, "usage: tonacity COMMAND [ARGS] [--json]")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(w, "  %-12s %-16s %s\n", name, commands[name].args, commands[name].about)
+	}
+	fmt.Fprintf(w, "  %-12s %-16s %s\n", "repl", "", "start an interactive session")
+}
+
+// noteName Writes a spelled pitch with ♯ and ♭ for plain output, or with # and b for JSON, and with or without its octave.
+func noteName(sp *tonacity.SpelledPitch, octave bool, opts *options) string {
+	if opts.json {
+		if !octave {
+			return sp.Name()
+		}
+		text, _ := sp.MarshalText()
+		return string(text)
+	}
+	name := sp.String()
+	if !octave {
+		name = strings.TrimRight(name, "-0123456789")
+	}
+	return name
+}
+
+type chordAnswer struct {
+	Notes []string `json:"notes"`
+	Name  string   `json:"name,omitempty"`
+	Known bool     `json:"known"`
+}
+
+func (a *chordAnswer) plain() string {
+	if !a.Known {
+		return "unknown chord " + strings.Join(a.Notes, " ")
+	}
+	return a.Name
+}
+
+// nameChord Names the chord made by the given notes, as tonacity.NameNotes does.
+func nameChord(args []string, opts *options) (answer, error) {
+	if len(args) < 2 {
+		return nil, errUsage
+	}
+	named, err := tonacity.NameNotes(args, opts.flats)
+	if err != nil {
+		return nil, err
+	}
+	result := &chordAnswer{Notes: make([]string, len(named.Notes)), Name: named.Name, Known: named.Known}
+	for i := range named.Notes {
+		result.Notes[i] = noteName(&named.Notes[i], named.Octaves, opts)
+	}
+	return result, nil
+}
+
+type scaleAnswer struct {
+	Root    string            `json:"root"`
+	Scale   string            `json:"scale"`
+	Pattern *tonacity.Pattern `json:"pattern"`
+	Notes   []string          `json:"notes"`
+}
+
+func (a *scaleAnswer) plain() string {
+	return strings.Join(a.Notes, " ")
+}
+
+// spellScale Writes out one octave of a scale, e.g. "D dorian" or "Eb harmonic minor".
+func spellScale(args []string, opts *options) (answer, error) {
+	if len(args) < 2 {
+		return nil, errUsage
+	}
+	root, octave, err := tonacity.ParseNoteName(args[0])
+	if err != nil {
+		return nil, err
+	}
+	name := strings.Join(args[1:], " ")
+	pattern, ok := tonacity.FindScale(name)
+	if !ok {
+		return nil, fmt.Errorf("unknown scale %q", name)
+	}
+	result := &scaleAnswer{Root: noteName(root, octave, opts), Scale: name, Pattern: pattern}
+	for _, sp := range tonacity.SpellScale(*root, pattern) {
+		result.Notes = append(result.Notes, noteName(&sp, octave, opts))
+	}
+	return result, nil
+}
+
+type transposeAnswer struct {
+	By   int      `json:"by"`
+	From []string `json:"from"`
+	To   []string `json:"to"`
+}
+
+func (a *transposeAnswer) plain() string {
+	return strings.Join(a.To, " ")
+}
+
+// transpose Transposes chord symbols, or pitches with octaves, by --by half steps. The results are spelled for the key the music moves
+// to, from --key if it is given, or else the major or minor key of the first chord (see tonacity.TransposeItems).
+func transpose(args []string, opts *options) (answer, error) {
+	fields := strings.Fields(strings.Join(args, " "))
+	if len(fields) == 0 {
+		return nil, errUsage
+	}
+	var key *tonacity.KeySignature
+	if opts.key != "" {
+		key = new(tonacity.KeySignature)
+		if err := key.UnmarshalText([]byte(opts.key)); err != nil {
+			return nil, err
+		}
+	}
+	items, _, err := tonacity.TransposeItems(fields, tonacity.HalfSteps(opts.by), key)
+	if err != nil {
+		return nil, err
+	}
+	result := &transposeAnswer{By: opts.by, From: fields}
+	for _, item := range items {
+		if item.Chord != nil {
+			result.To = append(result.To, item.Chord.String())
+		} else {
+			result.To = append(result.To, noteName(item.Pitch, true, opts))
+		}
+	}
+	return result, nil
+}
+
+type keyAnswer struct {
+	Key         *tonacity.KeySignature `json:"key"`
+	Fifths      int                    `json:"fifths"`
+	Accidentals []string               `json:"accidentals"`
+	Notes       []string               `json:"notes"`
+}
+
+func (a *keyAnswer) plain() string {
+	mode := a.Key.Mode()
+	switch mode {
+	case "Ionian":
+		mode = "Major"
+	case "Aeolian":
+		mode = "Minor"
+	}
+	name := fmt.Sprintf("%s %s: ", a.Notes[0], mode)
+	switch {
+	case a.Fifths == 0:
+		return name + "no sharps or flats"
+	case a.Fifths == 1:
+		return name + "1 sharp (" + a.Accidentals[0] + ")"
+	case a.Fifths == -1:
+		return name + "1 flat (" + a.Accidentals[0] + ")"
+	case a.Fifths > 0:
+		return fmt.Sprintf("%s%d sharps (%s)", name, a.Fifths, strings.Join(a.Accidentals, " "))
+	default:
+		return fmt.Sprintf("%s%d flats (%s)", name, -a.Fifths, strings.Join(a.Accidentals, " "))
+	}
+}
+
+// keySig Shows the sharps or flats of a key, in the order they are written, and the notes of its scale.
+func keySig(args []string, opts *options) (answer, error) {
+	if len(args) == 0 {
+		return nil, errUsage
+	}
+	key := new(tonacity.KeySignature)
+	if err := key.UnmarshalText([]byte(strings.Join(args, " "))); err != nil {
+		return nil, err
+	}
+	result := &keyAnswer{Key: key, Fifths: key.Fifths(), Accidentals: []string{}}
+	for _, sp := range key.Accidentals() {
+		result.Accidentals = append(result.Accidentals, noteName(&sp, false, opts))
+	}
+	for _, sp := range key.Scale() {
+		result.Notes = append(result.Notes, noteName(&sp, false, opts))
+	}
+	return result, nil
+}
+
+type frequency struct {
+	Pitch string  `json:"pitch"`
+	Hertz float64 `json:"hertz"`
+}
+
+type freqAnswer struct {
+	Concert     float64     `json:"concert"`
+	Frequencies []frequency `json:"frequencies"`
+}
+
+func (a *freqAnswer) plain() string {
+	lines := make([]string, len(a.Frequencies))
+	for i, f := range a.Frequencies {
+		lines[i] = fmt.Sprintf("%s %.2f Hz", f.Pitch, f.Hertz)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// freq Shows the frequency of each pitch, with A4 at --concert Hz.
+func freq(args []string, opts *options) (answer, error) {
+	if len(args) == 0 {
+		return nil, errUsage
+	}
+	if opts.concert <= 0 {
+		return nil, fmt.Errorf("invalid concert pitch %v", opts.concert)
+	}
+	result := &freqAnswer{Concert: opts.concert}
+	for _, arg := range args {
+		sp, octave, err := tonacity.ParseNoteName(arg)
+		if err != nil || !octave {
+			return nil, fmt.Errorf("invalid pitch %q", arg)
+		}
+		pitch := sp.Pitch()
+		result.Frequencies = append(result.Frequencies, frequency{noteName(sp, true, opts), pitch.FrequencyInHertz(opts.concert)})
+	}
+	return result, nil
+}
+
+type interval struct {
+	From      string             `json:"from"`
+	To        string             `json:"to"`
+	HalfSteps tonacity.HalfSteps `json:"halfSteps"`
+	Name      string             `json:"name"`
+}
+
+type intervalsAnswer struct {
+	Intervals []interval `json:"intervals"`
+}
+
+func (a *intervalsAnswer) plain() string {
+	lines := make([]string, len(a.Intervals))
+	for i, iv := range a.Intervals {
+		direction := ""
+		if iv.HalfSteps > 0 {
+			direction = " up"
+		} else if iv.HalfSteps < 0 {
+			direction = " down"
+		}
+		lines[i] = fmt.Sprintf("%s → %s: %s%s (%d half steps)", iv.From, iv.To, iv.Name, direction, iv.HalfSteps)
+	}
+	return strings.Join(lines, "\n")
+}
+
+// intervals Names the interval between each pitch and the next.
+func intervals(args []string, opts *options) (answer, error) {
+	if len(args) < 2 {
+		return nil, errUsage
+	}
+	pitches := make([]*tonacity.SpelledPitch, len(args))
+	for i, arg := range args {
+		sp, octave, err := tonacity.ParseNoteName(arg)
+		if err != nil || !octave {
+			return nil, fmt.Errorf("invalid pitch %q", arg)
+		}
+		pitches[i] = sp
+	}
+	result := &intervalsAnswer{}
+	for i := 1; i < len(pitches); i++ {
+		from, to := pitches[i-1].Pitch(), pitches[i].Pitch()
+		halfSteps := from.GetDistanceTo(&to)
+		result.Intervals = append(result.Intervals, interval{
+			noteName(pitches[i-1], true, opts), noteName(pitches[i], true, opts), halfSteps, tonacity.IntervalName(halfSteps),
+		})
+	}
+	return result, nil
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+)
+
+func TestRun(t *testing.T) {
+	tests := []struct {
+		name string
+		args []string
+		want string
+	}{
+		{"Chord from pitch classes", []string{"name-chord", "C", "E", "G", "B"}, "C Major Seventh\n"},
+		{"Inverted chord", []string{"name-chord", "E4", "G4", "C5"}, "C Major/E\n"},
+		{"Chord with flats", []string{"name-chord", "Bb", "D", "F", "Ab"}, "B♭ Dominant Seventh\n"},
+		{"Chord with flats asked for", []string{"name-chord", "--flats", "A#", "D", "F"}, "B♭ Major\n"},
+		{"Unknown chord", []string{"name-chord", "C", "C#"}, "unknown chord C C♯\n"},
+		{"Mode", []string{"spell-scale", "D", "dorian"}, "D E F G A B C\n"},
+		{"Scale with octaves", []string{"spell-scale", "Eb4", "harmonic", "minor"}, "E♭4 F4 G♭4 A♭4 B♭4 C♭5 D5\n"},
+		{"Transpose chords", []string{"transpose", "--by", "3", "Cmaj7 Am7 Dm7 G7"}, "Ebmaj7 Cm7 Fm7 Bb7\n"},
+		{"Transpose in key", []string{"transpose", "--key", "E", "--by", "-1", "E", "B7", "G#4"}, "Eb Bb7 G4\n"},
+		{"Transpose chromatic chords", []string{"transpose", "--by", "2", "C Ab Bb F"}, "D Bb C G\n"},
+		{"Key signature", []string{"key-sig", "Eb"}, "E♭ Major: 3 flats (B♭ E♭ A♭)\n"},
+		{"Minor key signature", []string{"key-sig", "F#m"}, "F♯ Minor: 3 sharps (F♯ C♯ G♯)\n"},
+		{"Modal key signature", []string{"key-sig", "E", "phrygian"}, "E Phrygian: no sharps or flats\n"},
+		{"Frequency", []string{"freq", "A4", "--concert", "432"}, "A4 432.00 Hz\n"},
+		{"Frequencies", []string{"freq", "C4", "A5"}, "C4 261.63 Hz\nA5 880.00 Hz\n"},
+		{"Intervals", []string{"intervals", "C4", "G4", "E4"}, "C4 → G4: Perfect Fifth up (7 half steps)\nG4 → E4: Minor Third down (-3 half steps)\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			if err := run(tt.args, nil, &stdout, &stderr); err != nil {
+				t.Fatalf("run() error = %v, stderr %s", err, stderr.String())
+			}
+			if got := stdout.String(); got != tt.want {
+				t.Errorf("run() wrote %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRun_JSON(t *testing.T) {
+	var stdout, stderr bytes.Buffer
+	if err := run([]string{"key-sig", "--json", "D", "dorian"}, nil, &stdout, &stderr); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]interface{}
+	if err := json.Unmarshal(stdout.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]interface{}{
+		"key":         "D Dorian",
+		"fifths":      0.0,
+		"accidentals": []interface{}{},
+		"notes":       []interface{}{"D", "E", "F", "G", "A", "B", "C"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("run() wrote %v, want %v", got, want)
+	}
+}
+
+func TestRun_Errors(t *testing.T) {
+	tests := []struct {
+		name  string
+		args  []string
+		usage bool
+	}{
+		{"No command", nil, true},
+		{"Unknown command", []string{"play"}, true},
+		{"Unknown flag", []string{"freq", "--loud", "A4"}, true},
+		{"Missing arguments", []string{"intervals", "C4"}, true},
+		{"Unknown scale", []string{"spell-scale", "C", "blues"}, false},
+		{"Pitch without octave", []string{"freq", "A"}, false},
+		{"Invalid chord", []string{"transpose", "--by", "2", "H7"}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr bytes.Buffer
+			err := run(tt.args, nil, &stdout, &stderr)
+			if err == nil {
+				t.Fatalf("run() should fail, wrote %q", stdout.String())
+			}
+			if errors.Is(err, errUsage) != tt.usage {
+				t.Errorf("run() error = %v, want usage %v", err, tt.usage)
+			}
+		})
+	}
+}
@@ -0,0 +1,551 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chris-franklin/tonacity"
+)
+
+// The REPL keeps a running context between lines: the key things are spelled in, whether chords are named with sharps or flats, the
+// concert pitch, and the chord or scale last asked about, which transpose and play act on. For example:
+//
+//	♪ key Eb
+//	key Eb, namer flat, concert 440 Hz
+//	♪ chord C E G Bb
+//	C Dominant Seventh: C4 E4 G4 B♭4
+//	♪ transpose +5
+//	F Dominant Seventh: F4 A4 C5 E♭5
+//	♪ play
+//	wrote tonacity.wav
+
+// errQuit Returned by a command that ends the session.
+var errQuit = errors.New("quit")
+
+const (
+	replPrompt = "♪ "
+	// replOctave The octave chords and scales are put in when their notes don't have octaves.
+	replOctave = 4
+	// replSampleRate The sample rate of the WAV files written by play.
+	replSampleRate = 44100
+)
+
+// subject A chord or scale that has been asked about. A chord is kept as its symbol if it was given as one, so that transposing it
+// respells it properly; a scale is kept as its root and pattern.
+type subject struct {
+	name    string
+	scale   string // The name of the scale, e.g. "Harmonic Minor"
+	symbol  *tonacity.ChordSymbol
+	root    *tonacity.SpelledPitch
+	pattern *tonacity.Pattern
+	pitches []tonacity.SpelledPitch
+}
+
+// session The running context of the REPL.
+type session struct {
+	out     io.Writer
+	key     *tonacity.KeySignature
+	flats   bool
+	concert float64
+	subject *subject
+	chords  map[string]*tonacity.Pattern // Chord patterns by name, e.g. "Dominant Seventh"
+	scales  map[string]*tonacity.Pattern // Scale and mode patterns by name, e.g. "Harmonic Minor"
+}
+
+// replCommand A command that can be typed at the prompt.
+type replCommand struct {
+	args  string
+	about string
+	run   func(s *session, args []string) error
+}
+
+var replCommands map[string]replCommand
+
+func init() {
+	// Set here rather than where it is declared, as help refers back to it
+	replCommands = map[string]replCommand{
+		"chord":     {"NOTE... | SYMBOL | ROOT NAME", "name or build a chord, e.g. C E G Bb, Cmaj7 or C dominant seventh", (*session).chord},
+		"scale":     {"ROOT NAME", "spell a scale or mode, e.g. F# harmonic minor", (*session).scale},
+		"transpose": {"N", "move the chord or scale, and the key, by N half steps, e.g. +5 or -2", (*session).transpose},
+		"play":      {"[FILE]", "render the chord or scale to a WAV file, tonacity.wav unless named", (*session).play},
+		"key":       {"[KEY | none]", "set the key things are spelled in, e.g. Eb, F#m or D dorian", (*session).setKey},
+		"namer":     {"sharp | flat", "name chords with sharps or flats", (*session).setNamer},
+		"concert":   {"[HZ]", "set the frequency of A4", (*session).setConcert},
+		"freq":      {"PITCH...", "show the frequency of pitches", (*session).freq},
+		"show":      {"", "show the context and the current chord or scale", (*session).show},
+		"help":      {"", "list the commands", (*session).help},
+		"quit":      {"", "leave", func(*session, []string) error { return errQuit }},
+	}
+}
+
+func newSession(out io.Writer) *session {
+	s := &session{
+		out:     out,
+		concert: tonacity.StandardConcertPitch,
+		chords:  make(map[string]*tonacity.Pattern),
+		scales:  make(map[string]*tonacity.Pattern),
+	}
+	tonacity.CreateChordDictionary().WalkChords(func(pattern *tonacity.Pattern, name string) {
+		if name = strings.TrimSpace(name); name != "5" {
+			s.chords[name] = pattern
+		}
+	})
+	for _, dict := range []*tonacity.PatternDictionary{tonacity.BuildScaleDictionary(), tonacity.BuildModeDictionary()} {
+		dict.Walk(func(pattern *tonacity.Pattern, entries []interface{}) {
+			for _, entry := range entries {
+				if name, ok := entry.(string); ok {
+					s.scales[name] = pattern
+				}
+			}
+		})
+	}
+	return s
+}
+
+// repl Reads lines from in and runs them until quit or the end of the input. Lines are edited with tab completion when in is a terminal.
+func repl(in io.Reader, out io.Writer) error {
+	s := newSession(out)
+	reader, err := newLineReader(in, out, s.complete)
+	if err != nil {
+		return err
+	}
+	defer reader.close()
+	fmt.Fprintln(out, "tonacity: type help for the commands, and press tab to complete them")
+	for {
+		line, err := reader.readLine(replPrompt)
+		if err == io.EOF {
+			fmt.Fprintln(out)
+			return nil
+		} else if err != nil {
+			return err
+		}
+		if err := s.execute(line); err == errQuit {
+			return nil
+		} else if err != nil {
+			fmt.Fprintln(out, "error:", err)
+		}
+	}
+}
+
+// execute Runs one line.
+func (s *session) execute(line string) error {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil
+	}
+	name := strings.ToLower(fields[0])
+	if name == "exit" {
+		name = "quit"
+	}
+	cmd, ok := replCommands[name]
+	if !ok {
+		return fmt.Errorf("unknown command %q, type help for the commands", fields[0])
+	}
+	return cmd.run(s, fields[1:])
+}
+
+// complete Returns the lines the given line could be completed to: command names, then scale names after "scale ROOT", chord names
+// after "chord ROOT", and sharp or flat after "namer".
+func (s *session) complete(line string) []string {
+	fields := strings.Fields(line)
+	trailing := strings.HasSuffix(line, " ")
+	if len(fields) == 0 || len(fields) == 1 && !trailing {
+		partial := ""
+		if len(fields) == 1 {
+			partial = fields[0]
+		}
+		return completions("", partial, sortedNames(replCommands), " ")
+	}
+	var names []string
+	words := 2
+	switch strings.ToLower(fields[0]) {
+	case "scale":
+		names = sortedNames(s.scales)
+	case "chord":
+		names = sortedNames(s.chords)
+	case "namer":
+		names, words = []string{"flat", "sharp"}, 1
+	}
+	if len(fields) < words || len(fields) == words && !trailing {
+		return nil
+	}
+	// Everything after the command (and root) is the partial name, which may be several words
+	prefix := line
+	for i := 0; i < words; i++ {
+		prefix = strings.TrimLeft(prefix, " ")
+		prefix = prefix[strings.IndexByte(prefix, ' ')+1:]
+	}
+	done := line[:len(line)-len(prefix)]
+	return completions(done, strings.TrimLeft(prefix, " "), names, "")
+}
+
+// completions Returns done followed by each of the names that starts with partial, ignoring case, and then by suffix.
+func completions(done string, partial string, names []string, suffix string) []string {
+	var lines []string
+	for _, name := range names {
+		if len(name) >= len(partial) && strings.EqualFold(name[:len(partial)], partial) {
+			lines = append(lines, done+name+suffix)
+		}
+	}
+	return lines
+}
+
+func sortedNames[V any](m map[string]V) []string {
+	names := make([]string, 0, len(m))
+	for name := range m {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// lookup Returns the pattern with the given name, ignoring case, and its name as written in the dictionary.
+func lookup(patterns map[string]*tonacity.Pattern, name string) (*tonacity.Pattern, string, bool) {
+	for n, pattern := range patterns {
+		if strings.EqualFold(n, name) {
+			return pattern, n, true
+		}
+	}
+	return nil, "", false
+}
+
+// spellingKey The key notes are spelled in: the current key, or with no key one that prefers sharps or flats as the namer does. F Major
+// prefers flats without changing any natural note.
+func (s *session) spellingKey() *tonacity.KeySignature {
+	if s.key == nil && s.flats {
+		return tonacity.MakeKeySignature(-1, false)
+	}
+	return s.key
+}
+
+func (s *session) namer() *tonacity.PitchNamer {
+	if s.flats {
+		return tonacity.CreateFlatPitchNamer()
+	}
+	return tonacity.CreateSharpPitchNamer()
+}
+
+// chord Sets the subject to a chord given as notes ("C E G Bb"), a symbol ("Cmaj7") or a root and a name from the chord dictionary
+// ("C dominant seventh"). Notes without octaves are stacked upwards from octave 4.
+func (s *session) chord(args []string) error {
+	if len(args) == 0 {
+		return errors.New("chord needs notes, a chord symbol, or a root and a name")
+	}
+	if len(args) == 1 {
+		symbol, err := tonacity.ParseChordSymbol(args[0])
+		if err != nil {
+			return err
+		}
+		s.subject = &subject{symbol: symbol, pitches: symbol.Spell(replOctave)}
+		return s.showChord()
+	}
+	notes := make([]tonacity.SpelledPitch, 0, len(args))
+	for i, arg := range args {
+		sp, octave, err := tonacity.ParseNoteName(arg)
+		if err != nil && i == 1 {
+			return s.namedChord(args[0], strings.Join(args[1:], " "))
+		} else if err != nil {
+			return err
+		}
+		if !octave && len(notes) > 0 {
+			// Stack the note above the one before
+			for above := replOctave + 1; !below(&notes[len(notes)-1], sp) && above <= 9; above++ {
+				sp = tonacity.MakeSpelledPitch(sp.Letter(), sp.Alter(), above)
+			}
+		}
+		notes = append(notes, *sp)
+	}
+	s.subject = &subject{pitches: notes}
+	return s.showChord()
+}
+
+// below Returns true if a sounds lower than b.
+func below(a *tonacity.SpelledPitch, b *tonacity.SpelledPitch) bool {
+	pa, pb := a.Pitch(), b.Pitch()
+	return pa.GetDistanceTo(&pb) > 0
+}
+
+// intervalLetters The number of letters a second, third, fourth or fifth moves on, by its size in half steps.
+var intervalLetters = map[tonacity.HalfSteps]tonacity.Letter{1: 1, 2: 1, 3: 2, 4: 2, 5: 3, 6: 4, 7: 4}
+
+// namedChord Sets the subject to the chord with the given root and name from the chord dictionary.
+func (s *session) namedChord(rootText string, name string) error {
+	root, _, err := tonacity.ParseNoteName(rootText)
+	if err != nil {
+		return err
+	}
+	pattern, _, ok := lookup(s.chords, name)
+	if !ok {
+		return fmt.Errorf("unknown chord %q", name)
+	}
+	// The chords in the dictionary are built from seconds, thirds, fourths and fifths, so each is spelled that many letters further on
+	notes := []tonacity.SpelledPitch{*root}
+	pitch, letter := root.Pitch(), root.Letter()
+	for _, interval := range pattern.Intervals() {
+		pitch.Transpose(interval)
+		letter = (letter + intervalLetters[interval]) % tonacity.LettersInOctave
+		notes = append(notes, spellAs(pitch, letter))
+	}
+	s.subject = &subject{pitches: notes}
+	return s.showChord()
+}
+
+// showChord Names the chord and shows it. Flats are used in the name if the namer uses them, or if any note is written with one.
+func (s *session) showChord() error {
+	pitches := make([]tonacity.Pitch, len(s.subject.pitches))
+	for i := range s.subject.pitches {
+		pitches[i] = s.subject.pitches[i].Pitch()
+	}
+	namer := s.namer()
+	for i := range s.subject.pitches {
+		if s.subject.pitches[i].Alter() < 0 {
+			namer = tonacity.CreateFlatPitchNamer()
+		}
+	}
+	name, ok := tonacity.MakeChord(pitches...).GetName(tonacity.CreateChordDictionary(), namer)
+	if s.subject.symbol != nil {
+		name, ok = s.subject.symbol.String(), true
+	} else if !ok {
+		name = "Unknown chord"
+	}
+	s.subject.name = name
+	return s.showSubject()
+}
+
+func (s *session) showSubject() error {
+	if s.subject == nil {
+		_, err := fmt.Fprintln(s.out, "no chord or scale yet")
+		return err
+	}
+	names := make([]string, len(s.subject.pitches))
+	for i := range s.subject.pitches {
+		names[i] = s.subject.pitches[i].String()
+	}
+	_, err := fmt.Fprintf(s.out, "%s: %s\n", s.subject.name, strings.Join(names, " "))
+	return err
+}
+
+// scale Sets the subject to one octave of a scale, e.g. "F# harmonic minor".
+func (s *session) scale(args []string) error {
+	if len(args) < 2 {
+		return errors.New("scale needs a root and a name, e.g. scale F# harmonic minor")
+	}
+	root, _, err := tonacity.ParseNoteName(args[0])
+	if err != nil {
+		return err
+	}
+	pattern, name, ok := lookup(s.scales, strings.Join(args[1:], " "))
+	if !ok {
+		return fmt.Errorf("unknown scale %q", strings.Join(args[1:], " "))
+	}
+	s.subject = &subject{scale: name, root: root, pattern: pattern}
+	return s.showScale()
+}
+
+func (s *session) showScale() error {
+	s.subject.pitches = tonacity.SpellScale(*s.subject.root, s.subject.pattern)
+	root := s.subject.root.String()
+	s.subject.name = strings.TrimRight(root, "-0123456789") + " " + s.subject.scale
+	return s.showSubject()
+}
+
+// transpose Moves the subject, and the key if there is one, by the given number of half steps.
+func (s *session) transpose(args []string) error {
+	if len(args) != 1 {
+		return errors.New("transpose needs a number of half steps, e.g. +5 or -2")
+	}
+	n, err := strconv.Atoi(args[0])
+	if err != nil || n < -tonacity.OctaveValue*4 || n > tonacity.OctaveValue*4 {
+		return fmt.Errorf("invalid number of half steps %q", args[0])
+	}
+	halfSteps := tonacity.HalfSteps(n)
+	// The subject keeps its place relative to the tonic of the key it was in
+	var from *tonacity.KeySignature
+	if s.key != nil {
+		copied := *s.key
+		from = &copied
+		s.key.Transpose(halfSteps)
+	}
+	if s.subject == nil {
+		return s.showContext()
+	}
+	switch subject := s.subject; {
+	case subject.symbol != nil:
+		// Spell the symbol afresh, keeping the octave of its bass
+		octave := subject.pitches[0].Pitch()
+		octave.Transpose(halfSteps)
+		if from != nil {
+			subject.symbol.TransposeInKey(halfSteps, from)
+		} else {
+			subject.symbol.Transpose(halfSteps)
+			subject.symbol.Respell(s.spellingKey())
+		}
+		subject.pitches = subject.symbol.Spell(int(octave.Octave(tonacity.MiddleC())))
+		return s.showChord()
+	case subject.pattern != nil:
+		subject.root = s.transposeNote(*subject.root, halfSteps, from)
+		return s.showScale()
+	default:
+		// Spell the lowest note for the key, and the others the same number of letters above it as before
+		first := subject.pitches[0]
+		for i := range subject.pitches {
+			pitch := subject.pitches[i].Pitch()
+			pitch.Transpose(halfSteps)
+			if i == 0 {
+				subject.pitches[i] = *s.transposeNote(subject.pitches[i], halfSteps, from)
+				continue
+			}
+			letters := (subject.pitches[i].Letter() + tonacity.LettersInOctave - first.Letter()) % tonacity.LettersInOctave
+			subject.pitches[i] = spellAs(pitch, (subject.pitches[0].Letter()+letters)%tonacity.LettersInOctave)
+		}
+		return s.showChord()
+	}
+}
+
+// transposeNote Moves a note by the given number of half steps, keeping its place relative to the tonic of the key it was in, or spelling
+// it for the session if there was no key.
+func (s *session) transposeNote(sp tonacity.SpelledPitch, halfSteps tonacity.HalfSteps, from *tonacity.KeySignature) *tonacity.SpelledPitch {
+	if from != nil {
+		moved := tonacity.TransposeInKey(sp, halfSteps, from)
+		return &moved
+	}
+	pitch := sp.Pitch()
+	pitch.Transpose(halfSteps)
+	spelled := tonacity.SpellPitch(pitch, s.spellingKey())
+	return &spelled
+}
+
+// spellAs Spells the pitch as the given letter, raised or lowered as much as it needs to be.
+func spellAs(pitch tonacity.Pitch, letter tonacity.Letter) tonacity.SpelledPitch {
+	natural := letter.Natural()
+	class := pitch.Class()
+	alter := int(natural.GetDistanceToHigherPitchClass(class)) % tonacity.OctaveValue
+	if alter > tonacity.OctaveValue/2 {
+		alter -= tonacity.OctaveValue
+	}
+	written := pitch.GetTransposedCopy(tonacity.HalfSteps(-alter))
+	return *tonacity.MakeSpelledPitch(letter, alter, int(written.Octave(tonacity.MiddleC())))
+}
+
+// play Renders the subject to a WAV file: a chord held for two seconds, or a scale up to its octave, half a second a note.
+func (s *session) play(args []string) error {
+	if s.subject == nil {
+		return errors.New("nothing to play yet, ask about a chord or scale first")
+	}
+	if len(args) > 1 {
+		return errors.New("play takes at most one file name")
+	}
+	path := "tonacity.wav"
+	if len(args) == 1 {
+		path = args[0]
+	}
+	synth := tonacity.NewSynthesiser(replSampleRate, s.concert)
+	pitches := make([]tonacity.TimedPitch, 0, len(s.subject.pitches)+1)
+	for i := range s.subject.pitches {
+		if s.subject.pattern != nil {
+			pitches = append(pitches, tonacity.TimedPitch{Pitch: s.subject.pitches[i].Pitch(), Start: float64(i) / 2, Length: 0.5})
+		} else {
+			pitches = append(pitches, tonacity.TimedPitch{Pitch: s.subject.pitches[i].Pitch(), Length: 2})
+		}
+	}
+	if s.subject.pattern != nil {
+		top := s.subject.root.Pitch()
+		top.Transpose(tonacity.OctaveValue)
+		pitches = append(pitches, tonacity.TimedPitch{Pitch: top, Start: float64(len(pitches)) / 2, Length: 1})
+	}
+	if err := tonacity.WriteWavFile(path, synth.Render(pitches), replSampleRate, tonacity.WavPCM16); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintln(s.out, "wrote", path)
+	return err
+}
+
+// setKey Sets or clears the key, or shows it if none is given.
+func (s *session) setKey(args []string) error {
+	switch {
+	case len(args) == 0:
+	case len(args) == 1 && strings.EqualFold(args[0], "none"):
+		s.key = nil
+	default:
+		key := new(tonacity.KeySignature)
+		if err := key.UnmarshalText([]byte(strings.Join(args, " "))); err != nil {
+			return err
+		}
+		s.key = key
+		s.flats = key.Fifths() < 0
+	}
+	return s.showContext()
+}
+
+// setNamer Chooses sharps or flats for naming chords.
+func (s *session) setNamer(args []string) error {
+	if len(args) != 1 || args[0] != "sharp" && args[0] != "flat" {
+		return errors.New("namer needs sharp or flat")
+	}
+	s.flats = args[0] == "flat"
+	return s.showContext()
+}
+
+// setConcert Sets the frequency of A4, or shows it if none is given.
+func (s *session) setConcert(args []string) error {
+	if len(args) == 1 {
+		hertz, err := strconv.ParseFloat(args[0], 64)
+		if err != nil || hertz <= 0 {
+			return fmt.Errorf("invalid concert pitch %q", args[0])
+		}
+		s.concert = hertz
+	} else if len(args) > 1 {
+		return errors.New("concert takes one frequency")
+	}
+	return s.showContext()
+}
+
+// freq Shows the frequency of each pitch at the current concert pitch.
+func (s *session) freq(args []string) error {
+	if len(args) == 0 {
+		return errors.New("freq needs pitches, e.g. A4")
+	}
+	for _, arg := range args {
+		sp, octave, err := tonacity.ParseNoteName(arg)
+		if err != nil || !octave {
+			return fmt.Errorf("invalid pitch %q", arg)
+		}
+		pitch := sp.Pitch()
+		fmt.Fprintf(s.out, "%s %.2f Hz\n", sp.String(), pitch.FrequencyInHertz(s.concert))
+	}
+	return nil
+}
+
+// show Shows the context, then the subject if there is one.
+func (s *session) show([]string) error {
+	if err := s.showContext(); err != nil || s.subject == nil {
+		return err
+	}
+	return s.showSubject()
+}
+
+func (s *session) showContext() error {
+	key := "none"
+	if s.key != nil {
+		text, _ := s.key.MarshalText()
+		key = string(text)
+	}
+	namer := "sharp"
+	if s.flats {
+		namer = "flat"
+	}
+	_, err := fmt.Fprintf(s.out, "key %s, namer %s, concert %g Hz\n", key, namer, s.concert)
+	return err
+}
+
+func (s *session) help([]string) error {
+	for _, name := range sortedNames(replCommands) {
+		cmd := replCommands[name]
+		fmt.Fprintf(s.out, "  %-9s %-29s %s\n", name, cmd.args, cmd.about)
+	}
+	return nil
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSession_Execute(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out)
+	tests := []struct {
+		line string
+		want string
+	}{
+		{"chord C E G Bb", "C Dominant Seventh: C4 E4 G4 B♭4\n"},
+		{"transpose +5", "F Dominant Seventh: F4 A4 C5 E♭5\n"},
+		{"chord E G C", "C Major/E: E4 G4 C5\n"},
+		{"chord Cmaj7", "Cmaj7: C4 E4 G4 B4\n"},
+		{"key Eb", "key Eb, namer flat, concert 440 Hz\n"},
+		{"transpose -2", "Bbmaj7: B♭3 D4 F4 A4\n"},
+		{"show", "key Db, namer flat, concert 440 Hz\nBbmaj7: B♭3 D4 F4 A4\n"},
+		{"key none", "key none, namer flat, concert 440 Hz\n"},
+		{"chord D minor seventh", "D Minor Seventh: D4 F4 A4 C5\n"},
+		{"scale F# harmonic minor", "F♯ Harmonic Minor: F♯4 G♯4 A4 B4 C♯5 D5 E♯5\n"},
+		{"transpose 1", "G Harmonic Minor: G4 A4 B♭4 C5 D5 E♭5 F♯5\n"},
+		{"scale D dorian", "D Dorian: D4 E4 F4 G4 A4 B4 C5\n"},
+		{"namer sharp", "key none, namer sharp, concert 440 Hz\n"},
+		{"concert 432", "key none, namer sharp, concert 432 Hz\n"},
+		{"freq A4 A5", "A4 432.00 Hz\nA5 864.00 Hz\n"},
+		{"chord Eb dominant seventh", "E♭ Dominant Seventh: E♭4 G4 B♭4 D♭5\n"},
+		{"chord C diminished seventh", "C Diminished Seventh: C4 E♭4 G♭4 B𝄫4\n"},
+		{"key C", "key C, namer sharp, concert 432 Hz\n"},
+		{"chord Ab", "Ab: A♭4 C5 E♭5\n"},
+		{"transpose 2", "Bb: B♭4 D5 F5\n"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		out.Reset()
+		if err := s.execute(tt.line); err != nil {
+			t.Fatalf("execute(%q) error = %v", tt.line, err)
+		}
+		if got := out.String(); got != tt.want {
+			t.Errorf("execute(%q) wrote %q, want %q", tt.line, got, tt.want)
+		}
+	}
+	for _, line := range []string{"bogus", "chord", "chord C blah", "scale C blues", "transpose up", "namer double", "freq A", "concert -1"} {
+		if err := s.execute(line); err == nil {
+			t.Errorf("execute(%q) should fail", line)
+		}
+	}
+	if err := s.execute("exit"); err != errQuit {
+		t.Errorf("execute(exit) = %v, want errQuit", err)
+	}
+}
+
+func TestSession_Play(t *testing.T) {
+	var out bytes.Buffer
+	s := newSession(&out)
+	path := filepath.Join(t.TempDir(), "scale.wav")
+	if err := s.execute("play " + path); err == nil {
+		t.Error("play with nothing to play should fail")
+	}
+	if err := s.execute("scale C major"); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.execute("play " + path); err != nil {
+		t.Fatal(err)
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// Eight notes half a second apart, the last held for a second, and the release, in 16-bit mono at 44.1kHz
+	if seconds := float64(info.Size()-44) / 2 / replSampleRate; seconds < 4.5 || seconds > 4.6 {
+		t.Errorf("play wrote %v seconds of audio, want about 4.55", seconds)
+	}
+}
+
+func TestSession_Complete(t *testing.T) {
+	s := newSession(nil)
+	tests := []struct {
+		line string
+		want []string
+	}{
+		{"tr", []string{"transpose "}},
+		{"s", []string{"scale ", "show "}},
+		{"scale F# harm", []string{"scale F# Harmonic Minor"}},
+		{"scale D d", []string{"scale D Dorian"}},
+		{"scale C pentatonic ", []string{"scale C Pentatonic Major", "scale C Pentatonic Minor"}},
+		{"chord C dom", []string{"chord C Dominant Seventh"}},
+		{"chord Bb Dim", []string{"chord Bb Diminished", "chord Bb Diminished Seventh"}},
+		{"namer f", []string{"namer flat"}},
+		{"scale", []string{"scale "}},
+		{"scale ", nil},
+		{"freq A", nil},
+	}
+	for _, tt := range tests {
+		if got := s.complete(tt.line); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("complete(%q) = %q, want %q", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestRepl(t *testing.T) {
+	var out bytes.Buffer
+	in := strings.NewReader("chord C E G\nnonsense\nquit\nchord D F A\n")
+	if err := run([]string{"repl"}, in, &out, nil); err != nil {
+		t.Fatal(err)
+	}
+	got := out.String()
+	for _, want := range []string{"C Major: C4 E4 G4\n", "error: unknown command \"nonsense\""} {
+		if !strings.Contains(got, want) {
+			t.Errorf("repl wrote %q, want it to contain %q", got, want)
+		}
+	}
+	if strings.Contains(got, "D Minor") {
+		t.Error("repl carried on after quit")
+	}
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"unicode/utf8"
+)
+
+// lineReader Reads the lines typed at the prompt.
+type lineReader interface {
+	readLine(prompt string) (string, error)
+	close()
+}
+
+// newLineReader Returns a reader that edits lines with tab completion and history if in is a terminal, or that just reads lines if it
+// isn't, or if the terminal can't be put into raw mode on this system.
+func newLineReader(in io.Reader, out io.Writer, complete func(line string) []string) (lineReader, error) {
+	if f, ok := in.(*os.File); ok {
+		if restore, err := makeRaw(int(f.Fd())); err == nil {
+			return &terminal{bufio.NewReader(f), out, complete, restore, nil}, nil
+		}
+	}
+	return &plainReader{bufio.NewScanner(in), out}, nil
+}
+
+// plainReader Reads whole lines, as when the input is piped in.
+type plainReader struct {
+	scanner *bufio.Scanner
+	out     io.Writer
+}
+
+func (r *plainReader) readLine(prompt string) (string, error) {
+	fmt.Fprint(r.out, prompt)
+	if !r.scanner.Scan() {
+		if err := r.scanner.Err(); err != nil {
+			return "", err
+		}
+		return "", io.EOF
+	}
+	return r.scanner.Text(), nil
+}
+
+func (r *plainReader) close() {}
+
+// terminal Edits lines a key at a time in a terminal in raw mode. Typing goes on the end of the line; backspace deletes, tab completes,
+// the up and down arrows step through earlier lines, Ctrl-C abandons the line and Ctrl-D on an empty line ends the input.
+type terminal struct {
+	in       *bufio.Reader
+	out      io.Writer
+	complete func(line string) []string
+	restore  func()
+	history  []string
+}
+
+const (
+	keyCtrlC     = 3
+	keyCtrlD     = 4
+	keyBell      = "\a"
+	keyBackspace = 127
+	keyEscape    = 27
+)
+
+func (t *terminal) readLine(prompt string) (string, error) {
+	line := ""
+	recalled := len(t.history)
+	fmt.Fprint(t.out, prompt)
+	for {
+		r, _, err := t.in.ReadRune()
+		if err != nil {
+			return "", err
+		}
+		switch r {
+		case '\r', '\n':
+			fmt.Fprint(t.out, "\r\n")
+			if strings.TrimSpace(line) != "" {
+				t.history = append(t.history, line)
+			}
+			return line, nil
+		case keyCtrlC:
+			fmt.Fprint(t.out, "^C\r\n"+prompt)
+			line = ""
+		case keyCtrlD:
+			if line == "" {
+				return "", io.EOF
+			}
+		case keyBackspace, '\b':
+			if line != "" {
+				_, size := utf8.DecodeLastRuneInString(line)
+				line = line[:len(line)-size]
+				fmt.Fprint(t.out, "\b \b")
+			}
+		case '\t':
+			line = t.completeLine(prompt, line)
+		case keyEscape:
+			// Arrow keys arrive as ESC [ A to D
+			if b, _ := t.in.ReadByte(); b != '[' {
+				continue
+			}
+			switch b, _ := t.in.ReadByte(); {
+			case b == 'A' && recalled > 0:
+				recalled--
+				line = t.history[recalled]
+			case b == 'B' && recalled < len(t.history):
+				recalled++
+				line = ""
+				if recalled < len(t.history) {
+					line = t.history[recalled]
+				}
+			}
+			t.redraw(prompt, line)
+		default:
+			if r >= ' ' {
+				line += string(r)
+				fmt.Fprint(t.out, string(r))
+			}
+		}
+	}
+}
+
+// completeLine Completes as much of the line as all the completions have in common. If that doesn't add anything and there is more
+// than one completion, they are listed.
+func (t *terminal) completeLine(prompt string, line string) string {
+	candidates := t.complete(line)
+	if len(candidates) == 0 {
+		fmt.Fprint(t.out, keyBell)
+		return line
+	}
+	common := candidates[0]
+	for _, c := range candidates[1:] {
+		for !strings.HasPrefix(strings.ToLower(c), strings.ToLower(common)) {
+			common = common[:len(common)-1]
+		}
+	}
+	if len(common) > len(line) || len(candidates) == 1 {
+		t.redraw(prompt, common)
+		return common
+	}
+	// List what each completion would add to the start of the word being completed
+	start := strings.LastIndexByte(strings.TrimRight(line, " "), ' ') + 1
+	fmt.Fprint(t.out, "\r\n")
+	for _, c := range candidates {
+		fmt.Fprint(t.out, c[start:]+"   ")
+	}
+	fmt.Fprint(t.out, "\r\n")
+	t.redraw(prompt, line)
+	return line
+}
+
+// redraw Clears the current line and writes the prompt and line again.
+func (t *terminal) redraw(prompt string, line string) {
+	fmt.Fprint(t.out, "\r\x1b[K"+prompt+line)
+}
+
+func (t *terminal) close() {
+	t.restore()
+}
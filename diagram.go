@@ -0,0 +1,360 @@
+package tonacity
+
+import (
+	"encoding/xml"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Chord diagrams show where to hold down the strings of a fretted instrument to play a chord: the strings run down the page, lowest on
+// the left, crossed by the frets, with a dot where each finger goes. Strings played open are marked with an o above the nut, and
+// strings that aren't played with an x. Keyboard diagrams show a piano keyboard with the keys of a chord or scale picked out. Both
+// are drawn as SVG images, measured in pixels, with labels in the reader's default sans serif font.
+
+// ChordShape Where to hold down each string of a fretted instrument to play a chord, along with which finger does it.
+type ChordShape struct {
+	frets   []int // For each string, lowest first: the fret held down, 0 for an open string, or -1 for a string that isn't played
+	fingers []int // For each string, lowest first: the finger holding it down, from 1 for the index finger to 4, or 0 if none is given
+}
+
+// MakeChordShape Creates a chord shape from the fret of each string, lowest first, where 0 is an open string and -1 a string that
+// isn't played, and the finger holding each one down, from 1 for the index finger to 4 for the little finger. Fingers may be nil, or
+// 0 for a string that has none. If fingers are given for a different number of strings than frets, then nil is returned.
+func MakeChordShape(frets []int, fingers []int) *ChordShape {
+	if fingers == nil {
+		fingers = make([]int, len(frets))
+	}
+	if len(fingers) != len(frets) {
+		return nil
+	}
+	return &ChordShape{frets, fingers}
+}
+
+// ParseChordShape Reads a chord shape written as the fret of each string, lowest first, with x for a string that isn't played, e.g.
+// "x32010" for C Major on a guitar. Frets of 10 or more need the strings separating with spaces, dashes or commas, e.g. "x-x-10-10-10-8".
+// Fingers are written the same way, with 0 for none, and may be empty.
+func ParseChordShape(frets string, fingers string) (*ChordShape, error) {
+	parse := func(text string, muted bool) ([]int, error) {
+		var fields []string
+		if strings.ContainsAny(text, " -,") {
+			fields = strings.FieldsFunc(text, func(r rune) bool { return r == ' ' || r == '-' || r == ',' })
+		} else {
+			fields = strings.Split(text, "")
+		}
+		values := make([]int, len(fields))
+		for i, field := range fields {
+			if muted && strings.EqualFold(field, "x") {
+				values[i] = -1
+				continue
+			}
+			value, err := strconv.Atoi(field)
+			if err != nil || value < 0 {
+				return nil, fmt.Errorf("chord shape %q: invalid fret or finger %q", text, field)
+			}
+			values[i] = value
+		}
+		return values, nil
+	}
+	shape := &ChordShape{}
+	var err error
+	if shape.frets, err = parse(frets, true); err != nil {
+		return nil, err
+	}
+	if fingers == "" {
+		shape.fingers = make([]int, len(shape.frets))
+		return shape, nil
+	}
+	if shape.fingers, err = parse(fingers, false); err != nil {
+		return nil, err
+	}
+	if len(shape.fingers) != len(shape.frets) {
+		return nil, fmt.Errorf("chord shape %q has %d strings but %d fingers", frets, len(shape.frets), len(shape.fingers))
+	}
+	return shape, nil
+}
+
+// Frets The fret held down on each string, lowest first, where 0 is an open string and -1 a string that isn't played.
+func (s *ChordShape) Frets() []int {
+	frets := make([]int, len(s.frets))
+	copy(frets, s.frets)
+	return frets
+}
+
+// Fingers The finger holding down each string, lowest first, from 1 for the index finger to 4, or 0 if none is given.
+func (s *ChordShape) Fingers() []int {
+	fingers := make([]int, len(s.fingers))
+	copy(fingers, s.fingers)
+	return fingers
+}
+
+// Chord Returns the chord sounded by playing the shape on the given fretboard, lowest pitch first.
+func (s *ChordShape) Chord(fretboard *Fretboard) *Chord {
+	pitches := make([]Pitch, 0, len(s.frets))
+	for i, fret := range s.frets {
+		if fret >= 0 && i < len(fretboard.tuning) {
+			pitches = append(pitches, *fretboard.tuning[i].GetTransposedCopy(HalfSteps(fret)))
+		}
+	}
+	sort.Sort(ByPitch(pitches))
+	return MakeChord(pitches...)
+}
+
+// ChordBarre A finger laid across several strings at the same fret.
+type ChordBarre struct {
+	Fret   int
+	Finger int
+	From   int // The lowest string covered, counting from 0 for the lowest string
+	To     int // The highest string covered
+}
+
+// Barres Returns the barres in the shape: wherever one finger holds down more than one string at the same fret, it is laid across all
+// the strings between them.
+func (s *ChordShape) Barres() []ChordBarre {
+	barres := make([]ChordBarre, 0)
+	for i, finger := range s.fingers {
+		if finger == 0 || s.frets[i] <= 0 {
+			continue
+		}
+		found := false
+		for j := range barres {
+			if barres[j].Finger == finger && barres[j].Fret == s.frets[i] {
+				barres[j].To, found = i, true
+			}
+		}
+		if !found {
+			barres = append(barres, ChordBarre{s.frets[i], finger, i, i})
+		}
+	}
+	kept := barres[:0]
+	for _, barre := range barres {
+		if barre.To > barre.From {
+			kept = append(kept, barre)
+		}
+	}
+	return kept
+}
+
+// Sizes of chord diagrams, in pixels.
+const (
+	chordDiagramString = 20 // The gap between strings
+	chordDiagramFret   = 24 // The gap between frets
+	chordDiagramLeft   = 30 // The room left of the lowest string, for the fret number
+	chordDiagramTop    = 50 // The room above the nut, for the name and the open and muted strings
+	chordDiagramFrets  = 4  // The fewest frets drawn
+)
+
+// ChordDiagramSVG Returns the shape drawn as a chord diagram with the given name above it, which may be empty, and the name of the
+// note each string sounds below it, named by the given pitch namer. Shapes high up the neck are drawn from their lowest fret, with its
+// number beside it.
+func ChordDiagramSVG(shape *ChordShape, name string, fretboard *Fretboard, namer *PitchNamer) string {
+	stringCount := len(shape.frets)
+	low, high := 0, 0
+	for _, fret := range shape.frets {
+		if fret > 0 && (low == 0 || fret < low) {
+			low = fret
+		}
+		if fret > high {
+			high = fret
+		}
+	}
+	// Start at the nut unless the shape doesn't fit there
+	first := 1
+	if high > chordDiagramFrets {
+		first = low
+	}
+	frets := chordDiagramFrets
+	if high-first+1 > frets {
+		frets = high - first + 1
+	}
+	width := chordDiagramLeft + (stringCount-1)*chordDiagramString + chordDiagramString
+	height := chordDiagramTop + frets*chordDiagramFret + 30
+	x := func(s int) int { return chordDiagramLeft + s*chordDiagramString }
+	y := func(fret int) int { return chordDiagramTop + (fret-first)*chordDiagramFret }
+	// Dots go in the middle of the gap before the fret
+	dotY := func(fret int) int { return y(fret-1) + chordDiagramFret/2 }
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`+"\n",
+		width, height, width, height)
+	if name != "" {
+		fmt.Fprintf(&b, `<text class="name" x="%d" y="18" font-size="16" text-anchor="middle">%s</text>`+"\n",
+			(x(0)+x(stringCount-1))/2, svgEscape(name))
+	}
+	for s := 0; s < stringCount; s++ {
+		fmt.Fprintf(&b, `<line class="string" x1="%d" y1="%d" x2="%d" y2="%d" stroke="black" stroke-width="1"/>`+"\n",
+			x(s), y(first-1), x(s), y(first-1+frets))
+	}
+	for fret := first - 1; fret <= first-1+frets; fret++ {
+		// The nut is drawn thicker than the frets
+		width := 1
+		class := "fret"
+		if fret == 0 {
+			width, class = 4, "nut"
+		}
+		fmt.Fprintf(&b, `<line class="%s" x1="%d" y1="%d" x2="%d" y2="%d" stroke="black" stroke-width="%d"/>`+"\n",
+			class, x(0), y(fret), x(stringCount-1), y(fret), width)
+	}
+	if first > 1 {
+		fmt.Fprintf(&b, `<text class="base-fret" x="%d" y="%d" font-size="11" text-anchor="end">%dfr</text>`+"\n",
+			x(0)-8, dotY(first)+4, first)
+	}
+	barred := make(map[int]bool)
+	for _, barre := range shape.Barres() {
+		fmt.Fprintf(&b, `<rect class="barre" x="%d" y="%d" width="%d" height="14" rx="7"/>`+"\n",
+			x(barre.From)-7, dotY(barre.Fret)-7, x(barre.To)-x(barre.From)+14)
+		for s := barre.From; s <= barre.To; s++ {
+			if shape.frets[s] == barre.Fret {
+				barred[s] = true
+			}
+		}
+	}
+	for s, fret := range shape.frets {
+		switch {
+		case fret < 0:
+			fmt.Fprintf(&b, `<path class="muted" d="M%d %dl8 8m0-8l-8 8" stroke="black" stroke-width="1.5"/>`+"\n",
+				x(s)-4, chordDiagramTop-14)
+		case fret == 0:
+			fmt.Fprintf(&b, `<circle class="open" cx="%d" cy="%d" r="4" fill="none" stroke="black" stroke-width="1.5"/>`+"\n",
+				x(s), chordDiagramTop-10)
+		case !barred[s]:
+			fmt.Fprintf(&b, `<circle class="finger" cx="%d" cy="%d" r="7"/>`+"\n", x(s), dotY(fret))
+		}
+		if fret > 0 && shape.fingers[s] > 0 && (!barred[s] || isBarreEnd(shape, s)) {
+			fmt.Fprintf(&b, `<text class="finger-number" x="%d" y="%d" font-size="10" text-anchor="middle" fill="white">%d</text>`+"\n",
+				x(s), dotY(fret)+4, shape.fingers[s])
+		}
+		if fret >= 0 && s < len(fretboard.tuning) {
+			pitch := fretboard.tuning[s].GetTransposedCopy(HalfSteps(fret))
+			fmt.Fprintf(&b, `<text class="label" x="%d" y="%d" font-size="11" text-anchor="middle">%s</text>`+"\n",
+				x(s), y(first-1+frets)+18, svgEscape(namer.Name(pitch.class)))
+		}
+	}
+	b.WriteString("</svg>\n")
+	return b.String()
+}
+
+// isBarreEnd Returns true if the given string is the lowest string of a barre, where its finger number is written.
+func isBarreEnd(shape *ChordShape, s int) bool {
+	for _, barre := range shape.Barres() {
+		if barre.From == s {
+			return true
+		}
+	}
+	return false
+}
+
+// svgEscape Escapes text for use in SVG.
+func svgEscape(text string) string {
+	var b strings.Builder
+	_ = xml.EscapeText(&b, []byte(text))
+	return b.String()
+}
+
+// Sizes of keyboard diagrams, in pixels.
+const (
+	keyboardWhiteWidth  = 24
+	keyboardWhiteHeight = 100
+	keyboardBlackWidth  = 14
+	keyboardBlackHeight = 62
+)
+
+// isBlackKey Returns true if the pitch class is played on a black key of a piano.
+func isBlackKey(class PitchClass) bool {
+	switch class.value {
+	case 1, 3, 6, 8, 10:
+		return true
+	}
+	return false
+}
+
+// keyboardSVG Draws the piano keys from one pitch to another, highlighting and labelling those the given function picks out.
+func keyboardSVG(from Pitch, to Pitch, highlighted func(p *Pitch) bool, namer *PitchNamer) string {
+	whites := 0
+	for p := from; p.value <= to.value; p.Transpose(1) {
+		if !isBlackKey(p.class) {
+			whites++
+		}
+	}
+	width, height := whites*keyboardWhiteWidth+2, keyboardWhiteHeight+2
+	var white, black strings.Builder
+	x := 1
+	for p := from; p.value <= to.value; p.Transpose(1) {
+		on := highlighted(&p)
+		class, fill := "key", "white"
+		if on {
+			class, fill = "key highlighted", "#f6a623"
+		}
+		if isBlackKey(p.class) {
+			if !on {
+				fill = "black"
+			}
+			left := x - keyboardBlackWidth/2
+			fmt.Fprintf(&black, `<rect class="%s" x="%d" y="1" width="%d" height="%d" fill="%s" stroke="black"/>`+"\n",
+				class, left, keyboardBlackWidth, keyboardBlackHeight, fill)
+			if on {
+				fmt.Fprintf(&black, `<text class="label" x="%d" y="%d" font-size="8" text-anchor="middle">%s</text>`+"\n",
+					x, keyboardBlackHeight-6, svgEscape(namer.Name(p.class)))
+			}
+			continue
+		}
+		fmt.Fprintf(&white, `<rect class="%s" x="%d" y="1" width="%d" height="%d" fill="%s" stroke="black"/>`+"\n",
+			class, x, keyboardWhiteWidth, keyboardWhiteHeight, fill)
+		if on {
+			fmt.Fprintf(&white, `<text class="label" x="%d" y="%d" font-size="11" text-anchor="middle">%s</text>`+"\n",
+				x+keyboardWhiteWidth/2, keyboardWhiteHeight-8, svgEscape(namer.Name(p.class)))
+		}
+		x += keyboardWhiteWidth
+	}
+	// Black keys are drawn last so they sit on top of the white keys
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" font-family="sans-serif">`+"\n",
+		width, height, width, height) + white.String() + black.String() + "</svg>\n"
+}
+
+// KeyboardSVG Returns a piano keyboard from one pitch to another, with the given pitches highlighted and labelled by the given pitch
+// namer. The keyboard is widened to start on a C and end on a B.
+func KeyboardSVG(from Pitch, to Pitch, pitches []Pitch, namer *PitchNamer) string {
+	if from.class != *C() {
+		from.LowerToNext(*C())
+	}
+	if to.class != *B() {
+		to.RaiseToNext(*B())
+	}
+	return keyboardSVG(from, to, func(p *Pitch) bool {
+		for i := range pitches {
+			if pitches[i].value == p.value {
+				return true
+			}
+		}
+		return false
+	}, namer)
+}
+
+// ChordKeyboardSVG Returns a piano keyboard covering the octaves of the chord, with its pitches highlighted and labelled by the given
+// pitch namer.
+func ChordKeyboardSVG(chord *Chord, namer *PitchNamer) string {
+	if len(chord.pitches) == 0 {
+		return KeyboardSVG(*MiddleC(), *MiddleC(), nil, namer)
+	}
+	sorted := make([]Pitch, len(chord.pitches))
+	copy(sorted, chord.pitches)
+	sort.Sort(ByPitch(sorted))
+	return KeyboardSVG(sorted[0], sorted[len(sorted)-1], sorted, namer)
+}
+
+// PitchClassKeyboardSVG Returns a one octave piano keyboard, from C to B, with every key in the given pitch classes (e.g. a scale or
+// key) highlighted and labelled by the given pitch namer.
+func PitchClassKeyboardSVG(producer PitchClassProducer, namer *PitchNamer) string {
+	classes := producer.ProducePitchClasses()
+	from := *MiddleC()
+	to := *from.GetTransposedCopy(OctaveValue - 1)
+	return keyboardSVG(from, to, func(p *Pitch) bool {
+		for _, class := range classes {
+			if class.HasSamePitchAs(&p.class) {
+				return true
+			}
+		}
+		return false
+	}, namer)
+}
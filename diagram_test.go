@@ -0,0 +1,134 @@
+package tonacity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseChordShape(t *testing.T) {
+	tests := []struct {
+		frets       string
+		fingers     string
+		wantFrets   []int
+		wantFingers []int
+	}{
+		{"x32010", "032010", []int{-1, 3, 2, 0, 1, 0}, []int{0, 3, 2, 0, 1, 0}},
+		{"X-X-10-10-10-8", "", []int{-1, -1, 10, 10, 10, 8}, []int{0, 0, 0, 0, 0, 0}},
+		{"1 3 3 2 1 1", "1,3,4,2,1,1", []int{1, 3, 3, 2, 1, 1}, []int{1, 3, 4, 2, 1, 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.frets, func(t *testing.T) {
+			shape, err := ParseChordShape(tt.frets, tt.fingers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(shape.Frets(), tt.wantFrets) || !reflect.DeepEqual(shape.Fingers(), tt.wantFingers) {
+				t.Errorf("ParseChordShape() = %v %v, want %v %v", shape.Frets(), shape.Fingers(), tt.wantFrets, tt.wantFingers)
+			}
+		})
+	}
+	for _, frets := range [][2]string{{"x3201y", ""}, {"x32010", "0320"}, {"x32010", "03201x"}} {
+		if _, err := ParseChordShape(frets[0], frets[1]); err == nil {
+			t.Errorf("ParseChordShape(%q, %q) should fail", frets[0], frets[1])
+		}
+	}
+}
+
+func TestMakeChordShape(t *testing.T) {
+	shape := MakeChordShape([]int{-1, 3, 2, 0, 1, 0}, nil)
+	if shape == nil || !reflect.DeepEqual(shape.Fingers(), []int{0, 0, 0, 0, 0, 0}) {
+		t.Errorf("MakeChordShape() without fingers = %v", shape)
+	}
+	shape = MakeChordShape([]int{-1, 3, 2, 0, 1, 0}, []int{0, 3, 2, 0, 1, 0})
+	if shape == nil || !reflect.DeepEqual(shape.Fingers(), []int{0, 3, 2, 0, 1, 0}) {
+		t.Errorf("MakeChordShape() with fingers = %v", shape)
+	}
+	if shape := MakeChordShape([]int{-1, 3, 2, 0, 1, 0}, []int{3, 2, 1}); shape != nil {
+		t.Errorf("MakeChordShape() with too few fingers = %v, want nil", shape)
+	}
+	if shape := MakeChordShape([]int{1, 1}, []int{1, 1, 1}); shape != nil {
+		t.Errorf("MakeChordShape() with too many fingers = %v, want nil", shape)
+	}
+}
+
+func TestChordShape_ChordAndBarres(t *testing.T) {
+	shape, _ := ParseChordShape("133211", "134211")
+	namer := CreateSharpPitchNamer()
+	names := make([]string, 0)
+	for _, p := range shape.Chord(CreateGuitarFretboard()).pitches {
+		sp := SpellPitch(p, nil)
+		names = append(names, namer.Name(p.class)+strings.TrimLeft(sp.String(), "ABCDEFG♯"))
+	}
+	if want := []string{"F2", "C3", "F3", "A3", "C4", "F4"}; !reflect.DeepEqual(names, want) {
+		t.Errorf("Chord() = %v, want %v", names, want)
+	}
+	if got, want := shape.Barres(), []ChordBarre{{Fret: 1, Finger: 1, From: 0, To: 5}}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Barres() = %v, want %v", got, want)
+	}
+}
+
+func TestChordDiagramSVG(t *testing.T) {
+	tests := []struct {
+		name    string
+		frets   string
+		fingers string
+		want    map[string]int
+	}{
+		{"C", "x32010", "032010", map[string]int{
+			"string": 6, "nut": 1, "fret": 4, "muted": 1, "open": 2, "finger": 3, "finger-number": 3, "label": 5, "barre": 0, "base-fret": 0}},
+		{"F", "133211", "134211", map[string]int{
+			"string": 6, "nut": 1, "muted": 0, "open": 0, "finger": 3, "finger-number": 4, "label": 6, "barre": 1}},
+		{"D", "x-x-10-10-10-8", "", map[string]int{
+			"nut": 0, "fret": 5, "muted": 2, "finger": 4, "finger-number": 0, "label": 4, "base-fret": 1}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shape, err := ParseChordShape(tt.frets, tt.fingers)
+			if err != nil {
+				t.Fatal(err)
+			}
+			svg := ChordDiagramSVG(shape, tt.name, CreateGuitarFretboard(), CreateFlatPitchNamer())
+			got := svgClasses(t, svg)
+			for class, count := range tt.want {
+				if got[class] != count {
+					t.Errorf("ChordDiagramSVG() drew %d %q, want %d", got[class], class, count)
+				}
+			}
+			if !strings.Contains(svg, ">"+tt.name+"</text>") {
+				t.Errorf("ChordDiagramSVG() doesn't show the name %q", tt.name)
+			}
+		})
+	}
+}
+
+func TestKeyboardSVG(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	chord := MakeChord(*pf.GetPitch(G(), 4), *pf.GetPitch(E(), 4), *pf.GetPitch(C().Sharp(), 5))
+	tests := []struct {
+		name        string
+		svg         string
+		keys        int
+		highlighted int
+		labels      []string
+	}{
+		{"chord", ChordKeyboardSVG(chord, CreateFlatPitchNamer()), 24, 3, []string{"E", "G", "D♭"}},
+		{"key", PitchClassKeyboardSVG(MakeKeySignature(1, false), CreateSharpPitchNamer()), 12, 7, []string{"C", "D", "E", "G", "A", "B", "F♯"}},
+		{"range", KeyboardSVG(*pf.GetPitch(E(), 3), *pf.GetPitch(E(), 3), nil, CreateSharpPitchNamer()), 12, 0, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := svgClasses(t, tt.svg)
+			if got["key"] != tt.keys-tt.highlighted || got["key highlighted"] != tt.highlighted {
+				t.Errorf("drew %v, want %d keys with %d highlighted", got, tt.keys, tt.highlighted)
+			}
+			labels := make([]string, 0)
+			for _, part := range strings.Split(tt.svg, `class="label"`)[1:] {
+				labels = append(labels, part[strings.Index(part, ">")+1:strings.Index(part, "<")])
+			}
+			if len(labels) != len(tt.labels) || (len(labels) > 0 && !reflect.DeepEqual(labels, tt.labels)) {
+				t.Errorf("labels = %v, want %v", labels, tt.labels)
+			}
+		})
+	}
+}
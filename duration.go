@@ -0,0 +1,104 @@
+package tonacity
+
+import "fmt"
+
+// Duration A length of musical time, held exactly as a fraction of a whole note. Floating point can't represent a triplet eighth
+// (1/12) exactly, and errors add up over a piece, so fractions are used instead.
+type Duration struct {
+	numerator   int64
+	denominator int64
+}
+
+// MakeDuration Creates the duration of the given fraction of a whole note, e.g. (3, 8) for a dotted quarter note. The denominator
+// must not be zero.
+func MakeDuration(numerator int64, denominator int64) Duration {
+	if denominator < 0 {
+		numerator, denominator = -numerator, -denominator
+	}
+	g := gcd(numerator, denominator)
+	return Duration{numerator / g, denominator / g}
+}
+
+// NoteValueDuration Returns how long a note of the given value (e.g. 4 for a quarter note) with the given number of dots lasts, e.g. 3/8
+// for a dotted quarter. The value must be positive.
+func NoteValueDuration(value int, dots int) Duration {
+	// Each dot adds half of what came before: 1 + 1/2 + 1/4 + ... = (2^(dots+1) - 1) / 2^dots
+	return MakeDuration(int64(1)<<uint(dots+1)-1, int64(value)<<uint(dots))
+}
+
+func gcd(a int64, b int64) int64 {
+	if a < 0 {
+		a = -a
+	}
+	for b != 0 {
+		a, b = b, a%b
+	}
+	if a == 0 {
+		return 1
+	}
+	return a
+}
+
+func lcm(a int64, b int64) int64 {
+	return a / gcd(a, b) * b
+}
+
+// Numerator The numerator of the duration as a fraction of a whole note, in lowest terms.
+func (d Duration) Numerator() int64 {
+	return d.numerator
+}
+
+// Denominator The denominator of the duration as a fraction of a whole note, in lowest terms. Zero durations have a denominator of 1.
+func (d Duration) Denominator() int64 {
+	if d.denominator == 0 {
+		return 1
+	}
+	return d.denominator
+}
+
+// Add Returns the sum of the two durations.
+func (d Duration) Add(other Duration) Duration {
+	return MakeDuration(d.numerator*other.Denominator()+other.numerator*d.Denominator(), d.Denominator()*other.Denominator())
+}
+
+// Sub Returns this duration with the other taken away.
+func (d Duration) Sub(other Duration) Duration {
+	return d.Add(Duration{-other.numerator, other.Denominator()})
+}
+
+// Mul Returns this duration scaled by the other, e.g. a triplet eighth is 1/8 scaled by 2/3.
+func (d Duration) Mul(other Duration) Duration {
+	return MakeDuration(d.numerator*other.numerator, d.Denominator()*other.Denominator())
+}
+
+// Div Returns how many of the other duration fit into this one, as a fraction, e.g. 3/8 divided by 1/8 is 3/1. The other duration
+// must not be zero.
+func (d Duration) Div(other Duration) Duration {
+	return MakeDuration(d.numerator*other.Denominator(), d.Denominator()*other.numerator)
+}
+
+// Cmp Returns -1 if this duration is shorter than the other, 0 if they are the same, and 1 if it is longer.
+func (d Duration) Cmp(other Duration) int {
+	a, b := d.numerator*other.Denominator(), other.numerator*d.Denominator()
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
+
+// Float64 Returns the duration as a number of whole notes, e.g. 0.375 for a dotted quarter, for when it has to be turned into time.
+func (d Duration) Float64() float64 {
+	return float64(d.numerator) / float64(d.Denominator())
+}
+
+// IsZero Returns true if the duration has no length.
+func (d Duration) IsZero() bool {
+	return d.numerator == 0
+}
+
+func (d Duration) String() string {
+	return fmt.Sprintf("%d/%d", d.numerator, d.Denominator())
+}
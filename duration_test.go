@@ -0,0 +1,160 @@
+package tonacity
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+func TestDuration_Arithmetic(t *testing.T) {
+	quarter, eighth, tripletEighth := MakeDuration(1, 4), MakeDuration(1, 8), MakeDuration(1, 12)
+	tests := []struct {
+		name string
+		got  Duration
+		want string
+	}{
+		{"Lowest terms", MakeDuration(4, 16), "1/4"},
+		{"Negative denominator", MakeDuration(1, -4), "-1/4"},
+		{"Quarter plus eighth", quarter.Add(eighth), "3/8"},
+		{"Three triplet eighths", tripletEighth.Add(tripletEighth).Add(tripletEighth), "1/4"},
+		{"Quarter minus eighth", quarter.Sub(eighth), "1/8"},
+		{"Zero", quarter.Sub(quarter), "0/1"},
+		{"Zero value", Duration{}, "0/1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.got.String(); got != tt.want {
+				t.Errorf("Duration = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if quarter.Cmp(eighth) != 1 || eighth.Cmp(quarter) != -1 || quarter.Cmp(MakeDuration(2, 8)) != 0 {
+		t.Error("Duration.Cmp() does not order a quarter after an eighth")
+	}
+}
+
+func TestMakeNote_Duration(t *testing.T) {
+	tests := []struct {
+		name  string
+		value int
+		dots  int
+		want  string
+	}{
+		{"Quarter", 4, 0, "1/4"},
+		{"Dotted quarter", 4, 1, "3/8"},
+		{"Double dotted half", 2, 2, "7/8"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MakeNote(tt.value, tt.dots).Duration(); got.String() != tt.want {
+				t.Errorf("MakeNote().Duration() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNoteValueDuration(t *testing.T) {
+	tests := []struct {
+		value int
+		dots  int
+		want  string
+	}{
+		{1, 0, "1/1"},
+		{2, 2, "7/8"},
+		{16, 1, "3/32"},
+		{128, 0, "1/128"},
+		{128, 2, "7/512"},
+	}
+	for _, tt := range tests {
+		if got := NoteValueDuration(tt.value, tt.dots); got.String() != tt.want {
+			t.Errorf("NoteValueDuration(%d, %d) = %v, want %v", tt.value, tt.dots, got, tt.want)
+		}
+	}
+	if got := MakeDuration(3, 8).Float64(); got != 0.375 {
+		t.Errorf("Duration.Float64() = %v, want 0.375", got)
+	}
+}
+
+func TestNote_SetTuplet(t *testing.T) {
+	tests := []struct {
+		name       string
+		note       *Note
+		actual     int
+		normal     int
+		want       string
+		wantActual int
+		wantNormal int
+	}{
+		{"Triplet eighth", MakeNote(8, 0), 3, 2, "1/12", 3, 2},
+		{"Quintuplet sixteenth", MakeNote(16, 0), 5, 4, "1/20", 5, 4},
+		{"Duplet in compound time", MakeNote(8, 0), 2, 3, "3/16", 2, 3},
+		{"Not a tuplet", MakeNote(4, 0), 2, 2, "1/4", 1, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.note.SetTuplet(tt.actual, tt.normal)
+			if got := tt.note.Duration(); got.String() != tt.want {
+				t.Errorf("Duration() = %v, want %v", got, tt.want)
+			}
+			if actual, normal := tt.note.Tuplet(); actual != tt.wantActual || normal != tt.wantNormal {
+				t.Errorf("Tuplet() = %d, %d, want %d, %d", actual, normal, tt.wantActual, tt.wantNormal)
+			}
+		})
+	}
+}
+
+func TestMakeTiedNotes(t *testing.T) {
+	c := *MakeSpelledPitch(LetterC, 0, 4)
+	tests := []struct {
+		name     string
+		duration Duration
+		pitches  []SpelledPitch
+		want     string
+	}{
+		{"Single value", MakeDuration(1, 4), []SpelledPitch{c}, "4"},
+		{"Dotted value", MakeDuration(3, 8), []SpelledPitch{c}, "4."},
+		{"Double dotted value", MakeDuration(7, 8), []SpelledPitch{c}, "2.."},
+		{"Two values", MakeDuration(5, 8), []SpelledPitch{c}, "2~ 8"},
+		{"Longer than a whole note", MakeDuration(9, 4), []SpelledPitch{c}, "1..~ 2"},
+		{"Rests aren't tied", MakeDuration(5, 16), nil, "4 16"},
+		{"Tuplet", MakeDuration(1, 6), []SpelledPitch{c}, "0"},
+		{"Nothing", MakeDuration(0, 1), []SpelledPitch{c}, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notes := MakeTiedNotes(tt.duration, tt.pitches...)
+			var written []string
+			total := Duration{}
+			for _, note := range notes {
+				w := fmt.Sprint(note.Value()) + strings.Repeat(".", note.Dots())
+				if note.Tied() {
+					w += "~"
+				}
+				written = append(written, w)
+				total = total.Add(note.Duration())
+			}
+			if got := strings.Join(written, " "); got != tt.want {
+				t.Errorf("MakeTiedNotes() = %v, want %v", got, tt.want)
+			}
+			if total.Cmp(tt.duration) != 0 {
+				t.Errorf("MakeTiedNotes() lasts %v, want %v", total, tt.duration)
+			}
+		})
+	}
+}
+
+func TestMakePitchedNote(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	chord := MakeChord(*pf.GetPitch(B().Flat(), 3), *pf.GetPitch(D(), 4), *pf.GetPitch(F(), 4))
+	note := MakePitchedNote(2, 0, MakeKeySignature(-2, false), chord.Pitches()...)
+	var names []string
+	for _, sp := range note.Pitches() {
+		names = append(names, sp.String())
+	}
+	if got := strings.Join(names, " "); got != "B♭3 D4 F4" || note.Duration().String() != "1/2" {
+		t.Errorf("MakePitchedNote() = %v lasting %v, want B♭3 D4 F4 lasting 1/2", got, note.Duration())
+	}
+	if !MakePitchedNote(4, 0, nil).IsRest() {
+		t.Error("MakePitchedNote() without pitches should be a rest")
+	}
+}
@@ -0,0 +1,3 @@
+module github.com/chris-franklin/tonacity
+
+go 1.23
@@ -0,0 +1,376 @@
+package tonacity
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// Humdrum is a tab separated text format for music analysis. Each column is a spine, and **kern spines hold notes, one voice to a
+// spine, with time running down the page. A note is written as its reciprocal duration followed by its pitch, e.g. "4c#" for a quarter
+// note C♯4 and "8.GG" for a dotted eighth G2: lower case letters are middle C and above, each repeat raising the octave, and upper case
+// letters are the octave below middle C and lower. Lines starting with * are interpretations, such as "*k[f#]" for a key signature,
+// "*G:" for a key, or "*M3/4" for a meter. Spines of other types, comments, and everything that doesn't affect pitch or timing are
+// skipped.
+
+// KernKey A key in force from some time onwards.
+type KernKey struct {
+	Start Duration
+	Key   *KeySignature
+}
+
+// KernMeter A meter in force from some time onwards.
+type KernMeter struct {
+	Start Duration
+	Time  *TimeSignature
+}
+
+// KernVoice The notes of one **kern spine, with the keys and meters interpreted along it.
+type KernVoice struct {
+	Name   string // The instrument name given by *I", or a number counting spines from the left if there isn't one
+	Notes  []TimedNote
+	Keys   []KernKey
+	Meters []KernMeter
+}
+
+// KernScore The voices read from a Humdrum file, in the order their spines first appear from left to right. A spine that splits in two
+// gives another voice with the same name.
+type KernScore struct {
+	Title  string // The title from the !!!OTL reference record
+	Voices []*KernVoice
+}
+
+// kernSpine A column of the file as it is read.
+type kernSpine struct {
+	voice    *KernVoice // The voice the spine is read into, or nil if it isn't **kern
+	nextFree Duration   // When the last note read finishes
+}
+
+type kernReader struct {
+	score  *KernScore
+	spines []*kernSpine
+	now    Duration // When the next line of data starts
+}
+
+// ReadKern Reads the **kern spines of a Humdrum file.
+func ReadKern(r io.Reader) (*KernScore, error) {
+	kr := &kernReader{score: &KernScore{}}
+	scanner := bufio.NewScanner(r)
+	for line := 1; scanner.Scan(); line++ {
+		if err := kr.readLine(strings.TrimRight(scanner.Text(), "\r")); err != nil {
+			return nil, fmt.Errorf("kern: line %d: %v", line, err)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("kern: %v", err)
+	}
+	if kr.spines == nil {
+		return nil, errors.New("kern: no exclusive interpretation")
+	}
+	return kr.score, nil
+}
+
+func (kr *kernReader) readLine(line string) error {
+	switch {
+	case strings.HasPrefix(line, "!!!OTL:"):
+		if kr.score.Title == "" {
+			kr.score.Title = strings.TrimSpace(line[len("!!!OTL:"):])
+		}
+		return nil
+	case line == "" || strings.HasPrefix(line, "!!"):
+		return nil
+	}
+	fields := strings.Split(line, "\t")
+	if kr.spines == nil {
+		if !strings.HasPrefix(line, "**") {
+			return errors.New("data before the exclusive interpretation")
+		}
+		for range fields {
+			kr.spines = append(kr.spines, &kernSpine{})
+		}
+	}
+	if len(fields) != len(kr.spines) {
+		return fmt.Errorf("%d spines, want %d", len(fields), len(kr.spines))
+	}
+	switch line[0] {
+	case '!', '=':
+		// Local comments and bar lines
+		return nil
+	case '*':
+		return kr.interpret(fields)
+	}
+	for i, token := range fields {
+		spine := kr.spines[i]
+		if spine.voice == nil || token == "." {
+			continue
+		}
+		if err := kr.readToken(spine, token); err != nil {
+			return fmt.Errorf("spine %d: %v", i+1, err)
+		}
+	}
+	// The next line starts when the first of the notes playing finishes
+	first := true
+	for _, spine := range kr.spines {
+		if spine.voice != nil && (first || spine.nextFree.Cmp(kr.now) < 0) {
+			kr.now = spine.nextFree
+			first = false
+		}
+	}
+	return nil
+}
+
+// interpret Reads a line of interpretations, which can split, join, add, swap and end spines as well as describe them.
+func (kr *kernReader) interpret(fields []string) error {
+	spines := make([]*kernSpine, 0, len(fields))
+	for i := 0; i < len(fields); i++ {
+		spine, token := kr.spines[i], fields[i]
+		switch {
+		case token == "*^":
+			spines = append(spines, spine, kr.split(spine))
+		case token == "*v":
+			// All the spines in a run of joins become the first one
+			spines = append(spines, spine)
+			for i+1 < len(fields) && fields[i+1] == "*v" {
+				i++
+			}
+		case token == "*-":
+		case token == "*x" && i+1 < len(fields) && fields[i+1] == "*x":
+			spines = append(spines, kr.spines[i+1], spine)
+			i++
+		case token == "*+":
+			spines = append(spines, spine, &kernSpine{nextFree: kr.now})
+		case strings.HasPrefix(token, "**"):
+			if token == "**kern" && spine.voice == nil {
+				spine.voice = &KernVoice{Name: strconv.Itoa(len(kr.score.Voices) + 1)}
+				spine.nextFree = kr.now
+				kr.score.Voices = append(kr.score.Voices, spine.voice)
+			}
+			spines = append(spines, spine)
+		default:
+			if spine.voice != nil {
+				kr.interpretSpine(spine.voice, token)
+			}
+			spines = append(spines, spine)
+		}
+	}
+	kr.spines = spines
+	return nil
+}
+
+// split Returns a new spine split off from the given one, which carries on with its key and meter.
+func (kr *kernReader) split(spine *kernSpine) *kernSpine {
+	split := &kernSpine{nextFree: kr.now}
+	if spine.voice != nil {
+		split.voice = &KernVoice{Name: spine.voice.Name}
+		if n := len(spine.voice.Keys); n > 0 {
+			split.voice.Keys = []KernKey{{kr.now, spine.voice.Keys[n-1].Key}}
+		}
+		if n := len(spine.voice.Meters); n > 0 {
+			split.voice.Meters = []KernMeter{{kr.now, spine.voice.Meters[n-1].Time}}
+		}
+		kr.score.Voices = append(kr.score.Voices, split.voice)
+	}
+	return split
+}
+
+// kernModes The names of the modes from BuildModeDictionary, by the abbreviations Humdrum uses for them.
+var kernModes = map[string]string{
+	"ion": "Ionian",
+	"dor": "Dorian",
+	"phr": "Phrygian",
+	"lyd": "Lydian",
+	"mix": "Mixolydian",
+	"aeo": "Aeolian",
+	"loc": "Locrian",
+}
+
+// interpretSpine Reads an interpretation for a single **kern voice. Only instrument names, key signatures, keys and meters are used.
+func (kr *kernReader) interpretSpine(voice *KernVoice, token string) {
+	switch {
+	case strings.HasPrefix(token, `*I"`):
+		voice.Name = token[3:]
+	case strings.HasPrefix(token, "*k[") && strings.HasSuffix(token, "]"):
+		fifths := strings.Count(token, "#") - strings.Count(token, "-")
+		// A key given along with the signature is more specific, so is kept
+		if n := len(voice.Keys); n > 0 && voice.Keys[n-1].Start.Cmp(kr.now) == 0 && voice.Keys[n-1].Key.Fifths() == fifths {
+			return
+		}
+		if key := MakeKeySignature(fifths, false); key != nil {
+			voice.addKey(kr.now, key)
+		}
+	case strings.HasPrefix(token, "*M") && strings.Contains(token, "/"):
+		parts := strings.SplitN(token[2:], "/", 2)
+		count, err1 := strconv.Atoi(parts[0])
+		value, err2 := strconv.Atoi(parts[1])
+		if time := MakeTimeSignature(count, value); err1 == nil && err2 == nil && time != nil {
+			if n := len(voice.Meters); n > 0 && voice.Meters[n-1].Start.Cmp(kr.now) == 0 {
+				voice.Meters[n-1].Time = time
+			} else {
+				voice.Meters = append(voice.Meters, KernMeter{kr.now, time})
+			}
+		}
+	case strings.Contains(token, ":"):
+		if key := parseKernKey(token[1:]); key != nil {
+			voice.addKey(kr.now, key)
+		}
+	}
+}
+
+// addKey Adds a key change, replacing any other at the same time.
+func (v *KernVoice) addKey(start Duration, key *KeySignature) {
+	if n := len(v.Keys); n > 0 && v.Keys[n-1].Start.Cmp(start) == 0 {
+		v.Keys[n-1].Key = key
+		return
+	}
+	v.Keys = append(v.Keys, KernKey{start, key})
+}
+
+// parseKernKey Reads a key, e.g. "G:" for G Major, "f#:" for F♯ Minor, or "D:dor" for D Dorian. Returns nil if it isn't a key that
+// can be written with a key signature.
+func parseKernKey(value string) *KeySignature {
+	colon := strings.IndexByte(value, ':')
+	if colon < 1 {
+		return nil
+	}
+	letter, ok := ParseLetter(value[0])
+	if !ok {
+		return nil
+	}
+	alter := strings.Count(value[1:colon], "#") - strings.Count(value[1:colon], "-")
+	mode := "Ionian"
+	if value[0] >= 'a' {
+		mode = "Aeolian"
+	}
+	if suffix := value[colon+1:]; suffix != "" {
+		if mode, ok = kernModes[suffix]; !ok {
+			return nil
+		}
+	}
+	return MakeModalKeySignature(letter, alter, mode)
+}
+
+// readToken Reads a note, chord or rest, and adds it to the spine's voice.
+func (kr *kernReader) readToken(spine *kernSpine, token string) error {
+	note, tie, err := parseKernToken(token)
+	if err != nil || note == nil {
+		return err
+	}
+	voice := spine.voice
+	start := spine.nextFree
+	spine.nextFree = start.Add(note.duration)
+	if n := len(voice.Notes); tie && n > 0 && samePitches(voice.Notes[n-1].Note.pitches, note.pitches) {
+		// Tied notes are played as one
+		last := &voice.Notes[n-1]
+		last.Note = *makeNoteOfDuration(last.Note.duration.Add(note.duration), note.pitches...)
+		return nil
+	}
+	voice.Notes = append(voice.Notes, TimedNote{start, *note})
+	return nil
+}
+
+// parseKernToken Reads a note, a chord (notes separated by spaces), or a rest, and whether it is tied to the note before. Grace notes,
+// which don't take up any time, are returned as nil.
+func parseKernToken(token string) (*Note, bool, error) {
+	var note *Note
+	pitches := make([]SpelledPitch, 0, 1)
+	tied := false
+	for _, sub := range strings.Fields(token) {
+		if strings.ContainsAny(sub, "qQ") {
+			return nil, false, nil
+		}
+		n, sp, err := parseKernNote(sub)
+		if err != nil {
+			return nil, false, fmt.Errorf("%q: %v", token, err)
+		}
+		if note == nil {
+			note = n
+		}
+		if sp != nil {
+			pitches = append(pitches, *sp)
+		}
+		// The end or middle of a tie carries on a note that has already started
+		tied = tied || strings.ContainsAny(sub, "_]")
+	}
+	if note == nil {
+		return nil, false, fmt.Errorf("%q: no note", token)
+	}
+	note.pitches = pitches
+	if len(pitches) == 0 {
+		note.pitches = nil
+	}
+	return note, tied, nil
+}
+
+// parseKernNote Reads a single note or rest, returning a note of its duration and its pitch, which is nil for a rest.
+func parseKernNote(token string) (*Note, *SpelledPitch, error) {
+	// The duration can come after the start of a slur, phrase or tie
+	start := strings.IndexAny(token, "0123456789")
+	if start < 0 {
+		return nil, nil, errors.New("no duration")
+	}
+	i := start
+	for i < len(token) && isDigit(token[i]) {
+		i++
+	}
+	reciprocal, _ := strconv.Atoi(token[start:i])
+	var duration Duration
+	rational := false
+	switch {
+	case reciprocal == 0:
+		// 0 is a breve, 00 a long, and so on
+		duration = MakeDuration(int64(1)<<uint(i-start), 1)
+	case i < len(token) && token[i] == '%':
+		j := i + 1
+		for j < len(token) && isDigit(token[j]) {
+			j++
+		}
+		divisor, err := strconv.Atoi(token[i+1 : j])
+		if err != nil || divisor == 0 {
+			return nil, nil, errors.New("invalid duration")
+		}
+		duration = MakeDuration(int64(divisor), int64(reciprocal))
+		rational = true
+		i = j
+	default:
+		duration = MakeDuration(1, int64(reciprocal))
+	}
+	dots := strings.Count(token[i:], ".")
+	// Each dot adds half of what came before
+	duration = duration.Mul(MakeDuration(int64(1)<<uint(dots+1)-1, int64(1)<<uint(dots)))
+
+	var note *Note
+	if reciprocal > 0 && !rational {
+		// A reciprocal that isn't a power of two is a tuplet, written as the next longer note value, e.g. 12 is a triplet eighth
+		value := reciprocal
+		for value&(value-1) != 0 {
+			value &= value - 1
+		}
+		note = MakeNote(value, dots)
+		note.SetDuration(duration)
+	} else {
+		note = makeNoteOfDuration(duration)
+	}
+
+	letters := strings.IndexAny(token, "abcdefgABCDEFG")
+	if letters < 0 {
+		if !strings.ContainsRune(token, 'r') {
+			return nil, nil, errors.New("no pitch")
+		}
+		return note, nil, nil
+	}
+	c := token[letters]
+	count := 1
+	for letters+count < len(token) && token[letters+count] == c {
+		count++
+	}
+	letter, _ := ParseLetter(c)
+	octave := 3 + count
+	if c < 'a' {
+		octave = 4 - count
+	}
+	alter := strings.Count(token, "#") - strings.Count(token, "-")
+	return note, MakeSpelledPitch(letter, alter, octave), nil
+}
@@ -0,0 +1,148 @@
+package tonacity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const kernFixture = `!!!OTL: Fixture
+!! A global comment
+**kern	**kern	**dynam
+*I"Bass	*I"Soprano	*
+*k[f#]	*k[f#]	*
+*G:	*G:	*
+*M3/4	*M3/4	*
+4G	4b	p
+=1	=1	=1
+8.F#L	[2dd	.
+16EJ	.	.
+4D	.	.
+*^	*	*
+4G	4B	4dd]	.
+*v	*v	*	*
+=2	=2	=2
+*M2/4	*M2/4	*
+12A	4cc# 4ee	.
+12B	.	.
+12c	.	.
+4GG	4dd;	f
+*-	*-	*-
+`
+
+func TestReadKern(t *testing.T) {
+	score, err := ReadKern(strings.NewReader(strings.ReplaceAll(kernFixture, "\n", "\r\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score.Title != "Fixture" {
+		t.Errorf("KernScore.Title = %q, want Fixture", score.Title)
+	}
+	tests := []struct {
+		name       string
+		notes      []string
+		keyStarts  []Duration
+		meterTimes []string
+	}{
+		{"Bass", []string{
+			"0/1 G3 1/4", "1/4 F♯3 3/16", "7/16 E3 1/16", "1/2 D3 1/4", "3/4 G3 1/4",
+			"1/1 A3 1/12", "13/12 B3 1/12", "7/6 C4 1/12", "5/4 G2 1/4"},
+			[]Duration{{}}, []string{"3/4", "2/4"}},
+		{"Soprano", []string{"0/1 B4 1/4", "1/4 D5 3/4", "1/1 C♯5+E5 1/4", "5/4 D5 1/4"}, []Duration{{}}, []string{"3/4", "2/4"}},
+		{"Bass", []string{"3/4 B3 1/4"}, []Duration{MakeDuration(3, 4)}, []string{"3/4"}},
+	}
+	if len(score.Voices) != len(tests) {
+		t.Fatalf("ReadKern() read %d voices, want %d", len(score.Voices), len(tests))
+	}
+	for i, tt := range tests {
+		voice := score.Voices[i]
+		if voice.Name != tt.name {
+			t.Errorf("voice %d name = %q, want %q", i, voice.Name, tt.name)
+		}
+		if got := abcSummary(voice.Notes); !reflect.DeepEqual(got, tt.notes) {
+			t.Errorf("voice %d notes = %v, want %v", i, got, tt.notes)
+		}
+		if len(voice.Keys) != len(tt.keyStarts) {
+			t.Errorf("voice %d keys = %v, want %d", i, voice.Keys, len(tt.keyStarts))
+		}
+		for j, key := range voice.Keys {
+			if key.Start.Cmp(tt.keyStarts[j]) != 0 || key.Key.Fifths() != 1 || key.Key.Mode() != "Ionian" {
+				t.Errorf("voice %d key %d = %v from %v, want G Major from %v", i, j, key.Key, key.Start, tt.keyStarts[j])
+			}
+		}
+		meters := make([]string, len(voice.Meters))
+		for j, meter := range voice.Meters {
+			meters[j] = meter.Time.String()
+		}
+		if !reflect.DeepEqual(meters, tt.meterTimes) {
+			t.Errorf("voice %d meters = %v, want %v", i, meters, tt.meterTimes)
+		}
+	}
+	// The second bass meter starts after the first bar
+	if got := score.Voices[0].Meters[1].Start; got.Cmp(MakeDuration(1, 1)) != 0 {
+		t.Errorf("meter change at %v, want 1/1", got)
+	}
+	// Triplet eighths are written as eighths
+	if note := score.Voices[0].Notes[5].Note; note.Value() != 8 || note.tupletRatio().Cmp(MakeDuration(3, 2)) != 0 {
+		t.Errorf("triplet = value %v ratio %v, want value 8 ratio 3/2", note.Value(), note.tupletRatio())
+	}
+}
+
+func TestParseKernToken(t *testing.T) {
+	tests := []struct {
+		token     string
+		want      string
+		wantValue int
+		wantDots  int
+		wantTie   bool
+	}{
+		{"4c", "0/1 C4 1/4", 4, 0, false},
+		{"8.ee-", "0/1 E♭5 3/16", 8, 1, false},
+		{"16AAA##", "0/1 A𝄪1 1/16", 16, 0, false},
+		{"2..Bn", "0/1 B3 7/8", 2, 2, false},
+		{"4r", "0/1 z 1/4", 4, 0, false},
+		{"0c", "0/1 C4 2/1", 0, 0, false},
+		{"3%2G", "0/1 G3 2/3", 0, 0, false},
+		{"4d]", "0/1 D4 1/4", 4, 0, true},
+		{"(8f#L 8a", "0/1 F♯4+A4 1/8", 8, 0, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.token, func(t *testing.T) {
+			note, tied, err := parseKernToken(tt.token)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := abcSummary([]TimedNote{{Note: *note}}); got[0] != tt.want {
+				t.Errorf("parseKernToken() = %v, want %v", got[0], tt.want)
+			}
+			if note.Value() != tt.wantValue || note.Dots() != tt.wantDots || tied != tt.wantTie {
+				t.Errorf("parseKernToken() = value %v, dots %v, tied %v, want %v, %v, %v",
+					note.Value(), note.Dots(), tied, tt.wantValue, tt.wantDots, tt.wantTie)
+			}
+		})
+	}
+	if note, _, err := parseKernToken("8qc"); note != nil || err != nil {
+		t.Errorf("parseKernToken(grace note) = %v, %v, want nil", note, err)
+	}
+	if _, _, err := parseKernToken("cc"); err == nil {
+		t.Error("parseKernToken() without a duration should fail")
+	}
+}
+
+func TestReadKern_Errors(t *testing.T) {
+	tests := []struct {
+		name string
+		kern string
+	}{
+		{"no exclusive interpretation", "4c\n"},
+		{"wrong number of spines", "**kern\t**kern\n4c\n"},
+		{"empty", "!! nothing\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadKern(strings.NewReader(tt.kern)); err == nil {
+				t.Errorf("ReadKern(%q) should fail", tt.kern)
+			}
+		})
+	}
+}
@@ -0,0 +1,209 @@
+package tonacity
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// LilyPond is a text format for engraving sheet music. Pitches are written in its default (Dutch) note names: "is" raises a letter and
+// "es" lowers it, so F♯ is "fis" and B♭ is "bes". Octaves are absolute: "c" is C3, each ' raises it an octave and each , lowers it, so
+// middle C is "c'".
+
+// LilyPondPitch Returns the LilyPond name of the given spelled pitch, e.g. "fis'" for F♯4.
+func LilyPondPitch(sp *SpelledPitch) string {
+	name := strings.ToLower(sp.letter.String())
+	alter := sp.Alter()
+	for i := 0; i < alter; i++ {
+		name += "is"
+	}
+	for i := 0; i > alter; i-- {
+		name += "es"
+	}
+	// The vowels drop their e when flattened: E♭ is "es" and A♭ is "as"
+	if strings.HasPrefix(name, "ees") || strings.HasPrefix(name, "aes") {
+		name = name[:1] + name[2:]
+	}
+	octave := sp.Octave() - 3
+	if octave > 0 {
+		name += strings.Repeat("'", octave)
+	} else if octave < 0 {
+		name += strings.Repeat(",", -octave)
+	}
+	return name
+}
+
+// LilyPondPitchInKey Returns the LilyPond name of the given pitch, spelled as it would be in the given key (see SpellPitch).
+func LilyPondPitchInKey(p *Pitch, key *KeySignature) string {
+	sp := SpellPitch(*p, key)
+	return LilyPondPitch(&sp)
+}
+
+// LilyPondChord Returns the given chord as a LilyPond chord, lowest pitch first, spelled as it would be in the given key,
+// e.g. "<c' e' g'>".
+func LilyPondChord(chord *Chord, key *KeySignature) string {
+	pitches := make([]Pitch, len(chord.pitches))
+	copy(pitches, chord.pitches)
+	sort.Sort(ByPitch(pitches))
+	names := make([]string, len(pitches))
+	for i := range pitches {
+		names[i] = LilyPondPitchInKey(&pitches[i], key)
+	}
+	return "<" + strings.Join(names, " ") + ">"
+}
+
+// LilyPondSinger Returns up to count pitches sung by the given singer as a sequence of LilyPond notes of the given value, spelled as they
+// would be in the given key, e.g. "d'4 e' fis' g'". A scale can be written out by passing the singer of its pattern.
+func LilyPondSinger(singer Singer, count int, value int, key *KeySignature) string {
+	notes := make([]string, 0, count)
+	for i := 0; i < count; i++ {
+		pitch, more := singer.Sing()
+		if !more {
+			break
+		}
+		note := LilyPondPitchInKey(&pitch, key)
+		if i == 0 {
+			// LilyPond carries the last duration forward, so it only needs writing once
+			note += fmt.Sprint(value)
+		}
+		notes = append(notes, note)
+	}
+	return strings.Join(notes, " ")
+}
+
+// lilyPondClefs The names LilyPond gives clefs, by symbol and line.
+var lilyPondClefs = map[ClefSign]map[int]string{
+	GClef: {1: "french", 2: "treble"},
+	FClef: {3: "varbaritone", 4: "bass", 5: "subbass"},
+	CClef: {1: "soprano", 2: "mezzosoprano", 3: "alto", 4: "tenor", 5: "baritone"},
+}
+
+// LilyPondClef Returns the LilyPond command for the given clef, e.g. "\clef treble", or "\clef "treble_8"" for a treble clef sounding
+// an octave lower.
+func LilyPondClef(clef *Clef) string {
+	if clef.sign == PercussionClef {
+		return `\clef percussion`
+	}
+	name, ok := lilyPondClefs[clef.sign][clef.line]
+	if !ok {
+		name = lilyPondClefs[clef.sign][map[ClefSign]int{GClef: 2, FClef: 4, CClef: 3}[clef.sign]]
+	}
+	switch {
+	case clef.octaveChange < 0:
+		return fmt.Sprintf(`\clef "%s_%d"`, name, 7*-clef.octaveChange+1)
+	case clef.octaveChange > 0:
+		return fmt.Sprintf(`\clef "%s^%d"`, name, 7*clef.octaveChange+1)
+	}
+	return `\clef ` + name
+}
+
+// lilyPondModes The LilyPond names of the modes, by their names in BuildModeDictionary.
+var lilyPondModes = map[string]string{
+	"Ionian":     `\major`,
+	"Dorian":     `\dorian`,
+	"Phrygian":   `\phrygian`,
+	"Lydian":     `\lydian`,
+	"Mixolydian": `\mixolydian`,
+	"Aeolian":    `\minor`,
+	"Locrian":    `\locrian`,
+}
+
+// LilyPondKey Returns the LilyPond command for the given key signature, e.g. "\key fis \minor" or "\key d \dorian".
+func LilyPondKey(key *KeySignature) string {
+	pf := &PitchFactory{*MiddleC()}
+	tonic := SpellPitch(*pf.GetPitch(&key.tonic, 3), key)
+	return fmt.Sprintf(`\key %s %s`, strings.TrimRight(LilyPondPitch(&tonic), "',"), lilyPondModes[key.Mode()])
+}
+
+// LilyPondTime Returns the LilyPond command for the given time signature, e.g. "\time 6/8", with the groups of beats if they were given,
+// e.g. "\time 2,2,3 7/8".
+func LilyPondTime(time *TimeSignature) string {
+	if groups := time.writtenGroups(","); groups != "" {
+		return `\time ` + groups + " " + time.String()
+	}
+	return `\time ` + time.String()
+}
+
+// lilyPondDuration Returns the LilyPond duration of a note, e.g. "4." for a dotted quarter, or a whole note scaled to length for notes
+// without a value, e.g. "1*3/4".
+func lilyPondDuration(note *Note) string {
+	if note.value == 0 {
+		return fmt.Sprintf("1*%v", note.duration)
+	}
+	return fmt.Sprint(note.value) + strings.Repeat(".", note.dots)
+}
+
+// lilyPondNote Returns a note, chord or rest in LilyPond, with its duration, and a tie if it is tied to the next. A rest without a value
+// is written as a whole bar rest if it fills the bar, and otherwise as the rests that make up its length.
+func lilyPondNote(note *Note, wholeBar bool) string {
+	if note.IsRest() {
+		if note.value == 0 && !wholeBar {
+			rests := MakeTiedNotes(note.duration)
+			names := make([]string, len(rests))
+			for i := range rests {
+				names[i] = "r" + lilyPondDuration(&rests[i])
+			}
+			return strings.Join(names, " ")
+		} else if note.value == 0 {
+			return "R" + lilyPondDuration(note)
+		}
+		return "r" + lilyPondDuration(note)
+	}
+	names := make([]string, len(note.pitches))
+	for i := range note.pitches {
+		names[i] = LilyPondPitch(&note.pitches[i])
+	}
+	tie := ""
+	if note.tied {
+		tie = "~"
+	}
+	if len(names) == 1 {
+		return names[0] + lilyPondDuration(note) + tie
+	}
+	return "<" + strings.Join(names, " ") + ">" + lilyPondDuration(note) + tie
+}
+
+// LilyPondStave Returns the given stave as a LilyPond staff, with clef, key and time signature written at the start and wherever they
+// change, and one bar per line. Consecutive notes in the same kind of tuplet are grouped, e.g. "\tuplet 3/2 { c'8 d' e' }".
+func LilyPondStave(stave *Stave) string {
+	var b strings.Builder
+	b.WriteString("\\new Staff {\n")
+	var previous *Bar
+	for i := range stave.bars {
+		bar := &stave.bars[i]
+		commands := make([]string, 0)
+		if bar.clef != nil && (previous == nil || previous.clef == nil || *previous.clef != *bar.clef) {
+			commands = append(commands, LilyPondClef(bar.clef))
+		}
+		if bar.key != nil && (previous == nil || previous.key == nil || *previous.key != *bar.key) {
+			commands = append(commands, LilyPondKey(bar.key))
+		}
+		if bar.time.noteCount > 0 && (previous == nil || previous.time != bar.time) {
+			commands = append(commands, LilyPondTime(&bar.time))
+		}
+		if len(commands) > 0 {
+			b.WriteString("  " + strings.Join(commands, " ") + "\n")
+		}
+
+		items := make([]string, 0, len(bar.notes))
+		wholeBar := len(bar.notes) == 1 && bar.time.noteCount > 0 && bar.notes[0].duration.Cmp(bar.time.Duration()) == 0
+		for j := 0; j < len(bar.notes); {
+			ratio := bar.notes[j].tupletRatio()
+			k := j
+			group := make([]string, 0)
+			for ; k < len(bar.notes) && bar.notes[k].tupletRatio().Cmp(ratio) == 0; k++ {
+				group = append(group, lilyPondNote(&bar.notes[k], wholeBar))
+			}
+			if ratio.Cmp(MakeDuration(1, 1)) == 0 {
+				items = append(items, group...)
+			} else {
+				items = append(items, fmt.Sprintf("\\tuplet %d/%d { %s }", ratio.numerator, ratio.Denominator(), strings.Join(group, " ")))
+			}
+			j = k
+		}
+		b.WriteString("  " + strings.Join(items, " ") + " |\n")
+		previous = bar
+	}
+	b.WriteString("}\n")
+	return b.String()
+}
@@ -0,0 +1,151 @@
+package tonacity
+
+import "testing"
+
+func TestLilyPondPitch(t *testing.T) {
+	tests := []struct {
+		name string
+		sp   *SpelledPitch
+		want string
+	}{
+		{"Middle C", MakeSpelledPitch(LetterC, 0, 4), "c'"},
+		{"F♯4", MakeSpelledPitch(LetterF, 1, 4), "fis'"},
+		{"C3", MakeSpelledPitch(LetterC, 0, 3), "c"},
+		{"B♭1", MakeSpelledPitch(LetterB, -1, 1), "bes,,"},
+		{"E♭5", MakeSpelledPitch(LetterE, -1, 5), "es''"},
+		{"A♭4", MakeSpelledPitch(LetterA, -1, 4), "as'"},
+		{"A𝄫4", MakeSpelledPitch(LetterA, -2, 4), "ases'"},
+		{"C𝄪4", MakeSpelledPitch(LetterC, 2, 4), "cisis'"},
+		{"C♭4", MakeSpelledPitch(LetterC, -1, 4), "ces'"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LilyPondPitch(tt.sp); got != tt.want {
+				t.Errorf("LilyPondPitch() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLilyPondChord(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	chord := MakeChord(*pf.GetPitch(B().Flat(), 3), *pf.GetPitch(D(), 4), *pf.GetPitch(F(), 4), *pf.GetPitch(A().Flat(), 4))
+	tests := []struct {
+		name string
+		key  *KeySignature
+		want string
+	}{
+		{"In E♭ Major", MakeKeySignature(-3, false), "<bes d' f' as'>"},
+		{"In E Major", MakeKeySignature(4, false), "<ais d' f' gis'>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LilyPondChord(chord, tt.key); got != tt.want {
+				t.Errorf("LilyPondChord() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLilyPondSinger(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	scale := make([]Pitch, 0)
+	for _, pc := range []*PitchClass{D(), E(), F().Sharp(), G(), A(), B()} {
+		scale = append(scale, *pf.GetPitch(pc, 4))
+	}
+	scale = append(scale, *pf.GetPitch(C().Sharp(), 5), *pf.GetPitch(D(), 5))
+	want := "d'8 e' fis' g' a' b' cis'' d''"
+	if got := LilyPondSinger(MakeSliceSinger(scale...), 10, 8, MakeKeySignature(2, false)); got != want {
+		t.Errorf("LilyPondSinger() = %v, want %v", got, want)
+	}
+}
+
+func TestLilyPondClef(t *testing.T) {
+	tests := []struct {
+		name string
+		clef *Clef
+		want string
+	}{
+		{"Treble", MakeClef(GClef, 2, 0), `\clef treble`},
+		{"Bass", MakeClef(FClef, 4, 0), `\clef bass`},
+		{"Tenor", MakeClef(CClef, 4, 0), `\clef tenor`},
+		{"Guitar", MakeClef(GClef, 2, -1), `\clef "treble_8"`},
+		{"Piccolo", MakeClef(GClef, 2, 1), `\clef "treble^8"`},
+		{"Percussion", MakeClef(PercussionClef, 3, 0), `\clef percussion`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := LilyPondClef(tt.clef); got != tt.want {
+				t.Errorf("LilyPondClef() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestLilyPondKey(t *testing.T) {
+	tests := []struct {
+		key  *KeySignature
+		want string
+	}{
+		{MakeKeySignature(3, true), `\key fis \minor`},
+		{MakeKeySignature(-6, false), `\key ges \major`},
+		{MakeModalKeySignature(LetterD, 0, "Dorian"), `\key d \dorian`},
+		{MakeModalKeySignature(LetterE, 0, "Phrygian"), `\key e \phrygian`},
+		{MakeModalKeySignature(LetterB, -1, "Lydian"), `\key bes \lydian`},
+		{MakeModalKeySignature(LetterG, 0, "Mixolydian"), `\key g \mixolydian`},
+		{MakeModalKeySignature(LetterF, 1, "Locrian"), `\key fis \locrian`},
+	}
+	for _, tt := range tests {
+		if got := LilyPondKey(tt.key); got != tt.want {
+			t.Errorf("LilyPondKey() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestLilyPondTime(t *testing.T) {
+	tests := []struct {
+		time *TimeSignature
+		want string
+	}{
+		{MakeTimeSignature(6, 8), `\time 6/8`},
+		{MakeTimeSignature(7, 8), `\time 7/8`},
+		{MakeGroupedTimeSignature(8, 2, 2, 3), `\time 2,2,3 7/8`},
+		{MakeGroupedTimeSignature(8, 3, 3, 2), `\time 3,3,2 8/8`},
+	}
+	for _, tt := range tests {
+		if got := LilyPondTime(tt.time); got != tt.want {
+			t.Errorf("LilyPondTime() = %v, want %v", got, tt.want)
+		}
+	}
+}
+
+func TestLilyPondStave(t *testing.T) {
+	want := `\new Staff {
+  \clef treble \key d \major \time 3/4
+  <fis' a'>4 \tuplet 3/2 { bes'8 cis''8 r8 } d''4~ |
+  \key c \minor
+  R1*3/4 |
+}
+`
+	if got := LilyPondStave(musicXMLFixtureStaves()[0]); got != want {
+		t.Errorf("LilyPondStave() =\n%v\nwant\n%v", got, want)
+	}
+}
+
+func TestLilyPondStave_Rests(t *testing.T) {
+	c := *MakeSpelledPitch(LetterC, 0, 4)
+	bar := MakeBar(MakeTimeSignature(4, 4), nil, nil)
+	bar.AddNotes(*makeNoteOfDuration(MakeDuration(5, 16)), *MakeNote(16, 0, c), *MakeNote(2, 0, c))
+	whole := MakeBar(MakeTimeSignature(5, 16), nil, nil)
+	whole.AddNotes(*makeNoteOfDuration(MakeDuration(5, 16)))
+	want := `\new Staff {
+  \time 4/4
+  r4 r16 c'16 c'2 |
+  \time 5/16
+  R1*5/16 |
+}
+`
+	if got := LilyPondStave(MakeStave(*bar, *whole)); got != want {
+		t.Errorf("LilyPondStave() =\n%v\nwant\n%v", got, want)
+	}
+}
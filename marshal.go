@@ -0,0 +1,546 @@
+package tonacity
+
+import (
+	"bufio"
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// The core types are marshalled as text in the way musicians write them, and as JSON strings holding the same text, except for the
+// types with more than one part, which are JSON objects:
+//
+//	Pitch             "A4", "C#4", "Bb3"    (the octave is that of the letter, so Cb4 is the B below middle C)
+//	PitchClass        "A", "C#", "Bb"
+//	SpelledPitch      "Cb4", "F##3"         (the letter is kept, unlike Pitch)
+//	KeySignature      "Eb", "F#m", "D Dorian"
+//	Pattern           "W-W-H-W-W-W-H"       (W is a whole step and H a half step; other sizes are written as numbers, e.g. "3")
+//	Chord             "C4 E4 G4 B4"         (a single word is read as a chord symbol such as "Cmaj7", built upwards from octave 4)
+//	RootedPattern     "C4 W-W-H-W-W-W-H"    {"root": "C4", "pattern": "W-W-H-W-W-W-H"}
+//	PatternDictionary one line per entry    {"min": 1, "max": 2, "entries": [{"pattern": "W-W-H-W-W-W-H", "name": "Ionian"}]}
+//
+// Sharps and flats are written as # and b, and ♯ and ♭ are also read. Pitches are written with sharps.
+
+// pitchClassName Returns the name of a pitch class, written with a sharp for the black keys.
+func pitchClassName(pc PitchClass) string {
+	sp := SpellPitch(Pitch{pc, pc.value}, nil)
+	return chordSymbolNote(&sp)
+}
+
+// MarshalText Writes the pitch class as its name, e.g. "C#".
+func (pc PitchClass) MarshalText() ([]byte, error) {
+	return []byte(pitchClassName(pc)), nil
+}
+
+// UnmarshalText Reads a pitch class from its name, e.g. "C#", "Db" or "D♭".
+func (pc *PitchClass) UnmarshalText(text []byte) error {
+	sp, rest, ok := parseChordSymbolNote(string(text))
+	if !ok || rest != "" {
+		return fmt.Errorf("invalid pitch class %q", text)
+	}
+	*pc = sp.pitch.class
+	return nil
+}
+
+// MarshalJSON Writes the pitch class as a JSON string of its name.
+func (pc PitchClass) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(pc)
+}
+
+// UnmarshalJSON Reads a pitch class from a JSON string of its name.
+func (pc *PitchClass) UnmarshalJSON(data []byte) error {
+	return unmarshalTextJSON(data, pc.UnmarshalText)
+}
+
+func (pc PitchClass) String() string {
+	return pitchClassName(pc)
+}
+
+// MarshalText Writes the pitch as its name and octave, e.g. "C#4".
+func (p Pitch) MarshalText() ([]byte, error) {
+	sp := SpellPitch(p, nil)
+	return []byte(chordSymbolNote(&sp) + strconv.Itoa(sp.Octave())), nil
+}
+
+// UnmarshalText Reads a pitch from its name and octave, e.g. "A4", "Bb3" or "F♯2". Octaves from -1 to 9 are accepted.
+func (p *Pitch) UnmarshalText(text []byte) error {
+	sp, rest, ok := parseChordSymbolNote(string(text))
+	octave, err := strconv.Atoi(rest)
+	if !ok || err != nil || octave < -1 || octave > 9 {
+		return fmt.Errorf("invalid pitch %q", text)
+	}
+	*p = MakeSpelledPitch(sp.letter, sp.Alter(), octave).Pitch()
+	return nil
+}
+
+// MarshalJSON Writes the pitch as a JSON string of its name and octave.
+func (p Pitch) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(p)
+}
+
+// UnmarshalJSON Reads a pitch from a JSON string of its name and octave.
+func (p *Pitch) UnmarshalJSON(data []byte) error {
+	return unmarshalTextJSON(data, p.UnmarshalText)
+}
+
+// MarshalText Writes the pitch as it is spelled, with its octave, e.g. "Cb4" or "F##3".
+func (sp SpelledPitch) MarshalText() ([]byte, error) {
+	return []byte(chordSymbolNote(&sp) + strconv.Itoa(sp.Octave())), nil
+}
+
+// UnmarshalText Reads a spelled pitch from its name and octave, e.g. "Eb4" or "C♯5", keeping the letter it is written as. Octaves from
+// -1 to 9 are accepted.
+func (sp *SpelledPitch) UnmarshalText(text []byte) error {
+	parsed, rest, ok := parseChordSymbolNote(string(text))
+	octave, err := strconv.Atoi(rest)
+	if !ok || err != nil || octave < -1 || octave > 9 {
+		return fmt.Errorf("invalid pitch %q", text)
+	}
+	*sp = *MakeSpelledPitch(parsed.letter, parsed.Alter(), octave)
+	return nil
+}
+
+// ParseNoteName Reads a note name with or without an octave, e.g. "Eb4" or "F♯". A note without an octave is put in octave 4. The bool is
+// true if an octave was given.
+func ParseNoteName(text string) (*SpelledPitch, bool, error) {
+	var sp SpelledPitch
+	if err := sp.UnmarshalText([]byte(text)); err == nil {
+		return &sp, true, nil
+	}
+	if err := sp.UnmarshalText([]byte(text + "4")); err != nil {
+		return nil, false, fmt.Errorf("invalid note %q", text)
+	}
+	return &sp, false, nil
+}
+
+// MarshalJSON Writes the spelled pitch as a JSON string of its name and octave.
+func (sp SpelledPitch) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(sp)
+}
+
+// UnmarshalJSON Reads a spelled pitch from a JSON string of its name and octave.
+func (sp *SpelledPitch) UnmarshalJSON(data []byte) error {
+	return unmarshalTextJSON(data, sp.UnmarshalText)
+}
+
+// keyModeNames The modes of keys, by the ways they are written after the tonic. Any other mode is written out in full.
+var keyModeNames = map[string]string{
+	"":      "Ionian",
+	"maj":   "Ionian",
+	"major": "Ionian",
+	"m":     "Aeolian",
+	"min":   "Aeolian",
+	"minor": "Aeolian",
+}
+
+// MarshalText Writes the key as its tonic followed by "m" for a minor key, or the name of its mode for the other modes, e.g. "Eb",
+// "F#m" or "D Dorian".
+func (k KeySignature) MarshalText() ([]byte, error) {
+	tonic := SpellPitch(Pitch{k.tonic, k.tonic.value}, &k)
+	switch mode := k.Mode(); mode {
+	case "Ionian":
+		return []byte(chordSymbolNote(&tonic)), nil
+	case "Aeolian":
+		return []byte(chordSymbolNote(&tonic) + "m"), nil
+	default:
+		return []byte(chordSymbolNote(&tonic) + " " + mode), nil
+	}
+}
+
+// UnmarshalText Reads a key written as its tonic and mode, e.g. "Eb", "C#m", "A minor" or "D dorian". Case doesn't matter in the mode.
+func (k *KeySignature) UnmarshalText(text []byte) error {
+	tonic, rest, ok := parseChordSymbolNote(strings.TrimSpace(string(text)))
+	if !ok {
+		return fmt.Errorf("invalid key %q", text)
+	}
+	mode := strings.TrimSpace(rest)
+	if name, ok := keyModeNames[strings.ToLower(mode)]; ok {
+		mode = name
+	} else if rest == mode {
+		// Modes other than major and minor must be separated from the tonic, as in "D dorian"
+		return fmt.Errorf("invalid key %q", text)
+	}
+	key := MakeModalKeySignature(tonic.letter, tonic.Alter(), mode)
+	if key == nil {
+		return fmt.Errorf("invalid key %q: unknown mode, or more than seven sharps or flats", text)
+	}
+	*k = *key
+	return nil
+}
+
+// MarshalJSON Writes the key as a JSON string of its tonic and mode.
+func (k KeySignature) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(k)
+}
+
+// UnmarshalJSON Reads a key from a JSON string of its tonic and mode.
+func (k *KeySignature) UnmarshalJSON(data []byte) error {
+	return unmarshalTextJSON(data, k.UnmarshalText)
+}
+
+// patternStepNames The names of the steps written as letters in patterns.
+var patternStepNames = map[HalfSteps]string{HalfStepValue: "H", WholeStepValue: "W"}
+
+// MarshalText Writes the pattern as its steps separated by dashes, e.g. "W-W-H-W-W-W-H". A step going down is written with a minus,
+// e.g. "-H--W".
+func (p Pattern) MarshalText() ([]byte, error) {
+	steps := make([]string, len(p.intervals))
+	for i, interval := range p.intervals {
+		name, ok := patternStepNames[interval]
+		if !ok && interval < 0 {
+			name, ok = patternStepNames[-interval]
+			name = "-" + name
+		}
+		if !ok {
+			name = strconv.Itoa(int(interval))
+		}
+		steps[i] = name
+	}
+	return []byte(strings.Join(steps, "-")), nil
+}
+
+// UnmarshalText Reads a pattern written as steps separated by dashes, e.g. "W-W-H-W-W-W-H" or "W-H-W-W-H-3-H". Letters may be in
+// either case.
+func (p *Pattern) UnmarshalText(text []byte) error {
+	s := strings.ToUpper(strings.TrimSpace(string(text)))
+	intervals := make([]HalfSteps, 0, len(s)/2+1)
+	for i := 0; i < len(s); {
+		negative := s[i] == '-'
+		if negative {
+			i++
+		}
+		end := strings.IndexByte(s[i:], '-')
+		if end < 0 {
+			end = len(s)
+		} else {
+			end += i
+		}
+		var interval HalfSteps
+		switch step := s[i:end]; step {
+		case "H":
+			interval = HalfStepValue
+		case "W":
+			interval = WholeStepValue
+		default:
+			n, err := strconv.Atoi(step)
+			if err != nil || n < 0 || n > OctaveValue*2 {
+				return fmt.Errorf("invalid step %q in pattern %q", step, text)
+			}
+			interval = HalfSteps(n)
+		}
+		if negative {
+			interval = -interval
+		}
+		intervals = append(intervals, interval)
+		// Skip the dash separating this step from the next
+		i = end + 1
+		if end == len(s)-1 {
+			return fmt.Errorf("pattern %q ends with a dash", text)
+		}
+	}
+	if len(intervals) == 0 {
+		return errors.New("empty pattern")
+	}
+	p.intervals = intervals
+	return nil
+}
+
+// MarshalJSON Writes the pattern as a JSON string of its steps.
+func (p Pattern) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(p)
+}
+
+// UnmarshalJSON Reads a pattern from a JSON string of its steps.
+func (p *Pattern) UnmarshalJSON(data []byte) error {
+	return unmarshalTextJSON(data, p.UnmarshalText)
+}
+
+// MarshalText Writes the chord as its pitches separated by spaces, e.g. "C4 E4 G4". A chord of fewer than two pitches can't be read back.
+func (c Chord) MarshalText() ([]byte, error) {
+	names := make([]string, len(c.pitches))
+	for i, p := range c.pitches {
+		text, _ := p.MarshalText()
+		names[i] = string(text)
+	}
+	return []byte(strings.Join(names, " ")), nil
+}
+
+// UnmarshalText Reads a chord written as two or more pitches separated by spaces, e.g. "C4 E4 G4", or as a chord symbol, e.g. "Cmaj7",
+// which is built upwards from the bass in octave 4. A single word is always a chord symbol, so "G7" is a G dominant seventh chord rather
+// than the pitch G7. An error is returned if the text is empty.
+func (c *Chord) UnmarshalText(text []byte) error {
+	fields := strings.Fields(string(text))
+	switch len(fields) {
+	case 0:
+		return errors.New("invalid chord: no pitches or chord symbol")
+	case 1:
+		return c.unmarshalChordSymbol(fields[0])
+	}
+	pitches := make([]Pitch, len(fields))
+	for i, field := range fields {
+		if err := pitches[i].UnmarshalText([]byte(field)); err != nil {
+			return fmt.Errorf("invalid chord %q: %v", text, err)
+		}
+	}
+	c.pitches = pitches
+	return nil
+}
+
+func (c *Chord) unmarshalChordSymbol(symbol string) error {
+	chord, err := ParseChordSymbol(symbol)
+	if err != nil {
+		return fmt.Errorf("invalid chord %q: %v", symbol, err)
+	}
+	pf := &PitchFactory{*MiddleC()}
+	classes := chord.PitchClasses()
+	pitches := make([]Pitch, len(classes))
+	pitches[0] = *pf.GetPitch(&classes[0], 4)
+	for i := 1; i < len(classes); i++ {
+		pitches[i] = pitches[i-1]
+		pitches[i].RaiseToNext(classes[i])
+	}
+	c.pitches = pitches
+	return nil
+}
+
+// MarshalJSON Writes the chord as a JSON string of its pitches.
+func (c Chord) MarshalJSON() ([]byte, error) {
+	return marshalTextJSON(c)
+}
+
+// UnmarshalJSON Reads a chord from a JSON string of its pitches or its chord symbol.
+func (c *Chord) UnmarshalJSON(data []byte) error {
+	return unmarshalTextJSON(data, c.UnmarshalText)
+}
+
+// MarshalText Writes the root and then the pattern, separated by a space, e.g. "C4 W-W-H-W-W-W-H".
+func (rp RootedPattern) MarshalText() ([]byte, error) {
+	root, _ := rp.root.MarshalText()
+	pattern, _ := rp.pattern.MarshalText()
+	return []byte(string(root) + " " + string(pattern)), nil
+}
+
+// UnmarshalText Reads a root and a pattern separated by a space, e.g. "C4 W-W-H-W-W-W-H".
+func (rp *RootedPattern) UnmarshalText(text []byte) error {
+	fields := strings.Fields(string(text))
+	if len(fields) != 2 {
+		return fmt.Errorf("invalid rooted pattern %q", text)
+	}
+	var root Pitch
+	var pattern Pattern
+	if err := root.UnmarshalText([]byte(fields[0])); err != nil {
+		return err
+	}
+	if err := pattern.UnmarshalText([]byte(fields[1])); err != nil {
+		return err
+	}
+	rp.root, rp.pattern = root, pattern
+	return nil
+}
+
+// rootedPatternJSON The JSON form of a rooted pattern.
+type rootedPatternJSON struct {
+	Root    Pitch   `json:"root"`
+	Pattern Pattern `json:"pattern"`
+}
+
+// MarshalJSON Writes the rooted pattern as a JSON object with its root and pattern.
+func (rp RootedPattern) MarshalJSON() ([]byte, error) {
+	return json.Marshal(rootedPatternJSON{rp.root, rp.pattern})
+}
+
+// UnmarshalJSON Reads a rooted pattern from a JSON object with its root and pattern.
+func (rp *RootedPattern) UnmarshalJSON(data []byte) error {
+	var v rootedPatternJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	if v.Pattern.intervals == nil {
+		return errors.New("rooted pattern has no pattern")
+	}
+	rp.root, rp.pattern = v.Root, v.Pattern
+	return nil
+}
+
+// patternDictionaryEntryJSON An entry in the JSON form of a pattern dictionary, which is either a name, or the name of a chord along
+// with which of its pitches is the root.
+type patternDictionaryEntryJSON struct {
+	Pattern Pattern `json:"pattern"`
+	Name    *string `json:"name,omitempty"`
+	Chord   *string `json:"chord,omitempty"`
+	Root    int     `json:"root,omitempty"`
+}
+
+// patternDictionaryJSON The JSON form of a pattern dictionary, with the smallest and largest step its patterns may have.
+type patternDictionaryJSON struct {
+	Min     HalfSteps                    `json:"min"`
+	Max     HalfSteps                    `json:"max"`
+	Entries []patternDictionaryEntryJSON `json:"entries"`
+}
+
+// entries Returns every entry in the dictionary, failing if one isn't a name or a chord.
+func (d *PatternDictionary) entries() ([]patternDictionaryEntryJSON, error) {
+	entries := make([]patternDictionaryEntryJSON, 0)
+	var err error
+	d.Walk(func(pattern *Pattern, values []interface{}) {
+		for _, value := range values {
+			entry := patternDictionaryEntryJSON{Pattern: *pattern}
+			switch v := value.(type) {
+			case string:
+				entry.Name = &v
+			case *chordDictionaryEntry:
+				entry.Chord, entry.Root = &v.name, v.rootIndex
+			default:
+				err = fmt.Errorf("pattern dictionary entry of type %T can't be marshalled", value)
+			}
+			entries = append(entries, entry)
+		}
+	})
+	return entries, err
+}
+
+// add Adds an entry to the dictionary.
+func (d *PatternDictionary) add(entry patternDictionaryEntryJSON) error {
+	for _, interval := range entry.Pattern.intervals {
+		if interval < d.searchTree.min || interval > d.searchTree.max {
+			return fmt.Errorf("pattern %v has a step outside the dictionary's range of %d to %d",
+				entry.Pattern.intervals, d.searchTree.min, d.searchTree.max)
+		}
+	}
+	switch {
+	case entry.Name != nil:
+		d.AddPattern(&entry.Pattern, *entry.Name)
+	case entry.Chord != nil:
+		d.AddPattern(&entry.Pattern, &chordDictionaryEntry{*entry.Chord, entry.Root})
+	default:
+		return errors.New("pattern dictionary entry has no name or chord")
+	}
+	return nil
+}
+
+// newPatternDictionary Creates an empty dictionary whose patterns have steps from min to max.
+func newPatternDictionary(min HalfSteps, max HalfSteps) (*PatternDictionary, error) {
+	trie := NewTrie(min, max)
+	if trie == nil {
+		return nil, fmt.Errorf("invalid pattern dictionary range %d to %d", min, max)
+	}
+	return &PatternDictionary{trie}, nil
+}
+
+// MarshalText Writes the dictionary as a line with the range of its steps, e.g. "range 1 2", followed by a line for each entry with
+// its pattern, then a tab and its name, e.g. "W-W-H-W-W-W-H	Ionian". Chords are written with the pattern, "chord", the index of the
+// root and the name, separated by tabs.
+func (d PatternDictionary) MarshalText() ([]byte, error) {
+	if d.searchTree == nil {
+		return nil, errors.New("pattern dictionary has no range")
+	}
+	entries, err := d.entries()
+	if err != nil {
+		return nil, err
+	}
+	var b strings.Builder
+	fmt.Fprintf(&b, "range %d %d\n", d.searchTree.min, d.searchTree.max)
+	for _, entry := range entries {
+		pattern, _ := entry.Pattern.MarshalText()
+		if entry.Name != nil {
+			fmt.Fprintf(&b, "%s\t%s\n", pattern, *entry.Name)
+		} else {
+			fmt.Fprintf(&b, "%s\tchord\t%d\t%s\n", pattern, entry.Root, *entry.Chord)
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+// UnmarshalText Reads a dictionary written by MarshalText.
+func (d *PatternDictionary) UnmarshalText(text []byte) error {
+	scanner := bufio.NewScanner(strings.NewReader(string(text)))
+	if !scanner.Scan() {
+		return errors.New("empty pattern dictionary")
+	}
+	var min, max HalfSteps
+	if _, err := fmt.Sscanf(scanner.Text(), "range %d %d", &min, &max); err != nil {
+		return fmt.Errorf("invalid pattern dictionary range %q", scanner.Text())
+	}
+	dict, err := newPatternDictionary(min, max)
+	if err != nil {
+		return err
+	}
+	for n := 2; scanner.Scan(); n++ {
+		if scanner.Text() == "" {
+			continue
+		}
+		fields := strings.SplitN(scanner.Text(), "\t", 4)
+		var entry patternDictionaryEntryJSON
+		if err := entry.Pattern.UnmarshalText([]byte(fields[0])); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+		switch {
+		case len(fields) == 4 && fields[1] == "chord":
+			if entry.Root, err = strconv.Atoi(fields[2]); err != nil {
+				return fmt.Errorf("line %d: invalid root %q", n, fields[2])
+			}
+			entry.Chord = &fields[3]
+		case len(fields) == 2:
+			entry.Name = &fields[1]
+		default:
+			return fmt.Errorf("line %d: invalid entry %q", n, scanner.Text())
+		}
+		if err := dict.add(entry); err != nil {
+			return fmt.Errorf("line %d: %v", n, err)
+		}
+	}
+	*d = *dict
+	return nil
+}
+
+// MarshalJSON Writes the dictionary as a JSON object with the range of its steps and its entries.
+func (d PatternDictionary) MarshalJSON() ([]byte, error) {
+	if d.searchTree == nil {
+		return nil, errors.New("pattern dictionary has no range")
+	}
+	entries, err := d.entries()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(patternDictionaryJSON{d.searchTree.min, d.searchTree.max, entries})
+}
+
+// UnmarshalJSON Reads a dictionary from a JSON object written by MarshalJSON.
+func (d *PatternDictionary) UnmarshalJSON(data []byte) error {
+	var v patternDictionaryJSON
+	if err := json.Unmarshal(data, &v); err != nil {
+		return err
+	}
+	dict, err := newPatternDictionary(v.Min, v.Max)
+	if err != nil {
+		return err
+	}
+	for _, entry := range v.Entries {
+		if err := dict.add(entry); err != nil {
+			return err
+		}
+	}
+	*d = *dict
+	return nil
+}
+
+// marshalTextJSON Writes the value's text as a JSON string.
+func marshalTextJSON(v encoding.TextMarshaler) ([]byte, error) {
+	text, err := v.MarshalText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(string(text))
+}
+
+// unmarshalTextJSON Reads a JSON string and passes its text to the given function.
+func unmarshalTextJSON(data []byte, unmarshalText func([]byte) error) error {
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return err
+	}
+	return unmarshalText([]byte(text))
+}
@@ -0,0 +1,282 @@
+package tonacity
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestPitch_MarshalText(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	tests := []struct {
+		text  string
+		pitch Pitch
+		want  string
+	}{
+		{"A4", *A4(), "A4"},
+		{"C#4", *pf.GetPitch(C().Sharp(), 4), "C#4"},
+		{"Db4", *pf.GetPitch(C().Sharp(), 4), "C#4"},
+		{"B♭3", *pf.GetPitch(B().Flat(), 3), "A#3"},
+		{"Cb4", *pf.GetPitch(B(), 3), "B3"},
+		{"C-1", *pf.GetPitch(C(), -1), "C-1"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var p Pitch
+			if err := p.UnmarshalText([]byte(tt.text)); err != nil {
+				t.Fatal(err)
+			}
+			if p != tt.pitch {
+				t.Errorf("UnmarshalText() = %v, want %v", p.value, tt.pitch.value)
+			}
+			if got := p.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	for _, text := range []string{"", "H4", "A", "A10", "A#x"} {
+		var p Pitch
+		if err := p.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) should fail", text)
+		}
+	}
+}
+
+func TestPitchClass_MarshalText(t *testing.T) {
+	var pc PitchClass
+	if err := pc.UnmarshalText([]byte("Eb")); err != nil || pc != *E().Flat() {
+		t.Errorf("UnmarshalText(Eb) = %v, %v, want D#", pc, err)
+	}
+	if got := pc.String(); got != "D#" {
+		t.Errorf("String() = %v, want D#", got)
+	}
+	if err := pc.UnmarshalText([]byte("E4")); err == nil {
+		t.Error("UnmarshalText(E4) should fail")
+	}
+}
+
+func TestPattern_MarshalText(t *testing.T) {
+	tests := []struct {
+		pattern *Pattern
+		want    string
+	}{
+		{CreateMajorScale(), "W-W-H-W-W-W-H"},
+		{MakePattern(2, 1, 2, 2, 1, 3, 1), "W-H-W-W-H-3-H"},
+		{MakePattern(-1, -2, -2), "-H--W--W"},
+		{MakePattern(4, -3, 12), "4--3-12"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.want, func(t *testing.T) {
+			text, err := tt.pattern.MarshalText()
+			if err != nil || string(text) != tt.want {
+				t.Fatalf("MarshalText() = %s, %v, want %v", text, err, tt.want)
+			}
+			var p Pattern
+			if err := p.UnmarshalText(text); err != nil || !reflect.DeepEqual(p.intervals, tt.pattern.intervals) {
+				t.Errorf("UnmarshalText() = %v, %v, want %v", p.intervals, err, tt.pattern.intervals)
+			}
+		})
+	}
+	for _, text := range []string{"", "W-", "W--", "W-X", "-"} {
+		var p Pattern
+		if err := p.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) should fail", text)
+		}
+	}
+}
+
+func TestChord_MarshalText(t *testing.T) {
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"C4 E4 G4", "C4 E4 G4"},
+		{"Cmaj7", "C4 E4 G4 B4"},
+		{"Am/E", "E4 A4 C5"},
+		{"G7", "G4 B4 D5 F5"},
+		{"C6", "C4 E4 G4 A4"},
+		{"A9", "A4 C#5 E5 G5 B5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var c Chord
+			if err := c.UnmarshalText([]byte(tt.text)); err != nil {
+				t.Fatal(err)
+			}
+			if got := c.String(); got != tt.want {
+				t.Errorf("String() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	for _, text := range []string{"C4 X4", "Cwhatever", "", " ", "C4"} {
+		var c Chord
+		if err := c.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) should fail", text)
+		}
+	}
+}
+
+func TestMarshalJSON(t *testing.T) {
+	type exercise struct {
+		Start   Pitch         `json:"start"`
+		Tonic   PitchClass    `json:"tonic"`
+		Scale   Pattern       `json:"scale"`
+		Chord   Chord         `json:"chord"`
+		Melody  RootedPattern `json:"melody"`
+		Pointer *Pitch        `json:"pointer"`
+	}
+	pf := &PitchFactory{*MiddleC()}
+	in := exercise{
+		Start:   *pf.GetPitch(F().Sharp(), 3),
+		Tonic:   *B().Flat(),
+		Scale:   *CreateDorianMode(),
+		Chord:   *MakeChord(*pf.GetPitch(C(), 4), *pf.GetPitch(E(), 4)),
+		Melody:  RootedPattern{*MakePattern(2, 2, 1), *MiddleC()},
+		Pointer: A4(),
+	}
+	data, err := json.Marshal(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := `{"start":"F#3","tonic":"A#","scale":"W-H-W-W-W-H-W","chord":"C4 E4","melody":{"root":"C4","pattern":"W-W-H"},"pointer":"A4"}`
+	if string(data) != want {
+		t.Errorf("json.Marshal() = %s, want %s", data, want)
+	}
+	var out exercise
+	if err := json.Unmarshal(data, &out); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(in, out) {
+		t.Errorf("json.Unmarshal() = %+v, want %+v", out, in)
+	}
+	if err := json.Unmarshal([]byte(`{"start":"X9"}`), &out); err == nil {
+		t.Error("json.Unmarshal() of an invalid pitch should fail")
+	}
+}
+
+func TestPatternDictionary_Marshal(t *testing.T) {
+	tests := []struct {
+		name string
+		dict *PatternDictionary
+	}{
+		{"modes", BuildModeDictionary()},
+		{"chords", CreateChordDictionary()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			text, err := tt.dict.MarshalText()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromText PatternDictionary
+			if err := fromText.UnmarshalText(text); err != nil {
+				t.Fatal(err)
+			}
+			data, err := json.Marshal(tt.dict)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fromJSON PatternDictionary
+			if err := json.Unmarshal(data, &fromJSON); err != nil {
+				t.Fatal(err)
+			}
+			for _, got := range []*PatternDictionary{&fromText, &fromJSON} {
+				if !reflect.DeepEqual(got, tt.dict) {
+					t.Errorf("unmarshalled dictionary differs from the original")
+				}
+			}
+		})
+	}
+	if name, ok := BuildModeDictionary().GetName(CreateLydianMode()); !ok || name != "Lydian" {
+		t.Fatalf("GetName() = %v, %v", name, ok)
+	}
+	var dict PatternDictionary
+	for _, text := range []string{"", "range 2 1\n", "range 1 2\nW-3\tToo big\n", "range 1 2\nW\n"} {
+		if err := dict.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) should fail", text)
+		}
+	}
+}
+
+func TestSpelledPitch_MarshalText(t *testing.T) {
+	for _, text := range []string{"Cb4", "F##3", "Eb5", "B#3"} {
+		var sp SpelledPitch
+		if err := sp.UnmarshalText([]byte(text)); err != nil {
+			t.Fatal(err)
+		}
+		if got, _ := sp.MarshalText(); string(got) != text {
+			t.Errorf("MarshalText() = %s, want %s", got, text)
+		}
+	}
+	var sp SpelledPitch
+	if err := sp.UnmarshalText([]byte("C♭4")); err != nil || sp.Letter() != LetterC || sp.Alter() != -1 || sp.Octave() != 4 {
+		t.Errorf("UnmarshalText(C♭4) = %v, %v", sp.String(), err)
+	}
+	if err := sp.UnmarshalText([]byte("Cb")); err == nil {
+		t.Error("UnmarshalText(Cb) should fail")
+	}
+}
+
+func TestKeySignature_MarshalText(t *testing.T) {
+	tests := []struct {
+		text   string
+		fifths int
+		want   string
+	}{
+		{"Eb", -3, "Eb"},
+		{"C#m", 4, "C#m"},
+		{"A minor", 0, "Am"},
+		{"Bb major", -2, "Bb"},
+		{"D dorian", 0, "D Dorian"},
+		{"F# Lydian", 7, "F# Lydian"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			var key KeySignature
+			if err := key.UnmarshalText([]byte(tt.text)); err != nil {
+				t.Fatal(err)
+			}
+			if key.Fifths() != tt.fifths {
+				t.Errorf("UnmarshalText() has %d fifths, want %d", key.Fifths(), tt.fifths)
+			}
+			if got, _ := key.MarshalText(); string(got) != tt.want {
+				t.Errorf("MarshalText() = %s, want %s", got, tt.want)
+			}
+		})
+	}
+	for _, text := range []string{"", "H", "Ddorian", "C blues", "Fb"} {
+		var key KeySignature
+		if err := key.UnmarshalText([]byte(text)); err == nil {
+			t.Errorf("UnmarshalText(%q) should fail", text)
+		}
+	}
+}
+
+func TestParseNoteName(t *testing.T) {
+	tests := []struct {
+		text   string
+		want   string
+		octave bool
+	}{
+		{"Eb5", "E♭5", true},
+		{"F♯", "F♯4", false},
+		{"C", "C4", false},
+		{"Cb-1", "C♭-1", true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.text, func(t *testing.T) {
+			sp, octave, err := ParseNoteName(tt.text)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if sp.String() != tt.want || octave != tt.octave {
+				t.Errorf("ParseNoteName() = %v, %v, want %v, %v", sp.String(), octave, tt.want, tt.octave)
+			}
+		})
+	}
+	for _, text := range []string{"", "H", "C#x", "c4"} {
+		if _, _, err := ParseNoteName(text); err == nil {
+			t.Errorf("ParseNoteName(%q) should fail", text)
+		}
+	}
+}
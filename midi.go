@@ -0,0 +1,444 @@
+package tonacity
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"math/bits"
+	"sort"
+)
+
+// Standard MIDI Files (SMF) are made of chunks: a header chunk ("MThd") followed by one track chunk ("MTrk") per track. A track is a
+// sequence of events, each preceded by the number of ticks since the previous event. Only files whose time division is in ticks per
+// quarter note are supported; SMPTE time code divisions are rejected.
+
+// MidiNoteNumberOfA4 The MIDI note number of A4, from which all other note numbers are counted.
+const MidiNoteNumberOfA4 = 69
+
+// MidiNoteNumber Returns the MIDI note number of this pitch, e.g. 60 for middle C. Pitches outside the range of MIDI (0 to 127) are
+// returned as they are, so should be checked by the caller.
+func (p *Pitch) MidiNoteNumber() int {
+	return int(A4().GetDistanceTo(p)) + MidiNoteNumberOfA4
+}
+
+// PitchFromMidiNoteNumber Returns the pitch with the given MIDI note number, which must be between 0 and 127.
+func PitchFromMidiNoteNumber(noteNumber int) *Pitch {
+	return A4().GetTransposedCopy(HalfSteps(noteNumber - MidiNoteNumberOfA4))
+}
+
+const (
+	midiNoteOff         = 0x80
+	midiNoteOn          = 0x90
+	midiProgramChange   = 0xC0
+	midiChannelPressure = 0xD0
+	midiSysEx           = 0xF0
+	midiSysExEscape     = 0xF7
+	midiMeta            = 0xFF
+
+	midiMetaTrackName     = 0x03
+	midiMetaEndOfTrack    = 0x2F
+	midiMetaTempo         = 0x51
+	midiMetaTimeSignature = 0x58
+	midiMetaKeySignature  = 0x59
+)
+
+// MidiNote A note in a MIDI track: a pitch played on a channel (0 to 15) with a velocity (1 to 127), starting at a tick and held for
+// a number of ticks.
+type MidiNote struct {
+	Pitch    Pitch
+	Start    uint32
+	Duration uint32
+	Channel  uint8
+	Velocity uint8
+}
+
+// MidiTempo A change of tempo at a tick, stored as MIDI does, in microseconds per quarter note.
+type MidiTempo struct {
+	Tick                   uint32
+	MicrosecondsPerQuarter uint32
+}
+
+// BeatsPerMinute Returns the tempo in quarter note beats per minute.
+func (t *MidiTempo) BeatsPerMinute() float64 {
+	return 60000000 / float64(t.MicrosecondsPerQuarter)
+}
+
+// MidiTimeSignature A change of time signature at a tick.
+type MidiTimeSignature struct {
+	Tick          uint32
+	TimeSignature TimeSignature
+}
+
+// MidiKeySignature A change of key signature at a tick.
+type MidiKeySignature struct {
+	Tick         uint32
+	KeySignature KeySignature
+}
+
+// MidiEvent Any other event, kept as it was read so it can be written back: a channel message (e.g. program change) including its
+// status byte, a system exclusive message, or a meta event other than those with their own types. For system exclusive messages
+// Data holds everything after the length; for meta events Data begins with the meta type.
+type MidiEvent struct {
+	Tick   uint32
+	Status byte
+	Data   []byte
+}
+
+// MidiTrack The contents of one track chunk, with notes paired up from their note on and note off events.
+type MidiTrack struct {
+	Name           string
+	Notes          []MidiNote
+	Tempos         []MidiTempo
+	TimeSignatures []MidiTimeSignature
+	KeySignatures  []MidiKeySignature
+	Events         []MidiEvent
+}
+
+// MidiFile A Standard MIDI File of format 0 (a single track) or 1 (several tracks played together).
+type MidiFile struct {
+	Format          int
+	TicksPerQuarter int
+	Tracks          []*MidiTrack
+}
+
+// ReadMidi Reads a Standard MIDI File of format 0 or 1.
+func ReadMidi(r io.Reader) (*MidiFile, error) {
+	br := bufio.NewReader(r)
+	id, header, err := readMidiChunk(br)
+	if err != nil {
+		return nil, err
+	}
+	if id != "MThd" || len(header) < 6 {
+		return nil, errors.New("midi: missing header chunk")
+	}
+	format := binary.BigEndian.Uint16(header[0:2])
+	trackCount := int(binary.BigEndian.Uint16(header[2:4]))
+	division := binary.BigEndian.Uint16(header[4:6])
+	if format > 1 {
+		return nil, fmt.Errorf("midi: unsupported format %d", format)
+	}
+	if division&0x8000 != 0 {
+		return nil, errors.New("midi: SMPTE time division is not supported")
+	}
+	file := &MidiFile{int(format), int(division), make([]*MidiTrack, 0, trackCount)}
+	for len(file.Tracks) < trackCount {
+		id, data, err := readMidiChunk(br)
+		if err != nil {
+			return nil, err
+		}
+		// Unknown chunks must be skipped
+		if id != "MTrk" {
+			continue
+		}
+		track, err := parseMidiTrack(data)
+		if err != nil {
+			return nil, fmt.Errorf("midi: track %d: %v", len(file.Tracks), err)
+		}
+		file.Tracks = append(file.Tracks, track)
+	}
+	return file, nil
+}
+
+// readMidiChunk Reads a chunk's type and data. The length in the chunk's header isn't trusted: the data is read as it arrives, so a
+// corrupt file that claims a huge chunk fails when it runs out rather than first asking for all that memory.
+func readMidiChunk(r io.Reader) (id string, data []byte, err error) {
+	var header [8]byte
+	if _, err = io.ReadFull(r, header[:]); err != nil {
+		return
+	}
+	length := int64(binary.BigEndian.Uint32(header[4:]))
+	if data, err = io.ReadAll(io.LimitReader(r, length)); err != nil {
+		return
+	}
+	if int64(len(data)) < length {
+		return "", nil, io.ErrUnexpectedEOF
+	}
+	return string(header[:4]), data, nil
+}
+
+// readVariableLengthQuantity Reads a number stored in seven bit groups, most significant first, where every byte but the last has its
+// top bit set.
+func readVariableLengthQuantity(r *bytes.Reader) (uint32, error) {
+	var v uint32
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, err
+		}
+		v = v<<7 | uint32(b&0x7f)
+		if b&0x80 == 0 {
+			return v, nil
+		}
+	}
+	return 0, errors.New("variable length quantity is longer than four bytes")
+}
+
+// appendVariableLengthQuantity Appends the given number, which must fit in 28 bits, as a variable length quantity.
+func appendVariableLengthQuantity(b []byte, v uint32) []byte {
+	groups := (bits.Len32(v) + 6) / 7
+	if groups == 0 {
+		groups = 1
+	}
+	for i := groups - 1; i >= 0; i-- {
+		group := byte(v>>(7*uint(i))) & 0x7f
+		if i > 0 {
+			group |= 0x80
+		}
+		b = append(b, group)
+	}
+	return b
+}
+
+func parseMidiTrack(data []byte) (*MidiTrack, error) {
+	track := &MidiTrack{}
+	r := bytes.NewReader(data)
+	var tick uint32
+	var status byte
+	// Notes that have started but not yet stopped, by channel and note number, oldest first
+	sounding := make(map[[2]byte][]int)
+	for r.Len() > 0 {
+		delta, err := readVariableLengthQuantity(r)
+		if err != nil {
+			return nil, err
+		}
+		tick += delta
+		b, err := r.ReadByte()
+		if err != nil {
+			return nil, err
+		}
+		if b >= 0x80 {
+			status = b
+		} else if status == 0 {
+			return nil, errors.New("running status without a previous status")
+		} else {
+			// Running status: this is the first data byte of a message with the same status as the last
+			r.UnreadByte()
+		}
+
+		switch {
+		case status == midiMeta:
+			kind, err := r.ReadByte()
+			if err != nil {
+				return nil, err
+			}
+			body, err := readMidiBytes(r)
+			if err != nil {
+				return nil, err
+			}
+			if kind == midiMetaEndOfTrack {
+				closeSoundingNotes(track, sounding, tick)
+				return track, nil
+			}
+			if err = track.addMeta(tick, kind, body); err != nil {
+				return nil, err
+			}
+			status = 0
+		case status == midiSysEx || status == midiSysExEscape:
+			body, err := readMidiBytes(r)
+			if err != nil {
+				return nil, err
+			}
+			track.Events = append(track.Events, MidiEvent{tick, status, body})
+			status = 0
+		default:
+			size := 2
+			if status&0xF0 == midiProgramChange || status&0xF0 == midiChannelPressure {
+				size = 1
+			}
+			message := make([]byte, size)
+			if _, err := io.ReadFull(r, message); err != nil {
+				return nil, err
+			}
+			command, channel := status&0xF0, status&0x0F
+			key := [2]byte{channel, message[0]}
+			if command == midiNoteOn && message[1] > 0 {
+				sounding[key] = append(sounding[key], len(track.Notes))
+				track.Notes = append(track.Notes, MidiNote{*PitchFromMidiNoteNumber(int(message[0])), tick, 0, channel, message[1]})
+			} else if command == midiNoteOn || command == midiNoteOff {
+				if started := sounding[key]; len(started) > 0 {
+					note := &track.Notes[started[0]]
+					note.Duration = tick - note.Start
+					sounding[key] = started[1:]
+				}
+			} else {
+				track.Events = append(track.Events, MidiEvent{tick, status, message})
+			}
+		}
+	}
+	// Tolerate a missing end of track event
+	closeSoundingNotes(track, sounding, tick)
+	return track, nil
+}
+
+// closeSoundingNotes Ends any notes that never received a note off at the given tick.
+func closeSoundingNotes(track *MidiTrack, sounding map[[2]byte][]int, tick uint32) {
+	for _, started := range sounding {
+		for _, i := range started {
+			track.Notes[i].Duration = tick - track.Notes[i].Start
+		}
+	}
+}
+
+func readMidiBytes(r *bytes.Reader) ([]byte, error) {
+	length, err := readVariableLengthQuantity(r)
+	if err != nil {
+		return nil, err
+	}
+	if int(length) > r.Len() {
+		return nil, io.ErrUnexpectedEOF
+	}
+	body := make([]byte, length)
+	_, err = io.ReadFull(r, body)
+	return body, err
+}
+
+func (t *MidiTrack) addMeta(tick uint32, kind byte, body []byte) error {
+	switch kind {
+	case midiMetaTrackName:
+		t.Name = string(body)
+	case midiMetaTempo:
+		if len(body) != 3 {
+			return errors.New("tempo event is not three bytes long")
+		}
+		t.Tempos = append(t.Tempos, MidiTempo{tick, uint32(body[0])<<16 | uint32(body[1])<<8 | uint32(body[2])})
+	case midiMetaTimeSignature:
+		if len(body) < 2 {
+			return errors.New("time signature event is too short")
+		}
+		ts := MakeTimeSignature(int(body[0]), 1<<body[1])
+		if ts == nil {
+			return fmt.Errorf("invalid time signature %d/2^%d", body[0], body[1])
+		}
+		t.TimeSignatures = append(t.TimeSignatures, MidiTimeSignature{tick, *ts})
+	case midiMetaKeySignature:
+		if len(body) != 2 {
+			return errors.New("key signature event is not two bytes long")
+		}
+		ks := MakeKeySignature(int(int8(body[0])), body[1] == 1)
+		if ks == nil {
+			return fmt.Errorf("invalid key signature with %d sharps", int8(body[0]))
+		}
+		t.KeySignatures = append(t.KeySignatures, MidiKeySignature{tick, *ks})
+	default:
+		t.Events = append(t.Events, MidiEvent{tick, midiMeta, append([]byte{kind}, body...)})
+	}
+	return nil
+}
+
+// midiOutputEvent An event ready to be written, with its position in the order events at the same tick are written in.
+type midiOutputEvent struct {
+	tick     uint32
+	priority int // Meta events, then note offs, then everything else, so notes never overlap themselves
+	data     []byte
+}
+
+// Write Writes this file as a Standard MIDI File. Note offs are written as note ons with zero velocity, and running status is used
+// wherever possible, which is what most sequencers do to keep files small.
+func (f *MidiFile) Write(w io.Writer) error {
+	if f.Format < 0 || f.Format > 1 {
+		return fmt.Errorf("midi: unsupported format %d", f.Format)
+	}
+	if f.Format == 0 && len(f.Tracks) != 1 {
+		return fmt.Errorf("midi: format 0 files have exactly one track, not %d", len(f.Tracks))
+	}
+	if f.TicksPerQuarter < 1 || f.TicksPerQuarter > 0x7FFF {
+		return fmt.Errorf("midi: invalid ticks per quarter note %d", f.TicksPerQuarter)
+	}
+	bw := bufio.NewWriter(w)
+	header := []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6}
+	header = binary.BigEndian.AppendUint16(header, uint16(f.Format))
+	header = binary.BigEndian.AppendUint16(header, uint16(len(f.Tracks)))
+	header = binary.BigEndian.AppendUint16(header, uint16(f.TicksPerQuarter))
+	if _, err := bw.Write(header); err != nil {
+		return err
+	}
+	for i, track := range f.Tracks {
+		data, err := track.encode()
+		if err != nil {
+			return fmt.Errorf("midi: track %d: %v", i, err)
+		}
+		chunk := binary.BigEndian.AppendUint32([]byte{'M', 'T', 'r', 'k'}, uint32(len(data)))
+		if _, err := bw.Write(append(chunk, data...)); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+func (t *MidiTrack) encode() ([]byte, error) {
+	events := make([]midiOutputEvent, 0)
+	meta := func(tick uint32, kind byte, body []byte) {
+		data := appendVariableLengthQuantity([]byte{midiMeta, kind}, uint32(len(body)))
+		events = append(events, midiOutputEvent{tick, 0, append(data, body...)})
+	}
+	if t.Name != "" {
+		meta(0, midiMetaTrackName, []byte(t.Name))
+	}
+	for _, tempo := range t.Tempos {
+		mpq := tempo.MicrosecondsPerQuarter
+		meta(tempo.Tick, midiMetaTempo, []byte{byte(mpq >> 16), byte(mpq >> 8), byte(mpq)})
+	}
+	for _, ts := range t.TimeSignatures {
+		// 24 MIDI clocks per metronome click and eight 32nd notes per quarter note are what every file uses in practice
+		meta(ts.Tick, midiMetaTimeSignature, []byte{byte(ts.TimeSignature.noteCount), byte(bits.TrailingZeros(uint(ts.TimeSignature.noteValue))), 24, 8})
+	}
+	for _, ks := range t.KeySignatures {
+		var minor byte
+		if ks.KeySignature.IsMinor() {
+			minor = 1
+		}
+		meta(ks.Tick, midiMetaKeySignature, []byte{byte(ks.KeySignature.fifths), minor})
+	}
+	for _, e := range t.Events {
+		switch e.Status {
+		case midiMeta:
+			if len(e.Data) < 1 {
+				return nil, errors.New("meta event has no type")
+			}
+			meta(e.Tick, e.Data[0], e.Data[1:])
+		case midiSysEx, midiSysExEscape:
+			data := appendVariableLengthQuantity([]byte{e.Status}, uint32(len(e.Data)))
+			events = append(events, midiOutputEvent{e.Tick, 2, append(data, e.Data...)})
+		default:
+			events = append(events, midiOutputEvent{e.Tick, 2, append([]byte{e.Status}, e.Data...)})
+		}
+	}
+	for _, n := range t.Notes {
+		number := n.Pitch.MidiNoteNumber()
+		if number < 0 || number > 127 || n.Channel > 15 || n.Velocity < 1 || n.Velocity > 127 {
+			return nil, fmt.Errorf("note %v on channel %d with velocity %d cannot be written", n.Pitch.MidiNoteNumber(), n.Channel, n.Velocity)
+		}
+		// A note of no length is let go of straight after it starts, rather than before, which would leave it sounding
+		off := 1
+		if n.Duration == 0 {
+			off = 3
+		}
+		events = append(events,
+			midiOutputEvent{n.Start, 2, []byte{midiNoteOn | n.Channel, byte(number), n.Velocity}},
+			midiOutputEvent{n.Start + n.Duration, off, []byte{midiNoteOn | n.Channel, byte(number), 0}})
+	}
+	sort.SliceStable(events, func(i, j int) bool {
+		if events[i].tick != events[j].tick {
+			return events[i].tick < events[j].tick
+		}
+		return events[i].priority < events[j].priority
+	})
+
+	data := make([]byte, 0)
+	var tick uint32
+	var status byte
+	for _, e := range events {
+		data = appendVariableLengthQuantity(data, e.tick-tick)
+		tick = e.tick
+		if e.data[0] == status && status < midiSysEx {
+			data = append(data, e.data[1:]...)
+			continue
+		}
+		data = append(data, e.data...)
+		status = e.data[0]
+	}
+	return append(appendVariableLengthQuantity(data, 0), midiMeta, midiMetaEndOfTrack, 0), nil
+}
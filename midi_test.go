@@ -0,0 +1,202 @@
+package tonacity
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+)
+
+// midiFormat0Fixture A format 0 file written exactly as MidiFile.Write writes it, using running status for note ons and offs.
+var midiFormat0Fixture = []byte{
+	'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0x01, 0xE0,
+	'M', 'T', 'r', 'k', 0, 0, 0, 57,
+	0x00, 0xFF, 0x03, 0x04, 'T', 'e', 's', 't', // Track name
+	0x00, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // 500000 microseconds per quarter
+	0x00, 0xFF, 0x58, 0x04, 0x03, 0x02, 0x18, 0x08, // 3/4
+	0x00, 0xFF, 0x59, 0x02, 0xFF, 0x01, // One flat, minor
+	0x00, 0xC0, 0x05, // Program change
+	0x00, 0x90, 0x3C, 0x64, // C4 on
+	0x00, 0x45, 0x50, // A4 on, running status
+	0x83, 0x60, 0x3C, 0x00, // C4 off after 480 ticks
+	0x00, 0x45, 0x00, // A4 off
+	0x00, 0x40, 0x64, // E4 on
+	0x8F, 0x00, 0x40, 0x00, // E4 off after 1920 ticks
+	0x00, 0xFF, 0x2F, 0x00, // End of track
+}
+
+func midiFormat0Expected() *MidiFile {
+	return &MidiFile{0, 480, []*MidiTrack{{
+		Name: "Test",
+		Notes: []MidiNote{
+			{*PitchFromMidiNoteNumber(60), 0, 480, 0, 100},
+			{*A4(), 0, 480, 0, 80},
+			{*PitchFromMidiNoteNumber(64), 480, 1920, 0, 100},
+		},
+		Tempos:         []MidiTempo{{0, 500000}},
+		TimeSignatures: []MidiTimeSignature{{0, *MakeTimeSignature(3, 4)}},
+		KeySignatures:  []MidiKeySignature{{0, *MakeKeySignature(-1, true)}},
+		Events:         []MidiEvent{{0, 0xC0, []byte{0x05}}},
+	}}}
+}
+
+func TestReadMidi_Format0(t *testing.T) {
+	got, err := ReadMidi(bytes.NewReader(midiFormat0Fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := midiFormat0Expected(); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadMidi() = %+v, want %+v", got.Tracks[0], want.Tracks[0])
+	}
+	ks := got.Tracks[0].KeySignatures[0].KeySignature
+	if tonic := ks.Tonic(); !tonic.HasSamePitchAs(D()) || !ks.IsMinor() {
+		t.Errorf("ReadMidi() key signature = %v, want D Minor", ks)
+	}
+	if bpm := got.Tracks[0].Tempos[0].BeatsPerMinute(); bpm != 120 {
+		t.Errorf("ReadMidi() tempo = %v BPM, want 120", bpm)
+	}
+}
+
+func TestMidiFile_Write(t *testing.T) {
+	var buf bytes.Buffer
+	if err := midiFormat0Expected().Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(buf.Bytes(), midiFormat0Fixture) {
+		t.Errorf("MidiFile.Write() = % x\nwant % x", buf.Bytes(), midiFormat0Fixture)
+	}
+}
+
+func TestMidiFile_WriteZeroLengthNote(t *testing.T) {
+	a4, c5 := *PitchFromMidiNoteNumber(69), *PitchFromMidiNoteNumber(72)
+	file := &MidiFile{0, 480, []*MidiTrack{{Notes: []MidiNote{{a4, 0, 0, 0, 64}, {c5, 0, 960, 0, 64}}}}}
+	var buf bytes.Buffer
+	if err := file.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ReadMidi(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := again.Tracks[0].Notes; !reflect.DeepEqual(got, file.Tracks[0].Notes) {
+		t.Errorf("ReadMidi() = %v, want %v", got, file.Tracks[0].Notes)
+	}
+}
+
+func TestReadMidi_Format1(t *testing.T) {
+	fixture := []byte{
+		'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 1, 0, 2, 0x00, 0x60,
+		'X', 'y', 'z', 'w', 0, 0, 0, 2, 0xAB, 0xCD, // Unknown chunk, which must be skipped
+		'M', 'T', 'r', 'k', 0, 0, 0, 19,
+		0x00, 0xFF, 0x51, 0x03, 0x0F, 0x42, 0x40, // 60 BPM
+		0x81, 0x40, 0xFF, 0x51, 0x03, 0x07, 0xA1, 0x20, // 120 BPM after 192 ticks
+		0x00, 0xFF, 0x2F, 0x00,
+		'M', 'T', 'r', 'k', 0, 0, 0, 28,
+		0x00, 0xF0, 0x03, 0x7E, 0x09, 0xF7, // System exclusive
+		0x00, 0x91, 0x30, 0x40, // C3 on, channel 1
+		0x00, 0x32, 0x41, // D3 on, running status
+		0x60, 0x81, 0x30, 0x00, // C3 off after 96 ticks, with a note off
+		0x00, 0x32, 0x00, // D3 off, running status
+		0x00, 0x91, 0x30, 0x40, // C3 on again, never turned off
+		0x60, 0xFF, 0x2F, 0x00,
+	}
+	got, err := ReadMidi(bytes.NewReader(fixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Format != 1 || got.TicksPerQuarter != 96 || len(got.Tracks) != 2 {
+		t.Fatalf("ReadMidi() = format %d, %d ticks, %d tracks, want format 1, 96 ticks, 2 tracks", got.Format, got.TicksPerQuarter, len(got.Tracks))
+	}
+	if want := []MidiTempo{{0, 1000000}, {192, 500000}}; !reflect.DeepEqual(got.Tracks[0].Tempos, want) {
+		t.Errorf("ReadMidi() tempos = %v, want %v", got.Tracks[0].Tempos, want)
+	}
+	c3, d3 := PitchFromMidiNoteNumber(48), PitchFromMidiNoteNumber(50)
+	wantNotes := []MidiNote{{*c3, 0, 96, 1, 64}, {*d3, 0, 96, 1, 65}, {*c3, 96, 96, 1, 64}}
+	if !reflect.DeepEqual(got.Tracks[1].Notes, wantNotes) {
+		t.Errorf("ReadMidi() notes = %v, want %v", got.Tracks[1].Notes, wantNotes)
+	}
+	if want := []MidiEvent{{0, 0xF0, []byte{0x7E, 0x09, 0xF7}}}; !reflect.DeepEqual(got.Tracks[1].Events, want) {
+		t.Errorf("ReadMidi() events = %v, want %v", got.Tracks[1].Events, want)
+	}
+
+	// Writing and reading again should give the same file, even though the bytes differ
+	var buf bytes.Buffer
+	if err := got.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	again, err := ReadMidi(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again, got) {
+		t.Errorf("ReadMidi(Write()) = %+v, want %+v", again, got)
+	}
+}
+
+func TestReadMidi_Invalid(t *testing.T) {
+	tests := []struct {
+		name    string
+		fixture []byte
+	}{
+		{"Not MIDI", []byte("RIFF....WAVE")},
+		{"SMPTE division", []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0xE7, 0x28}},
+		{"Format 2", []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 2, 0, 1, 0x00, 0x60}},
+		{"Running status without status", []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0x00, 0x60, 'M', 'T', 'r', 'k', 0, 0, 0, 3, 0x00, 0x3C, 0x40}},
+		{"Truncated", []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0x00, 0x60, 'M', 'T', 'r', 'k', 0, 0, 0, 9, 0x00}},
+		{"Huge chunk", []byte{'M', 'T', 'h', 'd', 0, 0, 0, 6, 0, 0, 0, 1, 0x00, 0x60, 'M', 'T', 'r', 'k', 0xFF, 0xFF, 0xFF, 0xFF, 0x00}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := ReadMidi(bytes.NewReader(tt.fixture)); err == nil {
+				t.Error("ReadMidi() should have failed")
+			}
+		})
+	}
+}
+
+func TestVariableLengthQuantity(t *testing.T) {
+	tests := []struct {
+		value   uint32
+		encoded []byte
+	}{
+		{0x00, []byte{0x00}},
+		{0x40, []byte{0x40}},
+		{0x7F, []byte{0x7F}},
+		{0x80, []byte{0x81, 0x00}},
+		{0x2000, []byte{0xC0, 0x00}},
+		{0x3FFF, []byte{0xFF, 0x7F}},
+		{0x4000, []byte{0x81, 0x80, 0x00}},
+		{0x200000, []byte{0x81, 0x80, 0x80, 0x00}},
+		{0x0FFFFFFF, []byte{0xFF, 0xFF, 0xFF, 0x7F}},
+	}
+	for _, tt := range tests {
+		if got := appendVariableLengthQuantity(nil, tt.value); !bytes.Equal(got, tt.encoded) {
+			t.Errorf("appendVariableLengthQuantity(%#x) = % x, want % x", tt.value, got, tt.encoded)
+		}
+		if got, err := readVariableLengthQuantity(bytes.NewReader(tt.encoded)); err != nil || got != tt.value {
+			t.Errorf("readVariableLengthQuantity(% x) = %#x, %v, want %#x", tt.encoded, got, err, tt.value)
+		}
+	}
+}
+
+func TestPitch_MidiNoteNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		p    *Pitch
+		want int
+	}{
+		{"A4", A4(), 69},
+		{"Middle C", MiddleC(), 60},
+		{"A0", A4().GetTransposedCopy(-48), 21},
+		{"C8", MiddleC().GetTransposedCopy(48), 108},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.p.MidiNoteNumber(); got != tt.want {
+				t.Errorf("Pitch.MidiNoteNumber() = %v, want %v", got, tt.want)
+			}
+			if got := PitchFromMidiNoteNumber(tt.want); !reflect.DeepEqual(got, tt.p) {
+				t.Errorf("PitchFromMidiNoteNumber(%d) = %v, want %v", tt.want, got, tt.p)
+			}
+		})
+	}
+}
@@ -0,0 +1,958 @@
+package tonacity
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// MusicXML is the format notation software uses to exchange scores. Only partwise documents (parts containing measures, rather than
+// measures containing parts) are supported, both as plain XML and compressed (.mxl). Each part is read as one Stave per staff. Each
+// staff holds a single voice; anything the model can't hold is reported in the score's Unsupported list instead of being dropped
+// without a word.
+
+// MusicXMLPart A part of a score, played by one instrument, which may be written on several staves (e.g. a piano's two hands).
+type MusicXMLPart struct {
+	ID     string
+	Name   string
+	Staves []*Stave
+}
+
+// MusicXMLIssue An element that couldn't be represented, and so was left out when a document was read. Part and Measure locate the
+// first occurrence, and Count is the number of times it occurred.
+type MusicXMLIssue struct {
+	Element string
+	Part    string
+	Measure string
+	Count   int
+}
+
+func (i MusicXMLIssue) String() string {
+	location := "the score"
+	if i.Part != "" {
+		location = fmt.Sprintf("part %s, measure %s", i.Part, i.Measure)
+	}
+	if i.Count > 1 {
+		return fmt.Sprintf("<%s> in %s (and %d more)", i.Element, location, i.Count-1)
+	}
+	return fmt.Sprintf("<%s> in %s", i.Element, location)
+}
+
+// MusicXMLScore The parts of a MusicXML score. Unsupported lists what was left out when the score was read.
+type MusicXMLScore struct {
+	Title       string
+	Parts       []*MusicXMLPart
+	Unsupported []MusicXMLIssue
+}
+
+// The elements of a MusicXML document that are understood. Anything else ends up in an Other field, to be reported.
+
+type mxlElement struct {
+	XMLName xml.Name
+}
+
+type mxlScorePartwise struct {
+	XMLName       xml.Name     `xml:"score-partwise"`
+	Version       string       `xml:"version,attr,omitempty"`
+	Work          *mxlWork     `xml:"work"`
+	MovementTitle string       `xml:"movement-title,omitempty"`
+	PartList      mxlPartList  `xml:"part-list"`
+	Parts         []mxlPart    `xml:"part"`
+	Other         []mxlElement `xml:",any"`
+}
+
+type mxlWork struct {
+	Title string       `xml:"work-title,omitempty"`
+	Other []mxlElement `xml:",any"`
+}
+
+type mxlPartList struct {
+	ScoreParts []mxlScorePart `xml:"score-part"`
+	Other      []mxlElement   `xml:",any"`
+}
+
+type mxlScorePart struct {
+	ID    string       `xml:"id,attr"`
+	Name  string       `xml:"part-name"`
+	Other []mxlElement `xml:",any"`
+}
+
+type mxlPart struct {
+	ID       string       `xml:"id,attr"`
+	Measures []mxlMeasure `xml:"measure"`
+}
+
+// mxlMeasure A measure, whose contents are kept in document order because the order of notes, attributes and backups matters.
+type mxlMeasure struct {
+	Number string
+	Items  []interface{}
+}
+
+type mxlAttributes struct {
+	XMLName   xml.Name     `xml:"attributes"`
+	Divisions int64        `xml:"divisions,omitempty"`
+	Key       []mxlKey     `xml:"key"`
+	Time      []mxlTime    `xml:"time"`
+	Staves    int          `xml:"staves,omitempty"`
+	Clef      []mxlClef    `xml:"clef"`
+	Other     []mxlElement `xml:",any"`
+}
+
+type mxlKey struct {
+	Fifths int          `xml:"fifths"`
+	Mode   string       `xml:"mode,omitempty"`
+	Other  []mxlElement `xml:",any"`
+}
+
+type mxlTime struct {
+	Beats    string       `xml:"beats"`
+	BeatType string       `xml:"beat-type"`
+	Other    []mxlElement `xml:",any"`
+}
+
+type mxlClef struct {
+	Number       int          `xml:"number,attr,omitempty"`
+	Sign         string       `xml:"sign"`
+	Line         int          `xml:"line,omitempty"`
+	OctaveChange int          `xml:"clef-octave-change,omitempty"`
+	Other        []mxlElement `xml:",any"`
+}
+
+type mxlNote struct {
+	XMLName          xml.Name             `xml:"note"`
+	Grace            *struct{}            `xml:"grace"`
+	Chord            *struct{}            `xml:"chord"`
+	Pitch            *mxlPitch            `xml:"pitch"`
+	Rest             *mxlRest             `xml:"rest"`
+	Duration         int64                `xml:"duration"`
+	Ties             []mxlTie             `xml:"tie"`
+	Voice            string               `xml:"voice,omitempty"`
+	Type             string               `xml:"type,omitempty"`
+	Dots             []struct{}           `xml:"dot"`
+	Accidental       string               `xml:"accidental,omitempty"`
+	TimeModification *mxlTimeModification `xml:"time-modification"`
+	Stem             string               `xml:"stem,omitempty"`
+	Staff            int                  `xml:"staff,omitempty"`
+	Beams            []mxlBeam            `xml:"beam"`
+	Other            []mxlElement         `xml:",any"`
+}
+
+type mxlTie struct {
+	Type string `xml:"type,attr"`
+}
+
+// mxlBeam Where a note is in a beam: "begin", "continue" or "end". Stems and beams are worked out again when writing, so are ignored when
+// reading.
+type mxlBeam struct {
+	Number int    `xml:"number,attr"`
+	Value  string `xml:",chardata"`
+}
+
+type mxlPitch struct {
+	Step   string `xml:"step"`
+	Alter  string `xml:"alter,omitempty"`
+	Octave int    `xml:"octave"`
+}
+
+type mxlRest struct {
+	Measure string       `xml:"measure,attr,omitempty"`
+	Other   []mxlElement `xml:",any"`
+}
+
+type mxlTimeModification struct {
+	ActualNotes int64 `xml:"actual-notes"`
+	NormalNotes int64 `xml:"normal-notes"`
+}
+
+// mxlBackup Moves the position in the measure back (for <backup>) or forward (for <forward>).
+type mxlBackup struct {
+	XMLName  xml.Name
+	Duration int64  `xml:"duration"`
+	Voice    string `xml:"voice,omitempty"`
+	Staff    int    `xml:"staff,omitempty"`
+}
+
+// UnmarshalXML Reads the measure's contents in order, keeping anything that isn't understood as an mxlElement.
+func (m *mxlMeasure) UnmarshalXML(d *xml.Decoder, start xml.StartElement) error {
+	for _, a := range start.Attr {
+		if a.Name.Local == "number" {
+			m.Number = a.Value
+		}
+	}
+	for {
+		token, err := d.Token()
+		if err != nil {
+			return err
+		}
+		switch t := token.(type) {
+		case xml.StartElement:
+			var item interface{}
+			switch t.Name.Local {
+			case "attributes":
+				item = &mxlAttributes{}
+			case "note":
+				item = &mxlNote{}
+			case "backup", "forward":
+				item = &mxlBackup{}
+			default:
+				m.Items = append(m.Items, &mxlElement{t.Name})
+				if err := d.Skip(); err != nil {
+					return err
+				}
+				continue
+			}
+			if err := d.DecodeElement(item, &t); err != nil {
+				return err
+			}
+			m.Items = append(m.Items, item)
+		case xml.EndElement:
+			return nil
+		}
+	}
+}
+
+// MarshalXML Writes the measure's contents in order.
+func (m mxlMeasure) MarshalXML(e *xml.Encoder, start xml.StartElement) error {
+	start.Attr = []xml.Attr{{Name: xml.Name{Local: "number"}, Value: m.Number}}
+	if err := e.EncodeToken(start); err != nil {
+		return err
+	}
+	for _, item := range m.Items {
+		if err := e.Encode(item); err != nil {
+			return err
+		}
+	}
+	return e.EncodeToken(start.End())
+}
+
+// musicXMLTypes The names MusicXML gives note values.
+var musicXMLTypes = map[int]string{
+	1:   "whole",
+	2:   "half",
+	4:   "quarter",
+	8:   "eighth",
+	16:  "16th",
+	32:  "32nd",
+	64:  "64th",
+	128: "128th",
+}
+
+var musicXMLStems = map[StemDirection]string{StemUp: "up", StemDown: "down"}
+
+// musicXMLBeams Returns the beams of each note in the bar. The first beam joins every note of a beam group, and each further beam, e.g.
+// the second for sixteenths, joins the neighbouring notes short enough to have it, or is a hook on a note that has no such neighbour.
+func musicXMLBeams(bar *Bar) [][]mxlBeam {
+	beams := make([][]mxlBeam, len(bar.notes))
+	for _, group := range bar.BeamGroups() {
+		for number, value := 1, 8; value <= 128; number, value = number+1, value*2 {
+			has := func(j int) bool {
+				return j >= 0 && j < len(group.Notes) && bar.notes[group.Notes[j]].value >= value
+			}
+			for j, i := range group.Notes {
+				var beam string
+				switch {
+				case !has(j):
+					continue
+				case has(j-1) && has(j+1):
+					beam = "continue"
+				case has(j + 1):
+					beam = "begin"
+				case has(j - 1):
+					beam = "end"
+				case j == 0:
+					beam = "forward hook"
+				default:
+					beam = "backward hook"
+				}
+				beams[i] = append(beams[i], mxlBeam{number, beam})
+			}
+		}
+	}
+	return beams
+}
+
+var musicXMLAccidentals = map[int]string{-2: "flat-flat", -1: "flat", 0: "natural", 1: "sharp", 2: "double-sharp"}
+
+var musicXMLClefSigns = map[string]ClefSign{"G": GClef, "F": FClef, "C": CClef, "percussion": PercussionClef}
+
+// ReadMusicXML Reads an uncompressed partwise MusicXML document.
+func ReadMusicXML(r io.Reader) (*MusicXMLScore, error) {
+	var doc mxlScorePartwise
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		var unexpected xml.UnmarshalError
+		if errors.As(err, &unexpected) {
+			return nil, fmt.Errorf("musicxml: only partwise scores are supported: %v", err)
+		}
+		return nil, fmt.Errorf("musicxml: %v", err)
+	}
+	reader := &musicXMLReader{issues: make(map[string]*MusicXMLIssue)}
+	score := &MusicXMLScore{Title: doc.MovementTitle}
+	if doc.Work != nil {
+		if doc.Work.Title != "" {
+			score.Title = doc.Work.Title
+		}
+		reader.reportAll(doc.Work.Other, "", "")
+	}
+	reader.reportAll(doc.Other, "", "")
+	reader.reportAll(doc.PartList.Other, "", "")
+
+	names := make(map[string]string)
+	for _, sp := range doc.PartList.ScoreParts {
+		names[sp.ID] = sp.Name
+		reader.reportAll(sp.Other, "", "")
+	}
+	for i := range doc.Parts {
+		part, err := reader.readPart(&doc.Parts[i])
+		if err != nil {
+			return nil, err
+		}
+		part.Name = names[part.ID]
+		score.Parts = append(score.Parts, part)
+	}
+	score.Unsupported = reader.report()
+	return score, nil
+}
+
+// ReadCompressedMusicXML Reads a compressed MusicXML (.mxl) archive, finding the score through its container file.
+func ReadCompressedMusicXML(r io.ReaderAt, size int64) (*MusicXMLScore, error) {
+	archive, err := zip.NewReader(r, size)
+	if err != nil {
+		return nil, fmt.Errorf("musicxml: %v", err)
+	}
+	files := make(map[string]*zip.File)
+	for _, f := range archive.File {
+		files[f.Name] = f
+	}
+	rootPath := ""
+	if container, ok := files["META-INF/container.xml"]; ok {
+		var c mxlContainer
+		if err := decodeZipXML(container, &c); err != nil {
+			return nil, fmt.Errorf("musicxml: container: %v", err)
+		}
+		if len(c.RootFiles) > 0 {
+			rootPath = c.RootFiles[0].FullPath
+		}
+	}
+	if rootPath == "" {
+		// No container, so fall back on the first score in the archive
+		for _, f := range archive.File {
+			if !strings.HasPrefix(f.Name, "META-INF/") && path.Ext(f.Name) == ".xml" {
+				rootPath = f.Name
+				break
+			}
+		}
+	}
+	root, ok := files[rootPath]
+	if !ok {
+		return nil, errors.New("musicxml: archive does not contain a score")
+	}
+	rc, err := root.Open()
+	if err != nil {
+		return nil, fmt.Errorf("musicxml: %v", err)
+	}
+	defer rc.Close()
+	return ReadMusicXML(rc)
+}
+
+// ReadMusicXMLFile Reads a MusicXML file, compressed or not.
+func ReadMusicXMLFile(filename string) (*MusicXMLScore, error) {
+	data, err := os.ReadFile(filename)
+	if err != nil {
+		return nil, err
+	}
+	// Compressed files are zip archives, which always start with "PK"
+	if bytes.HasPrefix(data, []byte("PK")) {
+		return ReadCompressedMusicXML(bytes.NewReader(data), int64(len(data)))
+	}
+	return ReadMusicXML(bytes.NewReader(data))
+}
+
+type mxlContainer struct {
+	XMLName   xml.Name `xml:"container"`
+	RootFiles []struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr,omitempty"`
+	} `xml:"rootfiles>rootfile"`
+}
+
+func decodeZipXML(f *zip.File, v interface{}) error {
+	rc, err := f.Open()
+	if err != nil {
+		return err
+	}
+	defer rc.Close()
+	return xml.NewDecoder(rc).Decode(v)
+}
+
+// musicXMLReader The state carried from measure to measure while a part is read.
+type musicXMLReader struct {
+	issues map[string]*MusicXMLIssue
+	order  []string
+
+	part      string
+	measure   string
+	divisions int64
+	staves    []*Stave
+	key       *KeySignature
+	time      *TimeSignature
+	clefs     map[int]*Clef
+}
+
+func (r *musicXMLReader) unsupported(element string) {
+	r.reportAt(element, r.part, r.measure)
+}
+
+func (r *musicXMLReader) reportAt(element string, part string, measure string) {
+	if issue, ok := r.issues[element]; ok {
+		issue.Count++
+		return
+	}
+	r.issues[element] = &MusicXMLIssue{element, part, measure, 1}
+	r.order = append(r.order, element)
+}
+
+func (r *musicXMLReader) reportAll(elements []mxlElement, part string, measure string) {
+	for _, e := range elements {
+		r.reportAt(e.XMLName.Local, part, measure)
+	}
+}
+
+func (r *musicXMLReader) report() []MusicXMLIssue {
+	issues := make([]MusicXMLIssue, len(r.order))
+	for i, element := range r.order {
+		issues[i] = *r.issues[element]
+	}
+	return issues
+}
+
+// staff Returns the stave for the given staff number, counting from 1, creating any that don't exist yet.
+func (r *musicXMLReader) staff(number int) *Stave {
+	if number < 1 {
+		number = 1
+	}
+	for len(r.staves) < number {
+		r.staves = append(r.staves, MakeStave())
+	}
+	return r.staves[number-1]
+}
+
+func (r *musicXMLReader) readPart(p *mxlPart) (*MusicXMLPart, error) {
+	r.part, r.divisions, r.staves, r.key, r.time, r.clefs = p.ID, 1, nil, nil, nil, make(map[int]*Clef)
+	r.staff(1)
+	for i := range p.Measures {
+		if err := r.readMeasure(&p.Measures[i]); err != nil {
+			return nil, fmt.Errorf("musicxml: part %s, measure %s: %v", r.part, r.measure, err)
+		}
+	}
+	return &MusicXMLPart{ID: p.ID, Staves: r.staves}, nil
+}
+
+func (r *musicXMLReader) readMeasure(m *mxlMeasure) error {
+	r.measure = m.Number
+	bars := make(map[int]*Bar)
+	voices := make(map[int]string)
+	bar := func(staff int) *Bar {
+		if staff < 1 {
+			staff = 1
+		}
+		if b, ok := bars[staff]; ok {
+			return b
+		}
+		b := MakeBar(r.time, r.key, r.clefs[staff])
+		bars[staff] = b
+		return b
+	}
+	// The bar and index of the last note read, which any following chord notes are added to
+	var lastBar *Bar
+	lastIndex := -1
+	for _, item := range m.Items {
+		switch item := item.(type) {
+		case *mxlAttributes:
+			if err := r.readAttributes(item); err != nil {
+				return err
+			}
+			for staff, b := range bars {
+				if len(b.notes) > 0 {
+					r.unsupported("attributes")
+					continue
+				}
+				*b = *MakeBar(r.time, r.key, r.clefs[staff])
+			}
+		case *mxlNote:
+			r.reportAll(item.Other, r.part, r.measure)
+			if item.Grace != nil {
+				r.unsupported("grace")
+				continue
+			}
+			// Only the first voice on each staff can be kept, as a stave holds a single line of notes
+			staff := item.Staff
+			if staff < 1 {
+				staff = 1
+			}
+			if voice, ok := voices[staff]; !ok {
+				voices[staff] = item.Voice
+			} else if voice != item.Voice {
+				r.unsupported("voice")
+				continue
+			}
+			note, err := r.readNote(item)
+			if err != nil {
+				return err
+			}
+			if item.Chord != nil && lastBar != nil {
+				last := &lastBar.notes[lastIndex]
+				last.pitches = append(last.pitches, note.pitches...)
+				last.tied = last.tied || note.tied
+				continue
+			}
+			lastBar = bar(staff)
+			lastBar.notes = append(lastBar.notes, *note)
+			lastIndex = len(lastBar.notes) - 1
+		case *mxlBackup:
+			// Moving backwards only matters for voices that aren't kept; moving forwards within a voice leaves a gap, which is a rest
+			if item.XMLName.Local == "forward" {
+				staff := item.Staff
+				if staff < 1 {
+					staff = 1
+				}
+				if voice, ok := voices[staff]; !ok || voice == item.Voice {
+					rest := makeNoteOfDuration(MakeDuration(item.Duration, r.divisions*4))
+					b := bar(staff)
+					b.notes = append(b.notes, *rest)
+				}
+			}
+		case *mxlElement:
+			r.unsupported(item.XMLName.Local)
+		}
+	}
+	for i := range r.staves {
+		r.staves[i].AddBars(*bar(i + 1))
+	}
+	return nil
+}
+
+// musicXMLKey Returns the key with the given number of fifths in the given MusicXML mode, e.g. "dorian", or nil if the mode isn't one
+// of the seven modes. No mode is taken to be major.
+func musicXMLKey(fifths int, mode string) *KeySignature {
+	major := MakeKeySignature(fifths, false)
+	switch mode {
+	case "", "major":
+		return major
+	case "minor":
+		mode = "Aeolian"
+	}
+	// The tonic is whichever note of the major scale gives the mode the same key signature
+	for _, tonic := range major.Scale() {
+		if key := MakeModalKeySignature(tonic.letter, tonic.Alter(), mode); key != nil && key.fifths == major.fifths {
+			return key
+		}
+	}
+	return nil
+}
+
+// musicXMLModes The MusicXML names of the modes, by their names in BuildModeDictionary.
+var musicXMLModes = map[string]string{
+	"Ionian":     "major",
+	"Dorian":     "dorian",
+	"Phrygian":   "phrygian",
+	"Lydian":     "lydian",
+	"Mixolydian": "mixolydian",
+	"Aeolian":    "minor",
+	"Locrian":    "locrian",
+}
+
+func (r *musicXMLReader) readAttributes(a *mxlAttributes) error {
+	r.reportAll(a.Other, r.part, r.measure)
+	if a.Divisions > 0 {
+		r.divisions = a.Divisions
+	}
+	if a.Staves > 0 {
+		r.staff(a.Staves)
+	}
+	if len(a.Key) > 0 {
+		k := a.Key[0]
+		r.reportAll(k.Other, r.part, r.measure)
+		if MakeKeySignature(k.Fifths, false) == nil {
+			return fmt.Errorf("invalid key with %d fifths", k.Fifths)
+		}
+		r.key = musicXMLKey(k.Fifths, k.Mode)
+		if r.key == nil {
+			r.unsupported("mode")
+			r.key = MakeKeySignature(k.Fifths, false)
+		}
+	}
+	if len(a.Time) > 0 {
+		t := a.Time[0]
+		r.reportAll(t.Other, r.part, r.measure)
+		if ts := readMusicXMLTime(t.Beats, t.BeatType); ts != nil {
+			r.time = ts
+		} else {
+			r.unsupported("time")
+		}
+	}
+	for _, c := range a.Clef {
+		r.reportAll(c.Other, r.part, r.measure)
+		sign, ok := musicXMLClefSigns[c.Sign]
+		if !ok {
+			r.unsupported("clef")
+			continue
+		}
+		number := c.Number
+		if number < 1 {
+			number = 1
+		}
+		r.clefs[number] = MakeClef(sign, c.Line, c.OctaveChange)
+	}
+	return nil
+}
+
+func (r *musicXMLReader) readNote(n *mxlNote) (*Note, error) {
+	var note *Note
+	if n.Rest != nil && n.Rest.Measure == "yes" && n.Type == "" {
+		// Whole bar rests are written the same whatever the length of the bar
+		note = MakeRest(0, 0)
+		note.SetDuration(MakeDuration(n.Duration, r.divisions*4))
+	} else if n.Type != "" {
+		value := 0
+		for v, name := range musicXMLTypes {
+			if name == n.Type {
+				value = v
+			}
+		}
+		if value == 0 {
+			r.unsupported("type")
+		}
+		note = MakeNote(value, len(n.Dots))
+		note.SetDuration(MakeDuration(n.Duration, r.divisions*4))
+	} else {
+		note = makeNoteOfDuration(MakeDuration(n.Duration, r.divisions*4))
+	}
+	if n.Rest != nil {
+		r.reportAll(n.Rest.Other, r.part, r.measure)
+		return note, nil
+	}
+	if n.Pitch == nil {
+		// Unpitched percussion notes, which would otherwise be read as rests
+		r.unsupported("unpitched")
+		return note, nil
+	}
+	if len(n.Pitch.Step) != 1 {
+		return nil, fmt.Errorf("invalid step %q", n.Pitch.Step)
+	}
+	letter, ok := ParseLetter(n.Pitch.Step[0])
+	if !ok {
+		return nil, fmt.Errorf("invalid step %q", n.Pitch.Step)
+	}
+	alter := 0
+	if n.Pitch.Alter != "" {
+		a, err := strconv.ParseFloat(n.Pitch.Alter, 64)
+		if err != nil {
+			return nil, fmt.Errorf("invalid alter %q", n.Pitch.Alter)
+		}
+		if a != float64(int(a)) {
+			// Quarter tones can't be represented in half steps
+			r.unsupported("alter")
+		}
+		alter = int(a)
+	}
+	note.pitches = []SpelledPitch{*MakeSpelledPitch(letter, alter, n.Pitch.Octave)}
+	for _, tie := range n.Ties {
+		note.tied = note.tied || tie.Type == "start"
+	}
+	return note, nil
+}
+
+// Write Writes the score as an uncompressed MusicXML 4.0 partwise document.
+func (s *MusicXMLScore) Write(w io.Writer) error {
+	doc := mxlScorePartwise{Version: "4.0"}
+	if s.Title != "" {
+		doc.Work = &mxlWork{Title: s.Title}
+	}
+	for i, part := range s.Parts {
+		id := part.ID
+		if id == "" {
+			id = fmt.Sprintf("P%d", i+1)
+		}
+		doc.PartList.ScoreParts = append(doc.PartList.ScoreParts, mxlScorePart{ID: id, Name: part.Name})
+		p, err := writeMusicXMLPart(part)
+		if err != nil {
+			return fmt.Errorf("musicxml: part %s: %v", id, err)
+		}
+		p.ID = id
+		doc.Parts = append(doc.Parts, *p)
+	}
+	header := xml.Header + `<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">` + "\n"
+	if _, err := io.WriteString(w, header); err != nil {
+		return err
+	}
+	e := xml.NewEncoder(w)
+	e.Indent("", "  ")
+	if err := e.Encode(doc); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// WriteCompressed Writes the score as a compressed MusicXML (.mxl) archive.
+func (s *MusicXMLScore) WriteCompressed(w io.Writer) error {
+	archive := zip.NewWriter(w)
+	// The media type must come first, and uncompressed, so the format can be recognised from the first few bytes of the file
+	mimetype, err := archive.CreateHeader(&zip.FileHeader{Name: "mimetype", Method: zip.Store})
+	if err != nil {
+		return err
+	}
+	if _, err = io.WriteString(mimetype, "application/vnd.recordare.musicxml"); err != nil {
+		return err
+	}
+	container, err := archive.Create("META-INF/container.xml")
+	if err != nil {
+		return err
+	}
+	c := mxlContainer{}
+	c.RootFiles = append(c.RootFiles, struct {
+		FullPath  string `xml:"full-path,attr"`
+		MediaType string `xml:"media-type,attr,omitempty"`
+	}{"score.musicxml", "application/vnd.recordare.musicxml+xml"})
+	if _, err = io.WriteString(container, xml.Header); err != nil {
+		return err
+	}
+	if err = xml.NewEncoder(container).Encode(c); err != nil {
+		return err
+	}
+	score, err := archive.Create("score.musicxml")
+	if err != nil {
+		return err
+	}
+	if err = s.Write(score); err != nil {
+		return err
+	}
+	return archive.Close()
+}
+
+// WriteFile Writes the score to the given file, compressed if its name ends in ".mxl".
+func (s *MusicXMLScore) WriteFile(filename string) error {
+	f, err := os.Create(filename)
+	if err != nil {
+		return err
+	}
+	if strings.EqualFold(path.Ext(filename), ".mxl") {
+		err = s.WriteCompressed(f)
+	} else {
+		err = s.Write(f)
+	}
+	if err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// musicXMLDivisions Returns the smallest number of divisions of a quarter note in which every note in the part is a whole number.
+func musicXMLDivisions(part *MusicXMLPart) int64 {
+	divisions := int64(1)
+	for _, stave := range part.Staves {
+		for i := range stave.bars {
+			for j := range stave.bars[i].notes {
+				d := stave.bars[i].notes[j].duration
+				divisions = lcm(divisions, MakeDuration(d.numerator*4, d.Denominator()).Denominator())
+			}
+		}
+	}
+	return divisions
+}
+
+func writeMusicXMLPart(part *MusicXMLPart) (*mxlPart, error) {
+	p := &mxlPart{}
+	divisions := musicXMLDivisions(part)
+	measures := 0
+	for _, stave := range part.Staves {
+		if len(stave.bars) > measures {
+			measures = len(stave.bars)
+		}
+	}
+	trackers := make([]*accidentalTracker, len(part.Staves))
+	tied := make([]bool, len(part.Staves)) // Whether the last note written on each staff is tied to the next
+	for m := 0; m < measures; m++ {
+		measure := mxlMeasure{Number: strconv.Itoa(m + 1)}
+		if attributes := musicXMLAttributesAt(part, m, divisions); attributes != nil {
+			measure.Items = append(measure.Items, attributes)
+		}
+		for s, stave := range part.Staves {
+			if m >= len(stave.bars) {
+				continue
+			}
+			bar := &stave.bars[m]
+			if trackers[s] == nil || trackers[s].key != bar.key {
+				trackers[s] = newAccidentalTracker(bar.key)
+			}
+			trackers[s].barline()
+			if s > 0 && m < len(part.Staves[s-1].bars) {
+				// Go back to the start of the measure to write the next staff
+				var previous Duration
+				for i := range part.Staves[s-1].bars[m].notes {
+					previous = previous.Add(part.Staves[s-1].bars[m].notes[i].duration)
+				}
+				if !previous.IsZero() {
+					measure.Items = append(measure.Items, &mxlBackup{XMLName: xml.Name{Local: "backup"}, Duration: musicXMLDuration(previous, divisions)})
+				}
+			}
+			stems, beams := bar.Stems(), musicXMLBeams(bar)
+			for i := range bar.notes {
+				items, err := writeMusicXMLNote(&bar.notes[i], bar, s, len(part.Staves), divisions, trackers[s], tied[s])
+				if err != nil {
+					return nil, err
+				}
+				for j, item := range items {
+					n := item.(*mxlNote)
+					n.Stem = musicXMLStems[stems[i]]
+					if j == 0 {
+						n.Beams = beams[i]
+					}
+				}
+				measure.Items = append(measure.Items, items...)
+				tied[s] = bar.notes[i].tied && !bar.notes[i].IsRest()
+			}
+		}
+		p.Measures = append(p.Measures, measure)
+	}
+	return p, nil
+}
+
+// readMusicXMLTime Returns the time signature with the given beats, which may be grouped, e.g. "2+2+3", and beat type, or nil if it isn't valid.
+func readMusicXMLTime(beats string, beatType string) *TimeSignature {
+	value, err := strconv.Atoi(beatType)
+	if err != nil {
+		return nil
+	}
+	parts := strings.Split(beats, "+")
+	groups := make([]int, len(parts))
+	for i, part := range parts {
+		if groups[i], err = strconv.Atoi(strings.TrimSpace(part)); err != nil {
+			return nil
+		}
+	}
+	if len(groups) == 1 {
+		return MakeTimeSignature(groups[0], value)
+	}
+	return MakeGroupedTimeSignature(value, groups...)
+}
+
+func musicXMLDuration(d Duration, divisions int64) int64 {
+	return d.numerator * 4 * divisions / d.Denominator()
+}
+
+// musicXMLAttributesAt Returns the attributes that change at the given measure, or nil if nothing does.
+func musicXMLAttributesAt(part *MusicXMLPart, m int, divisions int64) *mxlAttributes {
+	a := &mxlAttributes{}
+	changed := false
+	if m == 0 {
+		a.Divisions = divisions
+		if len(part.Staves) > 1 {
+			a.Staves = len(part.Staves)
+		}
+		changed = true
+	}
+	if len(part.Staves) == 0 || m >= len(part.Staves[0].bars) {
+		return nil
+	}
+	bar := &part.Staves[0].bars[m]
+	var previous *Bar
+	if m > 0 {
+		previous = &part.Staves[0].bars[m-1]
+	}
+	if bar.key != nil && (previous == nil || previous.key == nil || *previous.key != *bar.key) {
+		a.Key = []mxlKey{{Fifths: bar.key.Fifths(), Mode: musicXMLModes[bar.key.Mode()]}}
+		changed = true
+	}
+	if bar.time.noteCount > 0 && (previous == nil || previous.time != bar.time) {
+		beats := bar.time.writtenGroups("+")
+		if beats == "" {
+			beats = strconv.Itoa(bar.time.noteCount)
+		}
+		a.Time = []mxlTime{{Beats: beats, BeatType: strconv.Itoa(bar.time.noteValue)}}
+		changed = true
+	}
+	for s, stave := range part.Staves {
+		if m >= len(stave.bars) || stave.bars[m].clef == nil {
+			continue
+		}
+		clef := stave.bars[m].clef
+		if m > 0 && m-1 < len(stave.bars) && stave.bars[m-1].clef != nil && *stave.bars[m-1].clef == *clef {
+			continue
+		}
+		sign := ""
+		for name, v := range musicXMLClefSigns {
+			if v == clef.sign {
+				sign = name
+			}
+		}
+		c := mxlClef{Sign: sign, Line: clef.line, OctaveChange: clef.octaveChange}
+		if len(part.Staves) > 1 {
+			c.Number = s + 1
+		}
+		a.Clef = append(a.Clef, c)
+		changed = true
+	}
+	if !changed {
+		return nil
+	}
+	return a
+}
+
+func writeMusicXMLNote(note *Note, bar *Bar, staff int, staves int, divisions int64, tracker *accidentalTracker, tiedFrom bool) ([]interface{}, error) {
+	base := mxlNote{
+		Duration: musicXMLDuration(note.duration, divisions),
+		Voice:    strconv.Itoa(staff + 1),
+		Type:     musicXMLTypes[note.value],
+		Dots:     make([]struct{}, note.dots),
+	}
+	if tiedFrom && !note.IsRest() {
+		base.Ties = append(base.Ties, mxlTie{"stop"})
+	}
+	if note.tied && !note.IsRest() {
+		base.Ties = append(base.Ties, mxlTie{"start"})
+	}
+	// Written is to actual as actual notes are to normal notes, e.g. three triplet eighths are written as 3/8 but last 2/8
+	if ratio := note.tupletRatio(); ratio.Cmp(MakeDuration(1, 1)) != 0 {
+		base.TimeModification = &mxlTimeModification{ratio.numerator, ratio.Denominator()}
+	}
+	if staves > 1 {
+		base.Staff = staff + 1
+	}
+	if note.IsRest() {
+		base.Rest = &mxlRest{}
+		if note.value == 0 && bar.time.noteCount > 0 && note.duration.Cmp(bar.time.Duration()) == 0 {
+			base.Rest.Measure = "yes"
+		}
+		return []interface{}{&base}, nil
+	}
+	items := make([]interface{}, 0, len(note.pitches))
+	for i := range note.pitches {
+		n := base
+		sp := &note.pitches[i]
+		n.Pitch = &mxlPitch{Step: sp.letter.String(), Octave: sp.Octave()}
+		if alter := sp.Alter(); alter != 0 {
+			n.Pitch.Alter = strconv.Itoa(alter)
+		}
+		if alter, needed := tracker.accidental(sp); needed {
+			name, ok := musicXMLAccidentals[alter]
+			if !ok {
+				return nil, fmt.Errorf("%v cannot be written with a single accidental", sp)
+			}
+			n.Accidental = name
+		}
+		if i > 0 {
+			n.Chord = &struct{}{}
+		}
+		items = append(items, &n)
+	}
+	return items, nil
+}
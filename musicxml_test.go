@@ -0,0 +1,220 @@
+package tonacity
+
+import (
+	"bytes"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const musicXMLFixture = `<?xml version="1.0" encoding="UTF-8" standalone="no"?>
+<!DOCTYPE score-partwise PUBLIC "-//Recordare//DTD MusicXML 4.0 Partwise//EN" "http://www.musicxml.org/dtds/partwise.dtd">
+<score-partwise version="4.0">
+  <work><work-title>Fixture</work-title></work>
+  <part-list>
+    <score-part id="P1"><part-name>Piano</part-name></score-part>
+  </part-list>
+  <part id="P1">
+    <measure number="1">
+      <attributes>
+        <divisions>6</divisions>
+        <key><fifths>2</fifths><mode>major</mode></key>
+        <time><beats>3</beats><beat-type>4</beat-type></time>
+        <staves>2</staves>
+        <clef number="1"><sign>G</sign><line>2</line></clef>
+        <clef number="2"><sign>F</sign><line>4</line></clef>
+      </attributes>
+      <direction placement="above"><direction-type><words>Gently</words></direction-type></direction>
+      <note><pitch><step>F</step><alter>1</alter><octave>4</octave></pitch><duration>6</duration><voice>1</voice><type>quarter</type><staff>1</staff></note>
+      <note><chord/><pitch><step>A</step><octave>4</octave></pitch><duration>6</duration><voice>1</voice><type>quarter</type><staff>1</staff></note>
+      <note><pitch><step>B</step><alter>-1</alter><octave>4</octave></pitch><duration>2</duration><voice>1</voice><type>eighth</type><accidental>flat</accidental><time-modification><actual-notes>3</actual-notes><normal-notes>2</normal-notes></time-modification><staff>1</staff></note>
+      <note><pitch><step>C</step><alter>1</alter><octave>5</octave></pitch><duration>2</duration><voice>1</voice><type>eighth</type><time-modification><actual-notes>3</actual-notes><normal-notes>2</normal-notes></time-modification><staff>1</staff></note>
+      <note><rest/><duration>2</duration><voice>1</voice><type>eighth</type><time-modification><actual-notes>3</actual-notes><normal-notes>2</normal-notes></time-modification><staff>1</staff></note>
+      <note><pitch><step>D</step><octave>5</octave></pitch><duration>6</duration><tie type="start"/><voice>1</voice><type>quarter</type><staff>1</staff></note>
+      <backup><duration>18</duration></backup>
+      <note><pitch><step>D</step><octave>3</octave></pitch><duration>9</duration><voice>5</voice><type>quarter</type><dot/><staff>2</staff></note>
+      <note><pitch><step>A</step><octave>2</octave></pitch><duration>9</duration><voice>5</voice><type>quarter</type><dot/><staff>2</staff></note>
+      <backup><duration>18</duration></backup>
+      <note><pitch><step>F</step><alter>1</alter><octave>3</octave></pitch><duration>18</duration><voice>6</voice><type>half</type><dot/><staff>2</staff></note>
+    </measure>
+    <measure number="2">
+      <attributes>
+        <key><fifths>-3</fifths><mode>minor</mode></key>
+        <clef number="2"><sign>G</sign><line>2</line><clef-octave-change>-1</clef-octave-change></clef>
+      </attributes>
+      <note><rest measure="yes"/><duration>18</duration><voice>1</voice><staff>1</staff></note>
+      <backup><duration>18</duration></backup>
+      <note><pitch><step>C</step><octave>3</octave></pitch><duration>18</duration><voice>5</voice><type>half</type><dot/><staff>2</staff></note>
+    </measure>
+  </part>
+</score-partwise>
+`
+
+func musicXMLFixtureStaves() []*Stave {
+	d, c, dMin := MakeKeySignature(2, false), MakeKeySignature(-3, true), MakeTimeSignature(3, 4)
+	treble, bass, treble8 := MakeClef(GClef, 2, 0), MakeClef(FClef, 4, 0), MakeClef(GClef, 2, -1)
+
+	triplet := func(n *Note) Note {
+		n.SetDuration(MakeDuration(1, 12))
+		return *n
+	}
+	tied := func(n *Note) Note {
+		n.SetTied(true)
+		return *n
+	}
+	bar1 := MakeBar(dMin, d, treble)
+	bar1.AddNotes(
+		*MakeNote(4, 0, *MakeSpelledPitch(LetterF, 1, 4), *MakeSpelledPitch(LetterA, 0, 4)),
+		triplet(MakeNote(8, 0, *MakeSpelledPitch(LetterB, -1, 4))),
+		triplet(MakeNote(8, 0, *MakeSpelledPitch(LetterC, 1, 5))),
+		triplet(MakeRest(8, 0)),
+		tied(MakeNote(4, 0, *MakeSpelledPitch(LetterD, 0, 5))))
+	bar2 := MakeBar(dMin, c, treble)
+	rest := MakeRest(0, 0)
+	rest.SetDuration(MakeDuration(3, 4))
+	bar2.AddNotes(*rest)
+
+	bass1 := MakeBar(dMin, d, bass)
+	bass1.AddNotes(*MakeNote(4, 1, *MakeSpelledPitch(LetterD, 0, 3)), *MakeNote(4, 1, *MakeSpelledPitch(LetterA, 0, 2)))
+	bass2 := MakeBar(dMin, c, treble8)
+	bass2.AddNotes(*MakeNote(2, 1, *MakeSpelledPitch(LetterC, 0, 3)))
+
+	return []*Stave{MakeStave(*bar1, *bar2), MakeStave(*bass1, *bass2)}
+}
+
+func TestReadMusicXML(t *testing.T) {
+	score, err := ReadMusicXML(strings.NewReader(musicXMLFixture))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if score.Title != "Fixture" || len(score.Parts) != 1 || score.Parts[0].ID != "P1" || score.Parts[0].Name != "Piano" {
+		t.Fatalf("ReadMusicXML() = %+v, want one part P1 named Piano in Fixture", score)
+	}
+	want := musicXMLFixtureStaves()
+	for i, stave := range score.Parts[0].Staves {
+		if !reflect.DeepEqual(stave, want[i]) {
+			t.Errorf("ReadMusicXML() staff %d = %+v, want %+v", i+1, stave, want[i])
+		}
+	}
+	wantIssues := []string{"<direction> in part P1, measure 1", "<voice> in part P1, measure 1"}
+	if len(score.Unsupported) != len(wantIssues) {
+		t.Fatalf("ReadMusicXML() unsupported = %v, want %v", score.Unsupported, wantIssues)
+	}
+	for i, issue := range score.Unsupported {
+		if issue.String() != wantIssues[i] {
+			t.Errorf("ReadMusicXML() unsupported[%d] = %v, want %v", i, issue, wantIssues[i])
+		}
+	}
+}
+
+func TestMusicXMLScore_Write(t *testing.T) {
+	score := &MusicXMLScore{Title: "Fixture", Parts: []*MusicXMLPart{{ID: "P1", Name: "Piano", Staves: musicXMLFixtureStaves()}}}
+	var buf bytes.Buffer
+	if err := score.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	written := buf.String()
+	for _, want := range []string{
+		"<divisions>6</divisions>",
+		"<accidental>flat</accidental>",
+		"<actual-notes>3</actual-notes>",
+		"<normal-notes>2</normal-notes>",
+		`<rest measure="yes"></rest>`,
+		"<backup>",
+		"<clef-octave-change>-1</clef-octave-change>",
+		`<tie type="start"></tie>`,
+	} {
+		if !strings.Contains(written, want) {
+			t.Errorf("MusicXMLScore.Write() does not contain %q:\n%s", want, written)
+		}
+	}
+	// F♯ and C♯ are in D Major, so they don't need accidentals
+	if strings.Count(written, "<accidental>") != 1 {
+		t.Errorf("MusicXMLScore.Write() wrote %d accidentals, want 1", strings.Count(written, "<accidental>"))
+	}
+
+	again, err := ReadMusicXML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(again.Unsupported) != 0 {
+		t.Errorf("ReadMusicXML(Write()) unsupported = %v, want none", again.Unsupported)
+	}
+	for i, stave := range again.Parts[0].Staves {
+		if !reflect.DeepEqual(stave, score.Parts[0].Staves[i]) {
+			t.Errorf("ReadMusicXML(Write()) staff %d = %+v, want %+v", i+1, stave, score.Parts[0].Staves[i])
+		}
+	}
+}
+
+func TestMusicXMLScore_WriteCompressed(t *testing.T) {
+	score := &MusicXMLScore{Title: "Fixture", Parts: []*MusicXMLPart{{ID: "P1", Name: "Piano", Staves: musicXMLFixtureStaves()}}}
+	var buf bytes.Buffer
+	if err := score.WriteCompressed(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Contains(buf.Bytes()[:80], []byte("mimetypeapplication/vnd.recordare.musicxml")) {
+		t.Error("MusicXMLScore.WriteCompressed() does not start with an uncompressed media type")
+	}
+	again, err := ReadCompressedMusicXML(bytes.NewReader(buf.Bytes()), int64(buf.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(again.Parts, score.Parts) || again.Title != score.Title {
+		t.Errorf("ReadCompressedMusicXML(WriteCompressed()) = %+v, want %+v", again, score)
+	}
+}
+
+func TestReadMusicXML_Timewise(t *testing.T) {
+	if _, err := ReadMusicXML(strings.NewReader(`<score-timewise version="4.0"></score-timewise>`)); err == nil {
+		t.Error("ReadMusicXML() of a timewise score should fail")
+	}
+}
+
+func TestMusicXML_GroupedTime(t *testing.T) {
+	bar := MakeBar(MakeGroupedTimeSignature(8, 2, 2, 3), nil, MakeClef(GClef, 2, 0))
+	bar.AddNotes(*MakeNote(4, 0, *MakeSpelledPitch(LetterC, 0, 4)), *MakeNote(4, 0, *MakeSpelledPitch(LetterD, 0, 4)), *MakeNote(4, 1, *MakeSpelledPitch(LetterE, 0, 4)))
+	score := &MusicXMLScore{Parts: []*MusicXMLPart{{ID: "P1", Staves: []*Stave{MakeStave(*bar)}}}}
+	var buf bytes.Buffer
+	if err := score.Write(&buf); err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(buf.String(), "<beats>2+2+3</beats>") {
+		t.Errorf("MusicXMLScore.Write() does not group the beats:\n%s", buf.String())
+	}
+	again, err := ReadMusicXML(&buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := again.Parts[0].Staves[0].Bars()[0].TimeSignature(); got == nil || !reflect.DeepEqual(got.Groups(), []int{2, 2, 3}) {
+		t.Errorf("ReadMusicXML() time signature = %v, want 7/8 grouped 2+2+3", got)
+	}
+}
+
+func TestMusicXML_ModalKeys(t *testing.T) {
+	for _, mode := range []string{"Dorian", "Phrygian", "Lydian", "Mixolydian", "Locrian"} {
+		t.Run(mode, func(t *testing.T) {
+			key := MakeModalKeySignature(LetterA, 0, mode)
+			bar := MakeBar(MakeTimeSignature(1, 4), key, MakeClef(GClef, 2, 0))
+			bar.AddNotes(*MakeNote(4, 0, *MakeSpelledPitch(LetterA, 0, 4)))
+			score := &MusicXMLScore{Parts: []*MusicXMLPart{{ID: "P1", Staves: []*Stave{MakeStave(*bar)}}}}
+			var buf bytes.Buffer
+			if err := score.Write(&buf); err != nil {
+				t.Fatal(err)
+			}
+			if want := "<mode>" + strings.ToLower(mode) + "</mode>"; !strings.Contains(buf.String(), want) {
+				t.Errorf("MusicXMLScore.Write() is missing %v:\n%s", want, buf.String())
+			}
+			again, err := ReadMusicXML(&buf)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(again.Unsupported) > 0 {
+				t.Errorf("ReadMusicXML() reported %v", again.Unsupported)
+			}
+			if got := again.Parts[0].Staves[0].Bars()[0].KeySignature(); got == nil || *got != *key {
+				t.Errorf("ReadMusicXML() key = %v, want A %v", got, mode)
+			}
+		})
+	}
+}
@@ -1,5 +1,7 @@
 package tonacity
 
+import "strings"
+
 const (
 	// NotesInMode The number of notes that are in a Mode
 	NotesInMode = 7
@@ -61,7 +63,7 @@ func (p *Pattern) Reverse() *Pattern {
 	l := len(p.intervals)
 	reverse := make([]HalfSteps, l, l)
 	for i := 0; i < l; i++ {
-		reverse[i] = -p.intervals[l-i]
+		reverse[i] = -p.intervals[l-1-i]
 	}
 	return &Pattern{reverse}
 }
@@ -112,13 +114,13 @@ func (p *Pattern) PatternRepeatsAtOctave() bool {
 }
 
 // CreateAscendingSinger Creates a singer that applies this pattern from the given pitch.
-func (p Pattern) CreateAscendingSinger(pitch Pitch) Singer {
-	return PatternRepeatingSinger{p, pitch, 0}
+func (p Pattern) CreateAscendingSinger(pitch Pitch) *PatternRepeatingSinger {
+	return newPatternRepeatingSinger(*p.Copy(), pitch)
 }
 
 // CreateDescendingSinger Creates a singer that applies this pattern in reverse from the given pitch.
-func (p Pattern) CreateDescendingSinger(pitch Pitch) Singer {
-	return PatternRepeatingSinger{*p.Reverse(), pitch, 0}
+func (p Pattern) CreateDescendingSinger(pitch Pitch) *PatternRepeatingSinger {
+	return newPatternRepeatingSinger(*p.Reverse(), pitch)
 }
 
 // ionianModePattern The pattern of the Ionian (I) Mode, repeated twice to allow slicing it to create on of the other modes.
@@ -202,7 +204,7 @@ var harmonicMinorScalePattern = MakePattern(
 
 // CreateHarmonicMinorScalePattern Get the pattern of the Harmonic Minor Scale.
 func CreateHarmonicMinorScalePattern() *Pattern {
-	return CreateMajorScale().Offset(9)
+	return harmonicMinorScalePattern.Copy()
 }
 
 var melodicMinorScalePattern = MakePattern(
@@ -341,6 +343,15 @@ func (d *PatternDictionary) AddPattern(pattern *Pattern, entry interface{}) {
 	d.searchTree.AddValue(pattern.Intervals(), entry)
 }
 
+// Walk Calls visit with every pattern in the dictionary and the entries stored against it.
+func (d *PatternDictionary) Walk(visit func(pattern *Pattern, entries []interface{})) {
+	d.searchTree.Walk(func(path []HalfSteps, values []interface{}) {
+		if len(path) > 0 {
+			visit(MakePattern(path...).Copy(), values)
+		}
+	})
+}
+
 // BuildModeDictionary Builds a dictionary containing the seven modes.
 func BuildModeDictionary() *PatternDictionary {
 	dict := &PatternDictionary{NewTrie(1, 2)}
@@ -366,12 +377,35 @@ func BuildScaleDictionary() *PatternDictionary {
 	return dict
 }
 
+// FindScale Looks up the pattern of a scale or mode by its name in BuildScaleDictionary or BuildModeDictionary, e.g. "harmonic minor" or
+// "Dorian". Case doesn't matter. The bool will be false if no scale has the name.
+func FindScale(name string) (pattern *Pattern, ok bool) {
+	for _, dict := range []*PatternDictionary{BuildScaleDictionary(), BuildModeDictionary()} {
+		dict.Walk(func(p *Pattern, entries []interface{}) {
+			for _, entry := range entries {
+				if s, isName := entry.(string); isName && pattern == nil && strings.EqualFold(s, strings.TrimSpace(name)) {
+					pattern = p
+				}
+			}
+		})
+		if pattern != nil {
+			return pattern, true
+		}
+	}
+	return nil, false
+}
+
 // RootedPattern A pattern that is rooted at a specific pitch.
 type RootedPattern struct {
 	pattern Pattern
 	root    Pitch
 }
 
+// MakeRootedPattern Creates a copy of the given pattern rooted at the given pitch, e.g. the major scale pattern rooted at D4 for D Major.
+func MakeRootedPattern(pattern *Pattern, root Pitch) *RootedPattern {
+	return &RootedPattern{*pattern.Copy(), root}
+}
+
 // Root Returns the root note of this pattern.
 func (rp *RootedPattern) Root() Pitch {
 	return rp.root
@@ -384,10 +418,10 @@ func (rp *RootedPattern) Transpose(halfSteps HalfSteps) {
 
 // CreateSinger Create and return a new singer that will sing this pattern, starting at its root.
 func (rp *RootedPattern) CreateSinger() *PatternRepeatingSinger {
-	return &PatternRepeatingSinger{*rp.pattern.Copy(), rp.root, 0}
+	return newPatternRepeatingSinger(*rp.pattern.Copy(), rp.root)
 }
 
 // CreateReverseSinger Create and return a new singer that will sing this pattern, starting at its root, and applying the pattern in reverse.
 func (rp *RootedPattern) CreateReverseSinger() *PatternRepeatingSinger {
-	return &PatternRepeatingSinger{*rp.pattern.Reverse(), rp.root, 0}
+	return newPatternRepeatingSinger(*rp.pattern.Reverse(), rp.root)
 }
@@ -1,6 +1,9 @@
 package tonacity
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestPatternDictionary_GetName(t *testing.T) {
 	type args struct {
@@ -21,6 +24,7 @@ func TestPatternDictionary_GetName(t *testing.T) {
 		{"Dorian", modeDict, args{CreateDorianMode()}, "Dorian", true},
 		{"Aeolian", modeDict, args{CreateAeolianMode()}, "Aeolian", true},
 		{"Minor", scaleDict, args{CreateAeolianMode()}, "Minor", true},
+		{"Harmonic Minor", scaleDict, args{CreateHarmonicMinorScalePattern()}, "Harmonic Minor", true},
 		{"Pentatonic Minor", scaleDict, args{CreateMinorPentatonicScalePattern()}, "Pentatonic Minor", true},
 		{"Unknown", modeDict, args{CreateMinorPentatonicScalePattern()}, "", false},
 	}
@@ -36,3 +40,34 @@ func TestPatternDictionary_GetName(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateHarmonicMinorScalePattern(t *testing.T) {
+	// The natural minor with a raised seventh, which leaves an augmented second between the sixth and seventh
+	want := []HalfSteps{2, 1, 2, 2, 1, 3, 1}
+	if got := CreateHarmonicMinorScalePattern().Intervals(); !reflect.DeepEqual(got, want) {
+		t.Errorf("CreateHarmonicMinorScalePattern() = %v, want %v", got, want)
+	}
+}
+
+func TestFindScale(t *testing.T) {
+	tests := []struct {
+		name string
+		want *Pattern
+	}{
+		{"Major", CreateMajorScale()},
+		{"harmonic minor", CreateHarmonicMinorScalePattern()},
+		{"DORIAN", CreateDorianMode()},
+		{" Pentatonic Minor ", CreateMinorPentatonicScalePattern()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, ok := FindScale(tt.name)
+			if !ok || !reflect.DeepEqual(got.Intervals(), tt.want.Intervals()) {
+				t.Errorf("FindScale() = %v, %v, want %v", got, ok, tt.want)
+			}
+		})
+	}
+	if _, ok := FindScale("Blues"); ok {
+		t.Error("FindScale(Blues) should fail")
+	}
+}
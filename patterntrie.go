@@ -53,3 +53,20 @@ func (node *TrieNode) FindValue(path []HalfSteps) []interface{} {
 	}
 	return child.FindValue(path[1:])
 }
+
+// Walk Calls visit for every path in this trie that has at least one value, in ascending order of path. The path passed to
+// visit is only valid for the duration of the call.
+func (node *TrieNode) Walk(visit func(path []HalfSteps, values []interface{})) {
+	node.walk(make([]HalfSteps, 0), visit)
+}
+
+func (node *TrieNode) walk(path []HalfSteps, visit func(path []HalfSteps, values []interface{})) {
+	if len(node.values) > 0 {
+		visit(path, node.values)
+	}
+	for i, child := range node.children {
+		if child != nil {
+			child.walk(append(path, node.min+HalfSteps(i)), visit)
+		}
+	}
+}
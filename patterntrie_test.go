@@ -1,6 +1,9 @@
 package tonacity
 
-import "testing"
+import (
+	"reflect"
+	"testing"
+)
 
 func TestTrieNode_AddValue(t *testing.T) {
 	type args struct {
@@ -27,3 +30,17 @@ func TestTrieNode_AddValue(t *testing.T) {
 		})
 	}
 }
+
+func TestTrieNode_Walk(t *testing.T) {
+	node := NewTrie(1, 3)
+	node.AddValue([]HalfSteps{2, 1}, "B")
+	node.AddValue([]HalfSteps{1, 3}, "A")
+	node.AddValue([]HalfSteps{2}, "C")
+	got := make([]interface{}, 0)
+	node.Walk(func(path []HalfSteps, values []interface{}) {
+		got = append(got, values...)
+	})
+	if !reflect.DeepEqual(got, []interface{}{"A", "C", "B"}) {
+		t.Errorf("Walk() visited %v, want [A C B]", got)
+	}
+}
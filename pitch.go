@@ -1,9 +1,6 @@
 package tonacity
 
-import (
-	"fmt"
-	"math"
-)
+import "math"
 
 // StandardConcertPitch The almost-completely agreed-upon pitch of A4
 const StandardConcertPitch = 440
@@ -17,6 +14,9 @@ type PitchClass struct {
 // this operation will "loop" around. For example: transposing C by 2, 14, 26, etc. will produce D.
 func (pc *PitchClass) Transpose(halfSteps HalfSteps) {
 	pc.value = (pc.value + halfSteps) % OctaveValue
+	if pc.value < 0 {
+		pc.value += OctaveValue
+	}
 }
 
 // GetTransposedCopy Returns a copy of this pitch class transposed by the given number of half steps.
@@ -126,7 +126,8 @@ type Pitch struct {
 }
 
 func (p *Pitch) String() string {
-	return fmt.Sprintf("class %d, value %d", p.class.value, p.value)
+	text, _ := p.MarshalText()
+	return string(text)
 }
 
 // Class The class of the pitch, e.g., C
@@ -177,6 +178,14 @@ func (p *Pitch) FrequencyInHertz(concertPitch float64) float64 {
 	return math.Pow(2, float64(A4().GetDistanceTo(p))/12.0) * concertPitch
 }
 
+// NearestPitch Returns the pitch closest to the given frequency, using the given frequency as A4, along with how far the frequency is
+// from it in cents (hundredths of a half step), between -50 and 50.
+func NearestPitch(hertz float64, concertPitch float64) (pitch *Pitch, cents float64) {
+	halfSteps := 12 * math.Log2(hertz/concertPitch)
+	nearest := math.Round(halfSteps)
+	return A4().GetTransposedCopy(HalfSteps(nearest)), (halfSteps - nearest) * 100
+}
+
 // ByPitch allows sorting a slice of Pitches by their values, i.e., their pitches.
 type ByPitch []Pitch
 
@@ -208,5 +217,5 @@ func (f *PitchFactory) GetPitch(pitchClass *PitchClass, octave int) *Pitch {
 	// octave < 0  => After a pitch in a lower octave  (3-)
 
 	interval := (f.c4.class.GetDistanceToHigherPitchClass(*pitchClass) % OctaveValue)
-	return &Pitch{*pitchClass, interval + HalfSteps(octave*OctaveValue)}
+	return &Pitch{*pitchClass, f.c4.value + interval + HalfSteps(octave*OctaveValue)}
 }
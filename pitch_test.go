@@ -108,3 +108,57 @@ func TestPitch_FrequencyInHertz(t *testing.T) {
 		})
 	}
 }
+
+func TestNearestPitch(t *testing.T) {
+	tests := []struct {
+		name      string
+		hertz     float64
+		concert   float64
+		want      *Pitch
+		wantCents float64
+	}{
+		{"A4", 440, StandardConcertPitch, A4(), 0},
+		{"Middle C", 261.626, StandardConcertPitch, MiddleC(), 0},
+		{"Sharp A4", 445, StandardConcertPitch, A4(), 19.56},
+		{"Flat A5", 870, StandardConcertPitch, A4().GetTransposedCopy(12), -19.78},
+		{"A4 at 432", 432, 432, A4(), 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, cents := NearestPitch(tt.hertz, tt.concert)
+			if got.GetDistanceTo(tt.want) != 0 || math.Abs(cents-tt.wantCents) > 0.01 {
+				t.Errorf("NearestPitch() = %v, %v, want %v, %v", got, cents, tt.want, tt.wantCents)
+			}
+		})
+	}
+}
+
+func TestPitchFactory_GetPitch(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	if got := pf.GetPitch(A(), 4); got.GetDistanceTo(A4()) != 0 {
+		t.Errorf("PitchFactory.GetPitch(A, 4) is %v half steps from A4, want 0", got.GetDistanceTo(A4()))
+	}
+	if got := pf.GetPitch(C(), 4); got.GetDistanceTo(MiddleC()) != 0 {
+		t.Errorf("PitchFactory.GetPitch(C, 4) is %v half steps from middle C, want 0", got.GetDistanceTo(MiddleC()))
+	}
+}
+
+func TestPitchClass_Transpose(t *testing.T) {
+	tests := []struct {
+		name      string
+		pc        *PitchClass
+		halfSteps HalfSteps
+		want      *PitchClass
+	}{
+		{"C up 14", C(), 14, D()},
+		{"C down 1", C(), -1, B()},
+		{"D down 26", D(), -26, C()},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if tt.pc.Transpose(tt.halfSteps); *tt.pc != *tt.want {
+				t.Errorf("PitchClass.Transpose() = %v, want %v", tt.pc, tt.want)
+			}
+		})
+	}
+}
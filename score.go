@@ -0,0 +1,507 @@
+package tonacity
+
+import (
+	"fmt"
+	"iter"
+	"sort"
+)
+
+// Score A piece of music for any number of instruments, each a part written on one or more staves, each stave holding one or more voices
+// written in bars. The bars of every voice are lined up into measures, so the nth bar of each voice is played at the same time. Each bar
+// has its own key signature, time signature and clef, so they can change part way through.
+type Score struct {
+	Title string
+	Parts []*ScorePart
+}
+
+// ScorePart The music for one instrument, e.g. a violin, or a piano, which is written on two staves.
+type ScorePart struct {
+	ID     string
+	Name   string
+	Staves []*ScoreStave
+}
+
+// ScoreStave The voices written on one stave of a part, each with its own bars. The voices of a stave should share their key signatures,
+// time signatures and clefs.
+type ScoreStave struct {
+	Voices []*Stave
+}
+
+// ScoreEvent A note or rest in a score, along with where it is and when it starts, measured from the beginning of the score.
+type ScoreEvent struct {
+	Part    int // Indexes into the score's parts, the part's staves and the stave's voices
+	Stave   int
+	Voice   int
+	Measure int // Counted from 1
+	Start   Duration
+	Note    Note
+	Bar     *Bar // The bar the note is written in, which gives the key signature, time signature and clef in force
+}
+
+// End Returns when the event finishes, measured from the beginning of the score.
+func (e *ScoreEvent) End() Duration {
+	return e.Start.Add(e.Note.duration)
+}
+
+// Verticality Everything that sounds at a moment in a score where at least one note starts, such as a chord made by several voices. Notes
+// that started earlier and are still being held are included.
+type Verticality struct {
+	Start Duration
+	Notes []ScoreEvent // In the order of the score's parts, staves and voices
+}
+
+// Pitches Returns the pitches sounding in the verticality, from every note, in order.
+func (v *Verticality) Pitches() []SpelledPitch {
+	var pitches []SpelledPitch
+	for i := range v.Notes {
+		pitches = append(pitches, v.Notes[i].Note.pitches...)
+	}
+	return pitches
+}
+
+// voices Calls the given function with every voice in the score in order, along with its indexes.
+func (s *Score) voices(visit func(part int, stave int, voice int, bars []Bar)) {
+	for p, part := range s.Parts {
+		for st, stave := range part.Staves {
+			for v, voice := range stave.Voices {
+				visit(p, st, v, voice.bars)
+			}
+		}
+	}
+}
+
+// MeasureCount Returns the number of measures in the score, which is the number of bars in its longest voice.
+func (s *Score) MeasureCount() int {
+	count := 0
+	s.voices(func(_ int, _ int, _ int, bars []Bar) {
+		count = max(count, len(bars))
+	})
+	return count
+}
+
+// measureStarts Returns when each measure starts, followed by when the last one ends. Each measure lasts as long as the longest of its
+// bars, or if they are all empty, as long as their time signature says.
+func (s *Score) measureStarts() []Duration {
+	lengths := make([]Duration, s.MeasureCount())
+	s.voices(func(_ int, _ int, _ int, bars []Bar) {
+		for i := range bars {
+			length := bars[i].Duration()
+			if length.IsZero() && bars[i].time.noteCount > 0 {
+				length = bars[i].time.Duration()
+			}
+			if length.Cmp(lengths[i]) > 0 {
+				lengths[i] = length
+			}
+		}
+	})
+	starts := make([]Duration, len(lengths)+1)
+	for i, length := range lengths {
+		starts[i+1] = starts[i].Add(length)
+	}
+	return starts
+}
+
+// MeasureStart Returns when the given measure, counted from 1, starts, measured from the beginning of the score. A measure after the last
+// one starts when the score ends.
+func (s *Score) MeasureStart(measure int) Duration {
+	starts := s.measureStarts()
+	return starts[min(max(measure-1, 0), len(starts)-1)]
+}
+
+// Duration Returns how long the score lasts.
+func (s *Score) Duration() Duration {
+	starts := s.measureStarts()
+	return starts[len(starts)-1]
+}
+
+// VoiceEvents Returns the notes and rests of one voice, with the given indexes into the score's parts, the part's staves and the stave's
+// voices, in the order they are played. If there is no such voice then nothing is returned.
+func (s *Score) VoiceEvents(part int, stave int, voice int) iter.Seq[ScoreEvent] {
+	return func(yield func(ScoreEvent) bool) {
+		if part < 0 || part >= len(s.Parts) || stave < 0 || stave >= len(s.Parts[part].Staves) ||
+			voice < 0 || voice >= len(s.Parts[part].Staves[stave].Voices) {
+			return
+		}
+		starts := s.measureStarts()
+		bars := s.Parts[part].Staves[stave].Voices[voice].bars
+		for m := range bars {
+			now := starts[m]
+			for _, note := range bars[m].notes {
+				if !yield(ScoreEvent{part, stave, voice, m + 1, now, note, &bars[m]}) {
+					return
+				}
+				now = now.Add(note.duration)
+			}
+		}
+	}
+}
+
+// Events Returns every note and rest in the score in the order they start. Those that start together are in the order of the score's parts,
+// staves and voices.
+func (s *Score) Events() iter.Seq[ScoreEvent] {
+	return func(yield func(ScoreEvent) bool) {
+		var events []ScoreEvent
+		s.voices(func(part int, stave int, voice int, _ []Bar) {
+			for event := range s.VoiceEvents(part, stave, voice) {
+				events = append(events, event)
+			}
+		})
+		sort.SliceStable(events, func(i, j int) bool { return events[i].Start.Cmp(events[j].Start) < 0 })
+		for _, event := range events {
+			if !yield(event) {
+				return
+			}
+		}
+	}
+}
+
+// Verticalities Returns what sounds at each moment in the score where a note starts, in order. Rests are left out, and a note tied on
+// from the one before it is held rather than started again.
+func (s *Score) Verticalities() iter.Seq[Verticality] {
+	return func(yield func(Verticality) bool) {
+		var sounding []ScoreEvent
+		var pending *Verticality
+		tied := map[[3]int]bool{}
+		for event := range s.Events() {
+			voice := [3]int{event.Part, event.Stave, event.Voice}
+			held := tied[voice]
+			tied[voice] = event.Note.tied && !event.Note.IsRest()
+			if event.Note.IsRest() {
+				continue
+			}
+			if pending != nil && pending.Start.Cmp(event.Start) != 0 {
+				if !yield(*pending) {
+					return
+				}
+				pending = nil
+			}
+			if pending == nil && !held {
+				// Let go of the notes that have finished
+				kept := sounding[:0]
+				for _, e := range sounding {
+					if e.End().Cmp(event.Start) > 0 {
+						kept = append(kept, e)
+					}
+				}
+				sounding = kept
+				pending = &Verticality{Start: event.Start}
+			}
+			sounding = append(sounding, event)
+			if pending != nil {
+				pending.Notes = s.inScoreOrder(sounding)
+			}
+		}
+		if pending != nil {
+			yield(*pending)
+		}
+	}
+}
+
+// inScoreOrder Returns a copy of the given events sorted into the order of the score's parts, staves and voices.
+func (s *Score) inScoreOrder(events []ScoreEvent) []ScoreEvent {
+	sorted := append([]ScoreEvent(nil), events...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := &sorted[i], &sorted[j]
+		if a.Part != b.Part {
+			return a.Part < b.Part
+		}
+		if a.Stave != b.Stave {
+			return a.Stave < b.Stave
+		}
+		return a.Voice < b.Voice
+	})
+	return sorted
+}
+
+// Check Returns an error describing the first problem found with the score: a voice with a bar that doesn't add up, as described by
+// Stave.Check, or a measure whose bars have different time signatures.
+func (s *Score) Check() error {
+	times := make([]*TimeSignature, s.MeasureCount())
+	var err error
+	s.voices(func(part int, stave int, voice int, bars []Bar) {
+		if err != nil {
+			return
+		}
+		if e := MakeStave(bars...).Check(); e != nil {
+			err = fmt.Errorf("part %d stave %d voice %d: %w", part+1, stave+1, voice+1, e)
+			return
+		}
+		for i := range bars {
+			time := bars[i].time
+			if times[i] == nil {
+				times[i] = &time
+			} else if *times[i] != time {
+				err = fmt.Errorf("measure %d: part %d stave %d voice %d is in %v, not %v", i+1, part+1, stave+1, voice+1, time.String(), times[i].String())
+				return
+			}
+		}
+	})
+	return err
+}
+
+// Score Returns the score, with each stave a single voice. The staves are shared rather than copied.
+func (s *MusicXMLScore) Score() *Score {
+	score := &Score{Title: s.Title}
+	for _, part := range s.Parts {
+		sp := &ScorePart{ID: part.ID, Name: part.Name}
+		for _, stave := range part.Staves {
+			sp.Staves = append(sp.Staves, &ScoreStave{[]*Stave{stave}})
+		}
+		score.Parts = append(score.Parts, sp)
+	}
+	return score
+}
+
+// MusicXML Returns the score ready to be written as MusicXML. Parts without an ID are numbered "P1", "P2", and so on. An error is returned
+// if a stave has more than one voice, which isn't supported.
+func (s *Score) MusicXML() (*MusicXMLScore, error) {
+	score := &MusicXMLScore{Title: s.Title}
+	for p, part := range s.Parts {
+		mp := &MusicXMLPart{ID: part.ID, Name: part.Name}
+		if mp.ID == "" {
+			mp.ID = fmt.Sprintf("P%d", p+1)
+		}
+		for st, stave := range part.Staves {
+			if len(stave.Voices) != 1 {
+				return nil, fmt.Errorf("part %d stave %d has %d voices, but only one voice to a stave can be written", p+1, st+1, len(stave.Voices))
+			}
+			mp.Staves = append(mp.Staves, stave.Voices[0])
+		}
+		score.Parts = append(score.Parts, mp)
+	}
+	return score, nil
+}
+
+// scoreChange A time signature or key signature that comes into force part way through the music. Either may be nil if it doesn't change.
+type scoreChange struct {
+	start Duration
+	time  *TimeSignature
+	key   *KeySignature
+}
+
+// barVoice Divides the notes of a voice, which must be in the order they start and not overlap, into bars with the given clef. The time
+// signature and key signature of each bar are those of the last changes at or before it, which are in order. A change of time signature
+// must fall on a bar line. The music starts with a pickup bar of the given length, unless it is zero. Music without a time signature is
+// written in a single unmetered bar. Notes without a written value, such as those read from MIDI, are written as notes that have one,
+// tied together.
+func barVoice(notes []TimedNote, changes []scoreChange, pickup Duration, clef *Clef) (*Stave, error) {
+	var meters []scoreChange
+	for _, change := range changes {
+		if change.time != nil {
+			meters = append(meters, change)
+		}
+	}
+	stave := MakeStave()
+	if len(meters) == 0 {
+		bar := MakeBar(nil, nil, clef)
+		var now Duration
+		for _, note := range notes {
+			if gap := note.Start.Sub(now); gap.Cmp(Duration{}) > 0 {
+				bar.AddNotes(MakeTiedNotes(gap)...)
+			}
+			bar.AddNotes(note.Note)
+			now = note.Start.Add(note.Note.duration)
+		}
+		stave.bars = append(stave.bars, *bar)
+	}
+	first := 0
+	for m, meter := range meters {
+		// The notes of this meter are those starting before the next change, moved to start from it
+		var section []TimedNote
+		for first < len(notes) && (m+1 == len(meters) || notes[first].Start.Cmp(meters[m+1].start) < 0) {
+			section = append(section, TimedNote{notes[first].Start.Sub(meter.start), notes[first].Note})
+			first++
+		}
+		start := Duration{}
+		if m == 0 {
+			start = pickup
+		}
+		barred, err := BarNotes(section, meter.time, start, nil, clef)
+		if err != nil {
+			return nil, err
+		}
+		if m+1 < len(meters) {
+			// Fill the section up to the next change, with whole bars of rests if needed
+			length := meters[m+1].start.Sub(meter.start)
+			if len(section) == 0 {
+				barred.bars = barred.bars[:0]
+			}
+			for barred.Duration().Cmp(length) < 0 {
+				if n := len(barred.bars); n == 0 || barred.bars[n-1].Remaining().IsZero() {
+					barred.bars = append(barred.bars, *MakeBar(meter.time, nil, clef))
+				}
+				barred.bars[len(barred.bars)-1].FillWithRests()
+			}
+			if barred.Duration().Cmp(length) > 0 {
+				return nil, fmt.Errorf("change to %v at %v isn't on a bar line", meters[m+1].time.String(), meters[m+1].start)
+			}
+		}
+		stave.bars = append(stave.bars, barred.bars...)
+	}
+	// Give each bar the key in force where it starts, and write notes without a value as tied notes that have one
+	var now Duration
+	for i := range stave.bars {
+		written := make([]Note, 0, len(stave.bars[i].notes))
+		for _, note := range stave.bars[i].notes {
+			if note.value != 0 {
+				written = append(written, note)
+				continue
+			}
+			parts := MakeTiedNotes(note.duration, note.pitches...)
+			if !note.IsRest() {
+				parts[len(parts)-1].tied = note.tied
+			}
+			written = append(written, parts...)
+		}
+		stave.bars[i].notes = written
+		for _, change := range changes {
+			if change.key != nil && change.start.Cmp(now) <= 0 {
+				stave.bars[i].key = change.key
+			}
+		}
+		now = now.Add(stave.barLength(i))
+	}
+	return stave, nil
+}
+
+// voiceClef Returns the treble or bass clef, whichever suits the range of the given notes better.
+func voiceClef(notes []TimedNote) *Clef {
+	var low, high *SpelledPitch
+	for i := range notes {
+		for j := range notes[i].Note.pitches {
+			sp := &notes[i].Note.pitches[j]
+			if low == nil || sp.pitch.value < low.pitch.value {
+				low = sp
+			}
+			if high == nil || sp.pitch.value > high.pitch.value {
+				high = sp
+			}
+		}
+	}
+	if low == nil {
+		return MakeTrebleClef()
+	}
+	return BestClef(low, high)
+}
+
+// Score Returns the tune as a score of one part with a single voice, barred by its meter, starting with its pickup if it has one. The clef is chosen to suit the range of the tune.
+func (t *AbcTune) Score() (*Score, error) {
+	stave, err := barVoice(t.Notes, []scoreChange{{Duration{}, t.Time, t.Key}}, t.Pickup, voiceClef(t.Notes))
+	if err != nil {
+		return nil, err
+	}
+	return &Score{Title: t.Title, Parts: []*ScorePart{{ID: "P1", Staves: []*ScoreStave{{[]*Stave{stave}}}}}}, nil
+}
+
+// Score Returns the voices as a score, barred by their meters, with the voices that share a name (such as those of a spine that splits)
+// written on the same stave of one part. Each stave's clef is chosen to suit the range of its first voice.
+func (k *KernScore) Score() (*Score, error) {
+	score := &Score{Title: k.Title}
+	parts := map[string]*ScorePart{}
+	for _, voice := range k.Voices {
+		var changes []scoreChange
+		for _, meter := range voice.Meters {
+			changes = append(changes, scoreChange{start: meter.Start, time: meter.Time})
+		}
+		for _, key := range voice.Keys {
+			changes = append(changes, scoreChange{start: key.Start, key: key.Key})
+		}
+		sort.SliceStable(changes, func(i, j int) bool { return changes[i].start.Cmp(changes[j].start) < 0 })
+		part, ok := parts[voice.Name]
+		clef := voiceClef(voice.Notes)
+		if ok {
+			clef = part.Staves[0].Voices[0].bars[0].clef
+		}
+		stave, err := barVoice(voice.Notes, changes, Duration{}, clef)
+		if err != nil {
+			return nil, fmt.Errorf("voice %v: %w", voice.Name, err)
+		}
+		if !ok {
+			part = &ScorePart{ID: fmt.Sprintf("P%d", len(score.Parts)+1), Name: voice.Name, Staves: []*ScoreStave{{}}}
+			parts[voice.Name] = part
+			score.Parts = append(score.Parts, part)
+		}
+		part.Staves[0].Voices = append(part.Staves[0].Voices, stave)
+	}
+	return score, nil
+}
+
+// Score Returns the notes of the file as a score with a part for each track that has notes, barred by the time signatures of every track,
+// which are 4/4 if there are none. Notes that start and finish together are written as chords, and notes that overlap others are moved
+// into extra voices. Pitches are spelled in the key in force where they start, and each stave's clef is chosen to suit its range.
+func (f *MidiFile) Score() (*Score, error) {
+	ticksPerWhole := int64(max(f.TicksPerQuarter, 1)) * 4
+	at := func(tick uint32) Duration {
+		return MakeDuration(int64(tick), ticksPerWhole)
+	}
+	var changes []scoreChange
+	for _, track := range f.Tracks {
+		for _, ts := range track.TimeSignatures {
+			time := ts.TimeSignature
+			changes = append(changes, scoreChange{start: at(ts.Tick), time: &time})
+		}
+		for _, ks := range track.KeySignatures {
+			key := ks.KeySignature
+			changes = append(changes, scoreChange{start: at(ks.Tick), key: &key})
+		}
+	}
+	sort.SliceStable(changes, func(i, j int) bool { return changes[i].start.Cmp(changes[j].start) < 0 })
+	if len(changes) == 0 || !changes[0].start.IsZero() || changes[0].time == nil {
+		changes = append([]scoreChange{{time: MakeTimeSignature(4, 4)}}, changes...)
+	}
+	keyAt := func(start Duration) *KeySignature {
+		var key *KeySignature
+		for _, change := range changes {
+			if change.key != nil && change.start.Cmp(start) <= 0 {
+				key = change.key
+			}
+		}
+		return key
+	}
+	score := &Score{}
+	for _, track := range f.Tracks {
+		if len(track.Notes) == 0 {
+			continue
+		}
+		notes := append([]MidiNote(nil), track.Notes...)
+		sort.SliceStable(notes, func(i, j int) bool { return notes[i].Start < notes[j].Start })
+		var voices [][]TimedNote
+		var all []TimedNote
+		for _, note := range notes {
+			start, duration := at(note.Start), at(note.Duration)
+			if duration.IsZero() {
+				continue
+			}
+			sp := SpellPitch(note.Pitch, keyAt(start))
+			placed := false
+			for v := range voices {
+				last := &voices[v][len(voices[v])-1]
+				if last.Start.Cmp(start) == 0 && last.Note.duration.Cmp(duration) == 0 {
+					last.Note.pitches = append(last.Note.pitches, sp)
+					placed = true
+				} else if last.Start.Add(last.Note.duration).Cmp(start) <= 0 {
+					voices[v] = append(voices[v], TimedNote{start, *makeNoteOfDuration(duration, sp)})
+					placed = true
+				}
+				if placed {
+					break
+				}
+			}
+			if !placed {
+				voices = append(voices, []TimedNote{{start, *makeNoteOfDuration(duration, sp)}})
+			}
+			all = append(all, TimedNote{start, *makeNoteOfDuration(duration, sp)})
+		}
+		clef := voiceClef(all)
+		stave := &ScoreStave{}
+		for _, voice := range voices {
+			barred, err := barVoice(voice, changes, Duration{}, clef)
+			if err != nil {
+				return nil, fmt.Errorf("track %v: %w", track.Name, err)
+			}
+			stave.Voices = append(stave.Voices, barred)
+		}
+		score.Parts = append(score.Parts, &ScorePart{ID: fmt.Sprintf("P%d", len(score.Parts)+1), Name: track.Name, Staves: []*ScoreStave{stave}})
+	}
+	return score, nil
+}
@@ -0,0 +1,206 @@
+package tonacity
+
+import (
+	"strings"
+	"testing"
+)
+
+// pianoScore Returns a score of one bar of 4/4 for piano, with two voices in the right hand and one in the left.
+func pianoScore() *Score {
+	note := func(value int, letter Letter, octave int) Note {
+		return *MakeNote(value, 0, *MakeSpelledPitch(letter, 0, octave))
+	}
+	voice := func(clef *Clef, notes ...Note) *Stave {
+		bar := MakeBar(MakeTimeSignature(4, 4), nil, clef)
+		bar.AddNotes(notes...)
+		return MakeStave(*bar)
+	}
+	return &Score{Title: "Piece", Parts: []*ScorePart{{ID: "P1", Name: "Piano", Staves: []*ScoreStave{
+		{[]*Stave{
+			voice(MakeTrebleClef(), note(4, LetterC, 5), note(4, LetterD, 5), note(4, LetterE, 5), note(4, LetterF, 5)),
+			voice(MakeTrebleClef(), note(2, LetterA, 4), *MakeRest(4, 0), note(4, LetterB, 4)),
+		}},
+		{[]*Stave{voice(MakeBassClef(), note(1, LetterC, 3))}},
+	}}}}
+}
+
+// pitchNames Writes out the given pitches, e.g. "C4 E4".
+func pitchNames(pitches []SpelledPitch) string {
+	names := make([]string, len(pitches))
+	for i := range pitches {
+		names[i] = pitches[i].String()
+	}
+	return strings.Join(names, " ")
+}
+
+func TestScore_Traversal(t *testing.T) {
+	score := pianoScore()
+	if got := score.MeasureCount(); got != 1 {
+		t.Errorf("MeasureCount() = %v, want 1", got)
+	}
+	if got := score.Duration(); got.Cmp(MakeDuration(1, 1)) != 0 {
+		t.Errorf("Duration() = %v, want 1/1", got)
+	}
+	var starts []string
+	for event := range score.VoiceEvents(0, 0, 1) {
+		starts = append(starts, event.Start.String())
+	}
+	if got := strings.Join(starts, " "); got != "0/1 1/2 3/4" {
+		t.Errorf("VoiceEvents() start at %v, want 0/1 1/2 3/4", got)
+	}
+	var events []string
+	for event := range score.Events() {
+		if event.Start.IsZero() {
+			events = append(events, pitchNames(event.Note.pitches))
+		}
+	}
+	if got := strings.Join(events, ", "); got != "C5, A4, C3" {
+		t.Errorf("Events() at the start = %v, want C5, A4, C3", got)
+	}
+	var verticalities []string
+	for v := range score.Verticalities() {
+		verticalities = append(verticalities, v.Start.String()+": "+pitchNames(v.Pitches()))
+	}
+	want := []string{"0/1: C5 A4 C3", "1/4: D5 A4 C3", "1/2: E5 C3", "3/4: F5 B4 C3"}
+	if got := strings.Join(verticalities, ", "); got != strings.Join(want, ", ") {
+		t.Errorf("Verticalities() = %v, want %v", got, strings.Join(want, ", "))
+	}
+	if err := score.Check(); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+	score.Parts[0].Staves[1].Voices[0].bars[0].time = *MakeTimeSignature(2, 2)
+	if err := score.Check(); err == nil || err.Error() != "measure 1: part 1 stave 2 voice 1 is in 2/2, not 4/4" {
+		t.Errorf("Check() = %v", err)
+	}
+}
+
+func TestScore_MusicXML(t *testing.T) {
+	if _, err := pianoScore().MusicXML(); err == nil {
+		t.Errorf("MusicXML() of two voices on a stave should fail")
+	}
+	score := pianoScore()
+	score.Parts[0].Staves[0].Voices = score.Parts[0].Staves[0].Voices[:1]
+	mxl, err := score.MusicXML()
+	if err != nil {
+		t.Fatal(err)
+	}
+	again := mxl.Score()
+	if again.Title != "Piece" || len(again.Parts) != 1 || len(again.Parts[0].Staves) != 2 || again.Parts[0].Name != "Piano" {
+		t.Errorf("MusicXMLScore.Score() = %+v", again)
+	}
+}
+
+func TestKernScore_Score(t *testing.T) {
+	kern, err := ReadKern(strings.NewReader(strings.Join([]string{
+		"**kern\t**kern",
+		"*I\"Bass\t*I\"Soprano",
+		"*G:\t*G:",
+		"*M3/4\t*M3/4",
+		"2G\t4b",
+		".\t4a",
+		"4D\t4g",
+		"=1\t=1",
+		"*M2/4\t*M2/4",
+		"*C:\t*C:",
+		"4C\t2c",
+		"4E\t.",
+		"*-\t*-",
+	}, "\n")))
+	if err != nil {
+		t.Fatal(err)
+	}
+	score, err := kern.Score()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(score.Parts) != 2 || score.Parts[0].Name != "Bass" || score.Parts[1].Name != "Soprano" {
+		t.Fatalf("KernScore.Score() = %+v", score)
+	}
+	bass := score.Parts[0].Staves[0].Voices[0]
+	if got, want := barSummary(bass), "G3:2 D3:4 | C3:4 E3:4"; got != want {
+		t.Errorf("KernScore.Score() bass = %v, want %v", got, want)
+	}
+	first, second := bass.Bars()[0], bass.Bars()[1]
+	if first.KeySignature().Fifths() != 1 || first.Clef().String() != "bass" {
+		t.Errorf("KernScore.Score() first bar is in %v, %v", first.KeySignature(), first.Clef())
+	}
+	if second.TimeSignature().String() != "2/4" || second.KeySignature().Fifths() != 0 {
+		t.Errorf("KernScore.Score() second bar is in %v, %v", second.TimeSignature(), second.KeySignature())
+	}
+	if clef := score.Parts[1].Staves[0].Voices[0].Bars()[0].Clef(); clef.String() != "treble" {
+		t.Errorf("KernScore.Score() soprano clef = %v, want treble", clef)
+	}
+	if got := score.MeasureStart(2); got.Cmp(MakeDuration(3, 4)) != 0 {
+		t.Errorf("MeasureStart(2) = %v, want 3/4", got)
+	}
+	if err := score.Check(); err != nil {
+		t.Errorf("Check() = %v", err)
+	}
+}
+
+func TestAbcTune_Score(t *testing.T) {
+	tunes, err := ReadAbc(strings.NewReader("X:1\nT:Scale\nM:2/4\nL:1/8\nK:D\nDEFG|ABcd|e4|]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	score, err := tunes[0].Score()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stave := score.Parts[0].Staves[0].Voices[0]
+	if got := len(stave.Bars()); got != 3 || score.Title != "Scale" {
+		t.Errorf("AbcTune.Score() has %d bars, want 3", got)
+	}
+	if bar := stave.Bars()[0]; bar.Clef().String() != "treble" || bar.KeySignature() == nil {
+		t.Errorf("AbcTune.Score() bar is in %v, %v", bar.Clef(), bar.KeySignature())
+	}
+}
+
+func TestAbcTune_Score_Pickup(t *testing.T) {
+	tunes, err := ReadAbc(strings.NewReader("X:1\nM:4/4\nL:1/4\nK:C\nG|c d e f|g4|]\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	score, err := tunes[0].Score()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := barSummary(score.Parts[0].Staves[0].Voices[0]), "G4:4 | C5:4 D5:4 E5:4 F5:4 | G5:1"; got != want {
+		t.Errorf("AbcTune.Score() = %v, want %v", got, want)
+	}
+}
+
+func TestMidiFile_Score(t *testing.T) {
+	c4, e4, g4, c3 := PitchFromMidiNoteNumber(60), PitchFromMidiNoteNumber(64), PitchFromMidiNoteNumber(67), PitchFromMidiNoteNumber(48)
+	file := &MidiFile{TicksPerQuarter: 4, Tracks: []*MidiTrack{
+		{Name: "Tempo", TimeSignatures: []MidiTimeSignature{{0, *MakeTimeSignature(3, 4)}}},
+		{Name: "Piano", Notes: []MidiNote{
+			{*c4, 0, 4, 0, 64}, {*e4, 0, 4, 0, 64}, {*g4, 4, 8, 0, 64},
+			{*c3, 2, 10, 0, 64},
+		}},
+	}}
+	score, err := file.Score()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(score.Parts) != 1 || score.Parts[0].Name != "Piano" || len(score.Parts[0].Staves[0].Voices) != 2 {
+		t.Fatalf("MidiFile.Score() = %+v", score)
+	}
+	voices := score.Parts[0].Staves[0].Voices
+	if got, want := barSummary(voices[0]), "C4:4 G4:2"; got != want {
+		t.Errorf("MidiFile.Score() first voice = %v, want %v", got, want)
+	}
+	if got := pitchNames(voices[0].Bars()[0].Notes()[0].Pitches()); got != "C4 E4" {
+		t.Errorf("MidiFile.Score() chord = %v, want C4 E4", got)
+	}
+	if got, want := barSummary(voices[1]), "r:8 C3:2~ C3:8"; got != want {
+		t.Errorf("MidiFile.Score() second voice = %v, want %v", got, want)
+	}
+	var verticalities []string
+	for v := range score.Verticalities() {
+		verticalities = append(verticalities, v.Start.String()+": "+pitchNames(v.Pitches()))
+	}
+	if got, want := strings.Join(verticalities, ", "), "0/1: C4 E4, 1/8: C4 E4 C3, 1/4: G4 C3"; got != want {
+		t.Errorf("Verticalities() = %v, want %v", got, want)
+	}
+}
@@ -0,0 +1,144 @@
+package tonacity
+
+// DiatonicSequence A scale exercise made by repeating a motif of scale degrees from each note of the scale in turn, e.g. C Major in
+// thirds is the motif 1 3 played as C E, D F, E G, and so on. The motif is moved up and down the scale by degree rather than by half
+// step, so its intervals change size to stay in the scale, i.e. some of the thirds are major and some minor. On the way back down the
+// motif is turned upside down, so C Major in thirds descends C A, B G, A F, and so on.
+//
+// A DiatonicSequence sings the ascending sequence then the descending one, and each of them can be sung on its own.
+type DiatonicSequence struct {
+	ascending  []Pitch
+	descending []Pitch
+	next       int // The index into the ascending then descending pitches of the pitch that will be sung next
+}
+
+// MakeDiatonicSequence Creates the sequence of the given motif up and down the given scale, kept between the low and high pitches
+// inclusive. The motif is a list of scale degrees counted from the note it starts on, so 1 is that note, 3 is a third above, and -2 is
+// a second below it, e.g. 1 2 3 1 for groups of four, or 1 3 4 5 6 5 4 3 for the first Hanon exercise. Each repetition of the motif
+// starts on the next note of the scale, beginning with the lowest note of the scale in range, and the sequence stops before a repetition
+// that wouldn't fit within the range. The scale must ascend, i.e. every interval of its pattern must be positive. If it doesn't, if the
+// motif is empty or has a degree of 0, or if the low pitch is above the high one, then nil is returned.
+func MakeDiatonicSequence(scale *RootedPattern, motif []int, low Pitch, high Pitch) *DiatonicSequence {
+	if len(motif) == 0 || low.value > high.value {
+		return nil
+	}
+	if scale.pattern.Length() == 0 {
+		return nil
+	}
+	for _, step := range scale.pattern.intervals {
+		if step <= 0 {
+			return nil
+		}
+	}
+	// The offset of each note of the motif, in scale degrees, from the first note of the repetition
+	offsets := make([]int, len(motif))
+	lowest, highest := 0, 0
+	for i, degree := range motif {
+		switch {
+		case degree > 0:
+			offsets[i] = degree - 1
+		case degree < 0:
+			offsets[i] = degree + 1
+		default:
+			return nil
+		}
+		lowest, highest = min(lowest, offsets[i]), max(highest, offsets[i])
+	}
+	// The scale degrees of the lowest and highest notes of the scale in range, counted from its root at 0
+	bottom, top := 0, 0
+	for scaleDegreeValue(scale, bottom) < int(low.value) {
+		bottom++
+	}
+	for scaleDegreeValue(scale, bottom-1) >= int(low.value) {
+		bottom--
+	}
+	for top = bottom; scaleDegreeValue(scale, top+1) <= int(high.value); top++ {
+	}
+	sequence := &DiatonicSequence{}
+	if scaleDegreeValue(scale, bottom) > int(high.value) {
+		return sequence
+	}
+	for start := bottom - lowest; start <= top-highest; start++ {
+		for _, offset := range offsets {
+			sequence.ascending = append(sequence.ascending, scaleDegree(scale, start+offset))
+		}
+	}
+	for start := top + lowest; start >= bottom+highest; start-- {
+		for _, offset := range offsets {
+			sequence.descending = append(sequence.descending, scaleDegree(scale, start-offset))
+		}
+	}
+	return sequence
+}
+
+// scaleDegree Returns the pitch of the note the given number of scale degrees above (positive) or below (negative) the root of the scale.
+// The pitch must be within the range of a pitch.
+func scaleDegree(scale *RootedPattern, degree int) Pitch {
+	octaves, within := scaleDegreeOctaves(scale, degree)
+	pitch := scale.root
+	for ; octaves > 0; octaves-- {
+		pitch.Transpose(scaleOctave(scale))
+	}
+	for ; octaves < 0; octaves++ {
+		pitch.Transpose(-scaleOctave(scale))
+	}
+	for i := 0; i < within; i++ {
+		pitch.Transpose(scale.pattern.At(i))
+	}
+	return pitch
+}
+
+// scaleDegreeValue Returns the value the pitch returned by scaleDegree would have, which may be outside the range of a pitch.
+func scaleDegreeValue(scale *RootedPattern, degree int) int {
+	octaves, within := scaleDegreeOctaves(scale, degree)
+	value := int(scale.root.value) + octaves*int(scaleOctave(scale))
+	for i := 0; i < within; i++ {
+		value += int(scale.pattern.At(i))
+	}
+	return value
+}
+
+// scaleDegreeOctaves Splits the given number of scale degrees from the root into whole repetitions of the scale and the degrees left over.
+func scaleDegreeOctaves(scale *RootedPattern, degree int) (octaves int, within int) {
+	length := scale.pattern.Length()
+	octaves, within = degree/length, degree%length
+	if within < 0 {
+		octaves, within = octaves-1, within+length
+	}
+	return
+}
+
+// scaleOctave Returns the span of one repetition of the scale, which is normally an octave.
+func scaleOctave(scale *RootedPattern) (span HalfSteps) {
+	for _, step := range scale.pattern.intervals {
+		span += step
+	}
+	return
+}
+
+// Sing Produces the next pitch of the sequence, ascending and then descending.
+func (s *DiatonicSequence) Sing() (pitch Pitch, more bool) {
+	if s.next >= len(s.ascending)+len(s.descending) {
+		return
+	}
+	s.next++
+	if s.next <= len(s.ascending) {
+		return s.ascending[s.next-1], true
+	}
+	return s.descending[s.next-1-len(s.ascending)], true
+}
+
+// Reset Returns to the first pitch of the ascending sequence.
+func (s *DiatonicSequence) Reset() {
+	s.next = 0
+}
+
+// Ascending Returns a singer of just the ascending sequence.
+func (s *DiatonicSequence) Ascending() *SliceSinger {
+	return MakeSliceSinger(s.ascending...)
+}
+
+// Descending Returns a singer of just the descending sequence.
+func (s *DiatonicSequence) Descending() *SliceSinger {
+	return MakeSliceSinger(s.descending...)
+}
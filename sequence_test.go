@@ -0,0 +1,80 @@
+package tonacity
+
+import "testing"
+
+func TestMakeDiatonicSequence(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	cMajor := MakeRootedPattern(CreateMajorScale(), *pf.GetPitch(C(), 4))
+	tests := []struct {
+		name           string
+		scale          *RootedPattern
+		motif          []int
+		low, high      Pitch
+		wantAscending  string
+		wantDescending string
+	}{
+		{
+			"C Major in thirds", cMajor, []int{1, 3}, *pf.GetPitch(C(), 4), *pf.GetPitch(C(), 5),
+			"C4 E4 D4 F4 E4 G4 F4 A4 G4 B4 A4 C5",
+			"C5 A4 B4 G4 A4 F4 G4 E4 F4 D4 E4 C4",
+		},
+		{
+			"Groups of four", cMajor, []int{1, 2, 3, 1}, *pf.GetPitch(C(), 4), *pf.GetPitch(G(), 4),
+			"C4 D4 E4 C4 D4 E4 F4 D4 E4 F4 G4 E4",
+			"G4 F4 E4 G4 F4 E4 D4 F4 E4 D4 C4 E4",
+		},
+		{
+			"Motif below its first note", cMajor, []int{1, -2}, *pf.GetPitch(A(), 3), *pf.GetPitch(D(), 4),
+			"B3 A3 C4 B3 D4 C4",
+			"C4 D4 B3 C4 A3 B3",
+		},
+		{
+			"Range between notes of the scale", MakeRootedPattern(CreateMinorScale(), *pf.GetPitch(A(), 2)), []int{1, 3}, *pf.GetPitch(A().Sharp(), 3), *pf.GetPitch(F().Sharp(), 4),
+			"B3 D4 C4 E4 D4 F4",
+			"F4 D4 E4 C4 D4 B3",
+		},
+		{
+			"Pentatonic over two octaves", MakeRootedPattern(CreateMajorPentatonicScalePattern(), *pf.GetPitch(G(), 3)), []int{1, 4}, *pf.GetPitch(G(), 3), *pf.GetPitch(G(), 4),
+			"G3 D4 A3 E4 B3 G4",
+			"G4 B3 E4 A3 D4 G3",
+		},
+		{
+			"Range too small for the motif", cMajor, []int{1, 5}, *pf.GetPitch(C(), 4), *pf.GetPitch(F(), 4),
+			"",
+			"",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sequence := MakeDiatonicSequence(tt.scale, tt.motif, tt.low, tt.high)
+			if got := sung(sequence.Ascending()); got != tt.wantAscending {
+				t.Errorf("Ascending() sang %v, want %v", got, tt.wantAscending)
+			}
+			if got := sung(sequence.Descending()); got != tt.wantDescending {
+				t.Errorf("Descending() sang %v, want %v", got, tt.wantDescending)
+			}
+			want := tt.wantAscending + " " + tt.wantDescending
+			if tt.wantAscending == "" {
+				want = tt.wantDescending
+			}
+			if got := sung(sequence); got != want {
+				t.Errorf("sang %v, want %v", got, want)
+			}
+			sequence.Reset()
+			if got := sung(sequence); got != want {
+				t.Errorf("sang %v after Reset(), want %v", got, want)
+			}
+		})
+	}
+	for _, motif := range [][]int{nil, {1, 0, 3}} {
+		if sequence := MakeDiatonicSequence(cMajor, motif, *pf.GetPitch(C(), 4), *pf.GetPitch(C(), 5)); sequence != nil {
+			t.Errorf("MakeDiatonicSequence() of motif %v should be nil", motif)
+		}
+	}
+	if sequence := MakeDiatonicSequence(MakeRootedPattern(CreateMelodicMinorDescendingScalePattern(), *pf.GetPitch(A(), 4)), []int{1, 3}, *pf.GetPitch(A(), 3), *pf.GetPitch(A(), 4)); sequence != nil {
+		t.Error("MakeDiatonicSequence() of a descending scale should be nil")
+	}
+	if sequence := MakeDiatonicSequence(cMajor, []int{1, 3}, *pf.GetPitch(C(), 5), *pf.GetPitch(C(), 4)); sequence != nil {
+		t.Error("MakeDiatonicSequence() of an upside down range should be nil")
+	}
+}
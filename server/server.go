@@ -0,0 +1,377 @@
+// Package server exposes the theory engine as an HTTP service that answers with JSON. Every endpoint is a GET taking its arguments as
+// query parameters, and is described by the OpenAPI document served at /openapi.json. Requests with missing, unknown or invalid
+// parameters are answered with 400 Bad Request and a JSON object holding the error, e.g. {"error": "invalid pitch \"H4\""}.
+package server
+
+import (
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"net/url"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/chris-franklin/tonacity"
+)
+
+//go:embed openapi.json
+var openAPI []byte
+
+const (
+	// maxTranspose The furthest anything may be transposed, in half steps.
+	maxTranspose = tonacity.OctaveValue * 4
+	// minConcert, maxConcert The range of frequencies accepted for A4, which takes in baroque and historical tunings.
+	minConcert, maxConcert = 380, 480
+	// minHertz, maxHertz The range of frequencies that can be converted to pitches, which is roughly that of human hearing.
+	minHertz, maxHertz = 20, 16000
+)
+
+// errorResponse The body of every response to a request that fails.
+type errorResponse struct {
+	Error string `json:"error"`
+}
+
+// badRequest An error caused by the request, rather than by the server.
+type badRequest struct {
+	message string
+}
+
+func (e *badRequest) Error() string {
+	return e.message
+}
+
+func invalid(format string, args ...interface{}) error {
+	return &badRequest{fmt.Sprintf(format, args...)}
+}
+
+// endpoint Answers a request from its validated query parameters.
+type endpoint struct {
+	params   []string // The parameters the endpoint accepts
+	required []string // The parameters that must be given
+	answer   func(query url.Values) (interface{}, error)
+}
+
+// NewHandler Returns a handler that serves the API.
+func NewHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/openapi.json", func(w http.ResponseWriter, r *http.Request) {
+		if checkMethod(w, r) {
+			w.Header().Set("Content-Type", "application/json")
+			w.Write(openAPI)
+		}
+	})
+	mux.Handle("/v1/chords/name", &endpoint{[]string{"notes", "flats"}, []string{"notes"}, nameChord})
+	mux.Handle("/v1/chords/realise", &endpoint{[]string{"symbol", "octave"}, []string{"symbol"}, realiseChord})
+	mux.Handle("/v1/scales", &endpoint{[]string{"root", "scale"}, []string{"root", "scale"}, spellScale})
+	mux.Handle("/v1/keys", &endpoint{[]string{"key"}, []string{"key"}, keySignature})
+	mux.Handle("/v1/transpose", &endpoint{[]string{"items", "by", "key"}, []string{"items", "by"}, transpose})
+	mux.Handle("/v1/frequency", &endpoint{[]string{"pitch", "hertz", "concert"}, nil, frequency})
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		writeJSON(w, http.StatusNotFound, errorResponse{fmt.Sprintf("no endpoint %s %s", r.Method, r.URL.Path)})
+	})
+	return mux
+}
+
+// checkMethod Answers with 405 Method Not Allowed, and returns false, unless the request is a GET.
+func checkMethod(w http.ResponseWriter, r *http.Request) bool {
+	if r.Method == http.MethodGet || r.Method == http.MethodHead {
+		return true
+	}
+	w.Header().Set("Allow", "GET, HEAD")
+	writeJSON(w, http.StatusMethodNotAllowed, errorResponse{fmt.Sprintf("method %s not allowed", r.Method)})
+	return false
+}
+
+func (e *endpoint) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if !checkMethod(w, r) {
+		return
+	}
+	query := r.URL.Query()
+	if err := e.validate(query); err != nil {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	}
+	result, err := e.answer(query)
+	var bad *badRequest
+	if errors.As(err, &bad) {
+		writeJSON(w, http.StatusBadRequest, errorResponse{err.Error()})
+		return
+	} else if err != nil {
+		writeJSON(w, http.StatusInternalServerError, errorResponse{err.Error()})
+		return
+	}
+	writeJSON(w, http.StatusOK, result)
+}
+
+// validate Checks that only known parameters are given, each no more than once, and that the required ones are there.
+func (e *endpoint) validate(query url.Values) error {
+	names := make([]string, 0, len(query))
+	for name := range query {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if !slices.Contains(e.params, name) {
+			return invalid("unknown parameter %q", name)
+		}
+		if len(query[name]) > 1 {
+			return invalid("parameter %q given more than once", name)
+		}
+	}
+	for _, name := range e.required {
+		if strings.TrimSpace(query.Get(name)) == "" {
+			return invalid("missing parameter %q", name)
+		}
+	}
+	return nil
+}
+
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// list Splits a parameter holding several values separated by commas or spaces.
+func list(value string) []string {
+	return strings.FieldsFunc(value, func(r rune) bool { return r == ',' || r == ' ' })
+}
+
+// boolParam Reads an optional true or false parameter, which is false if it isn't given.
+func boolParam(query url.Values, name string) (bool, error) {
+	if query.Get(name) == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(query.Get(name))
+	if err != nil {
+		return false, invalid("parameter %q must be true or false", name)
+	}
+	return b, nil
+}
+
+// intParam Reads an optional whole number parameter from min to max, returning otherwise if it isn't given.
+func intParam(query url.Values, name string, min int, max int, otherwise int) (int, error) {
+	if query.Get(name) == "" {
+		return otherwise, nil
+	}
+	n, err := strconv.Atoi(query.Get(name))
+	if err != nil || n < min || n > max {
+		return 0, invalid("parameter %q must be a whole number from %d to %d", name, min, max)
+	}
+	return n, nil
+}
+
+// hertzParam Reads an optional frequency parameter from min to max hertz, returning otherwise if it isn't given.
+func hertzParam(query url.Values, name string, min float64, max float64, otherwise float64) (float64, error) {
+	if query.Get(name) == "" {
+		return otherwise, nil
+	}
+	f, err := strconv.ParseFloat(query.Get(name), 64)
+	if err != nil || !(f >= min && f <= max) {
+		return 0, invalid("parameter %q must be from %v to %v hertz", name, min, max)
+	}
+	return f, nil
+}
+
+// parseNote Reads a note name, with or without an octave, as tonacity.ParseNoteName does.
+func parseNote(text string) (*tonacity.SpelledPitch, bool, error) {
+	sp, octave, err := tonacity.ParseNoteName(text)
+	if err != nil {
+		return nil, false, &badRequest{err.Error()}
+	}
+	return sp, octave, nil
+}
+
+// noteName Writes a spelled pitch with # and b, with or without its octave.
+func noteName(sp *tonacity.SpelledPitch, octave bool) string {
+	if !octave {
+		return sp.Name()
+	}
+	text, _ := sp.MarshalText()
+	return string(text)
+}
+
+func parseKey(text string) (*tonacity.KeySignature, error) {
+	key := new(tonacity.KeySignature)
+	if err := key.UnmarshalText([]byte(text)); err != nil {
+		return nil, &badRequest{err.Error()}
+	}
+	return key, nil
+}
+
+type chordName struct {
+	Notes []string `json:"notes"`
+	Name  string   `json:"name,omitempty"`
+	Known bool     `json:"known"`
+}
+
+// nameChord Names the chord made by the notes, as tonacity.NameNotes does.
+func nameChord(query url.Values) (interface{}, error) {
+	flats, err := boolParam(query, "flats")
+	if err != nil {
+		return nil, err
+	}
+	fields := list(query.Get("notes"))
+	if len(fields) < 2 {
+		return nil, invalid("a chord needs at least two notes")
+	}
+	named, err := tonacity.NameNotes(fields, flats)
+	if err != nil {
+		return nil, &badRequest{err.Error()}
+	}
+	result := &chordName{Notes: make([]string, len(named.Notes)), Name: named.Name, Known: named.Known}
+	for i := range named.Notes {
+		result.Notes[i] = noteName(&named.Notes[i], named.Octaves)
+	}
+	return result, nil
+}
+
+type realisedChord struct {
+	Symbol      string    `json:"symbol"`
+	Root        string    `json:"root"`
+	Bass        string    `json:"bass"`
+	Pitches     []string  `json:"pitches"`
+	Frequencies []float64 `json:"frequencies"`
+}
+
+// realiseChord Writes out the pitches of a chord symbol from the bass upwards, with the bass in the given octave.
+func realiseChord(query url.Values) (interface{}, error) {
+	octave, err := intParam(query, "octave", 0, 8, 4)
+	if err != nil {
+		return nil, err
+	}
+	chord, err := tonacity.ParseChordSymbol(strings.TrimSpace(query.Get("symbol")))
+	if err != nil {
+		return nil, &badRequest{err.Error()}
+	}
+	result := &realisedChord{Symbol: chord.String(), Root: chord.Root().String(), Bass: chord.Bass().String()}
+	for _, sp := range chord.Spell(octave) {
+		pitch := sp.Pitch()
+		result.Pitches = append(result.Pitches, noteName(&sp, true))
+		result.Frequencies = append(result.Frequencies, pitch.FrequencyInHertz(tonacity.StandardConcertPitch))
+	}
+	return result, nil
+}
+
+type scale struct {
+	Root    string            `json:"root"`
+	Scale   string            `json:"scale"`
+	Pattern *tonacity.Pattern `json:"pattern"`
+	Notes   []string          `json:"notes"`
+}
+
+// spellScale Writes out one octave of a scale or mode, with octaves if the root has one.
+func spellScale(query url.Values) (interface{}, error) {
+	root, octave, err := parseNote(strings.TrimSpace(query.Get("root")))
+	if err != nil {
+		return nil, err
+	}
+	name := strings.TrimSpace(query.Get("scale"))
+	pattern, ok := tonacity.FindScale(name)
+	if !ok {
+		return nil, invalid("unknown scale %q", name)
+	}
+	result := &scale{Root: noteName(root, octave), Scale: name, Pattern: pattern}
+	for _, sp := range tonacity.SpellScale(*root, pattern) {
+		result.Notes = append(result.Notes, noteName(&sp, octave))
+	}
+	return result, nil
+}
+
+type key struct {
+	Key         *tonacity.KeySignature `json:"key"`
+	Mode        string                 `json:"mode"`
+	Fifths      int                    `json:"fifths"`
+	Accidentals []string               `json:"accidentals"`
+	Notes       []string               `json:"notes"`
+}
+
+// keySignature Gives the sharps or flats of a key, in the order they are written, and the notes of its scale.
+func keySignature(query url.Values) (interface{}, error) {
+	k, err := parseKey(query.Get("key"))
+	if err != nil {
+		return nil, err
+	}
+	result := &key{Key: k, Mode: k.Mode(), Fifths: k.Fifths(), Accidentals: []string{}}
+	for _, sp := range k.Accidentals() {
+		result.Accidentals = append(result.Accidentals, sp.Name())
+	}
+	for _, sp := range k.Scale() {
+		result.Notes = append(result.Notes, sp.Name())
+	}
+	return result, nil
+}
+
+type transposition struct {
+	By   int                    `json:"by"`
+	Key  *tonacity.KeySignature `json:"key,omitempty"`
+	From []string               `json:"from"`
+	To   []string               `json:"to"`
+}
+
+// transpose Transposes chord symbols, or pitches with octaves, spelling the results for the key the music moves to, as
+// tonacity.TransposeItems does.
+func transpose(query url.Values) (interface{}, error) {
+	by, err := intParam(query, "by", -maxTranspose, maxTranspose, 0)
+	if err != nil {
+		return nil, err
+	}
+	var k *tonacity.KeySignature
+	if text := query.Get("key"); text != "" {
+		if k, err = parseKey(text); err != nil {
+			return nil, err
+		}
+	}
+	fields := list(query.Get("items"))
+	items, k, err := tonacity.TransposeItems(fields, tonacity.HalfSteps(by), k)
+	if err != nil {
+		return nil, &badRequest{err.Error()}
+	}
+	result := &transposition{By: by, Key: k, From: fields}
+	for _, item := range items {
+		if item.Chord != nil {
+			result.To = append(result.To, item.Chord.String())
+		} else {
+			result.To = append(result.To, noteName(item.Pitch, true))
+		}
+	}
+	return result, nil
+}
+
+type pitchFrequency struct {
+	Pitch   string  `json:"pitch"`
+	Hertz   float64 `json:"hertz"`
+	Cents   float64 `json:"cents"`
+	Concert float64 `json:"concert"`
+}
+
+// frequency Converts a pitch to its frequency, or a frequency to the nearest pitch and how many cents it is off by. Exactly one of the
+// two must be given.
+func frequency(query url.Values) (interface{}, error) {
+	concert, err := hertzParam(query, "concert", minConcert, maxConcert, tonacity.StandardConcertPitch)
+	if err != nil {
+		return nil, err
+	}
+	if (query.Get("pitch") == "") == (query.Get("hertz") == "") {
+		return nil, invalid("exactly one of the parameters \"pitch\" and \"hertz\" must be given")
+	}
+	if text := query.Get("pitch"); text != "" {
+		sp, octave, err := parseNote(text)
+		if err != nil || !octave {
+			return nil, invalid("invalid pitch %q", text)
+		}
+		pitch := sp.Pitch()
+		return &pitchFrequency{noteName(sp, true), pitch.FrequencyInHertz(concert), 0, concert}, nil
+	}
+	hertz, err := hertzParam(query, "hertz", minHertz, maxHertz, 0)
+	if err != nil {
+		return nil, err
+	}
+	pitch, cents := tonacity.NearestPitch(hertz, concert)
+	return &pitchFrequency{pitch.String(), hertz, math.Round(cents*100) / 100, concert}, nil
+}
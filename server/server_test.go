@@ -0,0 +1,112 @@
+package server
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+)
+
+func get(t *testing.T, handler http.Handler, target string) (int, map[string]interface{}) {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, target, nil))
+	if ct := rec.Header().Get("Content-Type"); ct != "application/json" {
+		t.Errorf("GET %s has content type %q", target, ct)
+	}
+	var body map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("GET %s: %v", target, err)
+	}
+	return rec.Code, body
+}
+
+func TestHandler(t *testing.T) {
+	handler := NewHandler()
+	tests := []struct {
+		target string
+		field  string
+		want   interface{}
+	}{
+		{"/v1/chords/name?notes=C,E,G,B", "name", "C Major Seventh"},
+		{"/v1/chords/name?notes=E4+G4+C5", "name", "C Major/E"},
+		{"/v1/chords/name?notes=A%23,D,F&flats=true", "name", "B♭ Major"},
+		{"/v1/chords/name?notes=C,C%23", "known", false},
+		{"/v1/chords/realise?symbol=C7", "pitches", []interface{}{"C4", "E4", "G4", "Bb4"}},
+		{"/v1/chords/realise?symbol=D/F%23&octave=3", "pitches", []interface{}{"F#3", "D4", "A4"}},
+		{"/v1/scales?root=D&scale=dorian", "notes", []interface{}{"D", "E", "F", "G", "A", "B", "C"}},
+		{"/v1/scales?root=Eb4&scale=Harmonic+Minor", "notes", []interface{}{"Eb4", "F4", "Gb4", "Ab4", "Bb4", "Cb5", "D5"}},
+		{"/v1/keys?key=Eb", "accidentals", []interface{}{"Bb", "Eb", "Ab"}},
+		{"/v1/keys?key=F%23m", "fifths", 3.0},
+		{"/v1/keys?key=D+dorian", "mode", "Dorian"},
+		{"/v1/transpose?items=Cmaj7,Am7,Dm7,G7&by=3", "to", []interface{}{"Ebmaj7", "Cm7", "Fm7", "Bb7"}},
+		{"/v1/transpose?items=Cmaj7,Am7&by=3", "key", "Eb"},
+		{"/v1/transpose?items=E+B7+G%234&by=-1&key=E", "to", []interface{}{"Eb", "Bb7", "G4"}},
+		{"/v1/transpose?items=Gb,Cb,B7&by=-1&key=Eb", "to", []interface{}{"F", "Bb", "Bb7"}},
+		{"/v1/frequency?pitch=A4&concert=432", "hertz", 432.0},
+		{"/v1/frequency?hertz=445", "pitch", "A4"},
+		{"/v1/frequency?hertz=445", "cents", 19.56},
+	}
+	for _, tt := range tests {
+		t.Run(tt.target, func(t *testing.T) {
+			code, body := get(t, handler, tt.target)
+			if code != http.StatusOK {
+				t.Fatalf("GET %s = %d %v", tt.target, code, body)
+			}
+			if got := body[tt.field]; !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GET %s has %s %v, want %v", tt.target, tt.field, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestHandler_BadRequests(t *testing.T) {
+	handler := NewHandler()
+	for _, target := range []string{
+		"/v1/chords/name",
+		"/v1/chords/name?notes=C",
+		"/v1/chords/name?notes=C,H",
+		"/v1/chords/name?notes=C,E&flats=maybe",
+		"/v1/chords/name?notes=C,E&colour=blue",
+		"/v1/chords/realise?symbol=Cfoo",
+		"/v1/chords/realise?symbol=C&octave=12",
+		"/v1/scales?root=C&scale=blues",
+		"/v1/scales?root=C&root=D&scale=major",
+		"/v1/keys?key=Fb",
+		"/v1/transpose?items=C&by=100",
+		"/v1/transpose?items=C,X&by=1",
+		"/v1/transpose?items=C",
+		"/v1/frequency",
+		"/v1/frequency?pitch=A4&hertz=440",
+		"/v1/frequency?pitch=A",
+		"/v1/frequency?hertz=-1",
+		"/v1/frequency?pitch=A4&concert=0",
+	} {
+		code, body := get(t, handler, target)
+		if code != http.StatusBadRequest || body["error"] == "" {
+			t.Errorf("GET %s = %d %v, want 400 with an error", target, code, body)
+		}
+	}
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/v1/keys?key=C", nil))
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Errorf("POST /v1/keys = %d, want %d", rec.Code, http.StatusMethodNotAllowed)
+	}
+	if code, _ := get(t, handler, "/v2/keys"); code != http.StatusNotFound {
+		t.Errorf("GET /v2/keys = %d, want %d", code, http.StatusNotFound)
+	}
+}
+
+func TestOpenAPI(t *testing.T) {
+	code, body := get(t, NewHandler(), "/openapi.json")
+	if code != http.StatusOK || body["openapi"] != "3.0.3" {
+		t.Fatalf("GET /openapi.json = %d", code)
+	}
+	paths := body["paths"].(map[string]interface{})
+	for _, path := range []string{"/v1/chords/name", "/v1/chords/realise", "/v1/scales", "/v1/keys", "/v1/transpose", "/v1/frequency"} {
+		if _, ok := paths[path]; !ok {
+			t.Errorf("OpenAPI description is missing %s", path)
+		}
+	}
+}
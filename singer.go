@@ -0,0 +1,304 @@
+package tonacity
+
+import "iter"
+
+// Combinators for building exercises out of singers, e.g. C major in broken thirds is the scale from C interleaved with the same notes
+// from E, i.e. the Phrygian mode:
+//
+//	Take(Interleave(CreateMajorScale().CreateAscendingSinger(c4), CreatePhrygianMode().CreateAscendingSinger(e4)), 16)
+//
+// Each combinator returns a new singer that sings from the singers it is given as it is sung, so those singers shouldn't be sung
+// from elsewhere at the same time. Resetting a combined singer resets the singers it is made of.
+
+// SliceSinger A singer of a fixed sequence of pitches, e.g. a melody, which stops after the last of them.
+type SliceSinger struct {
+	pitches []Pitch
+	next    int // The index of the pitch that will be sung next
+}
+
+// MakeSliceSinger Creates a singer of the given pitches, in order.
+func MakeSliceSinger(pitches ...Pitch) *SliceSinger {
+	return &SliceSinger{pitches: append([]Pitch(nil), pitches...)}
+}
+
+// Sing Produces the next of the pitches, until there are none left.
+func (s *SliceSinger) Sing() (pitch Pitch, more bool) {
+	if s.next >= len(s.pitches) {
+		return
+	}
+	s.next++
+	return s.pitches[s.next-1], true
+}
+
+// Reset Returns to the first of the pitches.
+func (s *SliceSinger) Reset() {
+	s.next = 0
+}
+
+// takeSinger Sings no more than a number of pitches from another singer.
+type takeSinger struct {
+	singer Singer
+	count  int
+	sung   int
+}
+
+// Take Returns a singer of at most the first count pitches sung by the given singer.
+func Take(singer Singer, count int) Singer {
+	return &takeSinger{singer: singer, count: count}
+}
+
+func (s *takeSinger) Sing() (pitch Pitch, more bool) {
+	if s.sung >= s.count {
+		return
+	}
+	if pitch, more = s.singer.Sing(); more {
+		s.sung++
+	}
+	return
+}
+
+func (s *takeSinger) Reset() {
+	s.singer.Reset()
+	s.sung = 0
+}
+
+// untilSinger Sings from another singer up to a pitch.
+type untilSinger struct {
+	singer Singer
+	until  Pitch
+	first  *Pitch // The first pitch sung, which gives the direction of travel towards until
+	done   bool
+}
+
+// Until Returns a singer of the pitches sung by the given singer up to and including the given pitch. If the singer steps over the pitch
+// without landing on it, as seen from the first pitch it sings, then the singer stops before the first pitch beyond it.
+func Until(singer Singer, pitch Pitch) Singer {
+	return &untilSinger{singer: singer, until: pitch}
+}
+
+func (s *untilSinger) Sing() (pitch Pitch, more bool) {
+	if s.done {
+		return
+	}
+	if pitch, more = s.singer.Sing(); !more {
+		s.done = true
+		return
+	}
+	if s.first == nil {
+		first := pitch
+		s.first = &first
+	}
+	if steppedOver(s.first, &s.until, &pitch) {
+		s.done = true
+		return Pitch{}, false
+	}
+	s.done = pitch.value == s.until.value
+	return
+}
+
+func (s *untilSinger) Reset() {
+	s.singer.Reset()
+	s.first = nil
+	s.done = false
+}
+
+// concatSinger Sings each of a number of singers to the end in turn.
+type concatSinger struct {
+	singers []Singer
+	current int // The index of the singer being sung
+}
+
+// Concat Returns a singer of everything sung by the first of the given singers, then everything sung by the second, and so on.
+func Concat(singers ...Singer) Singer {
+	return &concatSinger{singers: singers}
+}
+
+func (s *concatSinger) Sing() (pitch Pitch, more bool) {
+	for ; s.current < len(s.singers); s.current++ {
+		if pitch, more = s.singers[s.current].Sing(); more {
+			return
+		}
+	}
+	return
+}
+
+func (s *concatSinger) Reset() {
+	for _, singer := range s.singers {
+		singer.Reset()
+	}
+	s.current = 0
+}
+
+// interleaveSinger Sings a pitch from each of a number of singers in turn.
+type interleaveSinger struct {
+	singers []Singer
+	next    int // The index of the singer to sing from next
+	done    bool
+}
+
+// Interleave Returns a singer that takes a pitch from each of the given singers in turn, e.g. a scale and the same scale from a third
+// higher make the scale in broken thirds. It stops as soon as any of the singers does, so that every round is complete.
+func Interleave(singers ...Singer) Singer {
+	return &interleaveSinger{singers: singers, done: len(singers) == 0}
+}
+
+func (s *interleaveSinger) Sing() (pitch Pitch, more bool) {
+	if s.done {
+		return
+	}
+	if pitch, more = s.singers[s.next].Sing(); !more {
+		s.done = true
+		return
+	}
+	s.next = (s.next + 1) % len(s.singers)
+	return
+}
+
+func (s *interleaveSinger) Reset() {
+	for _, singer := range s.singers {
+		singer.Reset()
+	}
+	s.next = 0
+	s.done = len(s.singers) == 0
+}
+
+// Zip Returns a sequence of chords made by taking a pitch from each of the given singers at once, e.g. two scales a sixth apart give
+// the scale in sixths as dyads. The pitches of each chord are in the order of the singers, and the sequence ends when any singer stops.
+func Zip(singers ...Singer) iter.Seq[*Chord] {
+	return func(yield func(*Chord) bool) {
+		if len(singers) == 0 {
+			return
+		}
+		for {
+			pitches := make([]Pitch, len(singers))
+			for i, singer := range singers {
+				pitch, more := singer.Sing()
+				if !more {
+					return
+				}
+				pitches[i] = pitch
+			}
+			if !yield(MakeChord(pitches...)) {
+				return
+			}
+		}
+	}
+}
+
+// mapSinger Sings the pitches of another singer, altered by a function.
+type mapSinger struct {
+	singer Singer
+	f      func(Pitch) Pitch
+}
+
+// Map Returns a singer of the pitches sung by the given singer, each passed through the given function.
+func Map(singer Singer, f func(pitch Pitch) Pitch) Singer {
+	return &mapSinger{singer, f}
+}
+
+func (s *mapSinger) Sing() (pitch Pitch, more bool) {
+	if pitch, more = s.singer.Sing(); more {
+		pitch = s.f(pitch)
+	}
+	return
+}
+
+func (s *mapSinger) Reset() {
+	s.singer.Reset()
+}
+
+// Transpose Returns a singer of the pitches sung by the given singer, each transposed by the given number of half steps.
+func Transpose(singer Singer, halfSteps HalfSteps) Singer {
+	return Map(singer, func(pitch Pitch) Pitch {
+		pitch.Transpose(halfSteps)
+		return pitch
+	})
+}
+
+// Quantise Returns a singer of the pitches sung by the given singer, each moved to the nearest pitch with one of the classes produced by
+// the given scale, e.g. a key signature. A pitch exactly between two such pitches is moved down. If the scale is empty then nothing is moved.
+func Quantise(singer Singer, scale PitchClassProducer) Singer {
+	classes := scale.ProducePitchClasses()
+	inScale := func(pitch *Pitch) bool {
+		for _, class := range classes {
+			if pitch.class.HasSamePitchAs(class) {
+				return true
+			}
+		}
+		return false
+	}
+	return Map(singer, func(pitch Pitch) Pitch {
+		if len(classes) == 0 {
+			return pitch
+		}
+		for distance := HalfSteps(0); ; distance++ {
+			if lower := pitch.GetTransposedCopy(-distance); inScale(lower) {
+				return *lower
+			}
+			if higher := pitch.GetTransposedCopy(distance); inScale(higher) {
+				return *higher
+			}
+		}
+	})
+}
+
+// filterSinger Sings the pitches of another singer that pass a test.
+type filterSinger struct {
+	singer Singer
+	keep   func(Pitch) bool
+}
+
+// Filter Returns a singer of only those pitches sung by the given singer for which keep returns true. Filtering a singer that sings
+// forever will never finish if no more of its pitches are kept, so bound it first.
+func Filter(singer Singer, keep func(pitch Pitch) bool) Singer {
+	return &filterSinger{singer, keep}
+}
+
+func (s *filterSinger) Sing() (pitch Pitch, more bool) {
+	for {
+		if pitch, more = s.singer.Sing(); !more || s.keep(pitch) {
+			return
+		}
+	}
+}
+
+func (s *filterSinger) Reset() {
+	s.singer.Reset()
+}
+
+// loopSinger Sings another singer through repeatedly.
+type loopSinger struct {
+	singer Singer
+	times  int  // The number of times to sing it through, or zero for forever
+	loop   int  // The number of times it has been sung through
+	sang   bool // Whether it has sung anything since it was last reset
+}
+
+// Loop Returns a singer that sings everything the given singer sings, then resets it and sings it all again, the given number of times.
+// If times is zero or less then it loops forever, unless the singer sings nothing at all.
+func Loop(singer Singer, times int) Singer {
+	return &loopSinger{singer: singer, times: max(times, 0)}
+}
+
+func (s *loopSinger) Sing() (pitch Pitch, more bool) {
+	for s.times == 0 || s.loop < s.times {
+		if pitch, more = s.singer.Sing(); more {
+			s.sang = true
+			return
+		}
+		if !s.sang {
+			// Nothing to repeat
+			return
+		}
+		s.loop++
+		s.singer.Reset()
+		s.sang = false
+	}
+	return
+}
+
+func (s *loopSinger) Reset() {
+	s.singer.Reset()
+	s.loop = 0
+	s.sang = false
+}
@@ -0,0 +1,94 @@
+package tonacity
+
+import (
+	"strings"
+	"testing"
+)
+
+// sung Names the pitches the given singer sings, separated by spaces.
+func sung(singer Singer) string {
+	var names []string
+	for pitch := range Pitches(singer) {
+		names = append(names, pitch.String())
+	}
+	return strings.Join(names, " ")
+}
+
+func TestSingerCombinators(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	major := CreateMajorScale()
+	scale := func(class *PitchClass, octave int) *PatternRepeatingSinger {
+		return major.CreateAscendingSinger(*pf.GetPitch(class, octave))
+	}
+	melody := func() *SliceSinger {
+		return MakeSliceSinger(*pf.GetPitch(C(), 4), *pf.GetPitch(F().Sharp(), 4), *pf.GetPitch(A().Sharp(), 4), *pf.GetPitch(D(), 5))
+	}
+	tests := []struct {
+		name   string
+		singer Singer
+		want   string
+	}{
+		{"Slice", melody(), "C4 F#4 A#4 D5"},
+		{"Take", Take(scale(C(), 4), 3), "C4 D4 E4"},
+		{"Take more than there are", Take(melody(), 10), "C4 F#4 A#4 D5"},
+		{"Until", Until(scale(G(), 3), *pf.GetPitch(D(), 4)), "G3 A3 B3 C4 D4"},
+		{"Until descending, stepped over", Until(major.CreateDescendingSinger(*pf.GetPitch(C(), 5)), *pf.GetPitch(G().Sharp(), 4)), "C5 B4 A4"},
+		{"Concat", Concat(Take(scale(C(), 4), 3), melody(), Take(scale(G(), 2), 2)), "C4 D4 E4 C4 F#4 A#4 D5 G2 A2"},
+		{"Interleave in broken thirds", Take(Interleave(scale(C(), 4), CreatePhrygianMode().CreateAscendingSinger(*pf.GetPitch(E(), 4))), 8), "C4 E4 D4 F4 E4 G4 F4 A4"},
+		{"Interleave stops with the first to stop", Interleave(scale(C(), 4), melody()), "C4 C4 D4 F#4 E4 A#4 F4 D5 G4"},
+		{"Interleave nothing", Interleave(), ""},
+		{"Transpose", Transpose(melody(), -12), "C3 F#3 A#3 D4"},
+		{"Map", Map(melody(), func(pitch Pitch) Pitch { return *pitch.GetTransposedCopy(OctaveValue) }), "C5 F#5 A#5 D6"},
+		{"Filter", Filter(Take(CreateChromaticScalePattern().CreateAscendingSinger(*pf.GetPitch(C(), 4)), 12), func(pitch Pitch) bool {
+			return pitch.class.HasSamePitchAs(C()) || pitch.class.HasSamePitchAs(G())
+		}), "C4 G4"},
+		{"Loop", Loop(Take(scale(C(), 4), 3), 3), "C4 D4 E4 C4 D4 E4 C4 D4 E4"},
+		{"Loop forever", Take(Loop(melody(), 0), 6), "C4 F#4 A#4 D5 C4 F#4"},
+		{"Loop nothing forever", Loop(MakeSliceSinger(), 0), ""},
+		{"Quantise", Quantise(melody(), MakeKeySignature(0, false)), "C4 F4 A4 D5"},
+		{"Quantise to F major", Quantise(CreateChromaticScalePattern().CreateAscendingSinger(*pf.GetPitch(C(), 4)).OneOctave(), MakeKeySignature(-1, false)), "C4 C4 D4 D4 E4 F4 F4 G4 G4 A4 A#4 A#4 C5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := sung(tt.singer); got != tt.want {
+				t.Errorf("sung %v, want %v", got, tt.want)
+			}
+			tt.singer.Reset()
+			if got := sung(tt.singer); got != tt.want {
+				t.Errorf("sung %v after Reset(), want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestZip(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	major := CreateMajorScale()
+	lower := major.CreateAscendingSinger(*pf.GetPitch(C(), 4)).OneOctave()
+	upper := major.CreateAscendingSinger(*pf.GetPitch(A(), 4))
+	var chords []string
+	for chord := range Zip(lower, Transpose(upper, -1)) {
+		chords = append(chords, chord.String())
+		if len(chords) == 3 {
+			break
+		}
+	}
+	if got, want := strings.Join(chords, ", "), "C4 G#4, D4 A#4, E4 C5"; got != want {
+		t.Errorf("Zip() = %v, want %v", got, want)
+	}
+	lower.Reset()
+	upper.Reset()
+	count := 0
+	for chord := range Zip(lower, upper) {
+		if len(chord.Pitches()) != 2 {
+			t.Fatalf("Zip() gave %v, want dyads", chord.String())
+		}
+		count++
+	}
+	if count != 8 {
+		t.Errorf("Zip() gave %d dyads, want 8 as the shortest singer sings 8 pitches", count)
+	}
+	for range Zip() {
+		t.Fatal("Zip() of nothing should give nothing")
+	}
+}
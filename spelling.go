@@ -0,0 +1,325 @@
+package tonacity
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Writing a pitch down means choosing which of the seven letters to write it as, and how far the letter is raised or lowered. The same
+// pitch can be spelled several ways (A♯4, B♭4, C𝄫5), and which is right depends on the key it's in.
+
+// Letter The letter name of one of the seven natural tones, in order from C.
+type Letter uint8
+
+const (
+	// LetterC The letter C.
+	LetterC Letter = iota
+	// LetterD The letter D.
+	LetterD
+	// LetterE The letter E.
+	LetterE
+	// LetterF The letter F.
+	LetterF
+	// LetterG The letter G.
+	LetterG
+	// LetterA The letter A.
+	LetterA
+	// LetterB The letter B.
+	LetterB
+)
+
+// LettersInOctave The number of letters before they repeat an octave higher.
+const LettersInOctave = 7
+
+var letterNames = [LettersInOctave]string{"C", "D", "E", "F", "G", "A", "B"}
+
+// naturalValues The pitch class values of the natural tones, by letter.
+var naturalValues = [LettersInOctave]HalfSteps{0, 2, 4, 5, 7, 9, 11}
+
+func (l Letter) String() string {
+	return letterNames[l%LettersInOctave]
+}
+
+// Natural The pitch class of the natural tone with this letter.
+func (l Letter) Natural() PitchClass {
+	return PitchClass{naturalValues[l%LettersInOctave]}
+}
+
+// ParseLetter Returns the letter with the given (upper or lower case) name. The bool will be false if it isn't one of A to G.
+func ParseLetter(name byte) (Letter, bool) {
+	i := strings.IndexByte("CDEFGAB", name&^0x20)
+	if i < 0 {
+		return 0, false
+	}
+	return Letter(i), true
+}
+
+// accidentalSymbols The symbols for alterations from -2 (double flat) to 2 (double sharp).
+var accidentalSymbols = [5]string{"𝄫", "♭", "♮", "♯", "𝄪"}
+
+// AccidentalSymbol Returns the symbol that alters a letter by the given number of half steps, e.g. "♭" for -1, or "♮" for 0.
+func AccidentalSymbol(alter int) string {
+	if alter < -2 || alter > 2 {
+		return fmt.Sprintf("(%+d)", alter)
+	}
+	return accidentalSymbols[alter+2]
+}
+
+// SpelledPitch A pitch along with the letter it is written as. The letter can't be worked out from the pitch alone: B♭4 and A♯4 are
+// the same pitch spelled differently.
+type SpelledPitch struct {
+	pitch  Pitch
+	letter Letter
+}
+
+// MakeSpelledPitch Creates the pitch written as the given letter, raised (positive) or lowered (negative) by the given number of half
+// steps, in the given octave. Octaves are numbered from the letter, so C♭4 is the B just below middle C.
+func MakeSpelledPitch(letter Letter, alter int, octave int) *SpelledPitch {
+	pf := &PitchFactory{*MiddleC()}
+	natural := letter.Natural()
+	p := pf.GetPitch(&natural, octave)
+	p.Transpose(HalfSteps(alter))
+	return &SpelledPitch{*p, letter}
+}
+
+// Pitch The pitch that sounds.
+func (sp *SpelledPitch) Pitch() Pitch {
+	return sp.pitch
+}
+
+// Letter The letter the pitch is written as.
+func (sp *SpelledPitch) Letter() Letter {
+	return sp.letter
+}
+
+// Alter The number of half steps the letter is raised (positive) or lowered (negative) by, e.g. 1 for F♯.
+func (sp *SpelledPitch) Alter() int {
+	natural := sp.letter.Natural()
+	alter := int(sp.pitch.class.value - natural.value)
+	// The letter and the pitch class are never more than a few half steps apart, so anything larger has wrapped around an octave
+	if alter > OctaveValue/2 {
+		alter -= OctaveValue
+	} else if alter < -OctaveValue/2 {
+		alter += OctaveValue
+	}
+	return alter
+}
+
+// Octave The octave the pitch is written in, which is the octave of its letter and not of the pitch itself, e.g. 4 for C♭4.
+func (sp *SpelledPitch) Octave() int {
+	natural := sp.pitch.GetTransposedCopy(HalfSteps(-sp.Alter()))
+	return int(natural.Octave(MiddleC()))
+}
+
+// Step The number of letters this pitch is above C0, which is what determines where it sits on a stave.
+func (sp *SpelledPitch) Step() int {
+	return sp.Octave()*LettersInOctave + int(sp.letter)
+}
+
+// Transpose Moves the pitch by the given number of half steps while keeping the same letter, e.g. transposing F♯ by 1 gives F𝄪.
+func (sp *SpelledPitch) Transpose(halfSteps HalfSteps) {
+	sp.pitch.Transpose(halfSteps)
+}
+
+func (sp *SpelledPitch) String() string {
+	name := sp.letter.String()
+	if alter := sp.Alter(); alter != 0 {
+		name += AccidentalSymbol(alter)
+	}
+	return fmt.Sprintf("%s%d", name, sp.Octave())
+}
+
+// Name Returns the letter and accidental of the pitch without its octave, with accidentals written as # and b, e.g. "F#" or "Bb".
+func (sp *SpelledPitch) Name() string {
+	return chordSymbolNote(sp)
+}
+
+// Orders in which sharps and flats are added to key signatures
+var (
+	sharpOrder = [LettersInOctave]Letter{LetterF, LetterC, LetterG, LetterD, LetterA, LetterE, LetterB}
+	flatOrder  = [LettersInOctave]Letter{LetterB, LetterE, LetterA, LetterD, LetterG, LetterC, LetterF}
+)
+
+// Accidentals Returns the sharps or flats of this key signature in the order they are written, in octave 4, e.g. F♯ C♯ for D Major.
+func (k *KeySignature) Accidentals() []SpelledPitch {
+	order, alter := sharpOrder, 1
+	if k.fifths < 0 {
+		order, alter = flatOrder, -1
+	}
+	accidentals := make([]SpelledPitch, 0, abs(int(k.fifths)))
+	for _, letter := range order[:abs(int(k.fifths))] {
+		accidentals = append(accidentals, *MakeSpelledPitch(letter, alter, 4))
+	}
+	return accidentals
+}
+
+// Alter Returns the number of half steps the given letter is raised or lowered by in this key signature, e.g. 1 for F in G Major.
+// A nil key signature is taken as having no sharps or flats.
+func (k *KeySignature) Alter(letter Letter) int {
+	if k == nil {
+		return 0
+	}
+	for i := 0; i < int(k.fifths); i++ {
+		if sharpOrder[i] == letter {
+			return 1
+		}
+	}
+	for i := 0; i < -int(k.fifths); i++ {
+		if flatOrder[i] == letter {
+			return -1
+		}
+	}
+	return 0
+}
+
+// SpellPitch Chooses how to write the given pitch in the given key. Notes in the key are written as the key signature writes them. The
+// leading note of a minor key is always written as the seventh letter raised, e.g. G♯ in A Minor. Other notes outside the key are
+// written with as small an accidental as possible, preferring sharps in sharp keys and flats in flat keys. A nil key is taken to be
+// C Major.
+func SpellPitch(p Pitch, key *KeySignature) SpelledPitch {
+	value := p.class.value
+	diatonic := func(letter Letter) HalfSteps {
+		return normalisePitchClassValue(naturalValues[letter] + HalfSteps(key.Alter(letter)))
+	}
+	for letter := LetterC; letter <= LetterB; letter++ {
+		if diatonic(letter) == value {
+			return SpelledPitch{p, letter}
+		}
+	}
+	if key != nil && key.IsMinor() && normalisePitchClassValue(key.tonic.value-HalfStepValue) == value {
+		for letter := LetterC; letter <= LetterB; letter++ {
+			if diatonic(letter) == key.tonic.value {
+				return SpelledPitch{p, (letter + LettersInOctave - 1) % LettersInOctave}
+			}
+		}
+	}
+	preferred := 1
+	if key != nil && key.fifths < 0 {
+		preferred = -1
+	}
+	best, bestAlter := LetterC, OctaveValue
+	for letter := LetterC; letter <= LetterB; letter++ {
+		sp := SpelledPitch{p, letter}
+		alter := sp.Alter()
+		if abs(alter) < abs(bestAlter) || (abs(alter) == abs(bestAlter) && alter == preferred) {
+			best, bestAlter = letter, alter
+		}
+	}
+	return SpelledPitch{p, best}
+}
+
+// SpellScale Writes out one octave of the scale with the given pattern, ascending from the given root. Scales of seven notes use each
+// letter once, e.g. D Dorian is D E F G A B C and G♯ Major has F𝄪. Other scales are spelled as in the major or minor key of the root,
+// depending on whether the scale has a minor third.
+func SpellScale(root SpelledPitch, pattern *Pattern) []SpelledPitch {
+	notes := make([]SpelledPitch, 0, pattern.Length())
+	current := root
+	if pattern.Length() == NotesInMode {
+		for i := 0; i < pattern.Length(); i++ {
+			notes = append(notes, current)
+			current.pitch.Transpose(pattern.At(i))
+			current.letter = (current.letter + 1) % LettersInOctave
+		}
+		return notes
+	}
+	mode := "Ionian"
+	var above HalfSteps
+	for i := 0; i < pattern.Length(); i++ {
+		if above == MinorThird {
+			mode = "Aeolian"
+		}
+		above += pattern.At(i)
+	}
+	key := MakeModalKeySignature(root.letter, root.Alter(), mode)
+	for i := 0; i < pattern.Length(); i++ {
+		if i == 0 {
+			notes = append(notes, root)
+		} else {
+			notes = append(notes, SpellPitch(current.pitch, key))
+		}
+		current.pitch.Transpose(pattern.At(i))
+	}
+	return notes
+}
+
+// scaleDegreeLetters The number of letters above the tonic that a pitch is usually written, by how many half steps above the tonic it
+// is, e.g. a minor sixth (8) is five letters up, as A♭ is in C Major, and the tritone (6) is an augmented fourth.
+var scaleDegreeLetters = [OctaveValue]Letter{0, 1, 1, 2, 2, 3, 3, 4, 5, 5, 6, 6}
+
+// usualInterval Whether a pitch the given number of letters and half steps above the tonic is a perfect, major or minor interval, or
+// the tritone written as an augmented fourth or diminished fifth.
+func usualInterval(letters Letter, above HalfSteps) bool {
+	diff := normalisePitchClassValue(above - naturalValues[letters])
+	if diff > OctaveValue/2 {
+		diff -= OctaveValue
+	}
+	switch letters {
+	case 0:
+		return diff == 0
+	case 3:
+		return diff == 0 || diff == 1
+	default:
+		return diff == 0 || diff == -1
+	}
+}
+
+// TransposeInKey Moves a pitch in the given key by the given number of half steps, writing it for the key moved by the same amount. The
+// pitch keeps its place relative to the tonic: it is written as many letters above the new tonic as it was above the old one, so A♭ in
+// C Major moved up a whole step is B♭ rather than A♯. A pitch written an augmented or diminished interval above the tonic, other than the
+// tritone, is first taken as the usual interval, e.g. B in E♭ Major as C♭. A nil key is taken to be C Major.
+func TransposeInKey(sp SpelledPitch, halfSteps HalfSteps, key *KeySignature) SpelledPitch {
+	if key == nil {
+		key = MakeKeySignature(0, false)
+	}
+	to := *key
+	to.Transpose(halfSteps)
+	from, moved := SpellPitch(Pitch{key.tonic, key.tonic.value}, key), SpellPitch(Pitch{to.tonic, to.tonic.value}, &to)
+	letters := (sp.letter + LettersInOctave - from.letter) % LettersInOctave
+	if above := normalisePitchClassValue(sp.pitch.class.value - from.pitch.class.value); !usualInterval(letters, above) {
+		letters = scaleDegreeLetters[above]
+	}
+	pitch := sp.pitch
+	pitch.Transpose(halfSteps)
+	return SpelledPitch{pitch, (moved.letter + letters) % LettersInOctave}
+}
+
+// Scale Writes out one octave of the scale of this key, ascending from its tonic in octave 4, e.g. E F♯ G A B C D for E Minor.
+func (k *KeySignature) Scale() []SpelledPitch {
+	tonic := SpellPitch(Pitch{k.tonic, k.tonic.value}, k)
+	pattern, _ := FindScale(k.Mode())
+	return SpellScale(*MakeSpelledPitch(tonic.letter, tonic.Alter(), 4), pattern)
+}
+
+func abs(v int) int {
+	if v < 0 {
+		return -v
+	}
+	return v
+}
+
+// accidentalTracker Works out which accidentals need writing in front of notes as a bar is written out. An accidental is needed when
+// a note's alteration differs from the key signature, or from an earlier accidental on the same line or space in the same bar.
+type accidentalTracker struct {
+	key     *KeySignature
+	altered map[int]int // Alteration in force at each step, from accidentals earlier in the bar
+}
+
+func newAccidentalTracker(key *KeySignature) *accidentalTracker {
+	return &accidentalTracker{key, make(map[int]int)}
+}
+
+// accidental Returns the alteration to write in front of the given pitch, and false if none is needed.
+func (t *accidentalTracker) accidental(sp *SpelledPitch) (alter int, needed bool) {
+	alter = sp.Alter()
+	current, ok := t.altered[sp.Step()]
+	if !ok {
+		current = t.key.Alter(sp.letter)
+	}
+	t.altered[sp.Step()] = alter
+	return alter, alter != current
+}
+
+// barline Forgets accidentals, as they only last until the end of a bar.
+func (t *accidentalTracker) barline() {
+	t.altered = make(map[int]int)
+}
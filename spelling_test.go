@@ -0,0 +1,177 @@
+package tonacity
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestSpellPitch(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	tests := []struct {
+		name  string
+		pitch *Pitch
+		key   *KeySignature
+		want  string
+	}{
+		{"C in C Major", pf.GetPitch(C(), 4), MakeKeySignature(0, false), "C4"},
+		{"C♯ in C Major", pf.GetPitch(C().Sharp(), 4), MakeKeySignature(0, false), "C♯4"},
+		{"No key", pf.GetPitch(A().Sharp(), 4), nil, "A♯4"},
+		{"B♭ in F Major", pf.GetPitch(B().Flat(), 4), MakeKeySignature(-1, false), "B♭4"},
+		{"E♭ in F Major", pf.GetPitch(E().Flat(), 4), MakeKeySignature(-1, false), "E♭4"},
+		{"Leading note of D Minor", pf.GetPitch(C().Sharp(), 5), MakeKeySignature(-1, true), "C♯5"},
+		{"F in B Major", pf.GetPitch(F(), 4), MakeKeySignature(5, false), "F4"},
+		{"D in E Major", pf.GetPitch(D(), 4), MakeKeySignature(4, false), "D4"},
+		{"D♭ in A♭ Major", pf.GetPitch(D().Flat(), 4), MakeKeySignature(-4, false), "D♭4"},
+		{"G♯ in A Minor", pf.GetPitch(G().Sharp(), 4), MakeKeySignature(0, true), "G♯4"},
+		{"C♭ in G♭ Major", pf.GetPitch(B(), 3), MakeKeySignature(-6, false), "C♭4"},
+		{"B♯ in C♯ Major", pf.GetPitch(C(), 5), MakeKeySignature(7, false), "B♯4"},
+		{"F𝄪 in G♯ Minor", pf.GetPitch(G(), 4), MakeKeySignature(5, true), "F𝄪4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := SpellPitch(*tt.pitch, tt.key)
+			if got.String() != tt.want {
+				t.Errorf("SpellPitch() = %v, want %v", got.String(), tt.want)
+			}
+			if p := got.Pitch(); p.GetDistanceTo(tt.pitch) != 0 {
+				t.Errorf("SpellPitch() changed the pitch by %d half steps", p.GetDistanceTo(tt.pitch))
+			}
+		})
+	}
+}
+
+func TestMakeSpelledPitch(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	tests := []struct {
+		name       string
+		letter     Letter
+		alter      int
+		octave     int
+		wantPitch  *Pitch
+		wantString string
+	}{
+		{"Middle C", LetterC, 0, 4, MiddleC(), "C4"},
+		{"C♭4", LetterC, -1, 4, pf.GetPitch(B(), 3), "C♭4"},
+		{"B♯3", LetterB, 1, 3, MiddleC(), "B♯3"},
+		{"A𝄫4", LetterA, -2, 4, pf.GetPitch(G(), 4), "A𝄫4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			sp := MakeSpelledPitch(tt.letter, tt.alter, tt.octave)
+			if p := sp.Pitch(); p.GetDistanceTo(tt.wantPitch) != 0 {
+				t.Errorf("MakeSpelledPitch() is %d half steps from the expected pitch", p.GetDistanceTo(tt.wantPitch))
+			}
+			if sp.Alter() != tt.alter || sp.Octave() != tt.octave || sp.String() != tt.wantString {
+				t.Errorf("MakeSpelledPitch() = %v (alter %d, octave %d), want %v", sp, sp.Alter(), sp.Octave(), tt.wantString)
+			}
+		})
+	}
+}
+
+func TestAccidentalTracker(t *testing.T) {
+	tracker := newAccidentalTracker(MakeKeySignature(1, false))
+	tests := []struct {
+		name   string
+		pitch  *SpelledPitch
+		want   int
+		needed bool
+	}{
+		{"F♯ is in the key", MakeSpelledPitch(LetterF, 1, 4), 1, false},
+		{"F needs a natural", MakeSpelledPitch(LetterF, 0, 4), 0, true},
+		{"F natural lasts the bar", MakeSpelledPitch(LetterF, 0, 4), 0, false},
+		{"Other octaves are unaffected", MakeSpelledPitch(LetterF, 1, 5), 1, false},
+		{"B♭ needs a flat", MakeSpelledPitch(LetterB, -1, 4), -1, true},
+	}
+	for _, tt := range tests {
+		if alter, needed := tracker.accidental(tt.pitch); alter != tt.want || needed != tt.needed {
+			t.Errorf("%s: accidental() = %d, %v, want %d, %v", tt.name, alter, needed, tt.want, tt.needed)
+		}
+	}
+	tracker.barline()
+	if _, needed := tracker.accidental(MakeSpelledPitch(LetterF, 1, 4)); needed {
+		t.Error("accidental() after a barline should have forgotten the F natural")
+	}
+}
+
+func TestSpellScale(t *testing.T) {
+	tests := []struct {
+		name    string
+		root    *SpelledPitch
+		pattern *Pattern
+		want    string
+	}{
+		{"D Dorian", MakeSpelledPitch(LetterD, 0, 4), CreateDorianMode(), "D4 E4 F4 G4 A4 B4 C5"},
+		{"G♯ Major", MakeSpelledPitch(LetterG, 1, 3), CreateMajorScale(), "G♯3 A♯3 B♯3 C♯4 D♯4 E♯4 F𝄪4"},
+		{"E♭ Harmonic Minor", MakeSpelledPitch(LetterE, -1, 4), CreateHarmonicMinorScalePattern(), "E♭4 F4 G♭4 A♭4 B♭4 C♭5 D5"},
+		{"E♭ Pentatonic Minor", MakeSpelledPitch(LetterE, -1, 4), CreateMinorPentatonicScalePattern(), "E♭4 G♭4 A♭4 B♭4 D♭5"},
+		{"F Pentatonic Major", MakeSpelledPitch(LetterF, 0, 4), CreateMajorPentatonicScalePattern(), "F4 G4 A4 C5 D5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			notes := SpellScale(*tt.root, tt.pattern)
+			names := make([]string, len(notes))
+			for i := range notes {
+				names[i] = notes[i].String()
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("SpellScale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySignature_Accidentals(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"C", ""},
+		{"D", "F# C#"},
+		{"C#", "F# C# G# D# A# E# B#"},
+		{"Eb", "Bb Eb Ab"},
+		{"Dm", "Bb"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			var key KeySignature
+			if err := key.UnmarshalText([]byte(tt.key)); err != nil {
+				t.Fatal(err)
+			}
+			var names []string
+			for _, sp := range key.Accidentals() {
+				names = append(names, sp.Name())
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("Accidentals() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestKeySignature_Scale(t *testing.T) {
+	tests := []struct {
+		key  string
+		want string
+	}{
+		{"C", "C4 D4 E4 F4 G4 A4 B4"},
+		{"Em", "E4 F♯4 G4 A4 B4 C5 D5"},
+		{"Cb", "C♭4 D♭4 E♭4 F♭4 G♭4 A♭4 B♭4"},
+		{"D dorian", "D4 E4 F4 G4 A4 B4 C5"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.key, func(t *testing.T) {
+			var key KeySignature
+			if err := key.UnmarshalText([]byte(tt.key)); err != nil {
+				t.Fatal(err)
+			}
+			notes := key.Scale()
+			names := make([]string, len(notes))
+			for i := range notes {
+				names[i] = notes[i].String()
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("Scale() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
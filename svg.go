@@ -0,0 +1,459 @@
+package tonacity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Staves are drawn as SVG images using only lines, ellipses and paths, so no music font is needed to show them. Everything is measured
+// in staff spaces, the distance between two lines of the stave, with the top line at y = 0 and the bottom line at y = 4. The image is
+// scaled so a staff space is svgSpace pixels. Each element has a class saying what it is, e.g. "notehead" or "ledger", so it can be
+// styled with CSS.
+
+// svgSpace The size of a staff space in pixels.
+const svgSpace = 10
+
+// svgStemLength How far a stem reaches past the last notehead, in staff spaces.
+const svgStemLength = 3.5
+
+// Glyphs drawn as stroked paths, centred on (0, 0) unless said otherwise, in staff spaces.
+var (
+	// svgTrebleClef The G clef, with (0, 0) on the line G4 is written on.
+	svgTrebleClef = "M0 2.7C-0.6 2.9-1 2.2-0.4 2M0 2.7L0.3-3.5C0.5-4.5 1.2-4.3 0.9-3.4C0.6-2.5-0.9-1.6-0.9-0.3" +
+		"C-0.9 1 1.2 1 1.2 0C1.2-0.9-0.1-0.9 0 0"
+	// svgBassClef The F clef, with (0, 0) on the line F3 is written on.
+	svgBassClef = "M-0.5 0.1C-0.6-0.9 1.2-1 1.3 0.2C1.4 1.4 0.2 2.4-0.8 2.8"
+	// svgCClef The C clef, with (0, 0) on the line middle C is written on.
+	svgCClef = "M0-2V2M0.6-2V2M0.8-0.1C1.3-0.1 1.2-1.9 1.9-1.9C2.5-1.9 2.5-0.9 2-0.9" +
+		"M0.8 0.1C1.3 0.1 1.2 1.9 1.9 1.9C2.5 1.9 2.5 0.9 2 0.9"
+	// svgAccidentals Glyphs for each alteration from a double flat to a double sharp.
+	svgAccidentals = map[int]string{
+		-2: "M-0.7-1.8V0.5C0.2 0-0.2-0.7-0.7-0.2M-0.1-1.8V0.5C0.8 0 0.4-0.7-0.1-0.2",
+		-1: "M-0.3-1.8V0.5C0.6 0 0.2-0.7-0.3-0.2",
+		0:  "M-0.3-1.3V0.5L0.3 0.3M0.3 1.3V-0.5L-0.3-0.3",
+		1:  "M-0.25-1V1.3M0.25-1.3V1M-0.55-0.25L0.55-0.55M-0.55 0.55L0.55 0.25",
+		2:  "M-0.4-0.4L0.4 0.4M-0.4 0.4L0.4-0.4",
+	}
+	// svgDigits Digits for time signatures, each in a box 1.2 wide and 2 high with (0, 0) at its top left.
+	svgDigits = [10]string{
+		"M0.6 0C1.3 0 1.3 2 0.6 2C-0.1 2-0.1 0 0.6 0Z",
+		"M0.3 0.4L0.7 0V2M0.3 2H1.1",
+		"M0.1 0.5C0.2-0.15 1.1-0.15 1.1 0.55C1.1 1.1 0.1 1.4 0.1 2H1.1",
+		"M0.1 0.3C0.4-0.1 1.1-0.05 1.1 0.5C1.1 0.9 0.7 1 0.5 1C0.9 1 1.2 1.2 1.1 1.5C1.1 2.1 0.3 2.1 0.05 1.7",
+		"M0.9 2V0L0.05 1.4H1.2",
+		"M1.05 0H0.2L0.15 0.9C0.5 0.7 1.15 0.8 1.15 1.4C1.15 2.1 0.3 2.1 0.05 1.75",
+		"M1 0.15C0.4-0.2 0.1 0.5 0.1 1.3C0.1 2.1 1.1 2.1 1.1 1.35C1.1 0.7 0.3 0.7 0.1 1.2",
+		"M0.05 0H1.15C0.7 0.6 0.45 1.2 0.4 2",
+		"M0.6 1C0.1 1 0.15 0 0.6 0C1.05 0 1.1 1 0.6 1C0 1 0 2 0.6 2C1.2 2 1.2 1 0.6 1Z",
+		"M1.1 0.8C0.9 1.3 0.1 1.3 0.1 0.65C0.1-0.1 1.1-0.1 1.1 0.7C1.1 1.5 0.8 2.2 0.2 1.85",
+	}
+	// svgQuarterRest A quarter rest, with (0, 0) at its top left.
+	svgQuarterRest = "M0.3 0.6L1 1.4L0.5 2L1.1 2.8C0.4 2.5 0.2 3 0.7 3.4"
+)
+
+// Where the sharps and flats of key signatures are written on the treble stave, in steps above the bottom line, in the order they are
+// added. Other clefs move them up or down to keep them on the stave.
+var (
+	svgSharpSteps = [LettersInOctave]int{8, 5, 9, 6, 3, 7, 4}
+	svgFlatSteps  = [LettersInOctave]int{4, 7, 3, 6, 2, 5, 1}
+)
+
+// svgY Returns the height on the image of the given step, where the top line is at 0 and the bottom line at 4.
+func svgY(step int) float64 {
+	return float64(8-step) / 2
+}
+
+// svgNumber Formats a length for SVG, to two decimal places without trailing zeros.
+func svgNumber(f float64) string {
+	return strconv.FormatFloat(math.Round(f*100)/100, 'f', -1, 64)
+}
+
+// staffDrawing An SVG image of a stave as it is drawn from left to right.
+type staffDrawing struct {
+	b          strings.Builder // The elements drawn so far, apart from the stave lines which are drawn last under everything else
+	x          float64         // How far across the next thing will be drawn
+	top        float64         // The highest point drawn, which is at most the top line
+	bottom     float64         // The lowest point drawn, which is at least the bottom line
+	clef       *Clef
+	key        *KeySignature
+	time       *TimeSignature
+	accidental *accidentalTracker
+}
+
+func (d *staffDrawing) extend(y float64) {
+	d.top = math.Min(d.top, y)
+	d.bottom = math.Max(d.bottom, y)
+}
+
+func (d *staffDrawing) path(class string, path string, x float64, y float64, width float64) {
+	fmt.Fprintf(&d.b, `<path class="%s" d="%s" transform="translate(%s %s)" fill="none" stroke="black" stroke-width="%s"/>`+"\n",
+		class, path, svgNumber(x), svgNumber(y), svgNumber(width))
+}
+
+func (d *staffDrawing) line(class string, x1 float64, y1 float64, x2 float64, y2 float64, width float64) {
+	fmt.Fprintf(&d.b, `<line class="%s" x1="%s" y1="%s" x2="%s" y2="%s" stroke="black" stroke-width="%s"/>`+"\n",
+		class, svgNumber(x1), svgNumber(y1), svgNumber(x2), svgNumber(y2), svgNumber(width))
+	d.extend(y1)
+	d.extend(y2)
+}
+
+func (d *staffDrawing) circle(class string, x float64, y float64, r float64) {
+	fmt.Fprintf(&d.b, `<circle class="%s" cx="%s" cy="%s" r="%s"/>`+"\n", class, svgNumber(x), svgNumber(y), svgNumber(r))
+}
+
+// drawClef Draws the clef, with a small 8 above or below it if it sounds an octave higher or lower.
+func (d *staffDrawing) drawClef(clef *Clef) {
+	if clef == nil {
+		return
+	}
+	x := d.x + 1
+	switch clef.sign {
+	case GClef:
+		y := svgY((clef.line - 1) * 2)
+		d.path("clef", svgTrebleClef, x, y, 0.18)
+		d.circle("clef", x-0.45, y+2.2, 0.3)
+		d.extend(y - 4.5)
+		d.extend(y + 3)
+	case FClef:
+		y := svgY((clef.line - 1) * 2)
+		d.path("clef", svgBassClef, x-0.5, y, 0.2)
+		d.circle("clef", x-0.9, y+0.05, 0.3)
+		d.circle("clef", x+1.3, y-0.5, 0.15)
+		d.circle("clef", x+1.3, y+0.5, 0.15)
+	case CClef:
+		d.path("clef", svgCClef, x-0.5, svgY((clef.line-1)*2), 0.2)
+	case PercussionClef:
+		d.line("clef", x, 1, x, 3, 0.3)
+		d.line("clef", x+0.7, 1, x+0.7, 3, 0.3)
+	}
+	if clef.octaveChange != 0 {
+		y := -5.5
+		if clef.octaveChange < 0 {
+			y = 5.5
+		}
+		fmt.Fprintf(&d.b, `<path class="clef" d="%s" transform="translate(%s %s) scale(0.5)" fill="none" stroke="black" stroke-width="0.3"/>`+"\n",
+			svgDigits[8], svgNumber(x), svgNumber(y))
+		d.extend(y)
+		d.extend(y + 1)
+	}
+	d.x += 3.5
+	d.clef = clef
+}
+
+// drawKey Draws the sharps or flats of the key signature.
+func (d *staffDrawing) drawKey(key *KeySignature) {
+	d.key = key
+	if key == nil || key.fifths == 0 {
+		return
+	}
+	steps, alter, count := svgSharpSteps, 1, int(key.fifths)
+	if count < 0 {
+		steps, alter, count = svgFlatSteps, -1, -count
+	}
+	// Move the signature up or down by as many steps as G4 moves from the treble clef, keeping it on the stave
+	shift := (d.clef.StaffPosition(MakeSpelledPitch(LetterG, 0, 4))-2)%LettersInOctave + LettersInOctave
+	if shift %= LettersInOctave; shift > 3 {
+		shift -= LettersInOctave
+	}
+	for i := 0; i < count; i++ {
+		step := steps[i] + shift
+		if step > 9 {
+			step -= LettersInOctave
+		} else if step < -1 {
+			step += LettersInOctave
+		}
+		d.path("key", svgAccidentals[alter], d.x+0.6, svgY(step), 0.15)
+		d.x += 1
+	}
+	d.x += 1
+}
+
+// drawTime Draws the time signature, with the number of notes above the note value.
+func (d *staffDrawing) drawTime(time *TimeSignature) {
+	d.time = time
+	if time == nil {
+		return
+	}
+	count, value := strconv.Itoa(time.noteCount), strconv.Itoa(time.noteValue)
+	width := math.Max(float64(len(count)), float64(len(value))) * 1.3
+	for i, number := range []string{count, value} {
+		x := d.x + (width-float64(len(number))*1.3)/2
+		for _, digit := range number {
+			d.path("time", svgDigits[digit-'0'], x, float64(i)*2, 0.28)
+			x += 1.3
+		}
+	}
+	d.x += width + 1.5
+}
+
+// drawBarLine Draws a bar line, which is a thin and a thick line at the end of the piece.
+func (d *staffDrawing) drawBarLine(final bool) {
+	d.x += 0.5
+	d.line("barline", d.x, 0, d.x, 4, 0.12)
+	if final {
+		d.x += 0.6
+		d.line("barline", d.x, 0, d.x, 4, 0.5)
+		d.x += 0.25
+		return
+	}
+	d.x += 1.5
+}
+
+// drawNote Draws a note or chord, with its accidentals, ledger lines, stem, flags and dots, or a rest.
+func (d *staffDrawing) drawNote(note *Note) {
+	if note.IsRest() {
+		d.drawRest(note)
+	} else {
+		d.x += d.drawChord(note)
+	}
+	// Longer notes are given more room, though not in proportion to their length
+	d.x += 1.5 + 1.5*math.Sqrt(float64(note.duration.numerator)*4/float64(note.duration.Denominator()))
+}
+
+// svgFlags Returns the number of flags on a note of the given value, e.g. 1 for an eighth note.
+func svgFlags(value int) int {
+	flags := 0
+	for v := value; v > 4; v /= 2 {
+		flags++
+	}
+	return flags
+}
+
+// drawChord Draws a note or chord, returning the extra room taken up by accidentals and noteheads on the far side of the stem.
+func (d *staffDrawing) drawChord(note *Note) float64 {
+	type head struct {
+		step       int
+		displaced  bool
+		accidental int
+		written    bool
+	}
+	heads := make([]head, len(note.pitches))
+	for i := range note.pitches {
+		sp := &note.pitches[i]
+		heads[i].step = d.clef.StaffPosition(sp)
+		heads[i].accidental, heads[i].written = d.accidental.accidental(sp)
+	}
+	sort.Slice(heads, func(i, j int) bool { return heads[i].step < heads[j].step })
+	low, high := heads[0].step, heads[len(heads)-1].step
+	up := stemGoesUp(low, high)
+
+	// Notes a step apart can't be side by side, so every other one moves to the far side of the stem
+	displaced := false
+	if up {
+		for i := 1; i < len(heads); i++ {
+			heads[i].displaced = heads[i].step-heads[i-1].step == 1 && !heads[i-1].displaced
+			displaced = displaced || heads[i].displaced
+		}
+	} else {
+		for i := len(heads) - 2; i >= 0; i-- {
+			heads[i].displaced = heads[i+1].step-heads[i].step == 1 && !heads[i+1].displaced
+			displaced = displaced || heads[i].displaced
+		}
+	}
+
+	// Accidentals are stacked in columns, from the top down, moving left when they would overlap the one above
+	columns := make([]int, len(heads))
+	lastStep, lastColumn, width := math.MaxInt32, 1, 0
+	for i := len(heads) - 1; i >= 0; i-- {
+		if !heads[i].written {
+			continue
+		}
+		columns[i] = 0
+		if lastColumn == 0 && lastStep-heads[i].step < 6 {
+			columns[i] = 1
+		}
+		lastStep, lastColumn = heads[i].step, columns[i]
+		if columns[i]+1 > width {
+			width = columns[i] + 1
+		}
+	}
+	x := d.x + float64(width)*1.3
+	if displaced && !up {
+		x += 1.3
+	}
+	for i, h := range heads {
+		if h.written {
+			d.path("accidental", svgAccidentals[h.accidental], x-0.7-float64(columns[i])*1.3, svgY(h.step), 0.15)
+			d.extend(svgY(h.step) - 1.8)
+			d.extend(svgY(h.step) + 1.3)
+		}
+	}
+
+	// Ledger lines above and below the stave
+	left, right := x-0.4, x+1.7
+	if displaced {
+		if up {
+			right += 1.3
+		} else {
+			left -= 1.3
+		}
+	}
+	for step := -2; step >= low; step -= 2 {
+		d.line("ledger", left, svgY(step), right, svgY(step), 0.12)
+	}
+	for step := 10; step <= high; step += 2 {
+		d.line("ledger", left, svgY(step), right, svgY(step), 0.12)
+	}
+
+	// Noteheads, which are hollow for half and whole notes
+	for _, h := range heads {
+		cx, cy := x+0.65, svgY(h.step)
+		if h.displaced {
+			if up {
+				cx += 1.25
+			} else {
+				cx -= 1.25
+			}
+		}
+		switch {
+		case note.value == 1:
+			fmt.Fprintf(&d.b, `<ellipse class="notehead" cx="%s" cy="%s" rx="0.75" ry="0.45" fill="white" stroke="black" stroke-width="0.25"/>`+"\n",
+				svgNumber(cx), svgNumber(cy))
+		case note.value == 2:
+			fmt.Fprintf(&d.b, `<ellipse class="notehead" cx="%s" cy="%s" rx="0.62" ry="0.43" fill="white" stroke="black" stroke-width="0.15" transform="rotate(-20 %s %s)"/>`+"\n",
+				svgNumber(cx), svgNumber(cy), svgNumber(cx), svgNumber(cy))
+		default:
+			fmt.Fprintf(&d.b, `<ellipse class="notehead" cx="%s" cy="%s" rx="0.62" ry="0.43" transform="rotate(-20 %s %s)"/>`+"\n",
+				svgNumber(cx), svgNumber(cy), svgNumber(cx), svgNumber(cy))
+		}
+		d.extend(cy - 0.5)
+		d.extend(cy + 0.5)
+		// Dots go in the space, so a note on a line has its dots in the space above
+		dotY := cy
+		if h.step%2 == 0 {
+			dotY -= 0.5
+		}
+		dotX := x + 1.8
+		if displaced && up {
+			dotX += 1.25
+		}
+		for i := 0; i < note.dots; i++ {
+			d.circle("dot", dotX+float64(i)*0.6, dotY, 0.18)
+		}
+	}
+
+	extra := x - d.x
+	if displaced && up {
+		extra += 1.3
+	}
+
+	// The stem, which reaches at least the middle line, and its flags
+	if note.value == 1 {
+		return extra
+	}
+	if up {
+		stemX, end := x+1.22, math.Min(svgY(high)-svgStemLength, 2)
+		d.line("stem", stemX, svgY(low), stemX, end, 0.12)
+		for i := 0; i < svgFlags(note.value); i++ {
+			d.path("flag", "M0 0C0.1 1 1.2 1.4 0.9 2.8", stemX, end+float64(i)*0.8, 0.2)
+		}
+	} else {
+		stemX, end := x+0.08, math.Max(svgY(low)+svgStemLength, 2)
+		d.line("stem", stemX, svgY(high), stemX, end, 0.12)
+		for i := 0; i < svgFlags(note.value); i++ {
+			d.path("flag", "M0 0C0.1-1 1.2-1.4 0.9-2.8", stemX, end-float64(i)*0.8, 0.2)
+		}
+	}
+	return extra
+}
+
+func (d *staffDrawing) drawRest(note *Note) {
+	x := d.x
+	switch {
+	case note.value <= 1:
+		// Whole rests hang from the fourth line, as do rests lasting a whole bar
+		fmt.Fprintf(&d.b, `<rect class="rest" x="%s" y="1" width="1.2" height="0.5"/>`+"\n", svgNumber(x))
+	case note.value == 2:
+		fmt.Fprintf(&d.b, `<rect class="rest" x="%s" y="1.5" width="1.2" height="0.5"/>`+"\n", svgNumber(x))
+	case note.value == 4:
+		d.path("rest", svgQuarterRest, x, 0, 0.25)
+	default:
+		flags := svgFlags(note.value)
+		d.path("rest", fmt.Sprintf("M1.1 1.2L%s %s", svgNumber(1.1-0.3*float64(flags+1)), svgNumber(1.2+float64(flags+1))), x, 0, 0.15)
+		for i := 0; i < flags; i++ {
+			y := 1.4 + float64(i)
+			d.circle("rest", x+0.35-0.3*float64(i), y, 0.25)
+			d.path("rest", "M0 0C0.3 0.3 0.8 0.2 1-0.2", x+0.15-0.3*float64(i), y, 0.15)
+		}
+	}
+	for i := 0; i < note.dots; i++ {
+		d.circle("dot", x+1.6+float64(i)*0.6, 1.5, 0.18)
+	}
+}
+
+// svg Returns the finished image, with the stave lines drawn under everything else.
+func (d *staffDrawing) svg() string {
+	width := d.x + 0.5
+	var lines strings.Builder
+	for y := 0; y <= 4; y++ {
+		fmt.Fprintf(&lines, `<line class="staff" x1="0" y1="%d" x2="%s" y2="%d" stroke="black" stroke-width="0.1"/>`+"\n",
+			y, svgNumber(d.x), y)
+	}
+	top, height := d.top-1, d.bottom-d.top+2
+	return fmt.Sprintf(`<svg xmlns="http://www.w3.org/2000/svg" width="%s" height="%s" viewBox="-0.5 %s %s %s">`+"\n",
+		svgNumber(width*svgSpace), svgNumber(height*svgSpace), svgNumber(top), svgNumber(width), svgNumber(height)) +
+		lines.String() + d.b.String() + "</svg>\n"
+}
+
+// StaveSVG Returns the stave drawn as an SVG image on a single line. The clef, key signature and time signature of the first bar are
+// drawn at the start, and again wherever they change. Accidentals are drawn where the key signature and earlier notes in the bar
+// don't already give the right alteration.
+func StaveSVG(stave *Stave) string {
+	d := &staffDrawing{bottom: 4}
+	for i := range stave.bars {
+		bar := &stave.bars[i]
+		if i == 0 || !sameClef(bar.clef, d.clef) {
+			d.drawClef(bar.clef)
+		}
+		if i == 0 || !sameKey(bar.key, d.key) {
+			d.drawKey(bar.key)
+		}
+		if time := bar.TimeSignature(); i == 0 || !sameTime(time, d.time) {
+			d.drawTime(time)
+		}
+		d.accidental = newAccidentalTracker(d.key)
+		d.x += 0.5
+		for j := range bar.notes {
+			d.drawNote(&bar.notes[j])
+		}
+		d.drawBarLine(i == len(stave.bars)-1)
+	}
+	return d.svg()
+}
+
+func sameClef(a *Clef, b *Clef) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+func sameKey(a *KeySignature, b *KeySignature) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+func sameTime(a *TimeSignature, b *TimeSignature) bool {
+	return a == b || (a != nil && b != nil && *a == *b)
+}
+
+// NotesSVG Returns the given notes drawn as an SVG image on a stave with the given clef, key signature and time signature, any of
+// which may be nil. With a time signature the notes are split into bars, and a note that doesn't fit in what is left of a bar is
+// drawn in it anyway, with the next bar starting after it; without one they are drawn in a single bar.
+func NotesSVG(notes []Note, clef *Clef, key *KeySignature, time *TimeSignature) string {
+	if clef == nil {
+		clef = MakeTrebleClef()
+	}
+	stave := MakeStave()
+	bar := MakeBar(time, key, clef)
+	for _, note := range notes {
+		bar.AddNotes(note)
+		if time != nil && bar.Duration().Cmp(time.Duration()) >= 0 {
+			stave.AddBars(*bar)
+			bar = MakeBar(time, key, clef)
+		}
+	}
+	if len(bar.notes) > 0 || len(stave.bars) == 0 {
+		stave.AddBars(*bar)
+	}
+	return StaveSVG(stave)
+}
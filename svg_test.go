@@ -0,0 +1,88 @@
+package tonacity
+
+import (
+	"encoding/xml"
+	"io"
+	"strings"
+	"testing"
+)
+
+// svgClasses Counts the elements of each class in an SVG image, failing the test if it isn't well formed XML.
+func svgClasses(t *testing.T, svg string) map[string]int {
+	t.Helper()
+	classes := make(map[string]int)
+	decoder := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		token, err := decoder.Token()
+		if err != nil {
+			if err == io.EOF {
+				return classes
+			}
+			t.Fatalf("invalid SVG: %v\n%s", err, svg)
+		}
+		if start, ok := token.(xml.StartElement); ok {
+			for _, attr := range start.Attr {
+				if attr.Name.Local == "class" {
+					classes[attr.Value]++
+				}
+			}
+		}
+	}
+}
+
+func TestStaveSVG(t *testing.T) {
+	first := MakeBar(MakeTimeSignature(3, 4), MakeKeySignature(2, false), MakeClef(GClef, 2, 0))
+	// Middle C needs a ledger line, and the second C♮ in the bar needs no natural
+	first.AddNotes(*MakeNote(4, 0, *MakeSpelledPitch(LetterC, 0, 4)), *MakeNote(8, 1, *MakeSpelledPitch(LetterC, 0, 5)),
+		*MakeNote(16, 0, *MakeSpelledPitch(LetterC, 0, 5)), *MakeRest(4, 0))
+	second := MakeBar(MakeTimeSignature(3, 4), MakeKeySignature(-1, false), MakeClef(FClef, 4, 0))
+	// A chord with a step between two notes, and a sharp
+	second.AddNotes(*MakeNote(2, 1, *MakeSpelledPitch(LetterG, 0, 2), *MakeSpelledPitch(LetterA, 0, 2), *MakeSpelledPitch(LetterC, 1, 3)))
+	svg := StaveSVG(MakeStave(*first, *second))
+	want := map[string]int{
+		"staff":      5,
+		"clef":       6, // The treble clef path and its ball, then the bass clef path and its three circles
+		"key":        3, // Two sharps, then one flat
+		"time":       2, // 3 over 4, which isn't drawn again
+		"notehead":   6,
+		"accidental": 3, // Natural, natural (the C♮ in the next octave is on another line) and sharp
+		"ledger":     1, // Middle C, while G2 is on the bottom line of the bass clef
+		"stem":       4,
+		"flag":       3,
+		"dot":        4, // One dot on the eighth and one on each note of the chord
+		"rest":       1,
+		"barline":    3,
+	}
+	got := svgClasses(t, svg)
+	for class, count := range want {
+		if got[class] != count {
+			t.Errorf("StaveSVG() drew %d %q, want %d", got[class], class, count)
+		}
+	}
+	if !strings.HasPrefix(svg, `<svg xmlns="http://www.w3.org/2000/svg"`) {
+		t.Errorf("StaveSVG() = %q, want an svg element", svg[:40])
+	}
+}
+
+func TestNotesSVG(t *testing.T) {
+	notes := make([]Note, 0)
+	for _, letter := range []Letter{LetterC, LetterD, LetterE, LetterF, LetterG} {
+		notes = append(notes, *MakeNote(4, 0, *MakeSpelledPitch(letter, 0, 4)))
+	}
+	tests := []struct {
+		name     string
+		time     *TimeSignature
+		barlines int
+	}{
+		{"split into bars", MakeTimeSignature(2, 4), 4},
+		{"unmetered", nil, 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := svgClasses(t, NotesSVG(notes, nil, nil, tt.time))
+			if got["barline"] != tt.barlines || got["notehead"] != len(notes) || got["clef"] != 2 {
+				t.Errorf("NotesSVG() drew %v, want %d bar lines, %d noteheads and a treble clef", got, tt.barlines, len(notes))
+			}
+		})
+	}
+}
@@ -0,0 +1,190 @@
+package tonacity
+
+import "math"
+
+// Waveform The shape of one cycle of a periodic sound. Given a phase between 0 (inclusive) and 1 (exclusive), measured in cycles,
+// it returns the displacement of the wave between -1 and 1.
+type Waveform func(phase float64) float64
+
+// SineWave A pure tone, with no harmonics.
+func SineWave(phase float64) float64 {
+	return math.Sin(2 * math.Pi * phase)
+}
+
+// SquareWave A hollow, clarinet-like tone made of the odd harmonics.
+func SquareWave(phase float64) float64 {
+	if phase < 0.5 {
+		return 1
+	}
+	return -1
+}
+
+// SawtoothWave A bright, brassy tone made of every harmonic.
+func SawtoothWave(phase float64) float64 {
+	return 2*phase - 1
+}
+
+// TriangleWave A soft, flute-like tone made of the odd harmonics, falling away faster than in a square wave.
+func TriangleWave(phase float64) float64 {
+	if phase < 0.5 {
+		return 4*phase - 1
+	}
+	return 3 - 4*phase
+}
+
+// CreateAdditiveWaveform Creates a waveform by adding together sine waves at the harmonics of the fundamental. The first amplitude is
+// that of the fundamental, the second that of the first overtone (an octave up), the third that of the second overtone (an octave and
+// a fifth up), and so on. The result is scaled so it never exceeds ±1.
+func CreateAdditiveWaveform(amplitudes ...float64) Waveform {
+	var total float64
+	for _, a := range amplitudes {
+		total += math.Abs(a)
+	}
+	if total == 0 {
+		total = 1
+	}
+	partials := make([]float64, len(amplitudes))
+	for i, a := range amplitudes {
+		partials[i] = a / total
+	}
+	return func(phase float64) float64 {
+		var v float64
+		for i, a := range partials {
+			v += a * math.Sin(2*math.Pi*phase*float64(i+1))
+		}
+		return v
+	}
+}
+
+// Envelope An ADSR envelope, shaping the loudness of a note over time. Once a note starts, its level rises to full over the attack,
+// falls to the sustain level over the decay, and holds there until the note is released, when it falls to silence over the release.
+// Times are in seconds; the sustain level is between 0 and 1.
+type Envelope struct {
+	attack  float64
+	decay   float64
+	sustain float64
+	release float64
+}
+
+// MakeEnvelope Creates an ADSR envelope.
+func MakeEnvelope(attack float64, decay float64, sustain float64, release float64) *Envelope {
+	return &Envelope{attack, decay, sustain, release}
+}
+
+// Release The time in seconds that a note continues to sound after it is released.
+func (e *Envelope) Release() float64 {
+	return e.release
+}
+
+// Level Returns the level of the envelope t seconds after the start of a note that is held for the given number of seconds.
+func (e *Envelope) Level(t float64, held float64) float64 {
+	if t < 0 || t >= held+e.release {
+		return 0
+	}
+	if t >= held {
+		// Release from wherever the note had got to, so short notes don't jump up to the sustain level
+		return e.level(held) * (1 - (t-held)/e.release)
+	}
+	return e.level(t)
+}
+
+// level The level of a note that is still being held after t seconds.
+func (e *Envelope) level(t float64) float64 {
+	switch {
+	case t < e.attack:
+		return t / e.attack
+	case t < e.attack+e.decay:
+		return 1 - (1-e.sustain)*(t-e.attack)/e.decay
+	default:
+		return e.sustain
+	}
+}
+
+// TimedPitch A pitch that starts sounding Start seconds into a piece of audio and is held for Length seconds.
+type TimedPitch struct {
+	Pitch  Pitch
+	Start  float64
+	Length float64
+}
+
+// Synthesiser Renders pitches to mono PCM samples between -1 and 1.
+type Synthesiser struct {
+	sampleRate   int
+	concertPitch float64 // The frequency of A4
+	waveform     Waveform
+	envelope     Envelope
+	amplitude    float64 // The peak amplitude of a single note
+}
+
+// NewSynthesiser Creates a synthesiser that renders sine waves at the given sample rate, tuned so that A4 is the given frequency.
+// Each note peaks at a quarter of full scale, so four note chords can be rendered without clipping.
+func NewSynthesiser(sampleRate int, concertPitch float64) *Synthesiser {
+	return &Synthesiser{sampleRate, concertPitch, SineWave, *MakeEnvelope(0.01, 0.1, 0.8, 0.05), 0.25}
+}
+
+// SampleRate The number of samples per second that this synthesiser renders.
+func (s *Synthesiser) SampleRate() int {
+	return s.sampleRate
+}
+
+// SetWaveform Sets the waveform that every note is rendered with.
+func (s *Synthesiser) SetWaveform(waveform Waveform) {
+	s.waveform = waveform
+}
+
+// SetEnvelope Sets the envelope that every note is shaped by.
+func (s *Synthesiser) SetEnvelope(envelope *Envelope) {
+	s.envelope = *envelope
+}
+
+// SetAmplitude Sets the peak amplitude of a single note, between 0 and 1.
+func (s *Synthesiser) SetAmplitude(amplitude float64) {
+	s.amplitude = amplitude
+}
+
+// Render Renders the given pitches, mixing together any that overlap. The result is long enough to include the release of the last
+// note to finish. Samples are not clipped, so too many loud overlapping notes may exceed ±1.
+func (s *Synthesiser) Render(pitches []TimedPitch) []float64 {
+	var end float64
+	for _, tp := range pitches {
+		end = math.Max(end, tp.Start+tp.Length+s.envelope.release)
+	}
+	samples := make([]float64, int(math.Ceil(end*float64(s.sampleRate))))
+	for _, tp := range pitches {
+		s.renderPitch(samples, &tp)
+	}
+	return samples
+}
+
+func (s *Synthesiser) renderPitch(samples []float64, tp *TimedPitch) {
+	frequency := tp.Pitch.FrequencyInHertz(s.concertPitch)
+	first := int(math.Ceil(tp.Start * float64(s.sampleRate)))
+	last := int((tp.Start + tp.Length + s.envelope.release) * float64(s.sampleRate))
+	for i := first; i < last && i < len(samples); i++ {
+		t := float64(i)/float64(s.sampleRate) - tp.Start
+		_, phase := math.Modf(t * frequency)
+		samples[i] += s.amplitude * s.envelope.Level(t, tp.Length) * s.waveform(phase)
+	}
+}
+
+// RenderChord Renders every pitch of the given chord at once, held for the given number of seconds.
+func (s *Synthesiser) RenderChord(chord *Chord, length float64) []float64 {
+	pitches := make([]TimedPitch, len(chord.pitches))
+	for i, p := range chord.pitches {
+		pitches[i] = TimedPitch{p, 0, length}
+	}
+	return s.Render(pitches)
+}
+
+// RenderSinger Renders up to count pitches sung by the given singer one after another, each held for the given number of seconds.
+func (s *Synthesiser) RenderSinger(singer Singer, count int, length float64) []float64 {
+	pitches := make([]TimedPitch, 0, count)
+	for i := 0; i < count; i++ {
+		pitch, more := singer.Sing()
+		if !more {
+			break
+		}
+		pitches = append(pitches, TimedPitch{pitch, float64(i) * length, length})
+	}
+	return s.Render(pitches)
+}
@@ -0,0 +1,98 @@
+package tonacity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestWaveforms(t *testing.T) {
+	tests := []struct {
+		name     string
+		waveform Waveform
+		phase    float64
+		want     float64
+	}{
+		{"Sine peak", SineWave, 0.25, 1},
+		{"Sine trough", SineWave, 0.75, -1},
+		{"Square first half", SquareWave, 0.1, 1},
+		{"Square second half", SquareWave, 0.6, -1},
+		{"Sawtooth start", SawtoothWave, 0, -1},
+		{"Sawtooth middle", SawtoothWave, 0.5, 0},
+		{"Triangle start", TriangleWave, 0, -1},
+		{"Triangle peak", TriangleWave, 0.5, 1},
+		{"Triangle falling", TriangleWave, 0.75, 0},
+		{"Additive fundamental only", CreateAdditiveWaveform(1), 0.25, 1},
+		{"Additive normalised", CreateAdditiveWaveform(1, 0, 1), 0.25, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.waveform(tt.phase); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("waveform(%v) = %v, want %v", tt.phase, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestEnvelope_Level(t *testing.T) {
+	e := MakeEnvelope(0.1, 0.1, 0.5, 0.2)
+	tests := []struct {
+		name string
+		t    float64
+		held float64
+		want float64
+	}{
+		{"Before start", -0.1, 1, 0},
+		{"Half way through attack", 0.05, 1, 0.5},
+		{"Peak", 0.1, 1, 1},
+		{"Half way through decay", 0.15, 1, 0.75},
+		{"Sustain", 0.5, 1, 0.5},
+		{"Half way through release", 1.1, 1, 0.25},
+		{"After release", 1.2, 1, 0},
+		{"Released during attack", 0.06, 0.04, 0.36},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := e.Level(tt.t, tt.held); math.Abs(got-tt.want) > 1e-9 {
+				t.Errorf("Envelope.Level(%v, %v) = %v, want %v", tt.t, tt.held, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSynthesiser_RenderSinger(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	s := NewSynthesiser(testSampleRate, StandardConcertPitch)
+	s.SetEnvelope(MakeEnvelope(0, 0, 1, 0))
+	singer := MakeSliceSinger(*pf.GetPitch(C(), 4), *pf.GetPitch(E(), 4), *pf.GetPitch(G(), 4))
+	samples := s.RenderSinger(singer, 10, 0.5)
+	if want := 3 * testSampleRate / 2; len(samples) != want {
+		t.Fatalf("RenderSinger() rendered %d samples, want %d", len(samples), want)
+	}
+
+	// Each note should be heard, in order, in its own half second
+	e, _ := NewChromagramExtractor(testSampleRate, 4096, testSampleRate/2, StandardConcertPitch)
+	frames := e.Extract(samples)
+	for i, want := range []*PitchClass{C(), E(), G()} {
+		if got := frames[i].Chroma.Energy(*want); got != 1 {
+			t.Errorf("RenderSinger() note %d energy = %v, want 1", i, got)
+		}
+	}
+}
+
+func TestSynthesiser_RenderChord(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	s := NewSynthesiser(testSampleRate, StandardConcertPitch)
+	s.SetWaveform(TriangleWave)
+	samples := s.RenderChord(MakeChord(*pf.GetPitch(A(), 3), *pf.GetPitch(C(), 4), *pf.GetPitch(E(), 4)), 1)
+
+	e, _ := NewChromagramExtractor(testSampleRate, 4096, 4096, StandardConcertPitch)
+	r := NewChordRecogniser(CreateChordDictionary(), CreateSharpPitchNamer())
+	if name, _, _ := r.Recognise(&e.Extract(samples)[1].Chroma); name != "A Minor" {
+		t.Errorf("RenderChord() was recognised as %v, want A Minor", name)
+	}
+	for _, v := range samples {
+		if math.Abs(v) > 0.75 {
+			t.Fatalf("RenderChord() sample %v exceeds the sum of three notes' amplitudes", v)
+		}
+	}
+}
@@ -0,0 +1,573 @@
+package tonacity
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Guitar tablature (tab) writes music as the fret to hold down on each string, one line per string with the highest sounding string
+// at the top:
+//
+//	e|-------0-----|
+//	B|-----1---1---|
+//	G|---2-------2-|
+//	D|-2-----------|
+//	A|-------------|
+//	E|-------------|
+//
+// Tab doesn't say how long notes last, so each character is taken as a fixed length of time, and a note lasts until the next note
+// on any string. Symbols between notes on the same string say how the second is played: h for a hammer-on, p for a pull-off, / and \
+// for slides up and down, b for a bend, and r for releasing one.
+
+// TabTechnique How a note is played, other than by picking the string.
+type TabTechnique byte
+
+const (
+	// TabPicked The string is picked.
+	TabPicked TabTechnique = 0
+	// TabHammerOn A finger is hammered onto the string to sound a higher note.
+	TabHammerOn TabTechnique = 'h'
+	// TabPullOff A finger is pulled off the string to sound a lower note.
+	TabPullOff TabTechnique = 'p'
+	// TabSlideUp The finger slides up the string.
+	TabSlideUp TabTechnique = '/'
+	// TabSlideDown The finger slides down the string.
+	TabSlideDown TabTechnique = '\\'
+	// TabBend The string is bent to raise its pitch.
+	TabBend TabTechnique = 'b'
+	// TabRelease A bent string is let go back towards its fretted pitch.
+	TabRelease TabTechnique = 'r'
+	// TabVibrato The pitch of the note is wavered.
+	TabVibrato TabTechnique = '~'
+)
+
+// tabConnections The techniques written between two notes, which say how the second is reached from the first.
+const tabConnections = "hp/\\br"
+
+// tabCharacters Everything that can be written on a line of tab after its label.
+const tabCharacters = "-|0123456789hp/\\br~x*.()<> "
+
+// TabNote A note in tab, along with where it is played on the fretboard.
+type TabNote struct {
+	Start     Duration
+	Length    Duration
+	Pitch     Pitch
+	String    int          // The string, counting from 1 for the highest sounding string as guitarists do
+	Fret      int          // The fret held down, or 0 for the open string
+	Technique TabTechnique // How the note is reached from the note before on the same string, e.g. TabHammerOn for the 7 in "5h7"
+	Ornament  TabTechnique // Written straight after the note when no note follows, e.g. TabVibrato for "7~" or TabBend for "7b"
+}
+
+// Tab Notes written as tablature for an instrument with the given tuning.
+type Tab struct {
+	Tuning []Pitch // The pitch of each open string, starting with the lowest
+	Notes  []TabNote
+}
+
+// Fretboard The strings and frets of a fretted instrument, such as a guitar.
+type Fretboard struct {
+	tuning []Pitch // The pitch of each open string, starting with the lowest
+	frets  int
+}
+
+// MakeFretboard Creates a fretboard with the given number of frets and strings tuned to the given pitches, starting with the lowest.
+func MakeFretboard(frets int, tuning ...Pitch) *Fretboard {
+	return &Fretboard{tuning, frets}
+}
+
+// CreateGuitarFretboard Creates a six string guitar fretboard with 22 frets in standard tuning: E2 A2 D3 G3 B3 E4.
+func CreateGuitarFretboard() *Fretboard {
+	return MakeFretboard(22, createStandardTuning()...)
+}
+
+func createStandardTuning() []Pitch {
+	tuning := make([]Pitch, 0, 6)
+	for _, open := range []struct {
+		letter Letter
+		octave int
+	}{{LetterE, 2}, {LetterA, 2}, {LetterD, 3}, {LetterG, 3}, {LetterB, 3}, {LetterE, 4}} {
+		tuning = append(tuning, MakeSpelledPitch(open.letter, 0, open.octave).Pitch())
+	}
+	return tuning
+}
+
+// createBassTuning Returns the standard tuning of a four string bass guitar: E1 A1 D2 G2.
+func createBassTuning() []Pitch {
+	tuning := createStandardTuning()[:4]
+	for i := range tuning {
+		tuning[i].Transpose(-OctaveValue)
+	}
+	return tuning
+}
+
+// Tuning The pitch of each open string, starting with the lowest.
+func (f *Fretboard) Tuning() []Pitch {
+	tuning := make([]Pitch, len(f.tuning))
+	copy(tuning, f.tuning)
+	return tuning
+}
+
+// Frets The number of frets.
+func (f *Fretboard) Frets() int {
+	return f.frets
+}
+
+// FretPosition A place on a fretboard: a string, counting from 1 for the highest, and a fret, or 0 for the open string.
+type FretPosition struct {
+	String int
+	Fret   int
+}
+
+// Positions Returns every place on the fretboard the given pitch can be played, highest string first.
+func (f *Fretboard) Positions(pitch Pitch) []FretPosition {
+	positions := make([]FretPosition, 0, len(f.tuning))
+	for i := len(f.tuning) - 1; i >= 0; i-- {
+		if fret := int(pitch.value - f.tuning[i].value); fret >= 0 && fret <= f.frets {
+			positions = append(positions, FretPosition{len(f.tuning) - i, fret})
+		}
+	}
+	return positions
+}
+
+// Pitch Returns the pitch that sounds at the given place on the fretboard.
+func (f *Fretboard) Pitch(position FretPosition) Pitch {
+	return *f.tuning[len(f.tuning)-position.String].GetTransposedCopy(HalfSteps(position.Fret))
+}
+
+// maxFretSpan The furthest apart, in frets, that notes held down at the same time can be.
+const maxFretSpan = 4
+
+// fingering A way of playing the pitches that start at the same time.
+type fingering struct {
+	positions []FretPosition
+	hand      int // The lowest fret held down, or 0 if every string is open
+	span      int // How many frets the fingers are spread over
+}
+
+// fingerings Returns every way of playing the given pitches at once, each on a different string, without stretching further than
+// maxFretSpan.
+func (f *Fretboard) fingerings(pitches []Pitch) []fingering {
+	found := make([]fingering, 0)
+	chosen := make([]FretPosition, len(pitches))
+	used := make(map[int]bool)
+	var choose func(i int, low int, high int)
+	choose = func(i int, low int, high int) {
+		if i == len(pitches) {
+			positions := make([]FretPosition, len(chosen))
+			copy(positions, chosen)
+			fg := fingering{positions: positions}
+			if high > 0 {
+				fg.hand, fg.span = low, high-low
+			}
+			found = append(found, fg)
+			return
+		}
+		for _, position := range f.Positions(pitches[i]) {
+			if used[position.String] {
+				continue
+			}
+			l, h := low, high
+			if position.Fret > 0 {
+				if h == 0 || position.Fret < l {
+					l = position.Fret
+				}
+				if position.Fret > h {
+					h = position.Fret
+				}
+				if h-l > maxFretSpan {
+					continue
+				}
+			}
+			used[position.String] = true
+			chosen[i] = position
+			choose(i+1, l, h)
+			used[position.String] = false
+		}
+	}
+	choose(0, 0, 0)
+	return found
+}
+
+// cost How much effort it is to play a fingering, not counting moving the hand to it: spreading the fingers, and to a lesser extent
+// playing high up the neck.
+func (fg *fingering) cost() float64 {
+	return 0.5*float64(fg.span) + 0.1*float64(fg.hand)
+}
+
+// Tab Chooses where to play each of the given notes and chords, and returns them as tab. Of all the ways they can be played, the one
+// that moves the hand along the neck the least is chosen. An error is returned if a note can't be played, e.g. because it is lower
+// than the lowest string.
+func (f *Fretboard) Tab(notes []TimedNote) (*Tab, error) {
+	// Notes starting at the same time are played together
+	type event struct {
+		start   Duration
+		length  Duration
+		pitches []Pitch
+	}
+	events := make([]event, 0, len(notes))
+	for _, note := range notes {
+		if note.Note.IsRest() {
+			continue
+		}
+		if n := len(events); n > 0 && events[n-1].start.Cmp(note.Start) == 0 {
+			for _, sp := range note.Note.pitches {
+				events[n-1].pitches = append(events[n-1].pitches, sp.pitch)
+			}
+			continue
+		}
+		e := event{start: note.Start, length: note.Note.duration}
+		for _, sp := range note.Note.pitches {
+			e.pitches = append(e.pitches, sp.pitch)
+		}
+		events = append(events, e)
+	}
+
+	// Find the cheapest way through all the fingerings of all the events, where moving the hand costs a fret's worth of effort for
+	// every fret it moves. Open strings can be played from anywhere, so the hand stays where it was for them, and until the first
+	// fretted note it can start anywhere (-1).
+	candidates := make([][]fingering, len(events))
+	costs := make([][]float64, len(events))
+	hands := make([][]int, len(events))
+	from := make([][]int, len(events))
+	for i, e := range events {
+		candidates[i] = f.fingerings(e.pitches)
+		if len(candidates[i]) == 0 {
+			return nil, fmt.Errorf("tab: the notes at %v can't be played together on this fretboard", e.start)
+		}
+		costs[i] = make([]float64, len(candidates[i]))
+		hands[i] = make([]int, len(candidates[i]))
+		from[i] = make([]int, len(candidates[i]))
+		for j := range candidates[i] {
+			fg := &candidates[i][j]
+			open := fg.hand == 0
+			if i == 0 {
+				costs[i][j], hands[i][j] = fg.cost(), fg.hand
+				if open {
+					hands[i][j] = -1
+				}
+				continue
+			}
+			costs[i][j] = math.Inf(1)
+			for k := range candidates[i-1] {
+				c, hand := costs[i-1][k]+fg.cost(), hands[i-1][k]
+				if !open {
+					if hand >= 0 {
+						c += math.Abs(float64(fg.hand - hand))
+					}
+					hand = fg.hand
+				}
+				if c < costs[i][j] {
+					costs[i][j], hands[i][j], from[i][j] = c, hand, k
+				}
+			}
+		}
+	}
+	tab := &Tab{Tuning: f.Tuning()}
+	if len(events) == 0 {
+		return tab, nil
+	}
+	best := 0
+	last := len(events) - 1
+	for j := range costs[last] {
+		if costs[last][j] < costs[last][best] {
+			best = j
+		}
+	}
+	for i := last; i >= 0; i-- {
+		for _, position := range candidates[i][best].positions {
+			tab.Notes = append(tab.Notes, TabNote{
+				Start: events[i].start, Length: events[i].length, Pitch: f.Pitch(position), String: position.String, Fret: position.Fret,
+			})
+		}
+		best = from[i][best]
+	}
+	sortTabNotes(tab.Notes)
+	return tab, nil
+}
+
+// sortTabNotes Sorts notes by when they start, and then from the highest string down.
+func sortTabNotes(notes []TabNote) {
+	sort.SliceStable(notes, func(i, j int) bool {
+		if c := notes[i].Start.Cmp(notes[j].Start); c != 0 {
+			return c < 0
+		}
+		return notes[i].String < notes[j].String
+	})
+}
+
+// ReadTab Reads tab where each character lasts the given length of time. The tuning can be given on a line such as
+// "Tuning: D2 A2 D3 G3 B3 E4", from the lowest string up. Without one, six line tab is taken to be for a guitar in standard tuning,
+// and four line tab for a bass guitar. Lines that aren't tab, such as lyrics or chord names, are skipped, and each group of tab lines
+// carries on from the end of the last.
+func ReadTab(r io.Reader, column Duration) (*Tab, error) {
+	tab := &Tab{}
+	var system []string
+	var now Duration
+	finish := func() error {
+		if system == nil {
+			return nil
+		}
+		if tab.Tuning == nil {
+			switch len(system) {
+			case 6:
+				tab.Tuning = createStandardTuning()
+			case 4:
+				tab.Tuning = createBassTuning()
+			default:
+				return fmt.Errorf("tab: %d strings but no tuning", len(system))
+			}
+		}
+		if len(system) != len(tab.Tuning) {
+			return fmt.Errorf("tab: %d strings, want %d for the tuning", len(system), len(tab.Tuning))
+		}
+		length := tab.readSystem(system, now, column)
+		now = now.Add(length)
+		system = nil
+		return nil
+	}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if content, ok := tabLineContent(line); ok {
+			system = append(system, content)
+			continue
+		}
+		if err := finish(); err != nil {
+			return nil, err
+		}
+		if trimmed := strings.TrimSpace(line); strings.HasPrefix(strings.ToLower(trimmed), "tuning:") {
+			tuning, err := parseTabTuning(trimmed[len("tuning:"):])
+			if err != nil {
+				return nil, err
+			}
+			tab.Tuning = tuning
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("tab: %v", err)
+	}
+	if err := finish(); err != nil {
+		return nil, err
+	}
+	if tab.Tuning == nil {
+		return nil, errors.New("tab: no tab lines")
+	}
+	return tab, nil
+}
+
+// parseTabTuning Reads the pitches of the open strings, e.g. "E2 A2 D3 G3 B3 E4".
+func parseTabTuning(text string) ([]Pitch, error) {
+	tuning := make([]Pitch, 0, 6)
+	for _, name := range strings.Fields(text) {
+		sp, rest, ok := parseChordSymbolNote(name)
+		octave, err := strconv.Atoi(rest)
+		if !ok || err != nil {
+			return nil, fmt.Errorf("tab: invalid tuning pitch %q", name)
+		}
+		tuning = append(tuning, MakeSpelledPitch(sp.letter, sp.Alter(), octave).Pitch())
+	}
+	if len(tuning) == 0 {
+		return nil, errors.New("tab: empty tuning")
+	}
+	return tuning, nil
+}
+
+// tabLineContent Returns what is written on a line of tab after the string's label and the first bar line, and false if the line isn't
+// tab.
+func tabLineContent(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	start := strings.IndexAny(line, "|-")
+	if start < 0 || start > 3 {
+		return "", false
+	}
+	for _, c := range line[:start] {
+		if !strings.ContainsRune("ABCDEFGabcdefg#♯♭ ", c) {
+			return "", false
+		}
+	}
+	content := strings.TrimRight(strings.TrimPrefix(line[start:], "|"), "| ")
+	if !strings.Contains(content, "-") {
+		return "", false
+	}
+	for _, c := range content {
+		if !strings.ContainsRune(tabCharacters, c) {
+			return "", false
+		}
+	}
+	return content, true
+}
+
+// readSystem Reads a group of tab lines, one for each string, starting at the given time, and returns how long they last. Columns with
+// a bar line on every string don't take up any time.
+func (t *Tab) readSystem(lines []string, start Duration, column Duration) Duration {
+	width := 0
+	for _, line := range lines {
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+	at := func(line string, i int) byte {
+		if i < 0 || i >= len(line) {
+			return '-'
+		}
+		return line[i]
+	}
+	// The time at the start of each column
+	times := make([]Duration, width+1)
+	steps := 0
+	for i := 0; i < width; i++ {
+		times[i] = start.Add(column.Mul(MakeDuration(int64(steps), 1)))
+		bar := true
+		for _, line := range lines {
+			bar = bar && at(line, i) == '|'
+		}
+		if !bar {
+			steps++
+		}
+	}
+	end := start.Add(column.Mul(MakeDuration(int64(steps), 1)))
+	times[width] = end
+
+	notes := make([]TabNote, 0)
+	for s, line := range lines {
+		for i := 0; i < len(line); i++ {
+			if !isDigit(line[i]) {
+				continue
+			}
+			j := i
+			for j < len(line) && isDigit(line[j]) {
+				j++
+			}
+			fret, _ := strconv.Atoi(line[i:j])
+			note := TabNote{Start: times[i], String: s + 1, Fret: fret}
+			note.Pitch = *t.Tuning[len(lines)-1-s].GetTransposedCopy(HalfSteps(fret))
+			if c := at(line, i-1); strings.IndexByte(tabConnections, c) >= 0 {
+				note.Technique = TabTechnique(c)
+			}
+			if c := at(line, j); c == byte(TabVibrato) || c == byte(TabBend) && !isDigit(at(line, j+1)) {
+				note.Ornament = TabTechnique(c)
+			}
+			notes = append(notes, note)
+			i = j - 1
+		}
+	}
+	sortTabNotes(notes)
+	// Each note lasts until the next one starts, on any string
+	for i := range notes {
+		next := end
+		for j := i + 1; j < len(notes); j++ {
+			if notes[j].Start.Cmp(notes[i].Start) > 0 {
+				next = notes[j].Start
+				break
+			}
+		}
+		notes[i].Length = next.Sub(notes[i].Start)
+	}
+	t.Notes = append(t.Notes, notes...)
+	return end.Sub(start)
+}
+
+// Write Writes the tab with each character lasting the given length of time, with the tuning on the line before. Where a note is
+// written wider than the time until the next one, e.g. a two digit fret, the notes after it are moved later to make room.
+func (t *Tab) Write(w io.Writer, column Duration) error {
+	count := len(t.Tuning)
+	lines := make([][]byte, count)
+
+	// Group the notes by when they start, and write each group at the column for its time, or later if the notes before need the room
+	notes := make([]TabNote, len(t.Notes))
+	copy(notes, t.Notes)
+	sortTabNotes(notes)
+	previous := -1 // The column of the last group of notes
+	var end Duration
+	for i := 0; i < len(notes); {
+		j := i
+		for j < len(notes) && notes[j].Start.Cmp(notes[i].Start) == 0 {
+			j++
+		}
+		col := tabColumns(notes[i].Start, column)
+		if col <= previous {
+			col = previous + 1
+		}
+		for _, note := range notes[i:j] {
+			if note.String < 1 || note.String > count {
+				return fmt.Errorf("tab: string %d doesn't exist", note.String)
+			}
+			// Notes on the same string need a character between them, so they aren't read as one fret, and a technique is written there
+			free := len(lines[note.String-1])
+			if free > 0 || note.Technique != TabPicked {
+				free++
+			}
+			if col < free {
+				col = free
+			}
+		}
+		for _, note := range notes[i:j] {
+			line := &lines[note.String-1]
+			text := strconv.Itoa(note.Fret)
+			if note.Ornament != TabPicked {
+				text += string(rune(note.Ornament))
+			}
+			for len(*line) < col+len(text) {
+				*line = append(*line, '-')
+			}
+			if note.Technique != TabPicked {
+				(*line)[col-1] = byte(note.Technique)
+			}
+			copy((*line)[col:], text)
+			if e := note.Start.Add(note.Length); e.Cmp(end) > 0 {
+				end = e
+			}
+		}
+		previous, i = col, j
+	}
+	width := 0
+	for _, line := range lines {
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+	if cols := tabColumns(end, column); cols > width {
+		width = cols
+	}
+
+	var b strings.Builder
+	names := make([]string, len(t.Tuning))
+	for i := range t.Tuning {
+		sp := SpellPitch(t.Tuning[i], nil)
+		names[i] = chordSymbolNote(&sp) + strconv.Itoa(sp.Octave())
+	}
+	b.WriteString("Tuning: " + strings.Join(names, " ") + "\n")
+	labels := make([]string, count)
+	labelWidth := 0
+	for s := range labels {
+		sp := SpellPitch(t.Tuning[count-1-s], nil)
+		labels[s] = chordSymbolNote(&sp)
+		if s == 0 && sp.letter == SpellPitch(t.Tuning[0], nil).letter && count > 1 {
+			// The highest string is written in lower case when it has the same name as the lowest, as in standard tuning
+			labels[s] = strings.ToLower(labels[s])
+		}
+		if len(labels[s]) > labelWidth {
+			labelWidth = len(labels[s])
+		}
+	}
+	for s, line := range lines {
+		for len(line) < width {
+			line = append(line, '-')
+		}
+		fmt.Fprintf(&b, "%-*s|%s|\n", labelWidth, labels[s], line)
+	}
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tabColumns Returns how many characters of tab, each lasting the given length of time, fit into the given time.
+func tabColumns(d Duration, column Duration) int {
+	columns := d.Div(column)
+	return int(columns.Numerator() / columns.Denominator())
+}
@@ -0,0 +1,203 @@
+package tonacity
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const tabFixture = `Intro
+e|-----------|-------0~|
+B|-----5h7---|---8b10--|
+G|---------7-|---------|
+D|-----------|---------|
+A|-0---------|-12/14---|
+E|-----------|---------|
+
+Verse
+e|-3-|
+B|-0-|
+G|-0-|
+D|-0-|
+A|-2-|
+E|-3-|
+`
+
+// tabSummary Describes each note as its start, string, fret and techniques, its pitch and its length.
+func tabSummary(notes []TabNote) []string {
+	summary := make([]string, len(notes))
+	for i, note := range notes {
+		fret := fmt.Sprint(note.Fret)
+		if note.Technique != TabPicked {
+			fret = string(rune(note.Technique)) + fret
+		}
+		if note.Ornament != TabPicked {
+			fret += string(rune(note.Ornament))
+		}
+		sp := SpellPitch(note.Pitch, nil)
+		summary[i] = fmt.Sprintf("%v %d:%s %v %v", note.Start, note.String, fret, sp.String(), note.Length)
+	}
+	return summary
+}
+
+func TestReadTab(t *testing.T) {
+	tab, err := ReadTab(strings.NewReader(tabFixture), MakeDuration(1, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		"1/16 5:0 A2 1/4",
+		"5/16 2:5 E4 1/8",
+		"7/16 2:h7 F♯4 1/8",
+		"9/16 3:7 D4 3/16",
+		"3/4 5:12 A3 1/8",
+		"7/8 2:8 G4 1/16",
+		"15/16 5:/14 B3 1/16",
+		"1/1 2:b10 A4 1/8",
+		"9/8 1:0~ E4 1/8",
+		"21/16 1:3 G4 1/8",
+		"21/16 2:0 B3 1/8",
+		"21/16 3:0 G3 1/8",
+		"21/16 4:0 D3 1/8",
+		"21/16 5:2 B2 1/8",
+		"21/16 6:3 G2 1/8",
+	}
+	if got := tabSummary(tab.Notes); !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadTab() notes =\n%v\nwant\n%v", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestReadTab_Tuning(t *testing.T) {
+	tab, err := ReadTab(strings.NewReader("Tuning: D2 A2 D3 F#3 A3 D4\nD|-2-|\nA|---|\nF|---|\nD|---|\nA|---|\nD|-0-|\n"), MakeDuration(1, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tabSummary(tab.Notes), []string{"1/8 1:2 E4 1/4", "1/8 6:0 D2 1/4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadTab() notes = %v, want %v", got, want)
+	}
+
+	bass, err := ReadTab(strings.NewReader("G|---|\nD|---|\nA|---|\nE|-3-|\n"), MakeDuration(1, 8))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := tabSummary(bass.Notes), []string{"1/8 4:3 G1 1/4"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("ReadTab() bass notes = %v, want %v", got, want)
+	}
+
+	for _, text := range []string{"e|-0-|\nB|-0-|\nG|-0-|\n", "Tuning: E2 A2\ne|-0-|\nB|-0-|\nG|-0-|\n", "Tuning: H2\n", "no tab here\n"} {
+		if _, err := ReadTab(strings.NewReader(text), MakeDuration(1, 8)); err == nil {
+			t.Errorf("ReadTab(%q) should fail", text)
+		}
+	}
+}
+
+func TestTab_Write(t *testing.T) {
+	tab, err := ReadTab(strings.NewReader(tabFixture), MakeDuration(1, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var b strings.Builder
+	if err := tab.Write(&b, MakeDuration(1, 16)); err != nil {
+		t.Fatal(err)
+	}
+	// The second line of tab carries on from the first, and the bar line between them isn't kept
+	want := `Tuning: E2 A2 D3 G3 B3 E4
+e|------------------0~-3-|
+B|-----5h7------8b10---0-|
+G|---------7-----------0-|
+D|---------------------0-|
+A|-0----------12/14----2-|
+E|---------------------3-|
+`
+	if b.String() != want {
+		t.Errorf("Write() =\n%s\nwant\n%s", b.String(), want)
+	}
+	again, err := ReadTab(strings.NewReader(b.String()), MakeDuration(1, 16))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var rewritten strings.Builder
+	if err := again.Write(&rewritten, MakeDuration(1, 16)); err != nil {
+		t.Fatal(err)
+	}
+	if rewritten.String() != want {
+		t.Errorf("Write(ReadTab(Write())) =\n%s\nwant\n%s", rewritten.String(), want)
+	}
+}
+
+func TestTab_WriteMakesRoom(t *testing.T) {
+	sixteenth := MakeDuration(1, 16)
+	e4 := MakeSpelledPitch(LetterE, 0, 4).Pitch()
+	tab := &Tab{Tuning: createStandardTuning(), Notes: []TabNote{
+		{Start: Duration{}, Length: sixteenth, Pitch: *e4.GetTransposedCopy(12), String: 1, Fret: 12},
+		{Start: sixteenth, Length: sixteenth, Pitch: *e4.GetTransposedCopy(10), String: 1, Fret: 10, Technique: TabPullOff},
+		{Start: sixteenth.Mul(MakeDuration(2, 1)), Length: sixteenth, Pitch: e4, String: 1, Fret: 0},
+	}}
+	var b strings.Builder
+	if err := tab.Write(&b, sixteenth); err != nil {
+		t.Fatal(err)
+	}
+	if got, want := strings.Split(b.String(), "\n")[1], "e|12p10-0|"; got != want {
+		t.Errorf("Write() = %q, want %q", got, want)
+	}
+}
+
+func TestFretboard_Tab(t *testing.T) {
+	eighth := MakeDuration(1, 8)
+	notes := func(names ...string) []TimedNote {
+		timed := make([]TimedNote, 0, len(names))
+		for i, name := range names {
+			var pitches []SpelledPitch
+			for _, n := range strings.Split(name, "+") {
+				sp, rest, _ := parseChordSymbolNote(n)
+				pitches = append(pitches, *MakeSpelledPitch(sp.letter, sp.Alter(), int(rest[0]-'0')))
+			}
+			timed = append(timed, TimedNote{eighth.Mul(MakeDuration(int64(i), 1)), *makeNoteOfDuration(eighth, pitches...)})
+		}
+		return timed
+	}
+	tests := []struct {
+		name  string
+		notes []TimedNote
+		want  []string
+	}{
+		{"open C chord", notes("C3+E3+G3+C4+E4"), []string{
+			"0/1 1:0 E4 1/8", "0/1 2:1 C4 1/8", "0/1 3:0 G3 1/8", "0/1 4:2 E3 1/8", "0/1 5:3 C3 1/8"}},
+		{"stays in position", notes("E5", "D5", "C5", "A4"), []string{
+			"0/1 1:12 E5 1/8", "1/8 1:10 D5 1/8", "1/4 1:8 C5 1/8", "3/8 2:10 A4 1/8"}},
+		{"low notes in open position", notes("A2", "C3", "D3"), []string{
+			"0/1 5:0 A2 1/8", "1/8 5:3 C3 1/8", "1/4 4:0 D3 1/8"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tab, err := CreateGuitarFretboard().Tab(tt.notes)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got := tabSummary(tab.Notes); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Tab() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+	if _, err := CreateGuitarFretboard().Tab(notes("D2")); err == nil {
+		t.Error("Tab() below the lowest string should fail")
+	}
+	if _, err := CreateGuitarFretboard().Tab(notes("E2+F2")); err == nil {
+		t.Error("Tab() of two notes only playable on one string should fail")
+	}
+}
+
+func TestFretboard_Positions(t *testing.T) {
+	fretboard := CreateGuitarFretboard()
+	e4 := MakeSpelledPitch(LetterE, 0, 4).Pitch()
+	want := []FretPosition{{1, 0}, {2, 5}, {3, 9}, {4, 14}, {5, 19}}
+	if got := fretboard.Positions(e4); !reflect.DeepEqual(got, want) {
+		t.Errorf("Positions(E4) = %v, want %v", got, want)
+	}
+	for _, position := range want {
+		if got := fretboard.Pitch(position); got != e4 {
+			t.Errorf("Pitch(%v) = %v, want E4", position, got)
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package tonacity
+
+import (
+	"fmt"
+	"math"
+	"sort"
+)
+
+// tempoPoint A tempo that the music reaches at a point in it.
+type tempoPoint struct {
+	at      Duration // From the start of the piece
+	beat    Duration // The length of the beat counted, e.g. 1/4 for quarter notes or 3/8 for dotted quarters
+	bpm     float64  // Beats per minute
+	gradual bool     // Whether the tempo changes smoothly to this one from the one before, as in an accelerando or ritardando
+}
+
+// wholesPerMinute The tempo in whole notes per minute, which lets tempos with different beats be compared.
+func (p *tempoPoint) wholesPerMinute() float64 {
+	return p.bpm * p.beat.Float64()
+}
+
+// TempoMap How fast a piece is played at each point in it, which converts between positions in the music and times in seconds. The tempo
+// can change suddenly, or gradually over a passage as in an accelerando or ritardando, in which case it changes steadily from one beat
+// to the next.
+type TempoMap struct {
+	points []tempoPoint // In order, the first being at the start of the piece
+}
+
+// MakeTempoMap Creates a tempo map of the given number of beats per minute, where the beat lasts the given duration, e.g. 1/4 for 120
+// quarter notes a minute. If the beat or tempo isn't positive then nil is returned.
+func MakeTempoMap(beat Duration, bpm float64) *TempoMap {
+	if beat.Cmp(Duration{}) <= 0 || !(bpm > 0) {
+		return nil
+	}
+	return &TempoMap{[]tempoPoint{{beat: beat, bpm: bpm}}}
+}
+
+// SetTempo Changes the tempo suddenly at the given point, from the start of the piece, to the given number of beats per minute of the given
+// beat. Any change already at that point is replaced. An error is returned if the point is negative or the beat or tempo isn't positive.
+func (m *TempoMap) SetTempo(at Duration, beat Duration, bpm float64) error {
+	return m.add(tempoPoint{at, beat, bpm, false})
+}
+
+// RampTempo Changes the tempo steadily from whatever it is at the previous change up to (accelerando) or down to (ritardando) the given
+// number of beats per minute of the given beat, reaching it at the given point. Any change already at that point is replaced. An error is
+// returned if the point isn't after the start of the piece or the beat or tempo isn't positive.
+func (m *TempoMap) RampTempo(at Duration, beat Duration, bpm float64) error {
+	if at.Cmp(Duration{}) <= 0 {
+		return fmt.Errorf("a gradual tempo change must end after the start, not at %v", at)
+	}
+	return m.add(tempoPoint{at, beat, bpm, true})
+}
+
+func (m *TempoMap) add(point tempoPoint) error {
+	switch {
+	case point.at.Cmp(Duration{}) < 0:
+		return fmt.Errorf("tempo change at %v is before the start", point.at)
+	case point.beat.Cmp(Duration{}) <= 0:
+		return fmt.Errorf("tempo beat of %v isn't positive", point.beat)
+	case !(point.bpm > 0):
+		return fmt.Errorf("tempo of %v beats per minute isn't positive", point.bpm)
+	}
+	i := sort.Search(len(m.points), func(i int) bool { return m.points[i].at.Cmp(point.at) >= 0 })
+	if i < len(m.points) && m.points[i].at.Cmp(point.at) == 0 {
+		m.points[i] = point
+	} else {
+		m.points = append(m.points, tempoPoint{})
+		copy(m.points[i+1:], m.points[i:])
+		m.points[i] = point
+	}
+	return nil
+}
+
+// Tempo Returns the tempo at the given point, from the start of the piece, as a number of beats per minute of a beat. Part way through a
+// gradual change the beat is that of the tempo being changed to.
+func (m *TempoMap) Tempo(at Duration) (beat Duration, bpm float64) {
+	i := m.segment(at)
+	from := &m.points[i]
+	if i+1 < len(m.points) && m.points[i+1].gradual {
+		to := &m.points[i+1]
+		return to.beat, m.rateAt(i, at.Float64()) / to.beat.Float64()
+	}
+	return from.beat, from.bpm
+}
+
+// segment Returns the index of the last tempo point at or before the given point, which is the first if the point is before the start.
+func (m *TempoMap) segment(at Duration) int {
+	return max(sort.Search(len(m.points), func(i int) bool { return m.points[i].at.Cmp(at) > 0 })-1, 0)
+}
+
+// rateAt Returns the tempo in whole notes per minute at the given number of whole notes from the start, which must be in the given segment.
+func (m *TempoMap) rateAt(i int, x float64) float64 {
+	from := &m.points[i]
+	if i+1 >= len(m.points) || !m.points[i+1].gradual {
+		return from.wholesPerMinute()
+	}
+	to := &m.points[i+1]
+	start, length := from.at.Float64(), to.at.Sub(from.at).Float64()
+	return from.wholesPerMinute() + (to.wholesPerMinute()-from.wholesPerMinute())*(x-start)/length
+}
+
+// minutesInto Returns how many minutes it takes to play from the start of the given segment to the given number of whole notes from the
+// start of the piece, which may be negative before the segment.
+func (m *TempoMap) minutesInto(i int, x float64) float64 {
+	from := &m.points[i]
+	w0, w := from.wholesPerMinute(), m.rateAt(i, x)
+	if x < from.at.Float64() || math.Abs(w-w0) < 1e-12 {
+		return (x - from.at.Float64()) / w0
+	}
+	// The tempo changes steadily with position, so the time taken is the integral of 1/w, which is logarithmic
+	k := (w - w0) / (x - from.at.Float64())
+	return math.Log(w/w0) / k
+}
+
+// Seconds Returns how many seconds into the piece the given point, from its start, is played. Points before the start are negative.
+func (m *TempoMap) Seconds(at Duration) float64 {
+	i := m.segment(at)
+	var minutes float64
+	for j := 0; j < i; j++ {
+		minutes += m.minutesInto(j, m.points[j+1].at.Float64())
+	}
+	return (minutes + m.minutesInto(i, at.Float64())) * 60
+}
+
+// Sample Returns the index of the sample at the given sample rate that is played at the given point, rounded to the nearest sample.
+func (m *TempoMap) Sample(at Duration, sampleRate int) int {
+	return int(math.Round(m.Seconds(at) * float64(sampleRate)))
+}
+
+// Position Returns the point in the piece, from its start, that is played the given number of seconds in, rounded to the nearest multiple
+// of the given resolution, e.g. 1/16 to line detected notes up with sixteenths. If the resolution isn't positive then zero is returned.
+func (m *TempoMap) Position(seconds float64, resolution Duration) Duration {
+	if resolution.Cmp(Duration{}) <= 0 {
+		return Duration{}
+	}
+	minutes := seconds / 60
+	i := 0
+	for ; i+1 < len(m.points); i++ {
+		length := m.minutesInto(i, m.points[i+1].at.Float64())
+		if minutes < length {
+			break
+		}
+		minutes -= length
+	}
+	from := &m.points[i]
+	x := from.at.Float64() + minutes*from.wholesPerMinute()
+	if i+1 < len(m.points) && m.points[i+1].gradual && minutes > 0 {
+		to := &m.points[i+1]
+		w0 := from.wholesPerMinute()
+		if k := (to.wholesPerMinute() - w0) / to.at.Sub(from.at).Float64(); k != 0 {
+			// Inverting the time taken: w grows exponentially with time when it changes steadily with position
+			x = from.at.Float64() + w0/k*(math.Exp(k*minutes)-1)
+		}
+	}
+	steps := math.Round(x / resolution.Float64())
+	return MakeDuration(int64(steps)*resolution.numerator, resolution.Denominator())
+}
+
+// SamplePosition Returns the point in the piece, from its start, that is played at the given sample at the given sample rate, rounded to
+// the nearest multiple of the given resolution.
+func (m *TempoMap) SamplePosition(sample int, sampleRate int, resolution Duration) Duration {
+	return m.Position(float64(sample)/float64(sampleRate), resolution)
+}
+
+// TempoMap Returns the tempo map made by the tempo changes in every track of the file. MIDI has no gradual changes, so an accelerando is a
+// run of sudden ones. A file that doesn't set the tempo at its start begins at 120 quarter notes a minute, as the standard says.
+func (f *MidiFile) TempoMap() *TempoMap {
+	m := MakeTempoMap(MakeDuration(1, 4), 120)
+	ticksPerWhole := int64(max(f.TicksPerQuarter, 1)) * 4
+	for _, track := range f.Tracks {
+		for _, tempo := range track.Tempos {
+			if tempo.MicrosecondsPerQuarter > 0 {
+				_ = m.SetTempo(MakeDuration(int64(tempo.Tick), ticksPerWhole), MakeDuration(1, 4), tempo.BeatsPerMinute())
+			}
+		}
+	}
+	return m
+}
+
+// MidiTempos Returns the tempo changes of the map as MIDI tempo events at the given number of ticks per quarter note. A gradual change is
+// made of a sudden change on each of its beats, each timed so that the beat lasts as long as it does in the map.
+func (m *TempoMap) MidiTempos(ticksPerQuarter int) []MidiTempo {
+	ticksPerWhole := float64(ticksPerQuarter) * 4
+	tempo := func(from Duration, to Duration) MidiTempo {
+		quarters := to.Sub(from).Float64() * 4
+		seconds := m.Seconds(to) - m.Seconds(from)
+		return MidiTempo{uint32(math.Round(from.Float64() * ticksPerWhole)), uint32(math.Round(seconds / quarters * 1e6))}
+	}
+	var tempos []MidiTempo
+	for i := range m.points {
+		from := &m.points[i]
+		if i+1 >= len(m.points) || !m.points[i+1].gradual {
+			tempos = append(tempos, MidiTempo{uint32(math.Round(from.at.Float64() * ticksPerWhole)), uint32(math.Round(60e6 / (from.wholesPerMinute() * 4)))})
+			continue
+		}
+		to := &m.points[i+1]
+		for start := from.at; start.Cmp(to.at) < 0; {
+			end := start.Add(to.beat)
+			if end.Cmp(to.at) > 0 {
+				end = to.at
+			}
+			tempos = append(tempos, tempo(start, end))
+			start = end
+		}
+	}
+	return tempos
+}
@@ -0,0 +1,91 @@
+package tonacity
+
+import (
+	"math"
+	"testing"
+)
+
+func TestTempoMap_Seconds(t *testing.T) {
+	steady := MakeTempoMap(MakeDuration(1, 4), 120)
+	changing := MakeTempoMap(MakeDuration(1, 4), 120)
+	if err := changing.SetTempo(MakeDuration(1, 1), MakeDuration(1, 4), 60); err != nil {
+		t.Fatal(err)
+	}
+	accelerando := MakeTempoMap(MakeDuration(1, 4), 60)
+	if err := accelerando.RampTempo(MakeDuration(1, 1), MakeDuration(1, 4), 120); err != nil {
+		t.Fatal(err)
+	}
+	tests := []struct {
+		name    string
+		tempo   *TempoMap
+		at      Duration
+		seconds float64
+	}{
+		{"Start", steady, Duration{}, 0},
+		{"One bar at 120", steady, MakeDuration(1, 1), 2},
+		{"Before the start", steady, MakeDuration(-1, 4), -0.5},
+		{"Dotted quarter beat", MakeTempoMap(MakeDuration(3, 8), 60), MakeDuration(3, 4), 2},
+		{"At a change", changing, MakeDuration(1, 1), 2},
+		{"After a change", changing, MakeDuration(3, 2), 4},
+		{"Through an accelerando", accelerando, MakeDuration(1, 1), 4 * math.Ln2},
+		{"After an accelerando", accelerando, MakeDuration(2, 1), 4*math.Ln2 + 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.tempo.Seconds(tt.at); math.Abs(got-tt.seconds) > 1e-9 {
+				t.Errorf("Seconds(%v) = %v, want %v", tt.at, got, tt.seconds)
+			}
+			if got := tt.tempo.Position(tt.seconds, MakeDuration(1, 64)); got.Cmp(tt.at) != 0 {
+				t.Errorf("Position(%v) = %v, want %v", tt.seconds, got, tt.at)
+			}
+		})
+	}
+	if beat, bpm := accelerando.Tempo(MakeDuration(1, 2)); beat.Cmp(MakeDuration(1, 4)) != 0 || math.Abs(bpm-90) > 1e-9 {
+		t.Errorf("Tempo() half way through the accelerando = %v at %v, want 90 at 1/4", bpm, beat)
+	}
+	if got := steady.Sample(MakeDuration(1, 4), 44100); got != 22050 {
+		t.Errorf("Sample() = %v, want 22050", got)
+	}
+	if got := steady.SamplePosition(22000, 44100, MakeDuration(1, 16)); got.Cmp(MakeDuration(1, 4)) != 0 {
+		t.Errorf("SamplePosition() = %v, want 1/4", got)
+	}
+	if MakeTempoMap(MakeDuration(1, 4), 0) != nil || MakeTempoMap(Duration{}, 120) != nil {
+		t.Errorf("MakeTempoMap() should be nil for a tempo or beat that isn't positive")
+	}
+	if steady.SetTempo(MakeDuration(-1, 4), MakeDuration(1, 4), 60) == nil || steady.RampTempo(Duration{}, MakeDuration(1, 4), 60) == nil {
+		t.Errorf("Tempo changes before or ramping to the start should fail")
+	}
+}
+
+func TestTempoMap_Midi(t *testing.T) {
+	file := &MidiFile{TicksPerQuarter: 480, Tracks: []*MidiTrack{{Tempos: []MidiTempo{{0, 1000000}, {960, 500000}}}}}
+	tempo := file.TempoMap()
+	if got := tempo.Seconds(MakeDuration(1, 1)); math.Abs(got-3) > 1e-9 {
+		t.Errorf("Seconds() = %v, want 3", got)
+	}
+	if got := tempo.MidiTempos(480); len(got) != 2 || got[0] != (MidiTempo{0, 1000000}) || got[1] != (MidiTempo{960, 500000}) {
+		t.Errorf("MidiTempos() = %v", got)
+	}
+	if got := (&MidiFile{TicksPerQuarter: 96}).TempoMap().Seconds(MakeDuration(1, 4)); math.Abs(got-0.5) > 1e-9 {
+		t.Errorf("Seconds() with no tempo = %v, want 0.5", got)
+	}
+
+	ritardando := MakeTempoMap(MakeDuration(1, 4), 120)
+	if err := ritardando.RampTempo(MakeDuration(1, 1), MakeDuration(1, 4), 60); err != nil {
+		t.Fatal(err)
+	}
+	tempos := ritardando.MidiTempos(480)
+	if len(tempos) != 5 {
+		t.Fatalf("MidiTempos() = %v, want a tempo on each beat and one after", tempos)
+	}
+	var seconds float64
+	for i, tempo := range tempos[:4] {
+		if tempo.Tick != uint32(i*480) || (i > 0 && tempo.MicrosecondsPerQuarter <= tempos[i-1].MicrosecondsPerQuarter) {
+			t.Errorf("MidiTempos()[%d] = %v", i, tempo)
+		}
+		seconds += float64(tempo.MicrosecondsPerQuarter) / 1e6
+	}
+	if math.Abs(seconds-ritardando.Seconds(MakeDuration(1, 1))) > 1e-5 {
+		t.Errorf("MidiTempos() last %v seconds, want %v", seconds, ritardando.Seconds(MakeDuration(1, 1)))
+	}
+}
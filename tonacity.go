@@ -1,5 +1,14 @@
 package tonacity
 
+import (
+	"fmt"
+	"iter"
+	"math"
+	"sort"
+	"strconv"
+	"strings"
+)
+
 // Welcome to music theory, where nothing is unanimously agreed upon, there are multiple equivalent ways of saying the same thing, and the distance between two notes is a second.
 // Evidence:
 //  - What physical frequency is a particular note?
@@ -26,9 +35,25 @@ type HalfSteps int8
 // Singer Make that object SING. Something which can, given a valid starting pitch, produce an indefinite sequence of notes. For example, the G Major key signature will produce,
 // given a starting pitch of B3, the pitch classes B3, C4, D4, E4, F♯4, G4, A4, B4, C5, D5, etc.
 // Not all starting pitches may be valid, for example if the receiver is the key of G Major then a starting pitch of E♭, which is not in the key, is not valid.
+// Each call to Sing moves the singer on, so a singer is normally held by pointer; Reset takes it back to its first pitch.
 type Singer interface {
-	// Sing Generates the next note. Bool will be false if there are no more notes.
+	// Sing Generates the next note. Bool will be false if there are no more notes, in which case the pitch is meaningless.
 	Sing() (pitch Pitch, more bool)
+	// Reset Returns to the start, so that the next note sung is the first one again.
+	Reset()
+}
+
+// Pitches Returns the pitches the given singer has still to sing as a sequence, for use in a range loop. Ranging over the sequence
+// moves the singer on, exactly as calling Sing does. A singer that sings forever must be bounded, or the loop broken out of.
+func Pitches(singer Singer) iter.Seq[Pitch] {
+	return func(yield func(Pitch) bool) {
+		for {
+			pitch, more := singer.Sing()
+			if !more || !yield(pitch) {
+				return
+			}
+		}
+	}
 }
 
 // PitchClassProducer Something which contains a set of pitch classes, where order and starting point are irrelevant. For example, the G Major key signature will produce
@@ -45,45 +70,609 @@ type Transposer interface {
 }
 
 // PatternRepeatingSinger A singer that can produce pitches indefinitely. Unless the pattern of steps purposefully loops back, i.e. the sum of all is zero, then
-// this singer does NOT loop, i.e., given infinite time it will either tend to a pitch of negative or positive infinity hertz.
+// this singer does NOT loop, i.e., given infinite time it will either tend to a pitch of negative or positive infinity hertz. In practice it stops
+// once the next pitch would be out of range, and it can be bounded to a number of pitches, a pitch to stop at, or a single octave.
 type PatternRepeatingSinger struct {
 	pattern   Pattern
-	nextPitch Pitch
-	offset    int
+	start     Pitch  // The first pitch sung, returned to on Reset
+	nextPitch Pitch  // The pitch that will be sung next
+	offset    int    // The index into the pattern of the step that follows the next pitch
+	sung      int    // The number of pitches sung since the start
+	limit     int    // The most pitches to sing, or zero for no limit
+	until     *Pitch // The pitch to stop at, or nil to carry on
+	done      bool   // Whether the singer has finished, regardless of its bounds
 }
 
-// Sing Keeps producing the next pitch in the sequence according to its underlying pattern of half-step intervals
-func (singer PatternRepeatingSinger) Sing() (pitch Pitch, more bool) {
-	pitch = singer.nextPitch
-	singer.nextPitch.Transpose(singer.pattern.At(singer.offset))
+// newPatternRepeatingSinger Creates an unbounded singer of the given pattern that starts at the given pitch.
+func newPatternRepeatingSinger(pattern Pattern, start Pitch) *PatternRepeatingSinger {
+	return &PatternRepeatingSinger{pattern: pattern, start: start, nextPitch: start}
+}
+
+// Sing Keeps producing the next pitch in the sequence according to its underlying pattern of half-step intervals, until it reaches one of its bounds
+func (singer *PatternRepeatingSinger) Sing() (pitch Pitch, more bool) {
+	if singer.done || (singer.limit > 0 && singer.sung >= singer.limit) || singer.passed(&singer.nextPitch) {
+		return
+	}
+	pitch, more = singer.nextPitch, true
+	singer.sung++
+	if singer.pattern.Length() == 0 || (singer.until != nil && pitch.value == singer.until.value) {
+		singer.done = true
+		return
+	}
+	step := singer.pattern.At(singer.offset)
+	if next := int(pitch.value) + int(step); next < math.MinInt8 || next > math.MaxInt8 {
+		// The next pitch can't be represented, so this is the last one
+		singer.done = true
+		return
+	}
+	singer.nextPitch.Transpose(step)
 	singer.offset = (singer.offset + 1) % singer.pattern.Length()
-	// Can always produce more notes
-	more = true
 	return
 }
 
+// passed Whether the given pitch lies beyond the pitch this singer stops at, as seen from its start, i.e. the singer has stepped over it.
+func (singer *PatternRepeatingSinger) passed(pitch *Pitch) bool {
+	return singer.until != nil && steppedOver(&singer.start, singer.until, pitch)
+}
+
+// steppedOver Whether a sequence heading from start towards until has gone beyond until by the time it reaches pitch.
+func steppedOver(start *Pitch, until *Pitch, pitch *Pitch) bool {
+	toUntil, remaining := start.GetDistanceTo(until), pitch.GetDistanceTo(until)
+	return (toUntil >= 0 && remaining < 0) || (toUntil <= 0 && remaining > 0)
+}
+
+// Reset Returns the singer to its starting pitch, keeping any bounds.
+func (singer *PatternRepeatingSinger) Reset() {
+	singer.nextPitch = singer.start
+	singer.offset = 0
+	singer.sung = 0
+	singer.done = false
+}
+
+// Limit Bounds the singer to sing at most count pitches, counted from its start. A count of zero or less removes the limit. Returns the singer.
+func (singer *PatternRepeatingSinger) Limit(count int) *PatternRepeatingSinger {
+	singer.limit = max(count, 0)
+	return singer
+}
+
+// Until Bounds the singer to stop once it has sung the given pitch. If the pattern steps over the pitch without landing on it, e.g. because
+// it isn't in the scale, then the singer stops before the first pitch beyond it. Returns the singer.
+func (singer *PatternRepeatingSinger) Until(pitch Pitch) *PatternRepeatingSinger {
+	singer.until = &pitch
+	return singer
+}
+
+// OneOctave Bounds the singer to a single octave from its start, including the pitch an octave away, e.g. C4 to C5 for an ascending major
+// scale. A pattern that loops back to where it started is instead sung through once, ending on its first pitch again. Returns the singer.
+func (singer *PatternRepeatingSinger) OneOctave() *PatternRepeatingSinger {
+	var sum int
+	for _, step := range singer.pattern.intervals {
+		sum += int(step)
+	}
+	switch {
+	case sum > 0:
+		return singer.Until(*singer.start.GetTransposedCopy(OctaveValue))
+	case sum < 0:
+		return singer.Until(*singer.start.GetTransposedCopy(-OctaveValue))
+	default:
+		return singer.Limit(singer.pattern.Length() + 1)
+	}
+}
+
+// KeySignature The sharps or flats written at the start of a stave, along with the key they imply. The same signature is shared by
+// a major key and its relative minor, e.g. one sharp is both G Major and E Minor, so the tonic is kept alongside it.
 type KeySignature struct {
-	pitches []*PitchClass
+	fifths int8       // The number of sharps (positive) or flats (negative), i.e., steps around the circle of fifths from C
+	tonic  PitchClass // The key note
+}
+
+// MakeKeySignature Creates the key signature with the given number of sharps (positive) or flats (negative) for a major or minor key.
+// If there are more than seven sharps or flats then nil is returned.
+func MakeKeySignature(fifths int, minor bool) *KeySignature {
+	if fifths < -7 || fifths > 7 {
+		return nil
+	}
+	tonic := C().GetTransposedCopy(HalfSteps(fifths * PerfectFifth % OctaveValue))
+	if minor {
+		tonic.Transpose(-MinorThird)
+	}
+	return &KeySignature{int8(fifths), *tonic}
+}
+
+// MakeModalKeySignature Creates the key signature of a mode, named as in BuildModeDictionary (e.g. "Dorian"), whose tonic is the given
+// letter raised (positive) or lowered (negative) by the given number of half steps. For example D Dorian has no sharps or flats, like
+// C Major. If the mode isn't known, or the key would need more than seven sharps or flats, then nil is returned.
+func MakeModalKeySignature(letter Letter, alter int, mode string) *KeySignature {
+	dict := BuildModeDictionary()
+	for degree, pattern := range CreateModes() {
+		if name, _ := dict.GetName(pattern); !strings.EqualFold(name, mode) {
+			continue
+		}
+		// The tonic is this many steps up the major scale of the key signature
+		var above HalfSteps
+		major := CreateMajorScale()
+		for i := 0; i < degree; i++ {
+			above += major.At(i)
+		}
+		natural := letter.Natural()
+		tonic := natural.GetTransposedCopy(HalfSteps(alter))
+		for fifths := -7; fifths <= 7; fifths++ {
+			key := MakeKeySignature(fifths, false)
+			// Each fifth is four letters further on, e.g. one sharp is G Major, four letters up from C
+			majorLetter := Letter(((4*fifths)%LettersInOctave + LettersInOctave) % LettersInOctave)
+			if (majorLetter+Letter(degree))%LettersInOctave == letter && *key.tonic.GetTransposedCopy(above) == *tonic {
+				key.tonic = *tonic
+				return key
+			}
+		}
+		return nil
+	}
+	return nil
+}
+
+// Fifths The number of sharps (positive) or flats (negative) in this key signature.
+func (k *KeySignature) Fifths() int {
+	return int(k.fifths)
+}
+
+// Tonic The key note of this key, e.g. G in G Major.
+func (k *KeySignature) Tonic() PitchClass {
+	return k.tonic
+}
+
+// MajorTonic The key note of the major key that has this key signature, e.g. G for E Minor.
+func (k *KeySignature) MajorTonic() PitchClass {
+	return *C().GetTransposedCopy(HalfSteps(int(k.fifths) * PerfectFifth % OctaveValue))
+}
+
+// IsMinor Returns true if this is the key signature of a minor key.
+func (k *KeySignature) IsMinor() bool {
+	major := k.MajorTonic()
+	return major.GetDistanceToHigherPitchClass(k.tonic) == MajorSixth
+}
+
+// Mode Returns the name of the mode this key is in, as named by BuildModeDictionary, e.g. "Aeolian" for A Minor.
+func (k *KeySignature) Mode() string {
+	major := CreateMajorScale()
+	pc := k.MajorTonic()
+	for degree, pattern := range CreateModes() {
+		if pc == k.tonic {
+			name, _ := BuildModeDictionary().GetName(pattern)
+			return name
+		}
+		pc.Transpose(major.At(degree))
+	}
+	return ""
+}
+
+// Transpose Moves the key by the given number of half steps, keeping its mode. The new tonic is spelled whichever way needs fewer sharps
+// or flats, so moving D Major up a half step gives E♭ Major rather than D♯ Major. If both need the same number, as for F♯ and G♭, then
+// sharps are used when moving up and flats when moving down.
+func (k *KeySignature) Transpose(halfSteps HalfSteps) {
+	mode := k.Mode()
+	tonic := k.tonic.GetTransposedCopy(halfSteps)
+	var best *KeySignature
+	for letter := LetterC; letter <= LetterB; letter++ {
+		alter := normalisePitchClassValue(tonic.value - naturalValues[letter])
+		if alter > OctaveValue/2 {
+			alter -= OctaveValue
+		}
+		key := MakeModalKeySignature(letter, int(alter), mode)
+		if key == nil {
+			continue
+		}
+		if best == nil || abs(key.Fifths()) < abs(best.Fifths()) ||
+			abs(key.Fifths()) == abs(best.Fifths()) && (key.fifths > 0) == (halfSteps > 0) {
+			best = key
+		}
+	}
+	if best != nil {
+		*k = *best
+	}
+}
+
+// ProducePitchClasses Returns the seven pitch classes in the key, in ascending order.
+func (k *KeySignature) ProducePitchClasses() []*PitchClass {
+	pattern := CreateMajorScale()
+	pitches := make([]*PitchClass, 0, NotesInMode)
+	pc := k.MajorTonic()
+	for i := 0; i < pattern.Length(); i++ {
+		pitches = append(pitches, pc.GetTransposedCopy(0))
+		pc.Transpose(pattern.At(i))
+	}
+	sort.Slice(pitches, func(i, j int) bool { return pitches[i].value < pitches[j].value })
+	return pitches
 }
 
+// TimeSignature The number of beats in a bar, and the value of the note that makes up each beat.
 type TimeSignature struct {
-	noteCount int
-	noteValue int
+	noteCount int // The number of beats in a bar
+	noteValue int // The value of each beat, as one over this value, e.g. 4 for quarter notes
+	// The number of beats in each group the bar is felt in, e.g. 2 2 3 for 7/8, up to the first zero. All zero for the usual grouping.
+	// An array rather than a slice keeps time signatures comparable.
+	groups [maxBeatGroups]uint8
+}
+
+// maxBeatGroups The most groups the beats of a bar can be divided into.
+const maxBeatGroups = 16
+
+// MakeTimeSignature Creates the time signature with the given number of beats of the given note value, e.g. (6, 8) for 6/8. If the count
+// isn't positive, or the value isn't a positive power of two, then nil is returned.
+func MakeTimeSignature(noteCount int, noteValue int) *TimeSignature {
+	if noteCount < 1 || noteValue < 1 || noteValue&(noteValue-1) != 0 {
+		return nil
+	}
+	return &TimeSignature{noteCount: noteCount, noteValue: noteValue}
+}
+
+// MakeGroupedTimeSignature Creates the time signature of beats of the given note value divided into groups of the given numbers of beats,
+// e.g. (8, 2, 2, 3) for 7/8 felt as 2+2+3. The number of beats in the bar is the total of the groups. If a group isn't positive, there
+// are more than 16 groups, or the value isn't a positive power of two, then nil is returned.
+func MakeGroupedTimeSignature(noteValue int, groups ...int) *TimeSignature {
+	if len(groups) == 0 || len(groups) > maxBeatGroups {
+		return nil
+	}
+	ts := &TimeSignature{noteValue: noteValue}
+	for i, group := range groups {
+		if group < 1 || group > math.MaxUint8 {
+			return nil
+		}
+		ts.groups[i] = uint8(group)
+		ts.noteCount += group
+	}
+	if MakeTimeSignature(ts.noteCount, noteValue) == nil {
+		return nil
+	}
+	return ts
+}
+
+// NoteCount The number of beats in a bar, i.e., the top number.
+func (ts *TimeSignature) NoteCount() int {
+	return ts.noteCount
+}
+
+// NoteValue The value of each beat, as one over this value, i.e., the bottom number.
+func (ts *TimeSignature) NoteValue() int {
+	return ts.noteValue
+}
+
+// Duration The length of a full bar in this time signature.
+func (ts *TimeSignature) Duration() Duration {
+	return MakeDuration(int64(ts.noteCount), int64(ts.noteValue))
 }
 
+// Groups The number of beats in each group the bar is felt in. Unless the groups were given, compound time (where the number of beats
+// is a multiple of three greater than three, e.g. 6/8) is grouped in threes, and irregular time with an odd number of beats from five,
+// e.g. 7/8, is grouped in twos ending with a three, e.g. 2+2+3. Otherwise each beat is a group of its own.
+func (ts *TimeSignature) Groups() []int {
+	var groups []int
+	for _, group := range ts.groups {
+		if group == 0 {
+			break
+		}
+		groups = append(groups, int(group))
+	}
+	if len(groups) > 0 {
+		return groups
+	}
+	size := 1
+	switch {
+	case ts.IsCompound():
+		size = 3
+	case ts.noteCount >= 5 && ts.noteCount%2 == 1:
+		size = 2
+	}
+	for beats := ts.noteCount; beats > 0; beats -= size {
+		if size == 2 && beats == 3 {
+			return append(groups, 3)
+		}
+		groups = append(groups, size)
+	}
+	return groups
+}
+
+// writtenGroups The groups of beats joined by sep, e.g. "2+2+3", or "" if the groups weren't given.
+func (ts *TimeSignature) writtenGroups(sep string) string {
+	if ts.groups[0] == 0 {
+		return ""
+	}
+	groups := ts.Groups()
+	written := make([]string, len(groups))
+	for i, group := range groups {
+		written[i] = strconv.Itoa(group)
+	}
+	return strings.Join(written, sep)
+}
+
+// IsCompound Returns true if each beat divides into three, i.e. the number of beats written is a multiple of three greater than three,
+// e.g. 6/8 or 12/8, and the beats haven't been grouped otherwise.
+func (ts *TimeSignature) IsCompound() bool {
+	return ts.groups[0] == 0 && ts.noteCount > 3 && ts.noteCount%3 == 0
+}
+
+// Beats The length of each group of beats the bar is felt in, e.g. two dotted quarters for 6/8, or a quarter, a quarter and a dotted
+// quarter for 7/8 grouped 2+2+3.
+func (ts *TimeSignature) Beats() []Duration {
+	groups := ts.Groups()
+	beats := make([]Duration, len(groups))
+	for i, group := range groups {
+		beats[i] = MakeDuration(int64(group), int64(ts.noteValue))
+	}
+	return beats
+}
+
+func (ts *TimeSignature) String() string {
+	return fmt.Sprintf("%d/%d", ts.noteCount, ts.noteValue)
+}
+
+// Bar A bar (or measure) of notes, along with the time signature, key signature and clef in force while it is played. These are
+// recorded in every bar, not just where they change, so a bar can be understood on its own.
 type Bar struct {
-	time TimeSignature
+	time  TimeSignature // The time signature, or the zero value if the bar is unmetered
+	key   *KeySignature // The key signature, or nil if there isn't one
+	clef  *Clef         // The clef, or nil if there isn't one
+	notes []Note
+}
+
+// MakeBar Creates an empty bar with the given time signature, key signature and clef, any of which may be nil.
+func MakeBar(time *TimeSignature, key *KeySignature, clef *Clef) *Bar {
+	bar := &Bar{key: key, clef: clef}
+	if time != nil {
+		bar.time = *time
+	}
+	return bar
 }
 
+// TimeSignature The time signature of this bar, or nil if it is unmetered.
+func (b *Bar) TimeSignature() *TimeSignature {
+	if b.time.noteCount == 0 {
+		return nil
+	}
+	ts := b.time
+	return &ts
+}
+
+// KeySignature The key signature of this bar, or nil if it doesn't have one.
+func (b *Bar) KeySignature() *KeySignature {
+	return b.key
+}
+
+// Clef The clef of this bar, or nil if it doesn't have one.
+func (b *Bar) Clef() *Clef {
+	return b.clef
+}
+
+// Notes The notes in this bar, in the order they are played. Modifying the returned slice modifies the bar.
+func (b *Bar) Notes() []Note {
+	return b.notes
+}
+
+// AddNotes Appends the given notes to the end of the bar.
+func (b *Bar) AddNotes(notes ...Note) {
+	b.notes = append(b.notes, notes...)
+}
+
+// Duration The total duration of the notes in this bar.
+func (b *Bar) Duration() Duration {
+	var total Duration
+	for i := range b.notes {
+		total = total.Add(b.notes[i].duration)
+	}
+	return total
+}
+
+// Note A single pitch, several pitches sounding together as a chord, or a rest, written as a note value.
 type Note struct {
 	// The value of this note is one over this value
-	value int
+	value    int
+	dots     int            // Each dot lengthens the note by half as much as the last
+	duration Duration       // How long the note lasts, which differs from its written value inside a tuplet
+	pitches  []SpelledPitch // The pitches that sound, or none for a rest
+	tied     bool           // Whether the note is held on into the next one, which sounds the same pitches
+}
+
+// MakeNote Creates a note of the given value (e.g. 4 for a quarter note) with the given number of dots, sounding the given pitches. If
+// no pitches are given then the note is a rest. A value of 0 creates a note whose length isn't a note value, e.g. a whole bar rest in
+// 3/4, and its duration must then be set.
+func MakeNote(value int, dots int, pitches ...SpelledPitch) *Note {
+	note := &Note{value: value, dots: dots, pitches: pitches}
+	if value > 0 {
+		note.duration = NoteValueDuration(value, dots)
+	}
+	return note
+}
+
+// MakePitchedNote Creates a note of the given value with the given number of dots, sounding the given pitches spelled as they would be
+// in the given key (nil for C Major), e.g. the pitches of a chord. If no pitches are given then the note is a rest.
+func MakePitchedNote(value int, dots int, key *KeySignature, pitches ...Pitch) *Note {
+	spelled := make([]SpelledPitch, len(pitches))
+	for i, pitch := range pitches {
+		spelled[i] = SpellPitch(pitch, key)
+	}
+	return MakeNote(value, dots, spelled...)
+}
+
+// MakeTiedNotes Writes the given pitches held for the given duration as notes tied together, using as few note values as possible, e.g.
+// 5/8 is a half note tied to an eighth. Every note but the last is tied to the next, unless there are no pitches, in which case they are
+// rests. A duration that can't be made from note values down to a 128th, e.g. a triplet, is a single note of value 0 lasting the duration.
+func MakeTiedNotes(duration Duration, pitches ...SpelledPitch) []Note {
+	if duration.Cmp(Duration{}) <= 0 {
+		return nil
+	}
+	if d := duration.Denominator(); d > 128 || d&(d-1) != 0 {
+		return []Note{*makeNoteOfDuration(duration, pitches...)}
+	}
+	var notes []Note
+	for !duration.IsZero() {
+		// Take the longest note value that fits, preferring fewer dots
+		longest := MakeNote(128, 0, pitches...)
+		for value := 1; value <= 128; value *= 2 {
+			for dots := 0; dots < 3; dots++ {
+				if note := MakeNote(value, dots, pitches...); note.duration.Cmp(duration) <= 0 && note.duration.Cmp(longest.duration) > 0 {
+					longest = note
+				}
+			}
+		}
+		if len(notes) > 0 && len(pitches) > 0 {
+			notes[len(notes)-1].tied = true
+		}
+		notes = append(notes, *longest)
+		duration = duration.Sub(longest.duration)
+	}
+	return notes
+}
+
+// makeNoteOfDuration Creates a note sounding the given pitches that lasts the given duration, written as a dotted note value if one
+// matches, or with a value of 0 if none does.
+func makeNoteOfDuration(duration Duration, pitches ...SpelledPitch) *Note {
+	for value := 1; value <= 128; value *= 2 {
+		for dots := 0; dots < 3; dots++ {
+			if note := MakeNote(value, dots, pitches...); note.duration.Cmp(duration) == 0 {
+				return note
+			}
+		}
+	}
+	note := MakeNote(0, 0, pitches...)
+	note.SetDuration(duration)
+	return note
+}
+
+// MakeRest Creates a rest of the given value and number of dots.
+func MakeRest(value int, dots int) *Note {
+	return MakeNote(value, dots)
+}
+
+// Value The written value of the note, as one over this value, or 0 if it doesn't have one.
+func (n *Note) Value() int {
+	return n.value
+}
+
+// Dots The number of dots written after the note.
+func (n *Note) Dots() int {
+	return n.dots
+}
+
+// Duration How long the note lasts.
+func (n *Note) Duration() Duration {
+	return n.duration
+}
+
+// SetDuration Sets how long the note lasts, for when it differs from the written value, e.g. in a tuplet.
+func (n *Note) SetDuration(duration Duration) {
+	n.duration = duration
+}
+
+// SetTuplet Makes the note part of a tuplet of the given number of notes played in the time of the given number of normal notes, e.g.
+// 3 in the time of 2 for a triplet, or 5 in the time of 4 for a quintuplet. A note without a note value is left as it is.
+func (n *Note) SetTuplet(actual int, normal int) {
+	if n.value == 0 || actual <= 0 || normal <= 0 {
+		return
+	}
+	n.duration = NoteValueDuration(n.value, n.dots).Mul(MakeDuration(int64(normal), int64(actual)))
+}
+
+// Tuplet Returns the number of notes played in the time of the number of normal notes of the tuplet this note is part of, in lowest
+// terms, e.g. 3 and 2 for a triplet. Both are 1 for a note that isn't in a tuplet.
+func (n *Note) Tuplet() (actual int, normal int) {
+	ratio := n.tupletRatio()
+	return int(ratio.numerator), int(ratio.Denominator())
 }
 
+// Tied Returns true if the note is held on into the next one, rather than being played again.
+func (n *Note) Tied() bool {
+	return n.tied
+}
+
+// SetTied Sets whether the note is held on into the next one, which should sound the same pitches.
+func (n *Note) SetTied(tied bool) {
+	n.tied = tied
+}
+
+// tupletRatio Returns the ratio of the note's written duration to its actual duration, e.g. 3/2 for a triplet. It is 1/1 outside tuplets.
+func (n *Note) tupletRatio() Duration {
+	if n.value == 0 || n.duration.IsZero() {
+		return MakeDuration(1, 1)
+	}
+	written := MakeNote(n.value, n.dots).duration
+	return MakeDuration(written.numerator*n.duration.Denominator(), written.Denominator()*n.duration.numerator)
+}
+
+// Pitches The pitches that sound, which is empty if the note is a rest.
+func (n *Note) Pitches() []SpelledPitch {
+	pitches := make([]SpelledPitch, len(n.pitches))
+	copy(pitches, n.pitches)
+	return pitches
+}
+
+// IsRest Returns true if no pitches sound.
+func (n *Note) IsRest() bool {
+	return len(n.pitches) == 0
+}
+
+// TimedNote A note along with when it starts, measured from the beginning of the piece.
+type TimedNote struct {
+	Start Duration
+	Note  Note
+}
+
+// Stave A sequence of bars, written on the same set of lines.
 type Stave struct {
 	bars []Bar
 }
 
+// MakeStave Creates a stave containing the given bars.
+func MakeStave(bars ...Bar) *Stave {
+	return &Stave{bars}
+}
+
+// Bars The bars on this stave. Modifying the returned slice modifies the stave.
+func (s *Stave) Bars() []Bar {
+	return s.bars
+}
+
+// AddBars Appends the given bars to the end of the stave.
+func (s *Stave) AddBars(bars ...Bar) {
+	s.bars = append(s.bars, bars...)
+}
+
+// ClefSign The symbol drawn for a clef, which marks the line a particular note is written on.
+type ClefSign uint8
+
+const (
+	// GClef The treble clef symbol, which curls around the line G4 is written on.
+	GClef ClefSign = iota
+	// FClef The bass clef symbol, whose dots sit either side of the line F3 is written on.
+	FClef
+	// CClef The alto/tenor clef symbol, which is centred on the line middle C is written on.
+	CClef
+	// PercussionClef Two thick vertical lines, for unpitched percussion.
+	PercussionClef
+)
+
+// Clef A clef symbol placed on one of the lines of the stave, which fixes the pitch of every line and space.
 type Clef struct {
-	firstNote int
+	sign         ClefSign
+	line         int // The line the symbol is placed on, counting up from 1 at the bottom
+	octaveChange int // The number of octaves the music sounds above (positive) or below (negative) where it is written
+}
+
+// MakeClef Creates a clef with the given symbol on the given line, counting up from 1 at the bottom, which sounds the given number of
+// octaves higher (positive) or lower (negative) than written.
+func MakeClef(sign ClefSign, line int, octaveChange int) *Clef {
+	return &Clef{sign, line, octaveChange}
+}
+
+// Sign The symbol of this clef.
+func (c *Clef) Sign() ClefSign {
+	return c.sign
+}
+
+// Line The line this clef is placed on, counting up from 1 at the bottom.
+func (c *Clef) Line() int {
+	return c.line
+}
+
+// OctaveChange The number of octaves this clef sounds above or below where it is written.
+func (c *Clef) OctaveChange() int {
+	return c.octaveChange
 }
@@ -0,0 +1,199 @@
+package tonacity
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestMakeKeySignature(t *testing.T) {
+	namer := CreateFlatPitchNamer()
+	tests := []struct {
+		name        string
+		fifths      int
+		minor       bool
+		wantTonic   string
+		wantClasses []*PitchClass
+	}{
+		{"C Major", 0, false, "C", []*PitchClass{C(), D(), E(), F(), G(), A(), B()}},
+		{"A Minor", 0, true, "A", []*PitchClass{C(), D(), E(), F(), G(), A(), B()}},
+		{"G Major", 1, false, "G", []*PitchClass{C(), D(), E(), F().Sharp(), G(), A(), B()}},
+		{"E♭ Major", -3, false, "E♭", []*PitchClass{C(), D(), E().Flat(), F(), G(), A().Flat(), B().Flat()}},
+		{"B♭ Minor", -5, true, "B♭", []*PitchClass{C(), D().Flat(), E().Flat(), F(), G().Flat(), A().Flat(), B().Flat()}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := MakeKeySignature(tt.fifths, tt.minor)
+			if got := namer.Name(k.Tonic()); got != tt.wantTonic {
+				t.Errorf("KeySignature.Tonic() = %v, want %v", got, tt.wantTonic)
+			}
+			if got := k.IsMinor(); got != tt.minor {
+				t.Errorf("KeySignature.IsMinor() = %v, want %v", got, tt.minor)
+			}
+			if got := k.ProducePitchClasses(); !reflect.DeepEqual(got, tt.wantClasses) {
+				t.Errorf("KeySignature.ProducePitchClasses() = %v, want %v", got, tt.wantClasses)
+			}
+		})
+	}
+	if k := MakeKeySignature(8, false); k != nil {
+		t.Errorf("MakeKeySignature(8) = %v, want nil", k)
+	}
+}
+
+func TestMakeModalKeySignature(t *testing.T) {
+	tests := []struct {
+		name       string
+		letter     Letter
+		alter      int
+		mode       string
+		wantFifths int
+	}{
+		{"D Dorian", LetterD, 0, "Dorian", 0},
+		{"E Phrygian", LetterE, 0, "Phrygian", 0},
+		{"G Mixolydian", LetterG, 0, "Mixolydian", 0},
+		{"A Mixolydian", LetterA, 0, "mixolydian", 2},
+		{"F♯ Aeolian", LetterF, 1, "Aeolian", 3},
+		{"G♭ Ionian", LetterG, -1, "Ionian", -6},
+		{"F♯ Ionian", LetterF, 1, "Ionian", 6},
+		{"B♭ Lydian", LetterB, -1, "Lydian", -1},
+		{"C♯ Locrian", LetterC, 1, "Locrian", 2},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			k := MakeModalKeySignature(tt.letter, tt.alter, tt.mode)
+			if k == nil {
+				t.Fatal("MakeModalKeySignature() = nil")
+			}
+			if k.Fifths() != tt.wantFifths {
+				t.Errorf("KeySignature.Fifths() = %v, want %v", k.Fifths(), tt.wantFifths)
+			}
+			if got := k.Mode(); !strings.EqualFold(got, tt.mode) {
+				t.Errorf("KeySignature.Mode() = %v, want %v", got, tt.mode)
+			}
+			natural := tt.letter.Natural()
+			if want := natural.GetTransposedCopy(HalfSteps(tt.alter)); k.Tonic() != *want {
+				t.Errorf("KeySignature.Tonic() = %v, want %v", k.Tonic(), *want)
+			}
+		})
+	}
+	if k := MakeModalKeySignature(LetterF, -1, "Locrian"); k != nil {
+		t.Errorf("MakeModalKeySignature(F♭ Locrian) = %v, want nil", k)
+	}
+	if k := MakeModalKeySignature(LetterC, 0, "Hypodorian"); k != nil {
+		t.Errorf("MakeModalKeySignature(C Hypodorian) = %v, want nil", k)
+	}
+}
+
+func TestKeySignature_Transpose(t *testing.T) {
+	tests := []struct {
+		name       string
+		key        *KeySignature
+		halfSteps  HalfSteps
+		wantFifths int
+		wantMode   string
+	}{
+		{"D Major up a half step", MakeKeySignature(2, false), 1, -3, "Ionian"},
+		{"G Major up a fifth", MakeKeySignature(1, false), PerfectFifth, 2, "Ionian"},
+		{"C Major up a tritone", MakeKeySignature(0, false), 6, 6, "Ionian"},
+		{"C Major down a tritone", MakeKeySignature(0, false), -6, -6, "Ionian"},
+		{"A Minor down a whole step", MakeKeySignature(0, true), -2, -2, "Aeolian"},
+		{"D Dorian up a fourth", MakeModalKeySignature(LetterD, 0, "Dorian"), PerfectFourth, -1, "Dorian"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.key.Transpose(tt.halfSteps)
+			if tt.key.Fifths() != tt.wantFifths || tt.key.Mode() != tt.wantMode {
+				t.Errorf("KeySignature.Transpose() = %d %s, want %d %s", tt.key.Fifths(), tt.key.Mode(), tt.wantFifths, tt.wantMode)
+			}
+		})
+	}
+}
+
+func TestMakeTimeSignature(t *testing.T) {
+	tests := []struct {
+		name      string
+		noteCount int
+		noteValue int
+		valid     bool
+	}{
+		{"4/4", 4, 4, true},
+		{"6/8", 6, 8, true},
+		{"0/4", 0, 4, false},
+		{"3/3", 3, 3, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ts := MakeTimeSignature(tt.noteCount, tt.noteValue)
+			if (ts != nil) != tt.valid {
+				t.Fatalf("MakeTimeSignature() = %v, want valid %v", ts, tt.valid)
+			}
+			if ts != nil && ts.String() != tt.name {
+				t.Errorf("TimeSignature.String() = %v, want %v", ts.String(), tt.name)
+			}
+		})
+	}
+}
+
+func TestPatternRepeatingSinger(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	major := CreateMajorScale()
+	tests := []struct {
+		name   string
+		singer *PatternRepeatingSinger
+		want   string
+	}{
+		{"Two octaves ascending", major.CreateAscendingSinger(*pf.GetPitch(C(), 4)).Limit(15), "C4 D4 E4 F4 G4 A4 B4 C5 D5 E5 F5 G5 A5 B5 C6"},
+		{"Two octaves descending", major.CreateDescendingSinger(*pf.GetPitch(C(), 6)).Until(*pf.GetPitch(C(), 4)), "C6 B5 A5 G5 F5 E5 D5 C5 B4 A4 G4 F4 E4 D4 C4"},
+		{"One octave ascending", CreateMinorScale().CreateAscendingSinger(*pf.GetPitch(A(), 3)).OneOctave(), "A3 B3 C4 D4 E4 F4 G4 A4"},
+		{"One octave descending", major.CreateDescendingSinger(*pf.GetPitch(D(), 5)).OneOctave(), "D5 C#5 B4 A4 G4 F#4 E4 D4"},
+		{"Pentatonic over three octaves", CreateMajorPentatonicScalePattern().CreateAscendingSinger(*pf.GetPitch(G(), 2)).Until(*pf.GetPitch(G(), 5)), "G2 A2 B2 D3 E3 G3 A3 B3 D4 E4 G4 A4 B4 D5 E5 G5"},
+		{"Until a pitch outside the scale", major.CreateAscendingSinger(*pf.GetPitch(C(), 4)).Until(*pf.GetPitch(F().Sharp(), 4)), "C4 D4 E4 F4"},
+		{"Looping pattern", MakePattern(4, -4).CreateAscendingSinger(*pf.GetPitch(C(), 4)).OneOctave(), "C4 E4 C4"},
+		{"Rooted pattern", (&RootedPattern{*major, *pf.GetPitch(E().Flat(), 4)}).CreateSinger().Limit(4), "D#4 F4 G4 G#4"},
+		{"Rooted pattern in reverse", (&RootedPattern{*major, *pf.GetPitch(E().Flat(), 4)}).CreateReverseSinger().Limit(4), "D#4 D4 C4 A#3"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var names []string
+			for pitch := range Pitches(tt.singer) {
+				names = append(names, pitch.String())
+			}
+			if got := strings.Join(names, " "); got != tt.want {
+				t.Errorf("Pitches() = %v, want %v", got, tt.want)
+			}
+			if _, more := tt.singer.Sing(); more {
+				t.Error("Sing() should stop once the sequence is over")
+			}
+		})
+	}
+	var values []HalfSteps
+	for pitch := range Pitches(CreateChromaticScalePattern().CreateAscendingSinger(*A4().GetTransposedCopy(125))) {
+		values = append(values, A4().GetDistanceTo(&pitch))
+	}
+	if want := []HalfSteps{125, 126, 127}; !reflect.DeepEqual(values, want) {
+		t.Errorf("Pitches() near the top of the range = %v, want %v", values, want)
+	}
+}
+
+func TestPatternRepeatingSinger_Reset(t *testing.T) {
+	pf := &PitchFactory{*MiddleC()}
+	var singer Singer = CreateMajorScale().CreateAscendingSinger(*pf.GetPitch(C(), 4))
+	for i, want := range []string{"C4", "D4", "E4"} {
+		if pitch, more := singer.Sing(); !more || pitch.String() != want {
+			t.Fatalf("Sing() %d = %v, %v, want %v", i, pitch.String(), more, want)
+		}
+	}
+	singer.Reset()
+	var names []string
+	for pitch := range Pitches(singer) {
+		if names = append(names, pitch.String()); len(names) == 2 {
+			break
+		}
+	}
+	if got := strings.Join(names, " "); got != "C4 D4" {
+		t.Errorf("Pitches() after Reset() = %v, want C4 D4", got)
+	}
+	if pitch, _ := singer.Sing(); pitch.String() != "E4" {
+		t.Errorf("Sing() after breaking out of a range loop = %v, want E4", pitch.String())
+	}
+}
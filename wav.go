@@ -0,0 +1,117 @@
+package tonacity
+
+import (
+	"bufio"
+	"encoding/binary"
+	"fmt"
+	"io"
+	"math"
+	"os"
+)
+
+// WavFormat The encoding of samples in a WAV file.
+type WavFormat int
+
+const (
+	// WavPCM16 16-bit signed integer samples, as on a CD.
+	WavPCM16 WavFormat = iota
+	// WavPCM24 24-bit signed integer samples.
+	WavPCM24
+	// WavFloat32 32-bit IEEE floating point samples.
+	WavFloat32
+)
+
+const (
+	wavFormatTagPCM   = 1
+	wavFormatTagFloat = 3
+)
+
+// bytesPerSample The number of bytes each sample takes in the given format.
+func (f WavFormat) bytesPerSample() int {
+	switch f {
+	case WavPCM16:
+		return 2
+	case WavPCM24:
+		return 3
+	default:
+		return 4
+	}
+}
+
+// WriteWav Writes the given mono samples, which should be between -1 and 1, as a WAV file. Integer formats clip anything outside that
+// range; the float format stores samples as they are.
+func WriteWav(w io.Writer, samples []float64, sampleRate int, format WavFormat) error {
+	if format < WavPCM16 || format > WavFloat32 {
+		return fmt.Errorf("unknown WAV format %d", format)
+	}
+	bytesPerSample := format.bytesPerSample()
+	dataSize := len(samples) * bytesPerSample
+	tag := uint16(wavFormatTagPCM)
+	if format == WavFloat32 {
+		tag = wavFormatTagFloat
+	}
+
+	bw := bufio.NewWriter(w)
+	header := []interface{}{
+		[4]byte{'R', 'I', 'F', 'F'},
+		uint32(36 + dataSize + dataSize%2),
+		[4]byte{'W', 'A', 'V', 'E'},
+		[4]byte{'f', 'm', 't', ' '},
+		uint32(16),
+		tag,
+		uint16(1), // Channels
+		uint32(sampleRate),
+		uint32(sampleRate * bytesPerSample), // Bytes per second
+		uint16(bytesPerSample),              // Block alignment
+		uint16(bytesPerSample * 8),          // Bits per sample
+		[4]byte{'d', 'a', 't', 'a'},
+		uint32(dataSize),
+	}
+	for _, v := range header {
+		if err := binary.Write(bw, binary.LittleEndian, v); err != nil {
+			return err
+		}
+	}
+
+	buffer := make([]byte, bytesPerSample)
+	for _, s := range samples {
+		switch format {
+		case WavPCM16:
+			binary.LittleEndian.PutUint16(buffer, uint16(int16(quantise(s, math.MaxInt16))))
+		case WavPCM24:
+			v := quantise(s, 1<<23-1)
+			buffer[0], buffer[1], buffer[2] = byte(v), byte(v>>8), byte(v>>16)
+		case WavFloat32:
+			binary.LittleEndian.PutUint32(buffer, math.Float32bits(float32(s)))
+		}
+		if _, err := bw.Write(buffer); err != nil {
+			return err
+		}
+	}
+	// Chunks must be an even number of bytes long, which an odd number of 24-bit samples isn't
+	if dataSize%2 == 1 {
+		if err := bw.WriteByte(0); err != nil {
+			return err
+		}
+	}
+	return bw.Flush()
+}
+
+// quantise Scales a sample between -1 and 1 to an integer between -max and max, clipping if it is out of range.
+func quantise(sample float64, max int32) int32 {
+	sample = math.Max(-1, math.Min(1, sample))
+	return int32(math.Round(sample * float64(max)))
+}
+
+// WriteWavFile Writes the given mono samples to a WAV file at the given path, replacing it if it already exists.
+func WriteWavFile(path string, samples []float64, sampleRate int, format WavFormat) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err = WriteWav(f, samples, sampleRate, format); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
@@ -0,0 +1,61 @@
+package tonacity
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"testing"
+)
+
+func TestWriteWav(t *testing.T) {
+	samples := []float64{0, 1, -1, 2, 0.5}
+	tests := []struct {
+		name         string
+		format       WavFormat
+		wantTag      uint16
+		wantBits     uint16
+		wantData     []byte
+		wantFileSize int
+	}{
+		{"16-bit", WavPCM16, 1, 16, []byte{0, 0, 0xff, 0x7f, 0x01, 0x80, 0xff, 0x7f, 0x00, 0x40}, 54},
+		{"24-bit", WavPCM24, 1, 24, []byte{0, 0, 0, 0xff, 0xff, 0x7f, 0x01, 0x00, 0x80, 0xff, 0xff, 0x7f, 0x00, 0x00, 0x40}, 60},
+		{"Float", WavFloat32, 3, 32, nil, 64},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var buf bytes.Buffer
+			if err := WriteWav(&buf, samples, 8000, tt.format); err != nil {
+				t.Fatal(err)
+			}
+			b := buf.Bytes()
+			if len(b) != tt.wantFileSize {
+				t.Fatalf("WriteWav() wrote %d bytes, want %d", len(b), tt.wantFileSize)
+			}
+			if string(b[0:4]) != "RIFF" || string(b[8:16]) != "WAVEfmt " || string(b[36:40]) != "data" {
+				t.Errorf("WriteWav() header = %q", b[:44])
+			}
+			if got := binary.LittleEndian.Uint32(b[4:8]); int(got) != len(b)-8 {
+				t.Errorf("WriteWav() RIFF size = %d, want %d", got, len(b)-8)
+			}
+			if got := binary.LittleEndian.Uint16(b[20:22]); got != tt.wantTag {
+				t.Errorf("WriteWav() format tag = %d, want %d", got, tt.wantTag)
+			}
+			if got := binary.LittleEndian.Uint32(b[24:28]); got != 8000 {
+				t.Errorf("WriteWav() sample rate = %d, want 8000", got)
+			}
+			if got := binary.LittleEndian.Uint16(b[34:36]); got != tt.wantBits {
+				t.Errorf("WriteWav() bits per sample = %d, want %d", got, tt.wantBits)
+			}
+			if tt.wantData != nil && !bytes.Equal(b[44:44+len(tt.wantData)], tt.wantData) {
+				t.Errorf("WriteWav() data = %x, want %x", b[44:], tt.wantData)
+			}
+			if tt.format == WavFloat32 {
+				for i, want := range samples {
+					if got := math.Float32frombits(binary.LittleEndian.Uint32(b[44+4*i:])); float64(got) != want {
+						t.Errorf("WriteWav() sample %d = %v, want %v", i, got, want)
+					}
+				}
+			}
+		})
+	}
+}